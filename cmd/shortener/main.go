@@ -12,12 +12,12 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/spitfy/urlshortener/internal/audit"
 	"github.com/spitfy/urlshortener/internal/logger"
 	"github.com/spitfy/urlshortener/internal/repository"
 
 	_ "github.com/spitfy/urlshortener/docs"
 	"github.com/spitfy/urlshortener/internal/config"
+	"github.com/spitfy/urlshortener/internal/geoip"
 	"github.com/spitfy/urlshortener/internal/handler"
 	"github.com/spitfy/urlshortener/internal/service"
 )
@@ -30,7 +30,9 @@ var (
 
 // @title URL Shortener API
 // @version 1.0
-// @description API сервиса для сокращения URL-ссылок
+// @description API сервиса для сокращения URL-ссылок.
+// @description Эндпоинты /api/v2 отвечают данными в конверте {"data": ...}, списки — страницами с курсором;
+// @description остальные эндпоинты (API v1) сохраняют прежний формат ответов.
 
 // @contact.name API Support
 // @contact.url http://example.com/support
@@ -53,10 +55,12 @@ var (
 
 func main() {
 	var (
-		httpServer *http.Server
+		httpServer *handler.HTTPServer
 		grpcServer *handler.GRPCServer
+		geo        *geoip.DB
 		err        error
 		quit       = make(chan os.Signal, 1)
+		hup        = make(chan os.Signal, 1)
 		serverErr  = make(chan error, 1)
 	)
 
@@ -69,12 +73,7 @@ func main() {
 	authManager := auth.New(cfg.Auth.SecretKey)
 	s := service.NewService(*cfg, store)
 
-	if cfg.Audit.AuditFile != "" {
-		s.AddObserver(audit.NewFileObserver(cfg.Audit.AuditFile))
-	}
-	if cfg.Audit.AuditURL != "" {
-		s.AddObserver(audit.NewHTTPObserver(cfg.Audit.AuditURL))
-	}
+	s.SetObservers(auditObservers(cfg))
 
 	l, err := logger.Initialize(cfg.Logger.LogLevel)
 	if err != nil {
@@ -91,6 +90,17 @@ func main() {
 		log.Fatal("Server is nil after run()")
 	}
 
+	// База GeoIP открывается после остальных проверок запуска: log.Fatal
+	// не выполняет отложенные вызовы, и база осталась бы незакрытой.
+	if cfg.Service.GeoIPDB != "" {
+		if geo, err = geoip.Open(cfg.Service.GeoIPDB); err != nil {
+			store.Close()
+			log.Fatal(err)
+		}
+		defer geo.Close()
+		s.SetGeoIP(geo)
+	}
+
 	fmt.Printf("Build version: %s\n", buildVersion)
 	fmt.Printf("Build date: %s\n", buildDate)
 	fmt.Printf("Build commit: %s\n", buildCommit)
@@ -101,20 +111,12 @@ func main() {
 		}
 	}()
 
+	enableHTTPS := cfg.Handlers.EnableHTTPS
 	go func() {
 		var serveErr error
-		if cfg.Handlers.EnableHTTPS {
-			certFile, err := handler.CertPath(cfg.Handlers.CertFile)
-			if err != nil {
-				serverErr <- fmt.Errorf("certificate file not readable: %s — %w", certFile, err)
-				return
-			}
-			keyFile, err := handler.CertPath(cfg.Handlers.KeyFile)
-			if err != nil {
-				serverErr <- fmt.Errorf("key file not readable: %s — %w", keyFile, err)
-				return
-			}
-			serveErr = httpServer.ListenAndServeTLS(certFile, keyFile)
+		if enableHTTPS {
+			// Сертификат отдается через TLSConfig.GetCertificate и обновляется по SIGHUP.
+			serveErr = httpServer.ListenAndServeTLS("", "")
 		} else {
 			serveErr = httpServer.ListenAndServe()
 		}
@@ -125,38 +127,56 @@ func main() {
 	}()
 
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-hup:
+			log.Println("Received SIGHUP. Reloading config...")
+			cfg = reload(cfg, l, httpServer, grpcServer, s)
+			continue
+		case sig := <-quit:
+			log.Printf("Received signal: %v. Starting graceful shutdown...", sig)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err = httpServer.Shutdown(ctx); err != nil {
+				log.Printf("HTTP Server forced to shutdown: %v", err)
+			}
 
-	select {
-	case sig := <-quit:
-		log.Printf("Received signal: %v. Starting graceful shutdown...", sig)
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		if err = httpServer.Shutdown(ctx); err != nil {
-			log.Printf("HTTP Server forced to shutdown: %v", err)
-		}
+			if err = grpcServer.Shutdown(ctx); err != nil {
+				log.Printf("gRPC Server forced to shutdown: %v", err)
+			}
 
-		if err = grpcServer.Shutdown(ctx); err != nil {
-			log.Printf("gRPC Server forced to shutdown: %v", err)
-		}
+			if err = s.Shutdown(ctx); err != nil {
+				log.Printf("Pending deletions left in queue: %v", err)
+			}
 
-		store.Close()
-		log.Println("Server exited properly")
+			store.Close()
+			log.Println("Server exited properly")
 
-	case err := <-serverErr:
-		log.Printf("Server failed: %v", err)
+		case err := <-serverErr:
+			log.Printf("Server failed: %v", err)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if shutdownErr := httpServer.Shutdown(ctx); shutdownErr != nil {
-			log.Printf("Error during emergency shutdown: %v", shutdownErr)
-		}
-		if grpcServer != nil {
-			if shutdownErr := grpcServer.Shutdown(ctx); shutdownErr != nil {
-				log.Printf("Error during gRPC emergency shutdown: %v", shutdownErr)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if shutdownErr := httpServer.Shutdown(ctx); shutdownErr != nil {
+				log.Printf("Error during emergency shutdown: %v", shutdownErr)
 			}
+			if grpcServer != nil {
+				if shutdownErr := grpcServer.Shutdown(ctx); shutdownErr != nil {
+					log.Printf("Error during gRPC emergency shutdown: %v", shutdownErr)
+				}
+			}
+			if shutdownErr := s.Shutdown(ctx); shutdownErr != nil {
+				log.Printf("Pending deletions left in queue: %v", shutdownErr)
+			}
+			store.Close()
+			if geo != nil {
+				geo.Close()
+			}
+			os.Exit(1)
 		}
-		store.Close()
-		os.Exit(1)
+		return
 	}
 }
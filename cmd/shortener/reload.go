@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/config"
+	"github.com/spitfy/urlshortener/internal/handler"
+	"github.com/spitfy/urlshortener/internal/logger"
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// auditObservers создает наблюдателей аудита, заданных в конфигурации.
+func auditObservers(cfg *config.Config) []audit.Observer {
+	var observers []audit.Observer
+	if cfg.Audit.AuditFile != "" {
+		observers = append(observers, audit.NewFileObserver(cfg.Audit.AuditFile))
+	}
+	if cfg.Audit.AuditURL != "" {
+		observers = append(observers, audit.NewHTTPObserver(cfg.Audit.AuditURL))
+	}
+	return observers
+}
+
+// reload перечитывает конфигурацию (по SIGHUP) и применяет параметры, которые
+// не требуют перезапуска: уровень логирования, доверенные подсети и прокси,
+// наблюдателей аудита и TLS-сертификат. Об остальных изменениях выводится предупреждение.
+// Если новая конфигурация некорректна, продолжает действовать текущая.
+// Возвращает действующую после перечитывания конфигурацию.
+func reload(cur *config.Config, l *logger.Logger, srv *handler.HTTPServer, grpcSrv *handler.GRPCServer, s *service.Service) *config.Config {
+	next, _, err := config.Load(os.Args[1:], nil)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		log.Printf("Config reload failed, keeping current config: %v", err)
+		return cur
+	}
+
+	for _, key := range cur.NonReloadableChanges(*next) {
+		log.Printf("Config reload: %s changed, restart required to apply", key)
+	}
+
+	applied := *cur
+	if err := l.SetLevel(next.Logger.LogLevel); err != nil {
+		log.Printf("Config reload: log_level: %v", err)
+	} else {
+		applied.Logger = next.Logger
+	}
+	if err := srv.Reload(*next); err != nil {
+		log.Printf("Config reload: %v", err)
+	} else {
+		applied.Handlers.TrustedSubnet = next.Handlers.TrustedSubnet
+		applied.Handlers.TrustedProxies = next.Handlers.TrustedProxies
+		applied.Handlers.CertFile = next.Handlers.CertFile
+		applied.Handlers.KeyFile = next.Handlers.KeyFile
+	}
+	if err := grpcSrv.Reload(*next); err != nil {
+		log.Printf("Config reload: gRPC: %v", err)
+	}
+	s.SetObservers(auditObservers(next))
+	applied.Audit = next.Audit
+
+	log.Println("Config reloaded")
+	return &applied
+}
@@ -45,6 +45,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -57,13 +63,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Некорректный запрос",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "401": {
                         "description": "Неавторизованный доступ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "409": {
@@ -97,6 +103,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.Request"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -107,15 +119,21 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректный запрос или неизвестный домен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "401": {
                         "description": "Неавторизованный доступ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Домен недоступен пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "409": {
@@ -127,7 +145,7 @@ const docTemplate = `{
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     }
                 }
@@ -135,7 +153,7 @@ const docTemplate = `{
         },
         "/api/shorten/batch": {
             "post": {
-                "description": "Создает несколько сокращенных URL из пакетного запроса",
+                "description": "Создает несколько сокращенных URL из пакетного запроса за одно обращение к хранилищу.\nДля каждого элемента возвращается статус: created, existing или invalid.\nВ режиме atomic (по умолчанию) пакет с невалидными элементами отклоняется целиком,\nв режиме best-effort сохраняются только валидные элементы.",
                 "consumes": [
                     "application/json"
                 ],
@@ -158,11 +176,27 @@ const docTemplate = `{
                                 "$ref": "#/definitions/model.BatchCreateRequest"
                             }
                         }
+                    },
+                    {
+                        "enum": [
+                            "atomic",
+                            "best-effort"
+                        ],
+                        "type": "string",
+                        "description": "Режим обработки",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Список созданных сокращенных URL",
+                        "description": "Результаты по каждому элементу",
                         "schema": {
                             "type": "array",
                             "items": {
@@ -171,68 +205,130 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректный запрос или список невалидных элементов",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BatchCreateResponse"
+                            }
                         }
                     },
                     "401": {
                         "description": "Неавторизованный доступ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с тем же ключом идемпотентности еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Превышен допустимый размер пакета",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/user/urls": {
+        "/api/user/deletions/{id}": {
             "get": {
-                "description": "Возвращает все сокращенные URL, созданные текущим пользователем",
+                "description": "Возвращает состояние задания на удаление URL текущего пользователя",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "User"
                 ],
-                "summary": "Получить URL пользователя",
+                "summary": "Статус удаления",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Идентификатор задания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "Список сокращенных URL",
+                        "description": "Состояние задания",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.URL"
-                            }
+                            "$ref": "#/definitions/model.DeletionJob"
                         }
                     },
-                    "204": {
-                        "description": "Нет сохраненных URL",
+                    "400": {
+                        "description": "Некорректный идентификатор",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "401": {
                         "description": "Неавторизованный доступ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Задание не найдено",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/experiments": {
+            "get": {
+                "description": "Возвращает эксперименты текущего пользователя (новые первыми) с числом перенаправлений на варианты",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Experiments"
+                ],
+                "summary": "Список A/B-экспериментов",
+                "responses": {
+                    "200": {
+                        "description": "Эксперименты пользователя",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Experiment"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Помечает указанные URL как удаленные (асинхронно)",
+            "post": {
+                "description": "Создает короткую ссылку, распределяющую посетителей между 2–5 вариантами по весам.\nВариант закрепляется за посетителем cookie vid; перенаправления на каждый вариант подсчитываются.",
                 "consumes": [
                     "application/json"
                 ],
@@ -240,143 +336,2425 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "URL"
+                    "Experiments"
                 ],
-                "summary": "Удалить URL",
+                "summary": "Создать A/B-эксперимент",
                 "parameters": [
                     {
-                        "description": "Список хешей URL для удаления",
+                        "description": "Параметры эксперимента",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.ExperimentRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Запрос на удаление принят",
+                    "201": {
+                        "description": "Созданный эксперимент",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Experiment"
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректный запрос или неизвестный домен",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "401": {
                         "description": "Неавторизованный доступ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Домен недоступен пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
                         }
                     }
                 }
             }
         },
-        "/ping": {
+        "/api/user/experiments/{id}": {
             "get": {
-                "description": "Проверяет, что сервер работает и доступен",
+                "description": "Возвращает эксперимент текущего пользователя с числом перенаправлений на каждый вариант",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Health"
+                    "Experiments"
+                ],
+                "summary": "Результаты A/B-эксперимента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Идентификатор эксперимента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Проверить доступность сервера",
                 "responses": {
                     "200": {
-                        "description": "Сервер доступен",
+                        "description": "Эксперимент и его результаты",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Experiment"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный идентификатор",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Эксперимент не найден",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "500": {
-                        "description": "Сервер недоступен",
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     }
                 }
             }
         },
-        "/{hash}": {
+        "/api/user/stream": {
             "get": {
-                "description": "Перенаправляет на оригинальный URL по сокращенному хешу",
+                "description": "Передает в реальном времени события ссылок текущего пользователя: created, clicked, deleted, expired.\nКаждое событие отправляется как \"event: \u003cсобытие\u003e\" и \"data: \u003cmodel.StreamEvent в JSON\u003e\".\nПри отсутствии событий каждые 15 секунд отправляется событие heartbeat. Клиент, не успевающий\nполучать события, отключается событием closed и должен переподключиться.",
+                "produces": [
+                    "text/event-stream"
+                ],
                 "tags": [
-                    "URL"
+                    "Streams"
                 ],
-                "summary": "Получить оригинальный URL",
+                "summary": "Поток событий ссылок (SSE)",
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/model.StreamEvent"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Открыто слишком много потоков",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер останавливается",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/stream/ws": {
+            "get": {
+                "description": "Передает те же события, что и /api/user/stream, текстовыми сообщениями с model.StreamEvent в JSON.\nСообщения клиента игнорируются. Соединения со сторонних сайтов (заголовок Origin) отклоняются.",
+                "tags": [
+                    "Streams"
+                ],
+                "summary": "Поток событий ссылок (WebSocket)",
+                "responses": {
+                    "101": {
+                        "description": "Переключение на WebSocket",
+                        "schema": {
+                            "$ref": "#/definitions/model.StreamEvent"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Недопустимый Origin",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Открыто слишком много потоков",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер останавливается",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls": {
+            "get": {
+                "description": "Возвращает сокращенные URL, созданные текущим пользователем, с поиском,\nфильтрами по тегам и недоступности адреса назначения и сортировкой. Удаленные ссылки не возвращаются.\nЕсли есть следующая страница, ее курсор передается в заголовке X-Next-Cursor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Получить URL пользователя",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Хеш сокращенного URL",
-                        "name": "hash",
-                        "in": "path",
-                        "required": true
+                        "description": "Слова для поиска в заголовке, заметке и оригинальном URL",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Теги, которые должны быть у ссылки (все перечисленные)",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created",
+                            "clicks"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "desc",
+                            "asc"
+                        ],
+                        "type": "string",
+                        "description": "Порядок сортировки",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (1..1000, по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из заголовка X-Next-Cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только ссылки, адрес назначения которых недоступен по результатам последней проверки",
+                        "name": "broken",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "307": {
-                        "description": "Перенаправление на оригинальный URL",
+                    "200": {
+                        "description": "Список сокращенных URL",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LinkPair"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Курсор следующей страницы"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "Нет подходящих URL",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректные параметры выборки",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
                     "401": {
                         "description": "Неавторизованный доступ",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     },
-                    "410": {
-                        "description": "URL был удален",
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Problem"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "model.BatchCreateRequest": {
-            "type": "object",
-            "properties": {
-                "correlation_id": {
-                    "description": "Идентификатор для сопоставления с ответом",
-                    "type": "string"
-                },
-                "original_url": {
-                    "description": "Оригинальный URL для сокращения",
-                    "type": "string"
-                }
-            }
-        },
-        "model.BatchCreateResponse": {
-            "type": "object",
-            "properties": {
-                "correlation_id": {
-                    "description": "Идентификатор из запроса",
-                    "type": "string"
-                },
+            },
+            "delete": {
+                "description": "Ставит задание на удаление указанных URL в постоянную очередь (асинхронно).\nСтатус задания доступен по адресу из заголовка Location.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Удалить URL",
+                "parameters": [
+                    {
+                        "description": "Список хешей URL для удаления",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылок; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задание на удаление принято",
+                        "schema": {
+                            "$ref": "#/definitions/model.DeletionJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/restore": {
+            "post": {
+                "description": "Восстанавливает удаленные ссылки текущего пользователя, если срок восстановления не истек.\nСсылка не восстанавливается, если ее оригинальный URL уже сокращен заново.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Восстановить URL",
+                "parameters": [
+                    {
+                        "description": "Список хешей URL для восстановления",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылок; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Результат восстановления по каждой ссылке",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.RestoreResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{hash}": {
+            "patch": {
+                "description": "Изменяет адрес назначения, заголовок, теги и параметры перенаправления ссылки.\nПредыдущее состояние сохраняется в истории изменений.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Редактировать ссылку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменяемые поля",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkUpdate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылок; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка после изменения",
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Новый адрес уже был сокращен ранее",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{hash}/history": {
+            "get": {
+                "description": "Возвращает предыдущие состояния ссылки, начиная с последнего изменения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "История изменений ссылки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылок; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "История изменений",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LinkRevision"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{hash}/qr": {
+            "get": {
+                "description": "Возвращает QR-код полного сокращенного URL ссылки текущего пользователя.\nОтвет кэшируется по ETag: при совпадении If-None-Match возвращается 304.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "QR-код ссылки пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылок; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Формат изображения: png (по умолчанию) или svg",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер изображения в пикселях (64..2048, по умолчанию 256)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Свободная зона в модулях (0..16, по умолчанию 4)",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Уровень коррекции ошибок: L, M (по умолчанию), Q или H",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Цвет модулей в формате RRGGBB (по умолчанию 000000)",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Цвет фона в формате RRGGBB (по умолчанию ffffff)",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение QR-кода",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Изображение не изменилось",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/webhooks": {
+            "get": {
+                "description": "Возвращает подписки текущего пользователя в порядке создания (без ключей подписи)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Список подписок на события",
+                "responses": {
+                    "200": {
+                        "description": "Подписки пользователя",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создает подписку текущего пользователя на события его ссылок: created, clicked, deleted, expired.\nСобытия отправляются POST-запросом с телом model.WebhookEvent и заголовками X-Webhook-Event,\nX-Webhook-Delivery, X-Webhook-Timestamp и X-Webhook-Signature (sha256=HMAC-SHA256 ключа подписи\nот строки \"\u003ctimestamp\u003e.\u003cтело\u003e\"). Неудачные доставки повторяются с экспоненциальной задержкой.\nКлюч подписи возвращается только в этом ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Подписаться на события ссылок",
+                "parameters": [
+                    {
+                        "description": "Параметры подписки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная подписка с ключом подписи",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/webhooks/{id}": {
+            "get": {
+                "description": "Возвращает подписку текущего пользователя (без ключа подписи)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Подписка на события",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный идентификатор",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет подписку текущего пользователя вместе с журналом доставок",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Удалить подписку на события",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Подписка удалена"
+                    },
+                    "400": {
+                        "description": "Некорректный идентификатор",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Изменяет заданные поля подписки: адрес, события, ключ подписи, включение доставки.\nНовый ключ подписи возвращается в ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Изменить подписку на события",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменяемые поля",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Измененная подписка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Возвращает последние 100 доставок событий подписке (новые первыми):\nстатус, число попыток, ответ получателя и время следующей попытки.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Журнал доставок событий",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Идентификатор подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Доставки событий",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный идентификатор",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/links": {
+            "get": {
+                "description": "Возвращает ссылки текущего пользователя с поиском, фильтрами и сортировкой.\nКурсор следующей страницы передается в page.next_cursor; пустой список — пустой массив data.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "Список ссылок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Слова для поиска в заголовке, заметке и оригинальном URL",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Теги, которые должны быть у ссылки (все перечисленные)",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created",
+                            "clicks"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "desc",
+                            "asc"
+                        ],
+                        "type": "string",
+                        "description": "Порядок сортировки",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (1..1000, по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из page.next_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только ссылки, адрес назначения которых недоступен по результатам последней проверки",
+                        "name": "broken",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница ссылок",
+                        "schema": {
+                            "$ref": "#/definitions/model.PageEnvelope-model_LinkResource"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры выборки",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создает ссылку и возвращает ее со всеми метаданными. Если URL уже сокращен,\nвозвращается существующая ссылка со статусом 200; метаданные чужой ссылки не раскрываются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "Создать ссылку",
+                "parameters": [
+                    {
+                        "description": "Запрос на сокращение URL",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Request"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL уже был сокращен ранее",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-model_LinkResource"
+                        }
+                    },
+                    "201": {
+                        "description": "Создана новая ссылка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-model_LinkResource"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес ссылки в API v2"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или неизвестный домен",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Домен недоступен пользователю",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с тем же ключом идемпотентности еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/links/batch": {
+            "post": {
+                "description": "Создает несколько ссылок за одно обращение к хранилищу.\nДля каждого элемента возвращается статус: created, existing или invalid.\nВ режиме atomic (по умолчанию) пакет с невалидными элементами отклоняется целиком:\nневалидные элементы перечисляются в поле items ответа об ошибке.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "Пакетное создание ссылок",
+                "parameters": [
+                    {
+                        "description": "Ссылки для сокращения и режим обработки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BatchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Результаты по каждому элементу",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-array_model_BatchCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или пакет с невалидными элементами",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с тем же ключом идемпотентности еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Превышен допустимый размер пакета",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/links/{id}": {
+            "get": {
+                "description": "Возвращает ссылку текущего пользователя со всеми метаданными",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "Получить ссылку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор (хеш) ссылки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылки; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-model_LinkResource"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Ставит задание на удаление ссылки в очередь (асинхронно).\nСтатус задания доступен по адресу из заголовка Location.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "Удалить ссылку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор (хеш) ссылки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылки; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Задание на удаление принято",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-model_DeletionJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес статуса задания"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Изменяет адрес назначения, метаданные и параметры перенаправления ссылки.\nПредыдущее состояние сохраняется в истории изменений.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "Редактировать ссылку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор (хеш) ссылки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменяемые поля",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkUpdate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылки; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка после изменения",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-model_LinkResource"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Новый адрес уже был сокращен ранее",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/links/{id}/history": {
+            "get": {
+                "description": "Возвращает предыдущие состояния ссылки, начиная с последнего изменения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links v2"
+                ],
+                "summary": "История изменений ссылки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор (хеш) ссылки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Домен ссылки; по умолчанию домен сервиса",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "История изменений",
+                        "schema": {
+                            "$ref": "#/definitions/model.Envelope-array_model_LinkRevision"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизованный доступ",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
+            "get": {
+                "description": "Проверяет, что сервер работает и доступен",
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Проверить доступность сервера",
+                "responses": {
+                    "200": {
+                        "description": "Сервер доступен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{hash}": {
+            "get": {
+                "description": "Перенаправляет на оригинальный URL по сокращенному хешу.\nКод ответа, передача query-параметров и UTM-метки задаются параметрами ссылки.\nСсылка ищется в домене из заголовка Host; для неизвестного хеша домен может\nперенаправлять (302) на адрес-заглушку.\nАдрес назначения выбирается правилами ссылки (платформа, язык, страна, время, доля\nпосетителей); если ни одно правило не подходит, используется оригинальный URL.\nСсылка A/B-эксперимента ведет на вариант, закрепленный за посетителем cookie vid.\nДля защищенной паролем ссылки пароль передается в заголовке X-Link-Password;\nбез него возвращается HTML-форма ввода пароля.",
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Получить оригинальный URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пароль защищенной ссылки",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "301": {
+                        "description": "Постоянное перенаправление (если задано для ссылки)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "Найдено (если задано для ссылки)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "307": {
+                        "description": "Перенаправление на оригинальный URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "308": {
+                        "description": "Постоянное перенаправление (если задано для ссылки)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Ссылка защищена паролем (HTML-форма)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль (HTML-форма)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "URL не найден",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "URL был удален или исчерпал лимит переходов",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неверных паролей",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Принимает пароль из HTML-формы и перенаправляет (303) на оригинальный URL.\nQuery-параметры запроса передаются так же, как при GET-переходе.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Переход по защищенной ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пароль ссылки",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Перенаправление на оригинальный URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Пароль не указан (HTML-форма)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль (HTML-форма)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Ссылка не защищена паролем",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "URL был удален или исчерпал лимит переходов",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неверных паролей",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{hash}+": {
+            "get": {
+                "description": "Показывает HTML-страницу с адресом назначения перед перенаправлением",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Предпросмотр ссылки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML-страница предпросмотра",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Ссылка защищена паролем (HTML-форма)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "URL был удален или исчерпал лимит переходов",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{hash}/qr": {
+            "get": {
+                "description": "Возвращает QR-код полного сокращенного URL. Ссылка ищется в домене из заголовка Host.\nОтвет кэшируется по ETag: при совпадении If-None-Match возвращается 304.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "QR-код ссылки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Хеш сокращенного URL",
+                        "name": "hash",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Формат изображения: png (по умолчанию) или svg",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер изображения в пикселях (64..2048, по умолчанию 256)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Свободная зона в модулях (0..16, по умолчанию 4)",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Уровень коррекции ошибок: L, M (по умолчанию), Q или H",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Цвет модулей в формате RRGGBB (по умолчанию 000000)",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Цвет фона в формате RRGGBB (по умолчанию ffffff)",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение QR-кода",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Изображение не изменилось",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "URL был удален или исчерпал лимит переходов",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.BatchCreateRequest": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "description": "Идентификатор для сопоставления с ответом",
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "Домен короткой ссылки; по умолчанию домен сервиса",
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "Допустимое число переходов; после него ссылка отвечает 410 Gone. 0 — без ограничения",
+                    "type": "integer"
+                },
+                "note": {
+                    "description": "Заметка владельца ссылки",
+                    "type": "string"
+                },
+                "original_url": {
+                    "description": "Оригинальный URL для сокращения",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Пароль, который нужно ввести перед переходом по ссылке",
+                    "type": "string"
+                },
+                "redirect": {
+                    "description": "Параметры перенаправления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Правила выбора адреса перенаправления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RouteRule"
+                    }
+                },
+                "tags": {
+                    "description": "Теги ссылки",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Заголовок ссылки; если не задан, может быть загружен со страницы назначения",
+                    "type": "string"
+                }
+            }
+        },
+        "model.BatchCreateResponse": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "description": "Идентификатор из запроса",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Причина отклонения невалидного элемента",
+                    "type": "string"
+                },
+                "short_url": {
+                    "description": "Сокращенный URL",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Статус элемента: created, existing или invalid",
+                    "type": "string"
+                }
+            }
+        },
+        "model.BatchMode": {
+            "type": "string",
+            "enum": [
+                "atomic",
+                "best-effort"
+            ],
+            "x-enum-varnames": [
+                "BatchAtomic",
+                "BatchBestEffort"
+            ]
+        },
+        "model.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Ссылки для сокращения",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchCreateRequest"
+                    }
+                },
+                "mode": {
+                    "description": "Режим обработки: atomic (по умолчанию) или best-effort",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BatchMode"
+                        }
+                    ]
+                }
+            }
+        },
+        "model.DeletionJob": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "description": "Время завершения задания",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "Время постановки задания в очередь",
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "Домен удаляемых ссылок; пустой для домена по умолчанию",
+                    "type": "string"
+                },
+                "hashes": {
+                    "description": "Хеши удаляемых ссылок",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "Идентификатор задания",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Статус задания: pending или done",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Идентификатор владельца ссылок",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Envelope-array_model_BatchCreateResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Данные ответа",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchCreateResponse"
+                    }
+                }
+            }
+        },
+        "model.Envelope-array_model_LinkRevision": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Данные ответа",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkRevision"
+                    }
+                }
+            }
+        },
+        "model.Envelope-model_DeletionJob": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeletionJob"
+                        }
+                    ]
+                }
+            }
+        },
+        "model.Envelope-model_LinkResource": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Данные ответа",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.LinkResource"
+                        }
+                    ]
+                }
+            }
+        },
+        "model.Experiment": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Время создания эксперимента",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Идентификатор эксперимента",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Название эксперимента",
+                    "type": "string"
+                },
+                "redirects": {
+                    "description": "Общее количество перенаправлений",
+                    "type": "integer"
+                },
+                "short_url": {
+                    "description": "Короткая ссылка эксперимента",
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Варианты эксперимента с числом перенаправлений",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Variant"
+                    }
+                }
+            }
+        },
+        "model.ExperimentRequest": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "description": "Домен короткой ссылки; по умолчанию домен сервиса",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Название эксперимента; становится заголовком ссылки",
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Варианты эксперимента (от 2 до 5)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Variant"
+                    }
+                }
+            }
+        },
+        "model.LinkHealth": {
+            "type": "object",
+            "properties": {
+                "broken": {
+                    "description": "Адрес недоступен: ответ не получен или получен статус 4xx/5xx (кроме 429)",
+                    "type": "boolean"
+                },
+                "checked_at": {
+                    "description": "Время проверки",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Ошибка запроса: адрес не разрешается, соединение не установлено и т. п.",
+                    "type": "string"
+                },
+                "redirects": {
+                    "description": "Адреса, на которые перенаправлял сервер назначения, по порядку",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "description": "HTTP-статус последнего ответа цепочки перенаправлений; 0, если ответ не получен",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.LinkMetadata": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "description": "Заметка владельца ссылки",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Теги ссылки",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Заголовок ссылки",
+                    "type": "string"
+                }
+            }
+        },
+        "model.LinkPair": {
+            "type": "object",
+            "properties": {
+                "broken": {
+                    "description": "Адрес назначения недоступен по результатам последней проверки",
+                    "type": "boolean"
+                },
+                "clicks": {
+                    "description": "Количество переходов по ссылке",
+                    "type": "integer"
+                },
+                "clicks_left": {
+                    "description": "Оставшееся число переходов; не задается для ссылок без ограничения",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "Время создания ссылки",
+                    "type": "string"
+                },
+                "experiment_id": {
+                    "description": "Идентификатор A/B-эксперимента, трафик которого распределяет ссылка",
+                    "type": "integer"
+                },
+                "health": {
+                    "description": "Результат последней проверки доступности адреса назначения; не задается, если проверки не было",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.LinkHealth"
+                        }
+                    ]
+                },
+                "max_clicks": {
+                    "description": "Допустимое число переходов по ссылке; не задается для ссылок без ограничения",
+                    "type": "integer"
+                },
+                "note": {
+                    "description": "Заметка владельца ссылки",
+                    "type": "string"
+                },
+                "original_url": {
+                    "description": "Оригинальный URL",
+                    "type": "string"
+                },
+                "protected": {
+                    "description": "Ссылка защищена паролем",
+                    "type": "boolean"
+                },
+                "redirect": {
+                    "description": "Параметры перенаправления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Правила выбора адреса перенаправления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RouteRule"
+                    }
+                },
+                "short_url": {
+                    "description": "Сокращенный URL",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Теги ссылки",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Заголовок ссылки",
+                    "type": "string"
+                }
+            }
+        },
+        "model.LinkResource": {
+            "type": "object",
+            "properties": {
+                "broken": {
+                    "description": "Адрес назначения недоступен по результатам последней проверки",
+                    "type": "boolean"
+                },
+                "clicks": {
+                    "description": "Количество переходов по ссылке",
+                    "type": "integer"
+                },
+                "clicks_left": {
+                    "description": "Оставшееся число переходов; не задается для ссылок без ограничения",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "Время создания ссылки",
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "Домен ссылки; пустой для домена по умолчанию",
+                    "type": "string"
+                },
+                "experiment_id": {
+                    "description": "Идентификатор A/B-эксперимента, трафик которого распределяет ссылка",
+                    "type": "integer"
+                },
+                "health": {
+                    "description": "Результат последней проверки доступности адреса назначения; не задается, если проверки не было",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.LinkHealth"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "Идентификатор ссылки — хеш сокращенного URL; уникален в пределах домена",
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "Допустимое число переходов по ссылке; не задается для ссылок без ограничения",
+                    "type": "integer"
+                },
+                "metadata": {
+                    "description": "Метаданные ссылки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.LinkMetadata"
+                        }
+                    ]
+                },
+                "original_url": {
+                    "description": "Оригинальный URL",
+                    "type": "string"
+                },
+                "protected": {
+                    "description": "Ссылка защищена паролем",
+                    "type": "boolean"
+                },
+                "redirect": {
+                    "description": "Параметры перенаправления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Правила выбора адреса перенаправления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RouteRule"
+                    }
+                },
                 "short_url": {
                     "description": "Сокращенный URL",
                     "type": "string"
                 }
             }
         },
+        "model.LinkRevision": {
+            "type": "object",
+            "properties": {
+                "edited_at": {
+                    "description": "Время редактирования",
+                    "type": "string"
+                },
+                "note": {
+                    "description": "Заметка до редактирования",
+                    "type": "string"
+                },
+                "original_url": {
+                    "description": "Оригинальный URL до редактирования",
+                    "type": "string"
+                },
+                "redirect": {
+                    "description": "Параметры перенаправления до редактирования",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "revision": {
+                    "description": "Порядковый номер ревизии",
+                    "type": "integer"
+                },
+                "rules": {
+                    "description": "Правила перенаправления до редактирования",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RouteRule"
+                    }
+                },
+                "tags": {
+                    "description": "Теги до редактирования",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Заголовок до редактирования",
+                    "type": "string"
+                }
+            }
+        },
+        "model.LinkUpdate": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "description": "Новая заметка",
+                    "type": "string"
+                },
+                "original_url": {
+                    "description": "Новый оригинальный URL",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Новый пароль ссылки; пустая строка снимает защиту паролем",
+                    "type": "string"
+                },
+                "redirect": {
+                    "description": "Новые параметры перенаправления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Новый список правил перенаправления; пустой список удаляет правила",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RouteRule"
+                    }
+                },
+                "tags": {
+                    "description": "Новый список тегов",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Новый заголовок",
+                    "type": "string"
+                }
+            }
+        },
+        "model.PageEnvelope-model_LinkResource": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Элементы страницы",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkResource"
+                    }
+                },
+                "page": {
+                    "description": "Параметры страницы",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.PageInfo"
+                        }
+                    ]
+                }
+            }
+        },
+        "model.PageInfo": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "description": "Размер страницы",
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "description": "Курсор следующей страницы; не задается для последней страницы",
+                    "type": "string"
+                }
+            }
+        },
+        "model.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Машинно-читаемый код ошибки, например invalid_url",
+                    "type": "string"
+                },
+                "detail": {
+                    "description": "Описание ошибки",
+                    "type": "string"
+                },
+                "items": {
+                    "description": "Невалидные элементы отклоненного пакета (API v2)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BatchCreateResponse"
+                    }
+                },
+                "status": {
+                    "description": "HTTP-статус ответа",
+                    "type": "integer"
+                },
+                "title": {
+                    "description": "Краткое описание статуса ответа",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "URI типа ошибки; \"about:blank\", если у ошибки нет кода",
+                    "type": "string"
+                }
+            }
+        },
+        "model.RedirectOptions": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "HTTP-код перенаправления: 301, 302, 307 или 308 (по умолчанию 307)",
+                    "type": "integer"
+                },
+                "pass_query": {
+                    "description": "Передавать query-параметры запроса в адрес перенаправления",
+                    "type": "boolean"
+                },
+                "utm": {
+                    "description": "UTM-метки, добавляемые к адресу перенаправления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.UTM"
+                        }
+                    ]
+                }
+            }
+        },
         "model.Request": {
             "type": "object",
             "properties": {
+                "domain": {
+                    "description": "Домен короткой ссылки; по умолчанию домен сервиса",
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "Допустимое число переходов; после него ссылка отвечает 410 Gone. 0 — без ограничения",
+                    "type": "integer"
+                },
+                "note": {
+                    "description": "Заметка владельца ссылки",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Пароль, который нужно ввести перед переходом по ссылке",
+                    "type": "string"
+                },
+                "redirect": {
+                    "description": "Параметры перенаправления",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "rules": {
+                    "description": "Правила выбора адреса перенаправления",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RouteRule"
+                    }
+                },
+                "tags": {
+                    "description": "Теги ссылки",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Заголовок ссылки; если не задан, может быть загружен со страницы назначения",
+                    "type": "string"
+                },
                 "url": {
                     "description": "Оригинальный URL для сокращения\nПример: \"https://example.com/very/long/url/to/be/shortened\"",
                     "type": "string"
@@ -392,15 +2770,303 @@ const docTemplate = `{
                 }
             }
         },
-        "model.URL": {
+        "model.RestoreResult": {
+            "type": "object",
+            "properties": {
+                "hash": {
+                    "description": "Хеш ссылки",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Статус: restored, not_found (ссылка не найдена, не удалена или срок восстановления истек)\nили conflict (оригинальный URL уже сокращен заново)",
+                    "type": "string"
+                }
+            }
+        },
+        "model.RouteRule": {
+            "type": "object",
+            "properties": {
+                "countries": {
+                    "description": "Страны посетителя (ISO 3166-1 alpha-2), определяемые по IP-адресу",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "languages": {
+                    "description": "Языки посетителя: сравниваются с наиболее предпочтительным языком из Accept-Language;\n\"en\" совпадает с \"en-GB\", \"en-GB\" — только с \"en-GB\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "percent": {
+                    "description": "Доля посетителей в процентах (1..100). Доли правил ссылки следуют друг за другом:\nправила с долями 30 и 20 получают 30% и следующие 20% посетителей.\nПосетитель с тем же IP-адресом и User-Agent всегда попадает в одну долю",
+                    "type": "integer"
+                },
+                "platforms": {
+                    "description": "Платформы посетителя: ios, android или desktop",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "time": {
+                    "description": "Период действия правила",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.TimeWindow"
+                        }
+                    ]
+                },
+                "url": {
+                    "description": "Адрес перенаправления при выполнении условий",
+                    "type": "string"
+                }
+            }
+        },
+        "model.StreamEvent": {
             "type": "object",
             "properties": {
+                "error": {
+                    "description": "Причина закрытия потока для события closed",
+                    "type": "string"
+                },
+                "event": {
+                    "description": "Событие: created, clicked, deleted, expired, heartbeat или closed",
+                    "type": "string"
+                },
                 "original_url": {
                     "description": "Оригинальный URL",
                     "type": "string"
                 },
                 "short_url": {
-                    "description": "Сокращенный URL",
+                    "description": "Короткая ссылка",
+                    "type": "string"
+                },
+                "ts": {
+                    "description": "Время события",
+                    "type": "string"
+                }
+            }
+        },
+        "model.TimeWindow": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "description": "Окончание действия правила (не включительно)",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "Начало ежедневного интервала в формате ЧЧ:ММ",
+                    "type": "string"
+                },
+                "location": {
+                    "description": "Часовой пояс ежедневного интервала (IANA); по умолчанию UTC",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "Начало действия правила",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "Конец ежедневного интервала в формате ЧЧ:ММ (не включительно); может быть меньше From",
+                    "type": "string"
+                }
+            }
+        },
+        "model.UTM": {
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "description": "utm_campaign",
+                    "type": "string"
+                },
+                "content": {
+                    "description": "utm_content",
+                    "type": "string"
+                },
+                "medium": {
+                    "description": "utm_medium",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "utm_source",
+                    "type": "string"
+                },
+                "term": {
+                    "description": "utm_term",
+                    "type": "string"
+                }
+            }
+        },
+        "model.Variant": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Название варианта; по умолчанию A, B, C и т. д. по порядку",
+                    "type": "string"
+                },
+                "redirects": {
+                    "description": "Количество перенаправлений на вариант; при создании эксперимента не задается",
+                    "type": "integer"
+                },
+                "url": {
+                    "description": "Адрес перенаправления варианта",
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Вес варианта (1..1000): доля посетителей пропорциональна весу. По умолчанию 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Доставка включена",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "description": "Время создания подписки",
+                    "type": "string"
+                },
+                "events": {
+                    "description": "События подписки",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "Идентификатор подписки",
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Ключ подписи; возвращается только при создании подписки и смене ключа",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "Адрес получателя событий",
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Количество выполненных попыток",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "Время создания события",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Ошибка последней попытки",
+                    "type": "string"
+                },
+                "event": {
+                    "description": "Событие",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Идентификатор доставки (заголовок X-Webhook-Delivery)",
+                    "type": "integer"
+                },
+                "last_attempt_at": {
+                    "description": "Время последней попытки",
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "description": "Время следующей попытки для ожидающих доставок",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Отправляемое тело запроса",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.WebhookEvent"
+                        }
+                    ]
+                },
+                "response_status": {
+                    "description": "Код ответа получателя на последнюю попытку",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Статус: pending, delivered или failed",
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookEvent": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Количество переходов с предыдущего события clicked",
+                    "type": "integer"
+                },
+                "event": {
+                    "description": "Событие: created, clicked, deleted или expired",
+                    "type": "string"
+                },
+                "original_url": {
+                    "description": "Оригинальный URL",
+                    "type": "string"
+                },
+                "short_url": {
+                    "description": "Короткая ссылка",
+                    "type": "string"
+                },
+                "ts": {
+                    "description": "Время события; для clicked — конец периода агрегирования",
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "description": "События подписки: created, clicked, deleted, expired; пустой список — все события",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Ключ подписи HMAC-SHA256; если не задан, генерируется сервисом",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "Адрес получателя событий (http или https)",
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookUpdate": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Включение и отключение доставки",
+                    "type": "boolean"
+                },
+                "events": {
+                    "description": "Новый список событий; пустой список — все события",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Новый ключ подписи",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "Новый адрес получателя",
                     "type": "string"
                 }
             }
@@ -427,7 +3093,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/",
 	Schemes:          []string{"http"},
 	Title:            "URL Shortener API",
-	Description:      "API сервиса для сокращения URL-ссылок",
+	Description:      "API сервиса для сокращения URL-ссылок.\nЭндпоинты /api/v2 отвечают данными в конверте {\"data\": ...}, списки — страницами с курсором;\nостальные эндпоинты (API v1) сохраняют прежний формат ответов.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
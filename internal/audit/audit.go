@@ -12,14 +12,28 @@ type Action string
 const (
 	Shorten Action = "shorten" // Действие: сокращение URL
 	Follow  Action = "follow"  // Действие: переход по сокращенному URL
+	Update  Action = "update"  // Действие: редактирование ссылки
+	Delete  Action = "delete"  // Действие: удаление ссылки
+	Expire  Action = "expire"  // Действие: исчерпание лимита переходов ссылки
 )
 
 // Event содержит информацию о событии для аудита
 type Event struct {
-	Timestamp time.Time `json:"ts"`      // Временная метка события
-	Action    Action    `json:"action"`  // Тип действия
-	UserID    int       `json:"user_id"` // ID пользователя
-	URL       string    `json:"url"`     // URL, к которому относится действие
+	Timestamp time.Time `json:"ts"`                  // Временная метка события
+	Action    Action    `json:"action"`              // Тип действия
+	UserID    int       `json:"user_id"`             // ID пользователя
+	URL       string    `json:"url"`                 // URL, к которому относится действие
+	IP        string    `json:"ip,omitempty"`        // IP-адрес клиента (см. пакет clientip)
+	OwnerID   int       `json:"owner_id,omitempty"`  // ID владельца ссылки, если действие выполнил другой пользователь
+	ShortURL  string    `json:"short_url,omitempty"` // Короткая ссылка, к которой относится действие
+}
+
+// Owner возвращает ID владельца ссылки, к которой относится событие.
+func (e Event) Owner() int {
+	if e.OwnerID != 0 {
+		return e.OwnerID
+	}
+	return e.UserID
 }
 
 // Observer определяет интерфейс для наблюдателей аудита
@@ -28,3 +42,11 @@ type Observer interface {
 	// Возвращает ошибку, если отправка не удалась
 	Notify(ctx context.Context, event Event) error
 }
+
+// ObserverFunc позволяет использовать функцию как Observer.
+type ObserverFunc func(ctx context.Context, event Event) error
+
+// Notify вызывает f(ctx, event).
+func (f ObserverFunc) Notify(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
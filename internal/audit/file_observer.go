@@ -39,6 +39,10 @@ func (o *FileObserver) Notify(_ context.Context, event Event) error {
 
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	_, err = file.WriteString(event.Timestamp.Format(time.RFC3339) + " " + string(event.Action) + " " + strconv.Itoa(event.UserID) + " " + event.URL + "\n")
+	line := event.Timestamp.Format(time.RFC3339) + " " + string(event.Action) + " " + strconv.Itoa(event.UserID) + " " + event.URL
+	if event.IP != "" {
+		line += " " + event.IP
+	}
+	_, err = file.WriteString(line + "\n")
 	return err
 }
@@ -2,5 +2,5 @@
 package config
 
 type Config struct {
-	SecretKey string
+	SecretKey string `env:"SECRET_KEY"`
 }
@@ -0,0 +1,225 @@
+// Package clientip определяет IP-адрес клиента с учетом доверенных прокси.
+//
+// Заголовки X-Forwarded-For, Forwarded и X-Real-IP учитываются только если
+// запрос пришел от доверенного прокси; иначе адресом клиента считается
+// адрес соединения (RemoteAddr). Найденный адрес сохраняется в контексте
+// запроса и используется логированием, аудитом и проверкой доступа.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// Networks — список подсетей IPv4/IPv6, который можно атомарно заменить во время работы.
+// Пустой список не содержит ни одного адреса.
+type Networks struct {
+	prefixes atomic.Pointer[[]netip.Prefix]
+}
+
+// ParseNetworks разбирает список подсетей в формате CIDR через запятую.
+// Одиночный адрес без маски означает подсеть из одного адреса.
+// Пример:
+//
+//	prefixes, err := clientip.ParseNetworks("10.0.0.0/8, 2001:db8::/32, 192.168.1.10")
+func ParseNetworks(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", item)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// NewNetworks создает Networks из списка подсетей (см. ParseNetworks).
+func NewNetworks(list string) (*Networks, error) {
+	n := &Networks{}
+	if err := n.Set(list); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Set заменяет список подсетей. При ошибке разбора текущий список сохраняется.
+func (n *Networks) Set(list string) error {
+	prefixes, err := ParseNetworks(list)
+	if err != nil {
+		return err
+	}
+	n.prefixes.Store(&prefixes)
+	return nil
+}
+
+// Empty сообщает, что список подсетей пуст.
+func (n *Networks) Empty() bool {
+	p := n.prefixes.Load()
+	return p == nil || len(*p) == 0
+}
+
+// Contains сообщает, входит ли addr хотя бы в одну из подсетей.
+func (n *Networks) Contains(addr netip.Addr) bool {
+	p := n.prefixes.Load()
+	if p == nil || !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range *p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver определяет адрес клиента по адресу соединения и заголовкам прокси.
+type Resolver struct {
+	proxies *Networks
+}
+
+// NewResolver создает Resolver, доверяющий заголовкам только от прокси из proxies.
+// Пример:
+//
+//	proxies, _ := clientip.NewNetworks("10.0.0.0/8")
+//	ip := clientip.NewResolver(proxies).Resolve(r)
+func NewResolver(proxies *Networks) *Resolver {
+	return &Resolver{proxies: proxies}
+}
+
+// Resolve возвращает адрес клиента HTTP-запроса.
+func (res *Resolver) Resolve(r *http.Request) netip.Addr {
+	return res.ResolveFrom(r.RemoteAddr, r.Header.Values)
+}
+
+// ResolveFrom возвращает адрес клиента по адресу соединения remoteAddr (host:port или IP)
+// и функции получения значений заголовка; используется и для метаданных gRPC.
+//
+// Если соединение установлено не доверенным прокси, заголовки игнорируются.
+// Иначе цепочка X-Forwarded-For (или Forwarded) просматривается справа налево,
+// и клиентом считается первый адрес не из доверенных прокси. При отсутствии
+// цепочки используется X-Real-IP.
+func (res *Resolver) ResolveFrom(remoteAddr string, header func(key string) []string) netip.Addr {
+	remote := parseHost(remoteAddr)
+	if res == nil || res.proxies == nil || !res.proxies.Contains(remote) {
+		return remote
+	}
+
+	chain := forwardedFor(header("Forwarded"))
+	if len(chain) == 0 {
+		chain = splitList(header("X-Forwarded-For"))
+	}
+	if len(chain) == 0 {
+		if realIP := header("X-Real-IP"); len(realIP) > 0 {
+			if addr := parseHost(strings.TrimSpace(realIP[0])); addr.IsValid() {
+				return addr
+			}
+		}
+		return remote
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr := parseHost(chain[i])
+		if !addr.IsValid() {
+			// Некорректное звено добавлено недоверенной стороной: клиентом
+			// считается последний проверенный адрес.
+			return client
+		}
+		client = addr
+		if !res.proxies.Contains(addr) {
+			return addr
+		}
+	}
+	return client
+}
+
+// splitList разбивает значения заголовка со списком через запятую.
+func splitList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// forwardedFor извлекает параметры for из заголовка Forwarded (RFC 7239).
+func forwardedFor(values []string) []string {
+	var items []string
+	for _, element := range splitList(values) {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				items = append(items, strings.Trim(value, `"`))
+			}
+		}
+	}
+	return items
+}
+
+// parseHost разбирает адрес вида IP, IP:port или [IPv6]:port.
+// Для некорректного адреса возвращает невалидный netip.Addr.
+func parseHost(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap().WithZone("")
+}
+
+type ctxKey struct{}
+
+// NewContext возвращает копию ctx с адресом клиента.
+func NewContext(ctx context.Context, addr netip.Addr) context.Context {
+	return context.WithValue(ctx, ctxKey{}, addr)
+}
+
+// FromContext возвращает адрес клиента, сохраненный в ctx.
+func FromContext(ctx context.Context) (netip.Addr, bool) {
+	addr, ok := ctx.Value(ctxKey{}).(netip.Addr)
+	return addr, ok && addr.IsValid()
+}
+
+// String возвращает адрес клиента из ctx строкой или пустую строку, если адрес неизвестен.
+func String(ctx context.Context) string {
+	if addr, ok := FromContext(ctx); ok {
+		return addr.String()
+	}
+	return ""
+}
+
+// Middleware определяет адрес клиента и сохраняет его в контексте запроса.
+func Middleware(res *Resolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), res.Resolve(r))))
+		})
+	}
+}
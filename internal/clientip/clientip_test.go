@@ -0,0 +1,93 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworks(t *testing.T) {
+	prefixes, err := ParseNetworks(" 10.0.0.0/8, 2001:db8::/32 ,192.168.1.10,, ::ffff:172.16.0.1 ")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("172.16.0.1/32"),
+	}, prefixes)
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0.0/8,bad"} {
+		_, err := ParseNetworks(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	n, err := NewNetworks("10.0.0.0/8, 2001:db8::/32")
+	require.NoError(t, err)
+	assert.True(t, n.Contains(netip.MustParseAddr("10.1.2.3")))
+	assert.True(t, n.Contains(netip.MustParseAddr("::ffff:10.1.2.3")), "IPv4-mapped address matches IPv4 subnet")
+	assert.True(t, n.Contains(netip.MustParseAddr("2001:db8::1")))
+	assert.False(t, n.Contains(netip.MustParseAddr("192.168.0.1")))
+	assert.False(t, n.Contains(netip.Addr{}))
+
+	require.Error(t, n.Set("bad"))
+	assert.True(t, n.Contains(netip.MustParseAddr("10.1.2.3")), "invalid list keeps the current one")
+	require.NoError(t, n.Set(""))
+	assert.True(t, n.Empty())
+	assert.True(t, (&Networks{}).Empty())
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	proxies, err := NewNetworks("10.0.0.0/8, fd00::/8")
+	require.NoError(t, err)
+	res := NewResolver(proxies)
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct client", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"spoofed headers from untrusted peer", "203.0.113.5:1234", map[string]string{
+			"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "1.1.1.1", "Forwarded": "for=1.1.1.1",
+		}, "203.0.113.5"},
+		{"x-forwarded-for chain", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"forwarded header", "10.0.0.1:1234", map[string]string{"Forwarded": `for=198.51.100.7;proto=https, for="[fd00::2]:4711"`}, "198.51.100.7"},
+		{"forwarded ipv6 client", "[fd00::1]:1234", map[string]string{"Forwarded": `for="[2001:db8::17]:4711"`}, "2001:db8::17"},
+		{"x-real-ip from proxy", "10.0.0.1:1234", map[string]string{"X-Real-IP": "198.51.100.7"}, "198.51.100.7"},
+		{"proxy without headers", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"all hops trusted", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"garbage hop", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "unknown, 10.0.0.2"}, "10.0.0.2"},
+		{"ipv4-mapped remote", "[::ffff:203.0.113.5]:1234", nil, "203.0.113.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, res.Resolve(r).String())
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var got string
+	h := Middleware(NewResolver(&Networks{}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = String(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "[2001:db8::1]:443"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "2001:db8::1", got)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, String(r.Context()))
+}
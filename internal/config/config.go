@@ -1,17 +1,30 @@
 // Package config предоставляет общую систему конфигурации приложения.
+//
+// Конфигурация собирается из нескольких источников; каждый следующий
+// источник переопределяет значения предыдущего:
+//
+//  1. значения по умолчанию (Default);
+//  2. файл конфигурации в формате JSON, YAML или TOML (флаг -c/-config или переменная CONFIG);
+//  3. переменные окружения;
+//  4. флаги командной строки (только явно заданные).
+//
+// Таким образом, приоритет: флаги > окружение > файл > значения по умолчанию.
 package config
 
 import (
 	"flag"
-	audit "github.com/spitfy/urlshortener/internal/audit/config"
-	"github.com/spitfy/urlshortener/internal/config/db"
-	storageConf "github.com/spitfy/urlshortener/internal/repository/config"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/caarlos0/env/v6"
+	audit "github.com/spitfy/urlshortener/internal/audit/config"
 	authConf "github.com/spitfy/urlshortener/internal/auth/config"
+	"github.com/spitfy/urlshortener/internal/config/db"
 	handlerConf "github.com/spitfy/urlshortener/internal/handler/config"
 	loggerConf "github.com/spitfy/urlshortener/internal/logger/config"
+	storageConf "github.com/spitfy/urlshortener/internal/repository/config"
 	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
 )
 
@@ -22,8 +35,10 @@ type Config struct {
 	FileStorage storageConf.Config
 	DB          db.Config
 	Auth        authConf.Config
-	SecretKey   string
 	Audit       audit.Config
+	// DedupeScope задает область уникальности оригинальных URL для любого
+	// хранилища: global, user или none.
+	DedupeScope string `env:"DEDUPE_SCOPE"`
 }
 
 const (
@@ -35,42 +50,166 @@ const (
 	DefaultFileStorageTest string = "/var/www/golang/yapracticum/go-advanced/urlshortener/storage/test.json"
 	DefaultDatabaseDsn     string = ""
 	DefaultHTTPS           bool   = false
+	DefaultHTTPSPort       string = "8443"
+	DefaultCertFile        string = "cert/cert.pem"
+	DefaultKeyFile         string = "cert/key.pem"
+	DefaultDedupeScope     string = "global"
 	SecretKey              string = "SecRetKey#!45"
 )
 
+const (
+	DefaultRetentionPeriod = 30 * 24 * time.Hour
+	DefaultPurgeInterval   = time.Hour
+	DefaultPurgeFreeHash   = false
+
+	DefaultHealthCheckInterval    = 0
+	DefaultHealthCheckConcurrency = 4
+	DefaultHealthCheckHostDelay   = time.Second
+
+	DefaultWebhookClickInterval = time.Minute
+
+	DefaultIdempotencyTTL = 24 * time.Hour
+)
+
+// Default возвращает конфигурацию со значениями по умолчанию.
+func Default() Config {
+	return Config{
+		Handlers: handlerConf.Config{
+			ServerAddr:  DefaultServerAddr,
+			GRPCAddr:    DefaultGRPCAddr,
+			EnableHTTPS: DefaultHTTPS,
+			HTTPSPort:   DefaultHTTPSPort,
+			CertFile:    DefaultCertFile,
+			KeyFile:     DefaultKeyFile,
+		},
+		Service: serviceConf.Config{
+			ServerURL:       DefaultServerURL,
+			RetentionPeriod: DefaultRetentionPeriod,
+			PurgeInterval:   DefaultPurgeInterval,
+			PurgeFreeHash:   DefaultPurgeFreeHash,
+
+			HealthCheckInterval:    DefaultHealthCheckInterval,
+			HealthCheckConcurrency: DefaultHealthCheckConcurrency,
+			HealthCheckHostDelay:   DefaultHealthCheckHostDelay,
+			WebhookClickInterval:   DefaultWebhookClickInterval,
+			IdempotencyTTL:         DefaultIdempotencyTTL,
+		},
+		Logger:      loggerConf.Config{LogLevel: DefaultLogLevel},
+		FileStorage: storageConf.Config{FileStoragePath: DefaultFileStorage},
+		DB:          db.Config{DatabaseDsn: DefaultDatabaseDsn},
+		Auth:        authConf.Config{SecretKey: SecretKey},
+		DedupeScope: DefaultDedupeScope,
+	}
+}
+
+// GetConfig загружает конфигурацию из аргументов командной строки, окружения
+// и файла конфигурации и проверяет ее. При ошибке завершает работу приложения.
+// С флагом --print-config выводит итоговую конфигурацию (секреты скрыты) и завершает работу.
 func GetConfig() *Config {
-	var (
-		conf = &Config{
-			Auth: authConf.Config{SecretKey: SecretKey},
+	conf, printConfig, err := Load(os.Args[1:], nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	verr := conf.Validate()
+	if printConfig {
+		if err := conf.Print(os.Stdout); err != nil {
+			log.Fatal(err)
 		}
-		configPath string
+		if verr != nil {
+			fmt.Fprintln(os.Stderr, verr)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if verr != nil {
+		log.Fatal(verr)
+	}
+	return conf
+}
+
+// Load собирает конфигурацию из значений по умолчанию, файла, окружения и флагов args.
+// Если environ равен nil, используются переменные окружения процесса.
+// Второе значение сообщает, запрошен ли вывод итоговой конфигурации (--print-config).
+// Проверка значений выполняется отдельно методом Validate.
+func Load(args []string, environ map[string]string) (*Config, bool, error) {
+	var (
+		conf        = &Config{}
+		configPath  string
+		printConfig bool
 	)
+	*conf = Default()
 
-	flag.StringVar(&conf.Handlers.ServerAddr, "a", DefaultServerAddr, "address of HTTP server")
-	flag.StringVar(&conf.Handlers.GRPCAddr, "grpc", DefaultGRPCAddr, "address of GRPC server")
-	flag.StringVar(&conf.Service.ServerURL, "b", DefaultServerURL, "URL of HTTP server")
-	flag.StringVar(&conf.Logger.LogLevel, "l", DefaultLogLevel, "Logger level")
-	flag.StringVar(&conf.FileStorage.FileStoragePath, "f", DefaultFileStorage, "file storage path")
-	flag.StringVar(&conf.DB.DatabaseDsn, "d", DefaultDatabaseDsn, "database DSN address")
-	flag.StringVar(&conf.Audit.AuditFile, "audit-file", "", "AUDIT FILE path")
-	flag.StringVar(&conf.Audit.AuditURL, "audit-url", "", "AUDIT URL path")
-	flag.BoolVar(&conf.Handlers.EnableHTTPS, "s", DefaultHTTPS, "Enable HTTPS server")
-	flag.StringVar(&configPath, "c", "", "path to config file")
-	flag.StringVar(&configPath, "config", "", "path to config file")
-	flag.StringVar(&conf.Handlers.TrustedSubnet, "t", "", "trusted subnet")
-
-	flag.Parse()
-
-	if err := env.Parse(conf); err != nil {
-		log.Fatal(err)
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	registerFlags(fs, conf)
+	fs.StringVar(&configPath, "c", "", "path to config file (JSON, YAML or TOML)")
+	fs.StringVar(&configPath, "config", "", "path to config file (JSON, YAML or TOML)")
+	fs.BoolVar(&printConfig, "print-config", false, "print effective config with secrets redacted and exit")
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
 	}
 
-	cfgJSON, err := parseJSON(configPath)
-	if err != nil {
-		log.Fatal(err)
+	// Флаги применяются последними, поэтому запоминаем явно заданные значения
+	// и собираем конфигурацию заново в порядке приоритета.
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
+	*conf = Default()
+
+	if configPath == "" {
+		configPath = lookupEnv(environ, "CONFIG")
+	}
+	if configPath != "" {
+		fc, err := parseFile(configPath)
+		if err != nil {
+			return nil, false, fmt.Errorf("config file %s: %w", configPath, err)
+		}
+		fc.apply(conf)
 	}
 
-	applyJSONConfig(conf, cfgJSON)
+	if err := env.Parse(conf, env.Options{Environment: environ}); err != nil {
+		return nil, false, err
+	}
 
-	return conf
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return conf, printConfig, nil
+}
+
+// registerFlags регистрирует флаги командной строки, привязанные к полям conf.
+func registerFlags(fs *flag.FlagSet, conf *Config) {
+	fs.StringVar(&conf.Handlers.ServerAddr, "a", conf.Handlers.ServerAddr, "address of HTTP server")
+	fs.StringVar(&conf.Handlers.GRPCAddr, "grpc", conf.Handlers.GRPCAddr, "address of GRPC server")
+	fs.StringVar(&conf.Service.ServerURL, "b", conf.Service.ServerURL, "URL of HTTP server")
+	fs.StringVar(&conf.Logger.LogLevel, "l", conf.Logger.LogLevel, "Logger level")
+	fs.StringVar(&conf.FileStorage.FileStoragePath, "f", conf.FileStorage.FileStoragePath, "file storage path")
+	fs.StringVar(&conf.DB.DatabaseDsn, "d", conf.DB.DatabaseDsn, "database DSN address")
+	fs.StringVar(&conf.Audit.AuditFile, "audit-file", conf.Audit.AuditFile, "AUDIT FILE path")
+	fs.StringVar(&conf.Audit.AuditURL, "audit-url", conf.Audit.AuditURL, "AUDIT URL path")
+	fs.BoolVar(&conf.Handlers.EnableHTTPS, "s", conf.Handlers.EnableHTTPS, "Enable HTTPS server")
+	fs.StringVar(&conf.Handlers.TrustedSubnet, "t", conf.Handlers.TrustedSubnet, "comma-separated trusted subnets (CIDR) for internal endpoints")
+	fs.StringVar(&conf.Handlers.TrustedProxies, "trusted-proxies", conf.Handlers.TrustedProxies, "comma-separated CIDRs of proxies allowed to set X-Forwarded-For/Forwarded/X-Real-IP")
+	fs.StringVar(&conf.DedupeScope, "dedupe", conf.DedupeScope, "original URL dedupe scope: global, user or none")
+	fs.DurationVar(&conf.Service.RetentionPeriod, "retention", conf.Service.RetentionPeriod, "grace period for restoring deleted URLs")
+	fs.DurationVar(&conf.Service.PurgeInterval, "purge-interval", conf.Service.PurgeInterval, "interval of purging expired deleted URLs, 0 disables purging")
+	fs.BoolVar(&conf.Service.PurgeFreeHash, "purge-free-hash", conf.Service.PurgeFreeHash, "allow reuse of purged URL hashes")
+	fs.BoolVar(&conf.Service.FetchTitles, "fetch-titles", conf.Service.FetchTitles, "fetch titles of target pages for links created without a title")
+	fs.StringVar(&conf.Service.GeoIPDB, "geoip-db", conf.Service.GeoIPDB, "MaxMind DB file for country routing rules")
+	fs.DurationVar(&conf.Service.HealthCheckInterval, "health-check-interval", conf.Service.HealthCheckInterval, "interval of checking link destinations, 0 disables checking")
+	fs.IntVar(&conf.Service.HealthCheckConcurrency, "health-check-concurrency", conf.Service.HealthCheckConcurrency, "number of concurrent link destination checks")
+	fs.DurationVar(&conf.Service.HealthCheckHostDelay, "health-check-host-delay", conf.Service.HealthCheckHostDelay, "minimal delay between destination checks of the same host")
+	fs.DurationVar(&conf.Service.WebhookClickInterval, "webhook-click-interval", conf.Service.WebhookClickInterval, "interval of aggregating clicks into webhook events, 0 sends an event per click")
+	fs.DurationVar(&conf.Service.IdempotencyTTL, "idempotency-ttl", conf.Service.IdempotencyTTL, "how long responses to requests with an Idempotency-Key are replayed, 0 disables idempotency keys")
+	fs.BoolVar(&conf.Service.AllowPrivateTargets, "allow-private-targets", conf.Service.AllowPrivateTargets, "allow webhooks, title fetching and health checks to reach private addresses")
+}
+
+// lookupEnv возвращает значение переменной окружения из environ
+// или из окружения процесса, если environ равен nil.
+func lookupEnv(environ map[string]string, key string) string {
+	if environ == nil {
+		return os.Getenv(key)
+	}
+	return environ[key]
 }
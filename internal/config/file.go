@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig описывает файл конфигурации. Формат определяется расширением
+// файла: .json, .yaml/.yml или .toml; ключи во всех форматах одинаковые.
+// Отсутствующие в файле ключи не изменяют конфигурацию, неизвестные ключи считаются ошибкой.
+// Пример (YAML):
+//
+//	server_address: ":8080"
+//	base_url: "https://sho.rt"
+//	retention_period: 720h
+type FileConfig struct {
+	ServerAddress          *string      `json:"server_address,omitempty" yaml:"server_address,omitempty" toml:"server_address,omitempty"`
+	GRPCAddress            *string      `json:"grpc_address,omitempty" yaml:"grpc_address,omitempty" toml:"grpc_address,omitempty"`
+	BaseURL                *string      `json:"base_url,omitempty" yaml:"base_url,omitempty" toml:"base_url,omitempty"`
+	LogLevel               *string      `json:"log_level,omitempty" yaml:"log_level,omitempty" toml:"log_level,omitempty"`
+	FileStoragePath        *string      `json:"file_storage_path,omitempty" yaml:"file_storage_path,omitempty" toml:"file_storage_path,omitempty"`
+	DedupeScope            *string      `json:"dedupe_scope,omitempty" yaml:"dedupe_scope,omitempty" toml:"dedupe_scope,omitempty"`
+	DatabaseDSN            *string      `json:"database_dsn,omitempty" yaml:"database_dsn,omitempty" toml:"database_dsn,omitempty"`
+	EnableHTTPS            *bool        `json:"enable_https,omitempty" yaml:"enable_https,omitempty" toml:"enable_https,omitempty"`
+	HTTPSPort              *string      `json:"https_port,omitempty" yaml:"https_port,omitempty" toml:"https_port,omitempty"`
+	CertFile               *string      `json:"cert_file,omitempty" yaml:"cert_file,omitempty" toml:"cert_file,omitempty"`
+	KeyFile                *string      `json:"key_file,omitempty" yaml:"key_file,omitempty" toml:"key_file,omitempty"`
+	TrustedSubnet          *string      `json:"trusted_subnet,omitempty" yaml:"trusted_subnet,omitempty" toml:"trusted_subnet,omitempty"`
+	TrustedProxies         *string      `json:"trusted_proxies,omitempty" yaml:"trusted_proxies,omitempty" toml:"trusted_proxies,omitempty"`
+	SecretKey              *string      `json:"secret_key,omitempty" yaml:"secret_key,omitempty" toml:"secret_key,omitempty"`
+	AuditFile              *string      `json:"audit_file,omitempty" yaml:"audit_file,omitempty" toml:"audit_file,omitempty"`
+	AuditURL               *string      `json:"audit_url,omitempty" yaml:"audit_url,omitempty" toml:"audit_url,omitempty"`
+	RetentionPeriod        *Duration    `json:"retention_period,omitempty" yaml:"retention_period,omitempty" toml:"retention_period,omitempty"`
+	PurgeInterval          *Duration    `json:"purge_interval,omitempty" yaml:"purge_interval,omitempty" toml:"purge_interval,omitempty"`
+	PurgeFreeHash          *bool        `json:"purge_free_hash,omitempty" yaml:"purge_free_hash,omitempty" toml:"purge_free_hash,omitempty"`
+	FetchTitles            *bool        `json:"fetch_titles,omitempty" yaml:"fetch_titles,omitempty" toml:"fetch_titles,omitempty"`
+	GeoIPDB                *string      `json:"geoip_db,omitempty" yaml:"geoip_db,omitempty" toml:"geoip_db,omitempty"`
+	HealthCheckInterval    *Duration    `json:"health_check_interval,omitempty" yaml:"health_check_interval,omitempty" toml:"health_check_interval,omitempty"`
+	HealthCheckConcurrency *int         `json:"health_check_concurrency,omitempty" yaml:"health_check_concurrency,omitempty" toml:"health_check_concurrency,omitempty"`
+	HealthCheckHostDelay   *Duration    `json:"health_check_host_delay,omitempty" yaml:"health_check_host_delay,omitempty" toml:"health_check_host_delay,omitempty"`
+	WebhookClickInterval   *Duration    `json:"webhook_click_interval,omitempty" yaml:"webhook_click_interval,omitempty" toml:"webhook_click_interval,omitempty"`
+	IdempotencyTTL         *Duration    `json:"idempotency_ttl,omitempty" yaml:"idempotency_ttl,omitempty" toml:"idempotency_ttl,omitempty"`
+	AllowPrivateTargets    *bool        `json:"allow_private_targets,omitempty" yaml:"allow_private_targets,omitempty" toml:"allow_private_targets,omitempty"`
+	Domains                []FileDomain `json:"domains,omitempty" yaml:"domains,omitempty" toml:"domains,omitempty"`
+}
+
+// FileDomain описывает дополнительный домен коротких ссылок в файле конфигурации.
+// Пример (YAML):
+//
+//	domains:
+//	  - name: go.brand.com
+//	    redirect_code: 301
+//	    not_found_url: https://brand.com/
+//	    users: [1, 2]
+type FileDomain struct {
+	Name         string `json:"name" yaml:"name" toml:"name"`
+	BaseURL      string `json:"base_url,omitempty" yaml:"base_url,omitempty" toml:"base_url,omitempty"`
+	RedirectCode int    `json:"redirect_code,omitempty" yaml:"redirect_code,omitempty" toml:"redirect_code,omitempty"`
+	NotFoundURL  string `json:"not_found_url,omitempty" yaml:"not_found_url,omitempty" toml:"not_found_url,omitempty"`
+	CertFile     string `json:"cert_file,omitempty" yaml:"cert_file,omitempty" toml:"cert_file,omitempty"`
+	KeyFile      string `json:"key_file,omitempty" yaml:"key_file,omitempty" toml:"key_file,omitempty"`
+	Users        []int  `json:"users,omitempty" yaml:"users,omitempty" toml:"users,omitempty"`
+}
+
+// Duration — длительность, записываемая в файле конфигурации строкой ("1h30m").
+type Duration time.Duration
+
+// UnmarshalText разбирает длительность в формате time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText записывает длительность в формате time.Duration.String.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// parseFile читает файл конфигурации, выбирая формат по расширению.
+func parseFile(configPath string) (FileConfig, error) {
+	var fc FileConfig
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fc, err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(configPath)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fc); err != nil && err != io.EOF {
+			return fc, err
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fc); err != nil && err != io.EOF {
+			return fc, err
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), &fc)
+		if err != nil {
+			return fc, err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, 0, len(undecoded))
+			for _, k := range undecoded {
+				keys = append(keys, k.String())
+			}
+			sort.Strings(keys)
+			return fc, fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+		}
+	default:
+		return fc, fmt.Errorf("unsupported config format %q: expected .json, .yaml, .yml or .toml", ext)
+	}
+	return fc, nil
+}
+
+// apply переносит заданные в файле значения в конфигурацию.
+func (fc FileConfig) apply(conf *Config) {
+	setString(&conf.Handlers.ServerAddr, fc.ServerAddress)
+	setString(&conf.Handlers.GRPCAddr, fc.GRPCAddress)
+	setString(&conf.Service.ServerURL, fc.BaseURL)
+	setString(&conf.Logger.LogLevel, fc.LogLevel)
+	setString(&conf.FileStorage.FileStoragePath, fc.FileStoragePath)
+	setString(&conf.DedupeScope, fc.DedupeScope)
+	setString(&conf.DB.DatabaseDsn, fc.DatabaseDSN)
+	setBool(&conf.Handlers.EnableHTTPS, fc.EnableHTTPS)
+	setString(&conf.Handlers.HTTPSPort, fc.HTTPSPort)
+	setString(&conf.Handlers.CertFile, fc.CertFile)
+	setString(&conf.Handlers.KeyFile, fc.KeyFile)
+	setString(&conf.Handlers.TrustedSubnet, fc.TrustedSubnet)
+	setString(&conf.Handlers.TrustedProxies, fc.TrustedProxies)
+	setString(&conf.Auth.SecretKey, fc.SecretKey)
+	setString(&conf.Audit.AuditFile, fc.AuditFile)
+	setString(&conf.Audit.AuditURL, fc.AuditURL)
+	setDuration(&conf.Service.RetentionPeriod, fc.RetentionPeriod)
+	setDuration(&conf.Service.PurgeInterval, fc.PurgeInterval)
+	setBool(&conf.Service.PurgeFreeHash, fc.PurgeFreeHash)
+	setBool(&conf.Service.FetchTitles, fc.FetchTitles)
+	setString(&conf.Service.GeoIPDB, fc.GeoIPDB)
+	setDuration(&conf.Service.HealthCheckInterval, fc.HealthCheckInterval)
+	setInt(&conf.Service.HealthCheckConcurrency, fc.HealthCheckConcurrency)
+	setDuration(&conf.Service.HealthCheckHostDelay, fc.HealthCheckHostDelay)
+	setDuration(&conf.Service.WebhookClickInterval, fc.WebhookClickInterval)
+	setDuration(&conf.Service.IdempotencyTTL, fc.IdempotencyTTL)
+	setBool(&conf.Service.AllowPrivateTargets, fc.AllowPrivateTargets)
+	if fc.Domains != nil {
+		conf.Service.Domains = make([]serviceConf.Domain, 0, len(fc.Domains))
+		for _, d := range fc.Domains {
+			conf.Service.Domains = append(conf.Service.Domains, serviceConf.Domain(d))
+		}
+	}
+}
+
+// toFile представляет конфигурацию в формате файла со всеми заданными ключами.
+func toFile(conf Config) FileConfig {
+	retention, purge := Duration(conf.Service.RetentionPeriod), Duration(conf.Service.PurgeInterval)
+	healthInterval, healthDelay := Duration(conf.Service.HealthCheckInterval), Duration(conf.Service.HealthCheckHostDelay)
+	clickInterval, idempotencyTTL := Duration(conf.Service.WebhookClickInterval), Duration(conf.Service.IdempotencyTTL)
+	var domains []FileDomain
+	for _, d := range conf.Service.Domains {
+		domains = append(domains, FileDomain(d))
+	}
+	return FileConfig{
+		ServerAddress:          &conf.Handlers.ServerAddr,
+		GRPCAddress:            &conf.Handlers.GRPCAddr,
+		BaseURL:                &conf.Service.ServerURL,
+		LogLevel:               &conf.Logger.LogLevel,
+		FileStoragePath:        &conf.FileStorage.FileStoragePath,
+		DedupeScope:            &conf.DedupeScope,
+		DatabaseDSN:            &conf.DB.DatabaseDsn,
+		EnableHTTPS:            &conf.Handlers.EnableHTTPS,
+		HTTPSPort:              &conf.Handlers.HTTPSPort,
+		CertFile:               &conf.Handlers.CertFile,
+		KeyFile:                &conf.Handlers.KeyFile,
+		TrustedSubnet:          &conf.Handlers.TrustedSubnet,
+		TrustedProxies:         &conf.Handlers.TrustedProxies,
+		SecretKey:              &conf.Auth.SecretKey,
+		AuditFile:              &conf.Audit.AuditFile,
+		AuditURL:               &conf.Audit.AuditURL,
+		RetentionPeriod:        &retention,
+		PurgeInterval:          &purge,
+		PurgeFreeHash:          &conf.Service.PurgeFreeHash,
+		FetchTitles:            &conf.Service.FetchTitles,
+		GeoIPDB:                &conf.Service.GeoIPDB,
+		HealthCheckInterval:    &healthInterval,
+		HealthCheckConcurrency: &conf.Service.HealthCheckConcurrency,
+		HealthCheckHostDelay:   &healthDelay,
+		WebhookClickInterval:   &clickInterval,
+		IdempotencyTTL:         &idempotencyTTL,
+		AllowPrivateTargets:    &conf.Service.AllowPrivateTargets,
+		Domains:                domains,
+	}
+}
+
+func setString(confValue *string, fileValue *string) {
+	if fileValue != nil {
+		*confValue = *fileValue
+	}
+}
+
+func setBool(confValue *bool, fileValue *bool) {
+	if fileValue != nil {
+		*confValue = *fileValue
+	}
+}
+
+func setInt(confValue *int, fileValue *int) {
+	if fileValue != nil {
+		*confValue = *fileValue
+	}
+}
+
+func setDuration(confValue *time.Duration, fileValue *Duration) {
+	if fileValue != nil {
+		*confValue = time.Duration(*fileValue)
+	}
+}
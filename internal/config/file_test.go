@@ -0,0 +1,192 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseFile(t *testing.T) {
+	addr, baseURL := "localhost:8080", "http://localhost:8080"
+	retention := Duration(48 * time.Hour)
+	https := true
+	want := FileConfig{ServerAddress: &addr, BaseURL: &baseURL, RetentionPeriod: &retention, EnableHTTPS: &https}
+
+	tests := []struct {
+		name       string
+		configPath string
+		setup      func(t *testing.T) string
+		want       FileConfig
+		wantErr    bool
+	}{
+		{
+			name: "Valid JSON",
+			setup: func(t *testing.T) string {
+				return createTempFile(t, "config.json", `{
+					"server_address": "localhost:8080",
+					"base_url": "http://localhost:8080",
+					"retention_period": "48h",
+					"enable_https": true
+				}`)
+			},
+			want: want,
+		},
+		{
+			name: "Valid YAML",
+			setup: func(t *testing.T) string {
+				return createTempFile(t, "config.yaml", `
+server_address: "localhost:8080"
+base_url: "http://localhost:8080"
+retention_period: 48h
+enable_https: true
+`)
+			},
+			want: want,
+		},
+		{
+			name: "Valid TOML",
+			setup: func(t *testing.T) string {
+				return createTempFile(t, "config.toml", `
+server_address = "localhost:8080"
+base_url = "http://localhost:8080"
+retention_period = "48h"
+enable_https = true
+`)
+			},
+			want: want,
+		},
+		{
+			name:       "Non-existent file",
+			configPath: "/nonexistent/file.json",
+			wantErr:    true,
+		},
+		{
+			name: "Corrupted JSON",
+			setup: func(t *testing.T) string {
+				return createTempFile(t, "config.json", `{"server_address": "localhost:8080", "incomplete_json`)
+			},
+			wantErr: true,
+		},
+		{
+			name:  "Empty JSON file",
+			setup: func(t *testing.T) string { return createTempFile(t, "config.json", "") },
+			want:  FileConfig{},
+		},
+		{
+			name:    "Unknown JSON key",
+			setup:   func(t *testing.T) string { return createTempFile(t, "config.json", `{"server_adress": ":8080"}`) },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown YAML key",
+			setup:   func(t *testing.T) string { return createTempFile(t, "config.yml", "server_adress: \":8080\"\n") },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown TOML key",
+			setup:   func(t *testing.T) string { return createTempFile(t, "config.toml", "server_adress = \":8080\"\n") },
+			wantErr: true,
+		},
+		{
+			name:    "Invalid duration",
+			setup:   func(t *testing.T) string { return createTempFile(t, "config.yaml", "purge_interval: soon\n") },
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported format",
+			setup:   func(t *testing.T) string { return createTempFile(t, "config.ini", "server_address=:8080") },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := tt.configPath
+			if tt.setup != nil {
+				configPath = tt.setup(t)
+			}
+
+			got, err := parseFile(configPath)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFileConfig_apply(t *testing.T) {
+	addr, subnet := "localhost:8081", "10.0.0.0/8"
+	https := false
+	purge := Duration(time.Minute)
+
+	conf := Default()
+	conf.Handlers.EnableHTTPS = true
+	FileConfig{ServerAddress: &addr, TrustedSubnet: &subnet, EnableHTTPS: &https, PurgeInterval: &purge}.apply(&conf)
+
+	assert.Equal(t, addr, conf.Handlers.ServerAddr)
+	assert.Equal(t, subnet, conf.Handlers.TrustedSubnet, "trusted_subnet is applied")
+	assert.False(t, conf.Handlers.EnableHTTPS, "explicit false overrides the current value")
+	assert.Equal(t, time.Minute, conf.Service.PurgeInterval)
+	assert.Equal(t, DefaultServerURL, conf.Service.ServerURL, "missing keys keep the current value")
+}
+
+func TestFileConfig_Domains(t *testing.T) {
+	want := []serviceConf.Domain{
+		{Name: "go.brand.com", RedirectCode: 301, NotFoundURL: "https://brand.com/", Users: []int{1, 2}},
+		{Name: "sho.rt", CertFile: "cert/short.pem", KeyFile: "cert/short.key"},
+	}
+	files := map[string]string{
+		"config.yaml": `
+domains:
+  - name: go.brand.com
+    redirect_code: 301
+    not_found_url: https://brand.com/
+    users: [1, 2]
+  - name: sho.rt
+    cert_file: cert/short.pem
+    key_file: cert/short.key
+`,
+		"config.toml": `
+[[domains]]
+name = "go.brand.com"
+redirect_code = 301
+not_found_url = "https://brand.com/"
+users = [1, 2]
+
+[[domains]]
+name = "sho.rt"
+cert_file = "cert/short.pem"
+key_file = "cert/short.key"
+`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			fc, err := parseFile(createTempFile(t, name, content))
+			require.NoError(t, err)
+			conf := Default()
+			fc.apply(&conf)
+			assert.Equal(t, want, conf.Service.Domains)
+			require.NoError(t, conf.Validate())
+		})
+	}
+}
+
+// createTempFile создает временный файл конфигурации с заданным именем и содержимым
+func createTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	return path
+}
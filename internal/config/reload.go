@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// reloadable перечисляет ключи файла конфигурации, которые применяются
+// без перезапуска при перечитывании конфигурации по SIGHUP.
+var reloadable = map[string]bool{
+	"log_level":       true,
+	"trusted_subnet":  true,
+	"trusted_proxies": true,
+	"audit_file":      true,
+	"audit_url":       true,
+	"cert_file":       true,
+	"key_file":        true,
+}
+
+// NonReloadableChanges возвращает отсортированные ключи параметров, которые
+// отличаются в next, но вступят в силу только после перезапуска.
+// Из параметров доменов без перезапуска применяются только их TLS-сертификаты.
+// Пример:
+//
+//	for _, key := range cur.NonReloadableChanges(*next) {
+//	    log.Printf("config: %s changed, restart required", key)
+//	}
+func (c Config) NonReloadableChanges(next Config) []string {
+	cur, upd := fileValues(c), fileValues(next)
+	var keys []string
+	for key, v := range upd {
+		if !reloadable[key] && string(cur[key]) != string(v) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fileValues представляет конфигурацию как ключи файла с JSON-значениями.
+// Сертификаты доменов не учитываются, так как они обновляются при перечитывании.
+func fileValues(c Config) map[string]json.RawMessage {
+	fc := toFile(c)
+	for i := range fc.Domains {
+		fc.Domains[i].CertFile, fc.Domains[i].KeyFile = "", ""
+	}
+	values := make(map[string]json.RawMessage)
+	data, _ := json.Marshal(fc)
+	_ = json.Unmarshal(data, &values)
+	return values
+}
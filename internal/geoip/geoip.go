@@ -0,0 +1,69 @@
+// Package geoip определяет страну по IP-адресу с помощью локальной базы
+// в формате MaxMind DB (GeoLite2-Country, GeoIP2-Country, GeoLite2-City и совместимых).
+// Обращений к внешним сервисам не выполняется.
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// DB — открытая база MaxMind DB. Методы безопасны для параллельного использования.
+type DB struct {
+	r *maxminddb.Reader
+}
+
+// record содержит поля записи базы, необходимые для определения страны.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Open открывает файл базы MaxMind DB.
+// Пример:
+//
+//	db, err := geoip.Open("/var/lib/GeoIP/GeoLite2-Country.mmdb")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer db.Close()
+func Open(path string) (*DB, error) {
+	r, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database %s: %w", path, err)
+	}
+	return &DB{r: r}, nil
+}
+
+// Country возвращает код страны IP-адреса (ISO 3166-1 alpha-2, в верхнем регистре).
+// Если страна адреса неизвестна, возвращается пустая строка; для адресов без
+// фактического местоположения используется страна регистрации сети.
+// Пример:
+//
+//	country := db.Country(netip.MustParseAddr("81.2.69.142")) // "GB"
+func (db *DB) Country(ip netip.Addr) string {
+	if db == nil || !ip.IsValid() {
+		return ""
+	}
+	var rec record
+	if err := db.r.Lookup(ip.Unmap().AsSlice(), &rec); err != nil {
+		return ""
+	}
+	code := rec.Country.ISOCode
+	if code == "" {
+		code = rec.RegisteredCountry.ISOCode
+	}
+	return strings.ToUpper(code)
+}
+
+// Close закрывает базу.
+func (db *DB) Close() error {
+	return db.r.Close()
+}
@@ -0,0 +1,79 @@
+package geoip
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestDB записывает минимальную IPv4-базу MaxMind DB, в которой сеть
+// 1.0.0.0/8 относится к стране US, а остальные адреса неизвестны.
+func writeTestDB(t *testing.T) string {
+	t.Helper()
+	const nodeCount = 8
+	var tree []byte
+	for i := range nodeCount {
+		// Первые восемь бит адреса 1.0.0.0 — 00000001.
+		left, right := uint32(i+1), uint32(nodeCount)
+		if i == nodeCount-1 {
+			left, right = nodeCount, nodeCount+16 // указатель на начало секции данных
+		}
+		tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+	}
+
+	str := func(s string) []byte { return append([]byte{0x40 | byte(len(s))}, s...) }
+	var data []byte
+	data = append(data, 0xe1) // map из одного элемента
+	data = append(data, str("country")...)
+	data = append(data, 0xe1)
+	data = append(data, str("iso_code")...)
+	data = append(data, str("US")...)
+
+	var meta []byte
+	meta = append(meta, 0xe3)
+	meta = append(meta, str("node_count")...)
+	meta = append(meta, 0xc1, nodeCount) // uint32
+	meta = append(meta, str("record_size")...)
+	meta = append(meta, 0xa1, 24) // uint16
+	meta = append(meta, str("ip_version")...)
+	meta = append(meta, 0xa1, 4)
+
+	var db []byte
+	db = append(db, tree...)
+	db = append(db, make([]byte, 16)...)
+	db = append(db, data...)
+	db = append(db, "\xab\xcd\xefMaxMind.com"...)
+	db = append(db, meta...)
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	require.NoError(t, os.WriteFile(path, db, 0o600))
+	return path
+}
+
+func TestDB_Country(t *testing.T) {
+	db, err := Open(writeTestDB(t))
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, "US", db.Country(netip.MustParseAddr("1.2.3.4")))
+	assert.Equal(t, "US", db.Country(netip.MustParseAddr("::ffff:1.2.3.4")))
+	assert.Empty(t, db.Country(netip.MustParseAddr("2.2.3.4")))
+	assert.Empty(t, db.Country(netip.Addr{}))
+
+	var missing *DB
+	assert.Empty(t, missing.Country(netip.MustParseAddr("1.2.3.4")))
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "broken.mmdb")
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o600))
+	_, err = Open(path)
+	assert.Error(t, err)
+}
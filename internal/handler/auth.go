@@ -9,7 +9,7 @@ import (
 	"golang.org/x/net/context"
 )
 
-// authMiddleware создает middleware для аутентификации пользователей.
+// authMiddleware создает middleware для аутентификации пользователей на эндпоинтах записи.
 // Проверяет наличие валидного токена в cookie:
 //   - Если токен отсутствует или невалиден, создает нового пользователя и токен
 //   - Добавляет ID пользователя в контекст запроса
@@ -28,11 +28,11 @@ func (h *Handler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			if errors.Is(err, auth.ErrUnAuth) {
 				userID, token, err = h.createUserAndToken(w, r)
 				if err != nil {
-					http.Error(w, "error create user", http.StatusInternalServerError)
+					httpProblem(w, "error create user", http.StatusInternalServerError)
 					return
 				}
 			} else {
-				http.Error(w, "invalid cookie", http.StatusUnauthorized)
+				httpProblem(w, "invalid cookie", http.StatusUnauthorized)
 				return
 			}
 		}
@@ -40,7 +40,7 @@ func (h *Handler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		if userID == 0 {
 			userID, err = h.auth.ParseUserID(token)
 			if err != nil {
-				http.Error(w, "", http.StatusUnauthorized)
+				httpProblem(w, "", http.StatusUnauthorized)
 				return
 			}
 		}
@@ -50,6 +50,49 @@ func (h *Handler) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// requireAuthMiddleware создает middleware для эндпоинтов, работающих с данными
+// существующего пользователя. Пользователь не создается: при отсутствии или
+// невалидности токена возвращается 401 Unauthorized.
+func (h *Handler) requireAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := h.userFromCookie(r)
+		if !ok {
+			httpProblem(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// optionalAuthMiddleware создает middleware для публичных эндпоинтов (перенаправление).
+// Если в cookie передан валидный токен, ID пользователя добавляется в контекст
+// (используется только для аудита). Анонимные посетители обрабатываются без
+// создания пользователя и без установки cookie.
+func (h *Handler) optionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if userID, ok := h.userFromCookie(r); ok {
+			r = r.WithContext(context.WithValue(r.Context(), "userID", userID))
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// userFromCookie извлекает ID пользователя из токена в cookie.
+// Возвращает false, если cookie отсутствует или токен невалиден.
+func (h *Handler) userFromCookie(r *http.Request) (int, bool) {
+	token, err := h.auth.GetTokenFromCookie(r)
+	if err != nil {
+		return 0, false
+	}
+	userID, err := h.auth.ParseUserID(token)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
+
 // createUserAndToken создает нового пользователя и генерирует для него токен.
 // Возвращает:
 //   - userID: ID созданного пользователя
@@ -8,9 +8,11 @@ import (
 	"github.com/spitfy/urlshortener/internal/auth"
 	"github.com/spitfy/urlshortener/internal/model"
 	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 )
 
 // Мок auth менеджера
@@ -43,18 +45,38 @@ func (m *mockAuth) CreateToken(w http.ResponseWriter, _ int) (string, error) {
 // Мок сервиса создания пользователя
 type mockService struct{}
 
-func (m *mockService) Add(_ context.Context, _ string, _ int) (string, error) {
+func (m *mockService) Add(_ context.Context, _ string, _ model.LinkOptions, _ int) (string, error) {
 	return "", nil
 }
 
-func (m *mockService) BatchAdd(_ context.Context, _ []model.BatchCreateRequest, _ int) ([]model.BatchCreateResponse, error) {
+func (m *mockService) AddLink(_ context.Context, _ string, _ model.LinkOptions, _ int) (model.LinkPair, error) {
+	return model.LinkPair{}, nil
+}
+
+func (m *mockService) GetLink(_ context.Context, _, _ string, _ int) (model.LinkPair, error) {
+	return model.LinkPair{}, nil
+}
+
+func (m *mockService) Idempotent(_ context.Context, _ int, _, _ string, _ []byte, do func() service.IdempotentResponse) (service.IdempotentResponse, bool, error) {
+	return do(), false, nil
+}
+
+func (m *mockService) BatchAdd(_ context.Context, _ []model.BatchCreateRequest, _ model.BatchMode, _ int) ([]model.BatchCreateResponse, error) {
 	return make([]model.BatchCreateResponse, 0), nil
 }
 
-func (m *mockService) GetByHash(_ context.Context, _ string) (repository.URL, error) {
+func (m *mockService) GetByHash(_ context.Context, _, _ string) (repository.URL, error) {
 	return repository.URL{}, nil
 }
 
+func (m *mockService) DomainByHost(_ string) service.Domain {
+	return service.Domain{}
+}
+
+func (m *mockService) ShortURL(_, _ string) (string, error) {
+	return "", nil
+}
+
 func (m *mockService) Ping() error {
 	return nil
 }
@@ -63,7 +85,72 @@ func (m *mockService) GetByUserID(_ context.Context, _ int) ([]model.LinkPair, e
 	return make([]model.LinkPair, 0), nil
 }
 
-func (m *mockService) DeleteEnqueue(_ context.Context, _ []string, _ int) {
+func (m *mockService) ListUserLinks(_ context.Context, _ int, _ model.LinkFilter) (model.LinkPage, error) {
+	return model.LinkPage{}, nil
+}
+
+func (m *mockService) Destination(u repository.URL, _ service.Visitor) string {
+	return u.Link
+}
+
+func (m *mockService) Follow(_ context.Context, u repository.URL, _ service.Visitor, _ url.Values, _ int) (string, error) {
+	return u.Link, nil
+}
+
+func (m *mockService) CreateExperiment(_ context.Context, _ model.ExperimentRequest, _ int) (model.Experiment, error) {
+	return model.Experiment{}, nil
+}
+
+func (m *mockService) GetExperiment(_ context.Context, _ int64, _ int) (model.Experiment, error) {
+	return model.Experiment{}, repository.ErrNotFound
+}
+
+func (m *mockService) ListExperiments(_ context.Context, _ int) ([]model.Experiment, error) {
+	return nil, nil
+}
+
+func (m *mockService) CreateWebhook(_ context.Context, _ model.WebhookRequest, _ int) (model.Webhook, error) {
+	return model.Webhook{}, nil
+}
+
+func (m *mockService) ListWebhooks(_ context.Context, _ int) ([]model.Webhook, error) {
+	return nil, nil
+}
+
+func (m *mockService) GetWebhook(_ context.Context, _ int64, _ int) (model.Webhook, error) {
+	return model.Webhook{}, nil
+}
+
+func (m *mockService) UpdateWebhook(_ context.Context, _ int64, _ model.WebhookUpdate, _ int) (model.Webhook, error) {
+	return model.Webhook{}, nil
+}
+
+func (m *mockService) DeleteWebhook(_ context.Context, _ int64, _ int) error {
+	return nil
+}
+
+func (m *mockService) ListDeliveries(_ context.Context, _ int64, _ int) ([]model.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *mockService) Subscribe(_ int) (*service.Subscription, error) {
+	return nil, service.ErrStreamClosed
+}
+
+func (m *mockService) CheckPassword(_ context.Context, _ repository.URL, _, _ string) error {
+	return nil
+}
+
+func (m *mockService) DeleteEnqueue(_ context.Context, _ string, _ []string, _ int) (model.DeletionJob, error) {
+	return model.DeletionJob{}, nil
+}
+
+func (m *mockService) GetDeletion(_ context.Context, _ int64, _ int) (model.DeletionJob, error) {
+	return model.DeletionJob{}, repository.ErrNotFound
+}
+
+func (m *mockService) Restore(_ context.Context, _ string, _ []string, _ int) ([]model.RestoreResult, error) {
+	return make([]model.RestoreResult, 0), nil
 }
 
 func (m *mockService) AddObserver(_ audit.Observer) {
@@ -80,6 +167,14 @@ func (m *mockService) Stats(_ context.Context) (model.Stats, error) {
 	return model.Stats{URLs: 1, Users: 1}, nil
 }
 
+func (m *mockService) Update(_ context.Context, _, _ string, _ model.LinkUpdate, _ int) (model.LinkPair, error) {
+	return model.LinkPair{}, nil
+}
+
+func (m *mockService) GetRevisions(_ context.Context, _, _ string, _ int) ([]model.LinkRevision, error) {
+	return make([]model.LinkRevision, 0), nil
+}
+
 // Example-функция для authMiddleware
 func ExampleHandler_authMiddleware() {
 	h := &Handler{
@@ -123,3 +218,42 @@ func ExampleHandler_authMiddleware() {
 	// Status Code: 200
 	// Body: Authenticated user ID: 42
 }
+
+// Example-функция для optionalAuthMiddleware
+func ExampleHandler_optionalAuthMiddleware() {
+	h := &Handler{
+		auth:    &mockAuth{},
+		service: &mockService{},
+	}
+
+	publicHandler := h.optionalAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("userID").(int)
+		fmt.Fprintf(w, "user ID: %d, authenticated: %v", userID, ok)
+	})
+
+	// Анонимный посетитель — пользователь не создается, cookie не устанавливается
+	w := httptest.NewRecorder()
+	publicHandler.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com", nil))
+	res := w.Result()
+	body1, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	fmt.Println("Body:", string(body1))
+	fmt.Println("Cookies:", len(res.Cookies()))
+
+	// Посетитель с валидным токеном — ID пользователя доступен для аудита
+	req2 := httptest.NewRequest("GET", "http://example.com", nil)
+	req2.AddCookie(&http.Cookie{Name: "ID", Value: "valid-token"})
+	w2 := httptest.NewRecorder()
+	publicHandler.ServeHTTP(w2, req2)
+	res2 := w2.Result()
+	body2, _ := io.ReadAll(res2.Body)
+	res2.Body.Close()
+
+	fmt.Println("Body:", string(body2))
+
+	// Output:
+	// Body: user ID: 0, authenticated: false
+	// Cookies: 0
+	// Body: user ID: 42, authenticated: true
+}
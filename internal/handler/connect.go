@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/spitfy/urlshortener/pkg/shortener"
+	"github.com/spitfy/urlshortener/pkg/shortener/shortenerconnect"
+)
+
+// connectServer обслуживает ShortenerService по протоколам Connect, gRPC-Web и
+// HTTP/JSON на HTTP-сервере. Вызовы передаются gRPC-реализации server, поэтому
+// оба транспорта описываются одним proto и ведут себя одинаково; статусы gRPC
+// преобразуются в ошибки Connect с тем же кодом.
+type connectServer struct {
+	rpc *server
+}
+
+var _ shortenerconnect.ShortenerServiceHandler = connectServer{}
+
+// streamProcedures — потоковые методы сервиса. Они длятся дольше тайм-аутов
+// HTTP-сервера, поэтому для них тайм-ауты соединения снимаются.
+var streamProcedures = map[string]bool{
+	shortenerconnect.ShortenerServiceStreamUserURLsProcedure: true,
+	shortenerconnect.ShortenerServiceShortenStreamProcedure:  true,
+	shortenerconnect.ShortenerServiceWatchLinksProcedure:     true,
+}
+
+// newConnectHandler возвращает префикс маршрута сервиса и его обработчик.
+func newConnectHandler(rpc *server) (string, http.Handler) {
+	path, h := shortenerconnect.NewShortenerServiceHandler(connectServer{rpc: rpc})
+	return path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if streamProcedures[r.URL.Path] {
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(time.Time{})
+			_ = rc.SetWriteDeadline(time.Time{})
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// incomingContext передает заголовки запроса в метаданные gRPC, откуда их
+// читает server: токен авторизации, пароль ссылки и идентификатор посетителя.
+func incomingContext(ctx context.Context, h http.Header) context.Context {
+	md := make(metadata.MD, len(h))
+	for k, v := range h {
+		md[strings.ToLower(k)] = v
+	}
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// unary выполняет унарный вызов server с запросом Connect.
+func unary[Req, Res any](ctx context.Context, req *connect.Request[Req], call func(context.Context, *Req) (*Res, error)) (*connect.Response[Res], error) {
+	res, err := call(incomingContext(ctx, req.Header()), req.Msg)
+	if err != nil {
+		return nil, connectError(err)
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (c connectServer) ShortenURL(ctx context.Context, req *connect.Request[pb.URLShortenRequest]) (*connect.Response[pb.URLShortenResponse], error) {
+	return unary(ctx, req, c.rpc.ShortenURL)
+}
+
+func (c connectServer) ExpandURL(ctx context.Context, req *connect.Request[pb.URLExpandRequest]) (*connect.Response[pb.URLExpandResponse], error) {
+	return unary(ctx, req, c.rpc.ExpandURL)
+}
+
+func (c connectServer) ListUserURLs(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[pb.UserURLsResponse], error) {
+	return unary(ctx, req, c.rpc.ListUserURLs)
+}
+
+func (c connectServer) UpdateURL(ctx context.Context, req *connect.Request[pb.URLUpdateRequest]) (*connect.Response[pb.URLData], error) {
+	return unary(ctx, req, c.rpc.UpdateURL)
+}
+
+func (c connectServer) ListURLRevisions(ctx context.Context, req *connect.Request[pb.URLRevisionsRequest]) (*connect.Response[pb.URLRevisionsResponse], error) {
+	return unary(ctx, req, c.rpc.ListURLRevisions)
+}
+
+func (c connectServer) StreamUserURLs(ctx context.Context, req *connect.Request[pb.StreamUserURLsRequest], stream *connect.ServerStream[pb.URLData]) error {
+	return connectError(c.rpc.StreamUserURLs(req.Msg, &connectStream[pb.StreamUserURLsRequest, pb.URLData]{
+		ctx:  incomingContext(ctx, req.Header()),
+		send: stream.Send,
+	}))
+}
+
+func (c connectServer) ShortenStream(ctx context.Context, stream *connect.ClientStream[pb.URLShortenRequest]) (*connect.Response[pb.ShortenStreamResponse], error) {
+	s := &connectStream[pb.URLShortenRequest, pb.ShortenStreamResponse]{
+		ctx: incomingContext(ctx, stream.RequestHeader()),
+		recv: func() (*pb.URLShortenRequest, error) {
+			if stream.Receive() {
+				return stream.Msg(), nil
+			}
+			if err := stream.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		},
+	}
+	if err := c.rpc.ShortenStream(s); err != nil {
+		return nil, connectError(err)
+	}
+	return connect.NewResponse(s.result), nil
+}
+
+func (c connectServer) WatchLinks(ctx context.Context, stream *connect.BidiStream[pb.WatchLinksRequest, pb.LinkEvent]) error {
+	return connectError(c.rpc.WatchLinks(&connectStream[pb.WatchLinksRequest, pb.LinkEvent]{
+		ctx:  incomingContext(ctx, stream.RequestHeader()),
+		recv: stream.Receive,
+		send: stream.Send,
+	}))
+}
+
+// connectStream представляет поток Connect в виде потока gRPC для методов server.
+// Метаданные ответа не передаются: server их не задает, а SendHeader только
+// отправляет заголовки ответа клиенту.
+type connectStream[Req, Res any] struct {
+	ctx    context.Context
+	recv   func() (*Req, error)
+	send   func(*Res) error
+	result *Res // Ответ клиентского потока (SendAndClose)
+}
+
+func (s *connectStream[Req, Res]) Context() context.Context {
+	return s.ctx
+}
+
+func (s *connectStream[Req, Res]) Recv() (*Req, error) {
+	if s.recv == nil {
+		return nil, io.EOF
+	}
+	return s.recv()
+}
+
+func (s *connectStream[Req, Res]) Send(m *Res) error {
+	return s.send(m)
+}
+
+func (s *connectStream[Req, Res]) SendAndClose(m *Res) error {
+	s.result = m
+	return nil
+}
+
+func (s *connectStream[Req, Res]) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *connectStream[Req, Res]) SendHeader(metadata.MD) error {
+	if s.send == nil {
+		return nil
+	}
+	// Отправка nil передает клиенту только заголовки ответа.
+	return s.send(nil)
+}
+
+func (s *connectStream[Req, Res]) SetTrailer(metadata.MD) {}
+
+func (s *connectStream[Req, Res]) SendMsg(m any) error {
+	res, ok := m.(*Res)
+	if !ok {
+		return errors.New("unexpected message type")
+	}
+	return s.Send(res)
+}
+
+func (s *connectStream[Req, Res]) RecvMsg(m any) error {
+	req, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	dst, ok := m.(proto.Message)
+	src, ok2 := any(req).(proto.Message)
+	if !ok || !ok2 {
+		return errors.New("unexpected message type")
+	}
+	proto.Merge(dst, src)
+	return nil
+}
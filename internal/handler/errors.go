@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// errorDomain — домен причин ошибок в errdetails.ErrorInfo ответов gRPC.
+const errorDomain = "urlshortener"
+
+// problemTypePrefix — префикс URI типа ошибки в ответах application/problem+json.
+const problemTypePrefix = "urn:urlshortener:problem:"
+
+// kindCodes сопоставляет видам ошибок сервиса коды gRPC.
+var kindCodes = map[fault.Kind]codes.Code{
+	fault.Internal:    codes.Internal,
+	fault.NotFound:    codes.NotFound,
+	fault.Gone:        codes.NotFound,
+	fault.Conflict:    codes.AlreadyExists,
+	fault.Invalid:     codes.InvalidArgument,
+	fault.Forbidden:   codes.PermissionDenied,
+	fault.RateLimited: codes.ResourceExhausted,
+	fault.Unavailable: codes.Unavailable,
+}
+
+// errorCode сопоставляет ошибке сервиса код gRPC. Таблица общая для всех
+// транспортов: gRPC и Connect отвечают этим кодом, HTTP — статусом httpStatus,
+// поэтому одна и та же ошибка везде получает согласованный ответ.
+func errorCode(err error) codes.Code {
+	if st, ok := status.FromError(err); ok {
+		return st.Code()
+	}
+	switch {
+	case errors.Is(err, service.ErrPasswordRequired):
+		return codes.Unauthenticated
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	return kindCodes[fault.KindOf(err)]
+}
+
+// httpStatuses сопоставляет кодам gRPC HTTP-статусы так же, как gRPC-Gateway.
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// httpStatus возвращает HTTP-статус ответа на ошибку сервиса.
+// Вид fault.Gone отличается от fault.NotFound только в HTTP: 410 Gone.
+func httpStatus(err error) int {
+	if _, ok := status.FromError(err); !ok && fault.KindOf(err) == fault.Gone {
+		return http.StatusGone
+	}
+	if s, ok := httpStatuses[errorCode(err)]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// writeProblem отвечает ошибкой в формате RFC 9457 со статусом code, описанием
+// detail и машинно-читаемым кодом reason (может быть пустым).
+func writeProblem(w http.ResponseWriter, code int, reason, detail string) {
+	sendProblem(w, newProblem(code, reason, detail))
+}
+
+// newProblem возвращает описание ошибки со статусом code, кодом reason и описанием detail.
+func newProblem(code int, reason, detail string) model.Problem {
+	p := model.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: detail,
+		Code:   reason,
+	}
+	if reason != "" {
+		p.Type = problemTypePrefix + reason
+	}
+	return p
+}
+
+// sendProblem отправляет описание ошибки p в формате application/problem+json.
+func sendProblem(w http.ResponseWriter, p model.Problem) {
+	code := p.Status
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// httpProblem отвечает ошибкой запроса, не связанной с ошибкой сервиса:
+// некорректное тело, параметры или отсутствие авторизации.
+func httpProblem(w http.ResponseWriter, detail string, code int) {
+	writeProblem(w, code, "", detail)
+}
+
+// notFoundProblem отвечает 404 с кодом repository.ErrNotFound и описанием detail,
+// уточняющим, какой объект не найден.
+func notFoundProblem(w http.ResponseWriter, detail string) {
+	writeProblem(w, http.StatusNotFound, fault.ReasonOf(repository.ErrNotFound), detail)
+}
+
+// httpError отвечает на ошибку сервиса статусом httpStatus, ее текстом и кодом.
+// Текст внутренних ошибок заменяется на internal.
+func httpError(w http.ResponseWriter, err error, internal string) {
+	code := httpStatus(err)
+	if code == http.StatusInternalServerError {
+		writeProblem(w, code, "", internal)
+		return
+	}
+	writeProblem(w, code, fault.ReasonOf(err), err.Error())
+}
+
+// grpcError преобразует ошибку сервиса в статус gRPC. Код ошибки сервиса
+// передается в деталях статуса как errdetails.ErrorInfo. Статусы gRPC не изменяются.
+// Текст внутренних ошибок, как и в httpError, не передается клиенту, а записывается в журнал.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, msg := errorCode(err), err.Error()
+	if code == codes.Internal || code == codes.Unknown {
+		log.Printf("internal error: %v", err)
+		msg = "internal error"
+	}
+	st := status.New(code, msg)
+	reason := fault.ReasonOf(err)
+	if reason == "" {
+		return st.Err()
+	}
+	if detailed, derr := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// connectError преобразует ошибку сервиса или статус gRPC в ошибку Connect
+// с тем же кодом и деталями: коды Connect совпадают с кодами gRPC.
+func connectError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var ce *connect.Error
+	if errors.As(err, &ce) {
+		return err
+	}
+	st := status.Convert(grpcError(err))
+	ce = connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+	for _, d := range st.Details() {
+		m, ok := d.(proto.Message)
+		if !ok {
+			continue
+		}
+		if detail, derr := connect.NewErrorDetail(m); derr == nil {
+			ce.AddDetail(detail)
+		}
+	}
+	return ce
+}
@@ -0,0 +1,159 @@
+// Package handler содержит обработчики A/B-экспериментов.
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// Параметры cookie посетителя, по которой закрепляется вариант эксперимента.
+const (
+	visitorCookie    = "vid"
+	visitorIDLen     = 16
+	visitorMaxIDLen  = 64
+	visitorCookieAge = 365 * 24 * time.Hour
+)
+
+// visitorID возвращает идентификатор посетителя из cookie или выдает новый.
+// Благодаря cookie посетитель при повторных переходах попадает в тот же вариант эксперимента.
+func visitorID(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(visitorCookie); err == nil && c.Value != "" && len(c.Value) <= visitorMaxIDLen {
+		return c.Value
+	}
+	id := service.RandString(visitorIDLen)
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(visitorCookieAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// CreateExperiment создает A/B-эксперимент
+// @Summary Создать A/B-эксперимент
+// @Description Создает короткую ссылку, распределяющую посетителей между 2–5 вариантами по весам.
+// @Description Вариант закрепляется за посетителем cookie vid; перенаправления на каждый вариант подсчитываются.
+// @Tags Experiments
+// @Accept json
+// @Produce json
+// @Param request body model.ExperimentRequest true "Параметры эксперимента"
+// @Success 201 {object} model.Experiment "Созданный эксперимент"
+// @Failure 400 {object} model.Problem "Некорректный запрос или неизвестный домен"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 403 {object} model.Problem "Домен недоступен пользователю"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/experiments [post]
+func (h *Handler) CreateExperiment(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		httpProblem(w, "invalid content-type", http.StatusBadRequest)
+		return
+	}
+
+	body, err := readBodyLimited(r.Body, 100*1024)
+	if err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	var req model.ExperimentRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	e, err := h.service.CreateExperiment(r.Context(), req, userID)
+	if err != nil {
+		httpError(w, err, "could not create experiment")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := encodeJSONBuffered(w, e); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ListExperiments возвращает эксперименты пользователя
+// @Summary Список A/B-экспериментов
+// @Description Возвращает эксперименты текущего пользователя (новые первыми) с числом перенаправлений на варианты
+// @Tags Experiments
+// @Produce json
+// @Success 200 {array} model.Experiment "Эксперименты пользователя"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/experiments [get]
+func (h *Handler) ListExperiments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	list, err := h.service.ListExperiments(r.Context(), userID)
+	if err != nil {
+		httpError(w, err, "could not list experiments")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSONBuffered(w, list); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetExperiment возвращает результаты эксперимента
+// @Summary Результаты A/B-эксперимента
+// @Description Возвращает эксперимент текущего пользователя с числом перенаправлений на каждый вариант
+// @Tags Experiments
+// @Produce json
+// @Param id path int true "Идентификатор эксперимента"
+// @Success 200 {object} model.Experiment "Эксперимент и его результаты"
+// @Failure 400 {object} model.Problem "Некорректный идентификатор"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Эксперимент не найден"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/experiments/{id} [get]
+func (h *Handler) GetExperiment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httpProblem(w, "invalid experiment id", http.StatusBadRequest)
+		return
+	}
+	e, err := h.service.GetExperiment(r.Context(), id, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		notFoundProblem(w, "experiment not found")
+		return
+	}
+	if err != nil {
+		httpError(w, err, "could not get experiment")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSONBuffered(w, e); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,90 +2,385 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/spitfy/urlshortener/internal/audit"
 	"github.com/spitfy/urlshortener/internal/auth"
+	"github.com/spitfy/urlshortener/internal/clientip"
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
 	pb "github.com/spitfy/urlshortener/pkg/shortener"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"sync"
+	"time"
 )
 
+// passwordMetadata — ключ метаданных, в котором передается пароль защищенной ссылки.
+const passwordMetadata = "link-password"
+
+// visitorMetadata — ключ метаданных с идентификатором посетителя, за которым
+// закрепляется вариант A/B-эксперимента; по умолчанию используется IP-адрес клиента.
+const visitorMetadata = "visitor-id"
+
 type server struct {
 	pb.UnimplementedShortenerServiceServer
-	service ServiceShortener
-	auth    *auth.Manager
+	service   ServiceShortener
+	auth      *auth.Manager
+	done      chan struct{} // Закрывается при остановке сервера (см. closeStreams)
+	closeOnce sync.Once
 }
 
 func newGRPC(service ServiceShortener, auth *auth.Manager) *server {
 	return &server{
 		service: service,
 		auth:    auth,
+		done:    make(chan struct{}),
 	}
 }
 
+// ShortenURL создает короткую ссылку. С ключом идемпотентности в метаданных
+// idempotency-key повторный запрос получает сохраненный ответ первого.
 func (s *server) ShortenURL(ctx context.Context, req *pb.URLShortenRequest) (*pb.URLShortenResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "authorization required")
-	}
-
-	authHeader := md.Get("authorization")
-	if len(authHeader) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "authorization token missing")
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	token := authHeader[0]
-	userID, err := s.auth.ParseUserID(token)
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid token")
+		return nil, status.Error(codes.InvalidArgument, "could not encode request")
 	}
+	return idempotentRPC(ctx, s.service, userID, pb.ShortenerService_ShortenURL_FullMethodName, payload, &pb.URLShortenResponse{},
+		func() (*pb.URLShortenResponse, error) {
+			shortURL, err := s.shorten(ctx, req, userID)
+			if err != nil {
+				return nil, err
+			}
+			return &pb.URLShortenResponse{Result: shortURL}, nil
+		})
+}
 
-	shortURL, err := s.service.Add(ctx, req.GetUrl(), userID)
+// shorten создает короткую ссылку по запросу и уведомляет наблюдателей.
+// Ошибки сервиса преобразуются в статусы gRPC; для уже сокращенного URL
+// возвращается codes.AlreadyExists вместе с существующей короткой ссылкой.
+func (s *server) shorten(ctx context.Context, req *pb.URLShortenRequest, userID int) (string, error) {
+	shortURL, err := s.service.Add(ctx, req.GetUrl(), linkOptionsFromPB(req), userID)
+	if errors.Is(err, repository.ErrExistsURL) {
+		return shortURL, grpcError(fmt.Errorf("%w: %s", err, shortURL))
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return "", grpcError(err)
 	}
 
-	return &pb.URLShortenResponse{Result: shortURL}, nil
+	s.service.NotifyObservers(ctx, audit.Event{
+		Timestamp: time.Now(),
+		Action:    audit.Shorten,
+		UserID:    userID,
+		URL:       req.GetUrl(),
+		IP:        clientip.String(ctx),
+		ShortURL:  shortURL,
+	})
+	return shortURL, nil
 }
 
 func (s *server) ExpandURL(ctx context.Context, req *pb.URLExpandRequest) (*pb.URLExpandResponse, error) {
-	originalURL, err := s.service.GetByHash(ctx, req.GetId())
+	originalURL, err := s.service.GetByHash(ctx, req.GetDomain(), req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	// Как и перенаправление, удаленные и исчерпанные ссылки не раскрываются.
+	if originalURL.DeletedFlag {
+		return nil, grpcError(service.ErrDeleted)
+	}
+	if originalURL.Exhausted() {
+		return nil, grpcError(repository.ErrExhausted)
+	}
+	ip, _ := clientip.FromContext(ctx)
+	visit := service.Visitor{IP: ip, Time: time.Now(), ID: clientip.String(ctx)}
+	var password string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(passwordMetadata); len(v) > 0 {
+			password = v[0]
+		}
+		if v := md.Get(visitorMetadata); len(v) > 0 && v[0] != "" {
+			visit.ID = v[0]
+		}
+		if v := md.Get("user-agent"); len(v) > 0 {
+			visit.UserAgent = v[0]
+		}
+		if v := md.Get("accept-language"); len(v) > 0 {
+			visit.AcceptLanguage = v[0]
+		}
+	}
+	if err := s.service.CheckPassword(ctx, originalURL, password, clientip.String(ctx)); err != nil {
+		return nil, grpcError(err)
+	}
+	// Раскрытие ссылки — такой же переход, как перенаправление: с правилами,
+	// учетом перехода и событием для наблюдателей.
+	// Для анонимных посетителей ID пользователя в аудите равен 0.
+	userID, _ := s.userID(ctx)
+	target, err := s.service.Follow(ctx, originalURL, visit, nil, userID)
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "URL not found")
+		return nil, grpcError(err)
 	}
 
-	return &pb.URLExpandResponse{Result: originalURL.Link}, nil
+	return &pb.URLExpandResponse{Result: target}, nil
 }
 
 func (s *server) ListUserURLs(ctx context.Context, _ *emptypb.Empty) (*pb.UserURLsResponse, error) {
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	urls, err := s.service.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var pbURLs []*pb.URLData
+	for _, u := range urls {
+		pbURLs = append(pbURLs, urlDataToPB(u))
+	}
+
+	return &pb.UserURLsResponse{Url: pbURLs}, nil
+}
+
+func (s *server) UpdateURL(ctx context.Context, req *pb.URLUpdateRequest) (*pb.URLData, error) {
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	upd := model.LinkUpdate{
+		OriginalURL: req.OriginalUrl,
+		Title:       req.Title,
+		Note:        req.Note,
+		Password:    req.Password,
+	}
+	if req.GetTags() != nil {
+		tags := req.GetTags().GetTags()
+		upd.Tags = &tags
+	}
+	if req.GetRedirect() != nil {
+		redirect := redirectFromPB(req.GetRedirect())
+		upd.Redirect = &redirect
+	}
+	if req.GetRules() != nil {
+		rules := rulesFromPB(req.GetRules().GetRules())
+		upd.Rules = &rules
+	}
+
+	link, err := s.service.Update(ctx, req.GetDomain(), req.GetId(), upd, userID)
+	if errors.Is(err, repository.ErrExistsURL) {
+		return nil, grpcError(fmt.Errorf("%w: %s", err, link.ShortURL))
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	s.service.NotifyObservers(ctx, audit.Event{
+		Timestamp: time.Now(),
+		Action:    audit.Update,
+		UserID:    userID,
+		URL:       link.OriginalURL,
+		IP:        clientip.String(ctx),
+	})
+
+	return urlDataToPB(link), nil
+}
+
+func (s *server) ListURLRevisions(ctx context.Context, req *pb.URLRevisionsRequest) (*pb.URLRevisionsResponse, error) {
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	revs, err := s.service.GetRevisions(ctx, req.GetDomain(), req.GetId(), userID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &pb.URLRevisionsResponse{}
+	for _, r := range revs {
+		res.Revisions = append(res.Revisions, &pb.URLRevision{
+			Revision:    int32(r.Revision),
+			OriginalUrl: r.OriginalURL,
+			Title:       r.Title,
+			Tags:        r.Tags,
+			Note:        r.Note,
+			Redirect:    redirectToPB(r.Redirect),
+			Rules:       rulesToPB(r.Rules),
+			EditedAt:    timestamppb.New(r.EditedAt),
+		})
+	}
+	return res, nil
+}
+
+// userID извлекает ID пользователя из токена в метаданных запроса.
+func (s *server) userID(ctx context.Context) (int, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "authorization required")
+		return 0, status.Error(codes.Unauthenticated, "authorization required")
 	}
 
 	authHeader := md.Get("authorization")
 	if len(authHeader) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "authorization token missing")
+		return 0, status.Error(codes.Unauthenticated, "authorization token missing")
 	}
 
-	token := authHeader[0]
-	userID, err := s.auth.ParseUserID(token)
+	userID, err := s.auth.ParseUserID(authHeader[0])
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid token")
+		return 0, status.Error(codes.Unauthenticated, "invalid token")
 	}
+	return userID, nil
+}
 
-	urls, err := s.service.GetByUserID(ctx, userID)
-	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+// linkOptionsFromPB преобразует параметры ссылки из gRPC-запроса в модель.
+func linkOptionsFromPB(req *pb.URLShortenRequest) model.LinkOptions {
+	return model.LinkOptions{
+		Redirect:  redirectFromPB(req.GetRedirect()),
+		Domain:    req.GetDomain(),
+		Title:     req.GetTitle(),
+		Tags:      req.GetTags(),
+		Note:      req.GetNote(),
+		Password:  req.GetPassword(),
+		MaxClicks: req.GetMaxClicks(),
+		Rules:     rulesFromPB(req.GetRules()),
 	}
+}
 
-	var pbURLs []*pb.URLData
-	for _, u := range urls {
-		pbURLs = append(pbURLs, &pb.URLData{
-			ShortUrl:    u.ShortURL,
-			OriginalUrl: u.OriginalURL,
-		})
+// redirectFromPB преобразует параметры перенаправления из gRPC-сообщения в модель.
+func redirectFromPB(r *pb.RedirectOptions) model.RedirectOptions {
+	if r == nil {
+		return model.RedirectOptions{}
+	}
+	utm := r.GetUtm()
+	return model.RedirectOptions{
+		Code:      int(r.GetCode()),
+		PassQuery: r.GetPassQuery(),
+		UTM: model.UTM{
+			Source:   utm.GetSource(),
+			Medium:   utm.GetMedium(),
+			Campaign: utm.GetCampaign(),
+			Term:     utm.GetTerm(),
+			Content:  utm.GetContent(),
+		},
 	}
+}
 
-	return &pb.UserURLsResponse{Url: pbURLs}, nil
+// redirectToPB преобразует параметры перенаправления в gRPC-сообщение.
+func redirectToPB(r model.RedirectOptions) *pb.RedirectOptions {
+	if r == (model.RedirectOptions{}) {
+		return nil
+	}
+	res := &pb.RedirectOptions{
+		Code:      int32(r.Code),
+		PassQuery: r.PassQuery,
+	}
+	if r.UTM != (model.UTM{}) {
+		res.Utm = &pb.UTM{
+			Source:   r.UTM.Source,
+			Medium:   r.UTM.Medium,
+			Campaign: r.UTM.Campaign,
+			Term:     r.UTM.Term,
+			Content:  r.UTM.Content,
+		}
+	}
+	return res
+}
+
+// urlDataToPB преобразует ссылку пользователя в gRPC-сообщение.
+func urlDataToPB(l model.LinkPair) *pb.URLData {
+	res := &pb.URLData{
+		ShortUrl:    l.ShortURL,
+		OriginalUrl: l.OriginalURL,
+		Title:       l.Title,
+		Tags:        l.Tags,
+		Redirect:    redirectToPB(l.Redirect),
+		Note:        l.Note,
+		Clicks:      l.Clicks,
+		Protected:   l.Protected,
+		MaxClicks:   l.MaxClicks,
+		ClicksLeft:  l.ClicksLeft,
+		Rules:       rulesToPB(l.Rules),
+
+		ExperimentId: l.ExperimentID,
+		Broken:       l.Broken,
+	}
+	if !l.CreatedAt.IsZero() {
+		res.CreatedAt = timestamppb.New(l.CreatedAt)
+	}
+	if h := l.Health; h != nil {
+		res.Health = &pb.LinkHealth{
+			Status:    int32(h.Status),
+			Error:     h.Error,
+			Redirects: h.Redirects,
+			Broken:    h.Broken,
+			CheckedAt: timestamppb.New(h.CheckedAt),
+		}
+	}
+	return res
+}
+
+// rulesFromPB преобразует правила перенаправления из gRPC-сообщения в модель.
+func rulesFromPB(rules []*pb.RouteRule) []model.RouteRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	res := make([]model.RouteRule, 0, len(rules))
+	for _, r := range rules {
+		rule := model.RouteRule{
+			URL:       r.GetUrl(),
+			Platforms: r.GetPlatforms(),
+			Languages: r.GetLanguages(),
+			Countries: r.GetCountries(),
+			Percent:   int(r.GetPercent()),
+		}
+		if w := r.GetTime(); w != nil {
+			rule.Time = &model.TimeWindow{From: w.GetFrom(), To: w.GetTo(), Location: w.GetLocation()}
+			if w.GetStart() != nil {
+				rule.Time.Start = w.GetStart().AsTime()
+			}
+			if w.GetEnd() != nil {
+				rule.Time.End = w.GetEnd().AsTime()
+			}
+		}
+		res = append(res, rule)
+	}
+	return res
+}
+
+// rulesToPB преобразует правила перенаправления в gRPC-сообщения.
+func rulesToPB(rules []model.RouteRule) []*pb.RouteRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	res := make([]*pb.RouteRule, 0, len(rules))
+	for _, r := range rules {
+		rule := &pb.RouteRule{
+			Url:       r.URL,
+			Platforms: r.Platforms,
+			Languages: r.Languages,
+			Countries: r.Countries,
+			Percent:   int32(r.Percent),
+		}
+		if r.Time != nil {
+			rule.Time = &pb.TimeWindow{From: r.Time.From, To: r.Time.To, Location: r.Time.Location}
+			if !r.Time.Start.IsZero() {
+				rule.Time.Start = timestamppb.New(r.Time.Start)
+			}
+			if !r.Time.End.IsZero() {
+				rule.Time.End = timestamppb.New(r.Time.End)
+			}
+		}
+		res = append(res, rule)
+	}
+	return res
 }
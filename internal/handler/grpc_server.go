@@ -2,26 +2,41 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"github.com/spitfy/urlshortener/internal/auth"
+	"github.com/spitfy/urlshortener/internal/clientip"
 	"net"
 
 	"github.com/spitfy/urlshortener/internal/config"
 	"github.com/spitfy/urlshortener/pkg/shortener"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
 )
 
 // GRPCServer — структура для gRPC-сервера
 type GRPCServer struct {
 	server   *grpc.Server
+	handler  *server
 	listener net.Listener
+	proxies  *clientip.Networks
 }
 
 // NewGRPCServer создает и настраивает gRPC-сервер
 func NewGRPCServer(cfg config.Config, service ServiceShortener, auth *auth.Manager) (*GRPCServer, error) {
-	grpcServer := grpc.NewServer()
+	proxies, err := clientip.NewNetworks(cfg.Handlers.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	resolver := clientip.NewResolver(proxies)
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(clientIPInterceptor(resolver)),
+		grpc.StreamInterceptor(clientIPStreamInterceptor(resolver)),
+	)
 
-	shortener.RegisterShortenerServiceServer(grpcServer, newGRPC(service, auth))
+	handler := newGRPC(service, auth)
+	shortener.RegisterShortenerServiceServer(grpcServer, handler)
 
 	reflection.Register(grpcServer)
 
@@ -32,17 +47,68 @@ func NewGRPCServer(cfg config.Config, service ServiceShortener, auth *auth.Manag
 
 	return &GRPCServer{
 		server:   grpcServer,
+		handler:  handler,
 		listener: listener,
+		proxies:  proxies,
 	}, nil
 }
 
+// Reload применяет обновляемые параметры конфигурации: список доверенных прокси.
+func (g *GRPCServer) Reload(cfg config.Config) error {
+	if err := g.proxies.Set(cfg.Handlers.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	return nil
+}
+
+// clientIPInterceptor определяет адрес клиента по адресу соединения и метаданным
+// x-forwarded-for, forwarded и x-real-ip (только от доверенных прокси)
+// и сохраняет его в контексте вызова.
+func clientIPInterceptor(res *clientip.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withClientIP(ctx, res), req)
+	}
+}
+
+// clientIPStreamInterceptor — вариант clientIPInterceptor для потоковых вызовов.
+// Пользователь потоковых вызовов определяется по тем же метаданным authorization,
+// что и у унарных.
+func clientIPStreamInterceptor(res *clientip.Resolver) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withClientIP(ss.Context(), res)})
+	}
+}
+
+// withClientIP возвращает контекст вызова с адресом клиента.
+func withClientIP(ctx context.Context, res *clientip.Resolver) context.Context {
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	addr := res.ResolveFrom(remote, func(key string) []string { return md.Get(key) })
+	return clientip.NewContext(ctx, addr)
+}
+
+// contextStream подменяет контекст потокового вызова.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст вызова с адресом клиента.
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
 // Serve запускает gRPC-сервер
 func (g *GRPCServer) Serve() error {
 	return g.server.Serve(g.listener)
 }
 
-// GracefulStop останавливает gRPC-сервер
+// GracefulStop останавливает gRPC-сервер, завершив открытые потоки WatchLinks.
 func (g *GRPCServer) GracefulStop() {
+	g.handler.closeStreams()
 	g.server.GracefulStop()
 }
 
@@ -50,7 +116,7 @@ func (g *GRPCServer) GracefulStop() {
 func (g *GRPCServer) Shutdown(ctx context.Context) error {
 	done := make(chan bool, 1)
 	go func() {
-		g.server.GracefulStop()
+		g.GracefulStop()
 		done <- true
 	}()
 
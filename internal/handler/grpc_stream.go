@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/service"
+	pb "github.com/spitfy/urlshortener/pkg/shortener"
+)
+
+// linkEventNames перечисляет события, на которые можно подписаться в WatchLinks.
+var linkEventNames = []string{model.WebhookCreated, model.WebhookClicked, model.WebhookDeleted, model.WebhookExpired}
+
+// closeStreams завершает открытые потоки WatchLinks. Вызывается при остановке
+// сервера: иначе GracefulStop ждал бы окончания бесконечных потоков.
+func (s *server) closeStreams() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// StreamUserURLs передает ссылки пользователя по одной, постранично читая их
+// из хранилища, так что список не собирается в памяти целиком.
+// Фильтры и сортировка совпадают с GET /api/user/urls.
+func (s *server) StreamUserURLs(req *pb.StreamUserURLsRequest, stream grpc.ServerStreamingServer[pb.URLData]) error {
+	ctx := stream.Context()
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return err
+	}
+
+	f := model.LinkFilter{
+		Query:  req.GetQuery(),
+		Tags:   req.GetTags(),
+		Sort:   req.GetSort(),
+		Order:  req.GetOrder(),
+		Broken: req.GetBroken(),
+		Limit:  service.MaxPageSize,
+	}
+	for {
+		page, err := s.service.ListUserLinks(ctx, userID, f)
+		if err != nil {
+			return grpcError(err)
+		}
+		for _, l := range page.Links {
+			if err := stream.Send(urlDataToPB(l)); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		f.Cursor = page.NextCursor
+	}
+}
+
+// ShortenStream создает ссылки из потока запросов. Ошибка одной ссылки не прерывает
+// поток: результат каждого запроса (с номером в потоке, начиная с 0) содержит
+// короткую ссылку или код и текст ошибки. В потоке допускается не более maxBatchSize запросов.
+// С ключом идемпотентности в метаданных idempotency-key запросы сначала читаются
+// целиком, и повторный поток с тем же ключом получает сохраненный ответ первого.
+func (s *server) ShortenStream(stream grpc.ClientStreamingServer[pb.URLShortenRequest, pb.ShortenStreamResponse]) error {
+	ctx := stream.Context()
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(idempotencyMetadata)) > 0 {
+		return s.shortenStreamIdempotent(stream, userID)
+	}
+	res := &pb.ShortenStreamResponse{}
+	for i := 0; ; i++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+		if i == maxBatchSize {
+			return status.Errorf(codes.InvalidArgument, "stream exceeds %d links", maxBatchSize)
+		}
+		res.Results = append(res.Results, s.shortenItem(ctx, req, i, userID))
+	}
+}
+
+// shortenStreamIdempotent читает запросы потока целиком и создает ссылки с учетом
+// ключа идемпотентности. Запрос отождествляется последовательностью сообщений.
+func (s *server) shortenStreamIdempotent(stream grpc.ClientStreamingServer[pb.URLShortenRequest, pb.ShortenStreamResponse], userID int) error {
+	ctx := stream.Context()
+	var reqs []*pb.URLShortenRequest
+	var payload []byte
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(reqs) == maxBatchSize {
+			return status.Errorf(codes.InvalidArgument, "stream exceeds %d links", maxBatchSize)
+		}
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "could not encode request")
+		}
+		payload = protowire.AppendBytes(payload, b)
+		reqs = append(reqs, req)
+	}
+
+	res, err := idempotentRPC(ctx, s.service, userID, pb.ShortenerService_ShortenStream_FullMethodName, payload, &pb.ShortenStreamResponse{},
+		func() (*pb.ShortenStreamResponse, error) {
+			res := &pb.ShortenStreamResponse{}
+			for i, req := range reqs {
+				res.Results = append(res.Results, s.shortenItem(ctx, req, i, userID))
+			}
+			return res, nil
+		})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(res)
+}
+
+// shortenItem создает ссылку для i-го запроса потока и возвращает результат
+// с короткой ссылкой или кодом и текстом ошибки.
+func (s *server) shortenItem(ctx context.Context, req *pb.URLShortenRequest, i, userID int) *pb.ShortenResult {
+	item := &pb.ShortenResult{Index: int32(i)}
+	var err error
+	item.Result, err = s.shorten(ctx, req, userID)
+	if err != nil {
+		st := status.Convert(err)
+		item.Code, item.Error = int32(st.Code()), st.Message()
+	}
+	return item
+}
+
+// WatchLinks передает события ссылок пользователя: created, clicked, deleted, expired.
+// Заголовки ответа отправляются, когда подписка начала действовать, поэтому
+// клиент может дождаться их и только после этого выполнять действия со ссылками.
+// Каждое сообщение клиента задает список интересующих событий (пустой — все) и
+// действует до следующего сообщения. Клиент, не успевающий получать события,
+// отключается с кодом codes.ResourceExhausted; при остановке сервера поток
+// завершается с кодом codes.Unavailable.
+func (s *server) WatchLinks(stream grpc.BidiStreamingServer[pb.WatchLinksRequest, pb.LinkEvent]) error {
+	ctx := stream.Context()
+	userID, err := s.userID(ctx)
+	if err != nil {
+		return err
+	}
+	sub, err := s.service.Subscribe(userID)
+	if err != nil {
+		return grpcError(err)
+	}
+	defer sub.Close()
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	filters := make(chan []string)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				// Клиент закончил менять фильтр, но продолжает получать события.
+				return
+			}
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			for _, e := range req.GetEvents() {
+				if !slices.Contains(linkEventNames, e) {
+					recvErr <- status.Error(codes.InvalidArgument, fmt.Sprintf("unknown event %q", e))
+					return
+				}
+			}
+			select {
+			case filters <- req.GetEvents():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var events []string
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.done:
+			return grpcError(service.ErrStreamClosed)
+		case err := <-recvErr:
+			return err
+		case events = <-filters:
+		case ev, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					return grpcError(err)
+				}
+				return grpcError(service.ErrStreamClosed)
+			}
+			if len(events) > 0 && !slices.Contains(events, ev.Event) {
+				continue
+			}
+			err := stream.Send(&pb.LinkEvent{
+				Event:       ev.Event,
+				Ts:          timestamppb.New(ev.Timestamp),
+				ShortUrl:    ev.ShortURL,
+				OriginalUrl: ev.OriginalURL,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
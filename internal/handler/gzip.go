@@ -40,6 +40,21 @@ func (cw *compressWriter) WriteHeader(statusCode int) {
 	cw.w.WriteHeader(statusCode)
 }
 
+// FlushError отправляет клиенту уже сжатые данные, не завершая поток gzip.
+// Вызывается http.ResponseController для потоковых ответов (Server-Sent Events),
+// которые иначе накапливались бы в буфере компрессора до конца запроса.
+func (cw *compressWriter) FlushError() error {
+	if err := cw.zw.Flush(); err != nil {
+		return err
+	}
+	return http.NewResponseController(cw.w).Flush()
+}
+
+// Unwrap возвращает исходный http.ResponseWriter для http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.w
+}
+
 var gzipWriterPool = sync.Pool{
 	New: func() interface{} {
 		return gzip.NewWriter(nil)
@@ -86,10 +101,12 @@ func (cr *compressReader) Close() error {
 }
 
 // gzipMiddleware проверяет поддержку gzip и применяет сжатие/распаковку к запросу/ответу.
+// Запросы на переключение протокола (WebSocket) не сжимаются: после захвата
+// соединения ответ пишется в него напрямую.
 func gzipMiddleware(h http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ow := w
-		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") && r.Header.Get("Upgrade") == "" {
 			w.Header().Set("Content-Encoding", "gzip")
 			cw := newCompressWriter(w)
 			ow = cw
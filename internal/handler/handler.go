@@ -7,25 +7,56 @@ import (
 	"github.com/spitfy/urlshortener/internal/auth"
 	"github.com/spitfy/urlshortener/internal/model"
 	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 )
 
 type Handler struct {
-	service ServiceShortener
-	auth    auth.AuthManager
+	service   ServiceShortener
+	auth      auth.AuthManager
+	rpc       *server       // Реализация ShortenerService для эндпоинтов Connect
+	heartbeat time.Duration // Интервал событий heartbeat в потоках
+	done      chan struct{} // Закрывается при остановке сервера (см. closeStreams)
+	closeOnce sync.Once
 }
 
 type ServiceShortener interface {
-	Add(ctx context.Context, link string, userID int) (string, error)
-	BatchAdd(ctx context.Context, req []model.BatchCreateRequest, userID int) ([]model.BatchCreateResponse, error)
-	GetByHash(ctx context.Context, hash string) (repository.URL, error)
+	Add(ctx context.Context, link string, opts model.LinkOptions, userID int) (string, error)
+	AddLink(ctx context.Context, link string, opts model.LinkOptions, userID int) (model.LinkPair, error)
+	GetLink(ctx context.Context, domain, hash string, userID int) (model.LinkPair, error)
+	Idempotent(ctx context.Context, userID int, key, scope string, payload []byte, do func() service.IdempotentResponse) (service.IdempotentResponse, bool, error)
+	BatchAdd(ctx context.Context, req []model.BatchCreateRequest, mode model.BatchMode, userID int) ([]model.BatchCreateResponse, error)
+	GetByHash(ctx context.Context, domain, hash string) (repository.URL, error)
+	DomainByHost(host string) service.Domain
+	ShortURL(domain, hash string) (string, error)
 	Ping() error
 	GetByUserID(ctx context.Context, userID int) ([]model.LinkPair, error)
+	ListUserLinks(ctx context.Context, userID int, f model.LinkFilter) (model.LinkPage, error)
+	CheckPassword(ctx context.Context, u repository.URL, password, ip string) error
+	Destination(u repository.URL, v service.Visitor) string
+	Follow(ctx context.Context, u repository.URL, v service.Visitor, query url.Values, userID int) (string, error)
+	CreateExperiment(ctx context.Context, req model.ExperimentRequest, userID int) (model.Experiment, error)
+	GetExperiment(ctx context.Context, id int64, userID int) (model.Experiment, error)
+	ListExperiments(ctx context.Context, userID int) ([]model.Experiment, error)
+	CreateWebhook(ctx context.Context, req model.WebhookRequest, userID int) (model.Webhook, error)
+	ListWebhooks(ctx context.Context, userID int) ([]model.Webhook, error)
+	GetWebhook(ctx context.Context, id int64, userID int) (model.Webhook, error)
+	UpdateWebhook(ctx context.Context, id int64, upd model.WebhookUpdate, userID int) (model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64, userID int) error
+	ListDeliveries(ctx context.Context, id int64, userID int) ([]model.WebhookDelivery, error)
+	Subscribe(userID int) (*service.Subscription, error)
 	CreateUser(ctx context.Context) (int, error)
-	DeleteEnqueue(ctx context.Context, req []string, userID int)
+	DeleteEnqueue(ctx context.Context, domain string, req []string, userID int) (model.DeletionJob, error)
+	GetDeletion(ctx context.Context, id int64, userID int) (model.DeletionJob, error)
+	Restore(ctx context.Context, domain string, hashes []string, userID int) ([]model.RestoreResult, error)
 	AddObserver(observer audit.Observer)
 	NotifyObservers(ctx context.Context, event audit.Event)
 	Stats(ctx context.Context) (model.Stats, error)
+	Update(ctx context.Context, domain, hash string, upd model.LinkUpdate, userID int) (model.LinkPair, error)
+	GetRevisions(ctx context.Context, domain, hash string, userID int) ([]model.LinkRevision, error)
 }
 
 type RequestLogger interface {
@@ -40,7 +71,10 @@ var allowedContent = map[string]bool{
 
 func newHandler(s ServiceShortener, a *auth.Manager) *Handler {
 	return &Handler{
-		service: s,
-		auth:    a,
+		service:   s,
+		auth:      a,
+		rpc:       newGRPC(s, a),
+		heartbeat: streamHeartbeat,
+		done:      make(chan struct{}),
 	}
 }
@@ -1,29 +1,51 @@
 package handler
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"connectrpc.com/connect"
+	"github.com/spitfy/urlshortener/internal/audit"
 	"github.com/spitfy/urlshortener/internal/auth"
 	authConf "github.com/spitfy/urlshortener/internal/auth/config"
+	"github.com/spitfy/urlshortener/internal/clientip"
 	handlerConf "github.com/spitfy/urlshortener/internal/handler/config"
 	"github.com/spitfy/urlshortener/internal/logger"
 	models "github.com/spitfy/urlshortener/internal/model"
 	repoConf "github.com/spitfy/urlshortener/internal/repository/config"
 	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/websocket"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/spitfy/urlshortener/internal/config"
 	"github.com/spitfy/urlshortener/internal/repository"
 	"github.com/spitfy/urlshortener/internal/service"
+	pb "github.com/spitfy/urlshortener/pkg/shortener"
+	"github.com/spitfy/urlshortener/pkg/shortener/shortenerconnect"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,6 +68,7 @@ func TestMain(m *testing.M) {
 	if err := os.Remove(cfg.FileStorage.FileStoragePath); err != nil {
 		log.Println(err)
 	}
+	_ = os.Remove(cfg.FileStorage.FileStoragePath + ".deletions")
 	os.Exit(code)
 }
 
@@ -106,10 +129,10 @@ func TestHandler_Get(t *testing.T) {
 	store, err := repository.CreateStore(&cfg)
 	require.NoError(t, err, "error creating store")
 	ctx := context.Background()
-	_, _ = store.Add(ctx, repository.URL{Hash: "XXAABBOO", Link: "https://pkg.go.dev/"}, -1)
+	_, _ = store.Add(ctx, repository.URL{Hash: "XXAABBOO", Link: "https://pkg.go.dev/std"}, -1)
 	handler := newHandler(service.NewService(cfg, store), am)
 	l := logger.InitMock()
-	srv = httptest.NewServer(newRouter(handler, l, &cfg))
+	srv = httptest.NewServer(newRouter(handler, l, &clientip.Networks{}, &clientip.Networks{}))
 
 	tests := []struct {
 		name         string
@@ -123,12 +146,12 @@ func TestHandler_Get(t *testing.T) {
 			method:       http.MethodGet,
 			expectedCode: http.StatusTemporaryRedirect,
 			hash:         "XXAABBOO",
-			location:     "https://pkg.go.dev/",
+			location:     "https://pkg.go.dev/std",
 		},
 		{
 			name:         "not_found",
 			method:       http.MethodGet,
-			expectedCode: http.StatusBadRequest,
+			expectedCode: http.StatusNotFound,
 			hash:         "UNKNOWN",
 			location:     "",
 		},
@@ -169,6 +192,7 @@ func TestHandler_Get(t *testing.T) {
 
 			if tt.location != "" {
 				assert.Equal(t, tt.location, resp.Header().Get("Location"))
+				assert.Empty(t, resp.Cookies(), "redirect must not create a user")
 			}
 
 			assert.Equal(t, tt.expectedCode, resp.StatusCode(), "Response code mismatch")
@@ -179,9 +203,13 @@ func TestHandler_Get(t *testing.T) {
 func TestHandler_ShortenURL(t *testing.T) {
 	store, err := repository.CreateStore(&cfg)
 	require.NoError(t, err, "error creating store")
-	h := newHandler(service.NewService(cfg, store), am)
+	s := service.NewService(cfg, store)
+	h := newHandler(s, am)
 	srv = httptest.NewServer(h.authMiddleware(h.ShortenURL))
 	token, _ := am.BuildJWT(123)
+	sub, err := s.Subscribe(123)
+	require.NoError(t, err)
+	defer sub.Close()
 
 	tests := []struct {
 		name         string
@@ -231,6 +259,22 @@ func TestHandler_ShortenURL(t *testing.T) {
 			expectedCode: http.StatusCreated,
 			expectedBody: true,
 		},
+		{
+			name:         "method_post_conflict",
+			method:       http.MethodPost,
+			body:         `{"url": "https://www.perplexity.ai"}`,
+			contentType:  "application/json",
+			expectedCode: http.StatusConflict,
+			expectedBody: true,
+		},
+		{
+			name:         "unknown_domain",
+			method:       http.MethodPost,
+			body:         `{"url": "https://www.perplexity.ai", "domain": "unknown.example"}`,
+			contentType:  "application/json",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: false,
+		},
 		{
 			name:         "bad_content_type",
 			method:       http.MethodPost,
@@ -265,4 +309,1383 @@ func TestHandler_ShortenURL(t *testing.T) {
 			}
 		})
 	}
+	assert.Len(t, sub.Events(), 1, "an existing URL is not reported as created")
+}
+
+func TestHandler_GetRedirectOptions(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	ctx := context.Background()
+	_, _ = store.Add(ctx, repository.URL{
+		Hash: "REDIR301",
+		Link: "https://pkg.go.dev/search?q=chi",
+		Redirect: models.RedirectOptions{
+			Code:      http.StatusMovedPermanently,
+			PassQuery: true,
+			UTM:       models.UTM{Source: "test"},
+		},
+	}, -1)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+
+	client := resty.New()
+	client.SetRedirectPolicy(resty.NoRedirectPolicy())
+
+	resp, err := client.R().Get(srv.URL + "/REDIR301?page=2")
+	if err != nil && !strings.Contains(err.Error(), "auto redirect is disabled") {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode())
+	assert.Equal(t, "https://pkg.go.dev/search?page=2&q=chi&utm_source=test", resp.Header().Get("Location"))
+
+	resp, err = client.R().Get(srv.URL + "/REDIR301+")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, string(resp.Body()), "https://pkg.go.dev/search?q=chi&amp;utm_source=test")
+}
+
+func TestHandler_GetDomains(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	ctx := context.Background()
+	_, _ = store.Add(ctx, repository.URL{Hash: "SAMEHASH", Link: "https://default.example/"}, -1)
+	_, _ = store.Add(ctx, repository.URL{Hash: "SAMEHASH", Domain: "go.brand.example", Link: "https://brand.example/"}, -1)
+
+	conf := cfg
+	conf.Service.Domains = []serviceConf.Domain{{
+		Name:         "go.brand.example",
+		RedirectCode: http.StatusMovedPermanently,
+		NotFoundURL:  "https://brand.example/404",
+	}}
+	handler := newHandler(service.NewService(conf, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+
+	client := resty.New()
+	client.SetRedirectPolicy(resty.NoRedirectPolicy())
+	get := func(host, path string) *resty.Response {
+		resp, err := client.R().SetHeader("Host", host).Get(srv.URL + path)
+		if err != nil && !strings.Contains(err.Error(), "auto redirect is disabled") {
+			require.NoError(t, err)
+		}
+		return resp
+	}
+
+	resp := get("go.brand.example", "/SAMEHASH")
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode(), "domain redirect code")
+	assert.Equal(t, "https://brand.example/", resp.Header().Get("Location"))
+
+	resp = get("localhost", "/SAMEHASH")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode())
+	assert.Equal(t, "https://default.example/", resp.Header().Get("Location"))
+
+	resp = get("go.brand.example", "/MISSING1")
+	assert.Equal(t, http.StatusFound, resp.StatusCode(), "domain 404 fallback")
+	assert.Equal(t, "https://brand.example/404", resp.Header().Get("Location"))
+
+	resp = get("localhost", "/MISSING1")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	var p models.Problem
+	require.NoError(t, json.Unmarshal(resp.Body(), &p))
+	assert.Equal(t, models.Problem{
+		Type:   "urn:urlshortener:problem:not_found",
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: "URL not found",
+		Code:   "not_found",
+	}, p)
+}
+
+func TestHandler_QRCode(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	ctx := context.Background()
+	_, _ = store.Add(ctx, repository.URL{Hash: "QRCODE01", Link: "https://example.com/"}, 1)
+	_, _ = store.Add(ctx, repository.URL{Hash: "QRGONE01", Link: "https://example.org/", DeletedFlag: true}, 1)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	owner, _ := am.BuildJWT(1)
+	other, _ := am.BuildJWT(2)
+
+	client := resty.New()
+	resp, err := client.R().Get(srv.URL + "/QRCODE01/qr")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Header().Get("Cache-Control"), "public")
+	etag := resp.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	resp, err = client.R().SetHeader("If-None-Match", etag).Get(srv.URL + "/QRCODE01/qr")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode())
+	assert.Empty(t, resp.Body())
+
+	resp, err = client.R().SetHeader("If-None-Match", etag).Get(srv.URL + "/QRCODE01/qr?format=svg")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode(), "parameters change the etag")
+	assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		code  int
+	}{
+		{"invalid options", "/QRCODE01/qr?size=1", "", http.StatusBadRequest},
+		{"unknown hash", "/MISSING1/qr", "", http.StatusNotFound},
+		{"deleted link", "/QRGONE01/qr", "", http.StatusGone},
+		{"owner", "/api/user/urls/QRCODE01/qr?format=svg", owner, http.StatusOK},
+		{"other user", "/api/user/urls/QRCODE01/qr", other, http.StatusNotFound},
+		{"anonymous", "/api/user/urls/QRCODE01/qr", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := client.R()
+			if tt.token != "" {
+				req.SetCookie(&http.Cookie{Name: "ID", Value: tt.token})
+			}
+			resp, err := req.Get(srv.URL + tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode())
+		})
+	}
+}
+
+func TestHandler_Update(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	ctx := context.Background()
+	_, _ = store.Add(ctx, repository.URL{Hash: "PATCHME1", Link: "https://pkg.go.dev/"}, 7)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+
+	owner, _ := am.BuildJWT(7)
+	stranger, _ := am.BuildJWT(8)
+
+	tests := []struct {
+		name         string
+		token        string
+		body         string
+		expectedCode int
+	}{
+		{"unauthorized", "", `{"title": "x"}`, http.StatusUnauthorized},
+		{"not_owner", stranger, `{"title": "x"}`, http.StatusNotFound},
+		{"invalid_url", owner, `{"original_url": "pkg.go.dev"}`, http.StatusBadRequest},
+		{"invalid_redirect", owner, `{"redirect": {"code": 200}}`, http.StatusBadRequest},
+		{"success", owner, `{"original_url": "https://go.dev/", "tags": ["go"]}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resty.New().R().
+				SetHeader("Content-Type", "application/json").
+				SetBody(tt.body)
+			if tt.token != "" {
+				req.SetCookie(&http.Cookie{Name: "ID", Value: tt.token})
+			}
+			resp, err := req.Patch(srv.URL + "/api/user/urls/PATCHME1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode(), "Response code mismatch")
+
+			if tt.expectedCode == http.StatusOK {
+				var link models.LinkPair
+				require.NoError(t, json.Unmarshal(resp.Body(), &link))
+				assert.Equal(t, "https://go.dev/", link.OriginalURL)
+				assert.Equal(t, []string{"go"}, link.Tags)
+			}
+		})
+	}
+
+	resp, err := resty.New().R().
+		SetCookie(&http.Cookie{Name: "ID", Value: owner}).
+		Get(srv.URL + "/api/user/urls/PATCHME1/history")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	var revs []models.LinkRevision
+	require.NoError(t, json.Unmarshal(resp.Body(), &revs))
+	require.Len(t, revs, 1)
+	assert.Equal(t, "https://pkg.go.dev/", revs[0].OriginalURL)
+}
+
+func TestHandler_GetByUserID(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, _ = store.Add(ctx, repository.URL{Hash: "LISTED01", Link: "https://go.dev/", Title: "Go", Tags: []string{"go"}}, 9)
+	_, _ = store.Add(ctx, repository.URL{Hash: "LISTED02", Link: "https://pkg.go.dev/", Note: "packages"}, 9)
+	_, _ = store.Add(ctx, repository.URL{Hash: "LISTED03", Link: "https://example.com/", Tags: []string{"go", "misc"}}, 9)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	token, _ := am.BuildJWT(9)
+
+	client := resty.New().SetRedirectPolicy(resty.NoRedirectPolicy())
+	for range 2 {
+		_, _ = client.R().Get(srv.URL + "/LISTED02")
+	}
+
+	list := func(query string) (*resty.Response, []models.LinkPair) {
+		resp, err := resty.New().R().
+			SetCookie(&http.Cookie{Name: "ID", Value: token}).
+			Get(srv.URL + "/api/user/urls?" + query)
+		require.NoError(t, err)
+		var links []models.LinkPair
+		if resp.StatusCode() == http.StatusOK {
+			require.NoError(t, json.Unmarshal(resp.Body(), &links))
+		}
+		return resp, links
+	}
+	urls := func(links []models.LinkPair) []string {
+		res := make([]string, 0, len(links))
+		for _, l := range links {
+			res = append(res, l.OriginalURL)
+		}
+		return res
+	}
+
+	resp, links := list("sort=clicks&limit=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, []string{"https://pkg.go.dev/"}, urls(links))
+	assert.Equal(t, int64(2), links[0].Clicks)
+	cursor := resp.Header().Get("X-Next-Cursor")
+	require.NotEmpty(t, cursor)
+
+	resp, links = list("sort=clicks&limit=5&cursor=" + cursor)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Len(t, links, 2)
+	assert.Empty(t, resp.Header().Get("X-Next-Cursor"))
+
+	_, links = list("tag=go&tag=misc")
+	assert.Equal(t, []string{"https://example.com/"}, urls(links))
+	_, links = list("tag=go,misc")
+	assert.Equal(t, []string{"https://example.com/"}, urls(links))
+	_, links = list("q=packages")
+	assert.Equal(t, []string{"https://pkg.go.dev/"}, urls(links))
+
+	resp, _ = list("q=nothing")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode())
+	for _, query := range []string{"sort=title", "limit=x", "cursor=" + cursor} {
+		resp, _ = list(query)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode(), query)
+	}
+}
+
+func TestHandler_GetProtected(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, _ = store.Add(context.Background(), repository.URL{
+		Hash: "PROTECT1", Link: "https://example.com/", PasswordHash: string(hash),
+	}, 9)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New().SetRedirectPolicy(resty.NoRedirectPolicy())
+
+	resp, _ := client.R().Get(srv.URL + "/PROTECT1?a=1")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+	assert.Contains(t, resp.String(), `action="/PROTECT1?a=1"`)
+	assert.Empty(t, resp.Header().Get("Location"))
+
+	resp, _ = client.R().Get(srv.URL + "/PROTECT1+")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+	assert.NotContains(t, resp.String(), "example.com", "preview must not reveal the target")
+
+	resp, _ = client.R().SetHeader(PasswordHeader, "secret").Get(srv.URL + "/PROTECT1")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode())
+	assert.Equal(t, "https://example.com/", resp.Header().Get("Location"))
+	assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+
+	resp, _ = client.R().SetFormData(map[string]string{"password": "secret"}).Post(srv.URL + "/PROTECT1")
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode())
+	assert.Equal(t, "https://example.com/", resp.Header().Get("Location"))
+
+	for range service.MaxPasswordAttempts - 1 {
+		resp, _ = client.R().SetFormData(map[string]string{"password": "wrong"}).Post(srv.URL + "/PROTECT1")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode())
+		assert.Contains(t, resp.String(), "Неверный пароль")
+	}
+	resp, _ = client.R().SetHeader(PasswordHeader, "wrong").Get(srv.URL + "/PROTECT1")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, "900", resp.Header().Get("Retry-After"))
+}
+
+func TestHandler_GetLimited(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	_, _ = store.Add(context.Background(), repository.URL{Hash: "ONETIME1", Link: "https://example.com/", MaxClicks: 1}, 9)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New().SetRedirectPolicy(resty.NoRedirectPolicy())
+
+	resp, _ := client.R().Get(srv.URL + "/ONETIME1+")
+	assert.Equal(t, http.StatusOK, resp.StatusCode(), "preview does not use the link")
+	resp, _ = client.R().Get(srv.URL + "/ONETIME1")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode())
+	resp, _ = client.R().Get(srv.URL + "/ONETIME1")
+	assert.Equal(t, http.StatusGone, resp.StatusCode())
+	resp, _ = client.R().Get(srv.URL + "/ONETIME1+")
+	assert.Equal(t, http.StatusGone, resp.StatusCode())
+
+	token, _ := am.BuildJWT(9)
+	resp, err = resty.New().R().SetCookie(&http.Cookie{Name: "ID", Value: token}).Get(srv.URL + "/api/user/urls")
+	require.NoError(t, err)
+	assert.Contains(t, resp.String(), `"max_clicks":1,"clicks_left":0`)
+}
+
+func TestHandler_GetRules(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New().SetRedirectPolicy(resty.NoRedirectPolicy())
+	token, _ := am.BuildJWT(9)
+
+	resp, err := client.R().SetCookie(&http.Cookie{Name: "ID", Value: token}).
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/", "rules": [{"url": "https://countries.example/", "countries": ["US"]}]}`).
+		Post(srv.URL + "/api/shorten")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode(), "country rules need a GeoIP database")
+
+	resp, err = client.R().SetCookie(&http.Cookie{Name: "ID", Value: token}).
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/", "rules": [
+			{"url": "https://apps.apple.com/app", "platforms": ["ios"]},
+			{"url": "https://example.com/ru", "languages": ["ru"]}
+		]}`).
+		Post(srv.URL + "/api/shorten")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode(), resp.String())
+	var created models.Response
+	require.NoError(t, json.Unmarshal(resp.Body(), &created))
+	hash := created.Result[strings.LastIndex(created.Result, "/")+1:]
+
+	tests := []struct {
+		name     string
+		ua       string
+		lang     string
+		location string
+	}{
+		{"ios", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)", "ru", "https://apps.apple.com/app"},
+		{"language", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "ru-RU,en;q=0.5", "https://example.com/ru"},
+		{"fallback", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "en", "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := client.R().SetHeader("User-Agent", tt.ua).SetHeader("Accept-Language", tt.lang).Get(srv.URL + "/" + hash)
+			assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode())
+			assert.Equal(t, tt.location, resp.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHandler_Experiment(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New().SetRedirectPolicy(resty.NoRedirectPolicy())
+	token, _ := am.BuildJWT(9)
+	owner := &http.Cookie{Name: "ID", Value: token}
+
+	resp, err := client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"variants": [{"url": "https://example.com/a"}]}`).
+		Post(srv.URL + "/api/user/experiments")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode(), "an experiment needs at least two variants")
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"name": "landing", "variants": [
+			{"url": "https://example.com/a", "weight": 1},
+			{"url": "https://example.com/b", "weight": 1}
+		]}`).
+		Post(srv.URL + "/api/user/experiments")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode(), resp.String())
+	var e models.Experiment
+	require.NoError(t, json.Unmarshal(resp.Body(), &e))
+	hash := e.ShortURL[strings.LastIndex(e.ShortURL, "/")+1:]
+
+	resp, _ = client.R().Get(srv.URL + "/" + hash)
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode())
+	var visitor *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "vid" {
+			visitor = c
+		}
+	}
+	require.NotNil(t, visitor, "the first visit issues a visitor cookie")
+	location := resp.Header().Get("Location")
+	assert.Contains(t, []string{"https://example.com/a", "https://example.com/b"}, location)
+	for range 3 {
+		resp, _ = client.R().SetCookie(visitor).Get(srv.URL + "/" + hash)
+		assert.Equal(t, location, resp.Header().Get("Location"), "the variant sticks to the visitor")
+		assert.Empty(t, resp.Cookies())
+	}
+
+	resp, err = client.R().SetCookie(owner).Get(fmt.Sprintf("%s/api/user/experiments/%d", srv.URL, e.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.NoError(t, json.Unmarshal(resp.Body(), &e))
+	assert.Equal(t, int64(4), e.Redirects)
+
+	resp, err = client.R().SetCookie(owner).Get(srv.URL + "/api/user/experiments")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Contains(t, resp.String(), `"name":"landing"`)
+
+	other, _ := am.BuildJWT(10)
+	resp, err = client.R().SetCookie(&http.Cookie{Name: "ID", Value: other}).
+		Get(fmt.Sprintf("%s/api/user/experiments/%d", srv.URL, e.ID))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+}
+
+func TestHandler_BatchAdd(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	handler := newHandler(service.NewService(cfg, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+
+	tooLarge := make([]models.BatchCreateRequest, maxBatchSize+1)
+	for i := range tooLarge {
+		tooLarge[i] = models.BatchCreateRequest{CorrelationID: fmt.Sprint(i), OriginalURL: "https://example.com/"}
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		body         any
+		expectedCode int
+		statuses     []string
+	}{
+		{
+			name:         "duplicates_in_batch",
+			body:         `[{"correlation_id": "1", "original_url": "https://go.dev/batch"}, {"correlation_id": "2", "original_url": "https://go.dev/batch"}]`,
+			expectedCode: http.StatusCreated,
+			statuses:     []string{models.BatchStatusCreated, models.BatchStatusExisting},
+		},
+		{
+			name:         "atomic_rejects_invalid",
+			body:         `[{"correlation_id": "1", "original_url": "https://go.dev/a"}, {"correlation_id": "2", "original_url": "go.dev"}]`,
+			expectedCode: http.StatusBadRequest,
+			statuses:     []string{models.BatchStatusInvalid},
+		},
+		{
+			name:         "best_effort",
+			query:        "?mode=best-effort",
+			body:         `[{"correlation_id": "1", "original_url": "https://go.dev/a"}, {"correlation_id": "2", "original_url": "go.dev"}]`,
+			expectedCode: http.StatusCreated,
+			statuses:     []string{models.BatchStatusCreated, models.BatchStatusInvalid},
+		},
+		{name: "unknown_mode", query: "?mode=partial", body: `[]`, expectedCode: http.StatusBadRequest},
+		{name: "empty_batch", body: `[]`, expectedCode: http.StatusBadRequest},
+		{name: "too_many_items", body: tooLarge, expectedCode: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := resty.New().R().
+				SetHeader("Content-Type", "application/json").
+				SetBody(tt.body).
+				Post(srv.URL + "/api/shorten/batch" + tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode(), "Response code mismatch")
+
+			if tt.statuses != nil {
+				var res []models.BatchCreateResponse
+				require.NoError(t, json.Unmarshal(resp.Body(), &res))
+				statuses := make([]string, 0, len(res))
+				for _, r := range res {
+					statuses = append(statuses, r.Status)
+				}
+				assert.Equal(t, tt.statuses, statuses)
+			}
+		})
+	}
+}
+
+func TestHandler_Delete(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	_, _ = store.Add(context.Background(), repository.URL{Hash: "DELETE01", Link: "https://pkg.go.dev/delete"}, 7)
+	svc := service.NewService(cfg, store)
+	handler := newHandler(svc, am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+
+	owner, _ := am.BuildJWT(7)
+	stranger, _ := am.BuildJWT(8)
+	ownerEvents, err := svc.Subscribe(7)
+	require.NoError(t, err)
+	strangerEvents, err := svc.Subscribe(8)
+	require.NoError(t, err)
+
+	// Удаление чужой ссылки принимается, но не порождает событий.
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetCookie(&http.Cookie{Name: "ID", Value: stranger}).
+		SetBody(`["DELETE01"]`).
+		Delete(srv.URL + "/api/user/urls")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode())
+
+	resp, err = resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetCookie(&http.Cookie{Name: "ID", Value: owner}).
+		SetBody(`["DELETE01"]`).
+		Delete(srv.URL + "/api/user/urls")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode())
+	var job models.DeletionJob
+	require.NoError(t, json.Unmarshal(resp.Body(), &job))
+	location := resp.Header().Get("Location")
+	assert.Equal(t, fmt.Sprintf("/api/user/deletions/%d", job.ID), location)
+
+	require.NoError(t, svc.Shutdown(context.Background()))
+	assert.Empty(t, strangerEvents.Events(), "the caller's stream gets nothing for a link it does not own")
+	require.Len(t, ownerEvents.Events(), 1)
+	ev := <-ownerEvents.Events()
+	assert.Equal(t, models.WebhookDeleted, ev.Event)
+	assert.Equal(t, "https://pkg.go.dev/delete", ev.OriginalURL)
+
+	tests := []struct {
+		name         string
+		token        string
+		path         string
+		expectedCode int
+	}{
+		{"unauthorized", "", location, http.StatusUnauthorized},
+		{"not_owner", stranger, location, http.StatusNotFound},
+		{"invalid_id", owner, "/api/user/deletions/abc", http.StatusBadRequest},
+		{"success", owner, location, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resty.New().R()
+			if tt.token != "" {
+				req.SetCookie(&http.Cookie{Name: "ID", Value: tt.token})
+			}
+			resp, err := req.Get(srv.URL + tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode(), "Response code mismatch")
+			if tt.expectedCode == http.StatusOK {
+				var got models.DeletionJob
+				require.NoError(t, json.Unmarshal(resp.Body(), &got))
+				assert.Equal(t, models.DeletionDone, got.Status)
+			}
+		})
+	}
+}
+
+func TestHandler_Restore(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err, "error creating store")
+	ctx := context.Background()
+	_, _ = store.Add(ctx, repository.URL{Hash: "RESTORE1", Link: "https://pkg.go.dev/restore"}, 7)
+	require.NoError(t, store.BatchDelete(ctx, repository.UserHash{UserID: 7, Hash: []string{"RESTORE1"}}))
+	conf := cfg
+	conf.Service.RetentionPeriod = time.Hour
+	handler := newHandler(service.NewService(conf, store), am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+
+	owner, _ := am.BuildJWT(7)
+
+	tests := []struct {
+		name         string
+		token        string
+		body         string
+		expectedCode int
+		want         []models.RestoreResult
+	}{
+		{name: "unauthorized", body: `["RESTORE1"]`, expectedCode: http.StatusUnauthorized},
+		{name: "invalid_body", token: owner, body: `{"hash": "RESTORE1"}`, expectedCode: http.StatusBadRequest},
+		{
+			name:         "success",
+			token:        owner,
+			body:         `["RESTORE1", "MISSING1"]`,
+			expectedCode: http.StatusOK,
+			want: []models.RestoreResult{
+				{Hash: "RESTORE1", Status: models.RestoreRestored},
+				{Hash: "MISSING1", Status: models.RestoreNotFound},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resty.New().R().
+				SetHeader("Content-Type", "application/json").
+				SetBody(tt.body)
+			if tt.token != "" {
+				req.SetCookie(&http.Cookie{Name: "ID", Value: tt.token})
+			}
+			resp, err := req.Post(srv.URL + "/api/user/urls/restore")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode(), "Response code mismatch")
+			if tt.want != nil {
+				var res []models.RestoreResult
+				require.NoError(t, json.Unmarshal(resp.Body(), &res))
+				assert.Equal(t, tt.want, res)
+			}
+		})
+	}
+
+	u, err := store.GetByHash(ctx, "", "RESTORE1")
+	require.NoError(t, err)
+	assert.False(t, u.DeletedFlag)
+}
+
+func TestClientIPInterceptor(t *testing.T) {
+	proxies, err := clientip.NewNetworks("10.0.0.0/8")
+	require.NoError(t, err)
+	interceptor := clientIPInterceptor(clientip.NewResolver(proxies))
+	call := func(remote string, md metadata.MD) string {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(remote), Port: 5000}})
+		ctx = metadata.NewIncomingContext(ctx, md)
+		var got string
+		_, err := interceptor(ctx, nil, nil, func(ctx context.Context, _ any) (any, error) {
+			got = clientip.String(ctx)
+			return nil, nil
+		})
+		require.NoError(t, err)
+		return got
+	}
+
+	assert.Equal(t, "203.0.113.5", call("203.0.113.5", metadata.Pairs("x-forwarded-for", "1.1.1.1")))
+	assert.Equal(t, "198.51.100.7", call("10.0.0.1", metadata.Pairs("x-forwarded-for", "198.51.100.7")))
+	assert.Equal(t, "2001:db8::1", call("2001:db8::1", nil))
+}
+
+func TestHandler_Webhooks(t *testing.T) {
+	events := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get("X-Webhook-Event")
+	}))
+	defer receiver.Close()
+
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	conf := cfg
+	conf.Service.AllowPrivateTargets = true
+	s := service.NewService(conf, store)
+	defer s.Shutdown(context.Background())
+	handler := newHandler(s, am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New()
+	token, _ := am.BuildJWT(11)
+	owner := &http.Cookie{Name: "ID", Value: token}
+	otherToken, _ := am.BuildJWT(12)
+	other := &http.Cookie{Name: "ID", Value: otherToken}
+
+	resp, err := client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "ftp://example.com/hook"}`).
+		Post(srv.URL + "/api/user/webhooks")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(fmt.Sprintf(`{"url": %q, "events": ["created"]}`, receiver.URL)).
+		Post(srv.URL + "/api/user/webhooks")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode(), resp.String())
+	var hook models.Webhook
+	require.NoError(t, json.Unmarshal(resp.Body(), &hook))
+	assert.NotEmpty(t, hook.Secret)
+	hookURL := fmt.Sprintf("%s/api/user/webhooks/%d", srv.URL, hook.ID)
+
+	resp, err = client.R().SetCookie(other).Get(hookURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode(), "webhooks of other users are hidden")
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/webhooks"}`).
+		Post(srv.URL + "/api/shorten")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	select {
+	case e := <-events:
+		assert.Equal(t, models.WebhookCreated, e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("created event was not delivered")
+	}
+	require.Eventually(t, func() bool {
+		var list []models.WebhookDelivery
+		resp, err := client.R().SetCookie(owner).SetResult(&list).Get(hookURL + "/deliveries")
+		return err == nil && resp.StatusCode() == http.StatusOK &&
+			len(list) == 1 && list[0].Status == models.DeliveryDelivered
+	}, 5*time.Second, 10*time.Millisecond)
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"active": false}`).
+		Patch(hookURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode(), resp.String())
+	var updated models.Webhook
+	require.NoError(t, json.Unmarshal(resp.Body(), &updated))
+	assert.False(t, updated.Active)
+	assert.Empty(t, updated.Secret, "the secret is returned only when it changes")
+
+	var list []models.Webhook
+	resp, err = client.R().SetCookie(owner).SetResult(&list).Get(srv.URL + "/api/user/webhooks")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, list, 1)
+	assert.Equal(t, hook.ID, list[0].ID)
+
+	resp, err = client.R().SetCookie(other).Delete(hookURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+	resp, err = client.R().SetCookie(owner).Delete(hookURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode())
+	resp, err = client.R().SetCookie(owner).Get(hookURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+}
+
+func TestHandler_Stream(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := service.NewService(cfg, store)
+	defer s.Shutdown(context.Background())
+	handler := newHandler(s, am)
+	handler.heartbeat = 50 * time.Millisecond
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	token, _ := am.BuildJWT(13)
+	owner := &http.Cookie{Name: "ID", Value: token}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/user/stream", nil)
+	require.NoError(t, err)
+	req.AddCookie(owner)
+	// Поток сжимается, но каждое событие отправляется клиенту сразу.
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	zr, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	lines := bufio.NewReader(zr)
+	next := func() (string, models.StreamEvent) {
+		event, err := lines.ReadString('\n')
+		require.NoError(t, err)
+		data, err := lines.ReadString('\n')
+		require.NoError(t, err)
+		_, err = lines.ReadString('\n')
+		require.NoError(t, err)
+		var ev models.StreamEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &ev))
+		return strings.TrimSpace(strings.TrimPrefix(event, "event: ")), ev
+	}
+	name, _ := next()
+	assert.Equal(t, models.StreamHeartbeat, name)
+
+	resp2, err := resty.New().R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/stream"}`).
+		Post(srv.URL + "/api/shorten")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp2.StatusCode())
+	for {
+		name, ev := next()
+		if name == models.StreamHeartbeat {
+			continue
+		}
+		assert.Equal(t, models.WebhookCreated, name)
+		assert.Equal(t, "https://example.com/stream", ev.OriginalURL)
+		break
+	}
+
+	// Остановка сервера завершает поток событием closed.
+	handler.closeStreams()
+	for {
+		name, ev := next()
+		if name == models.StreamHeartbeat {
+			continue
+		}
+		assert.Equal(t, models.StreamClosed, name)
+		assert.NotEmpty(t, ev.Error)
+		break
+	}
+	_, err = lines.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestHandler_StreamWebSocket(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := service.NewService(cfg, store)
+	defer s.Shutdown(context.Background())
+	handler := newHandler(s, am)
+	srv := httptest.NewServer(newRouter(handler, logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	token, _ := am.BuildJWT(14)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/user/stream/ws"
+
+	foreign, err := websocket.NewConfig(wsURL, "https://evil.example.com")
+	require.NoError(t, err)
+	foreign.Header.Set("Cookie", "ID="+token)
+	_, err = websocket.DialConfig(foreign)
+	assert.Error(t, err, "connections from other sites are rejected")
+
+	wsConf, err := websocket.NewConfig(wsURL, srv.URL)
+	require.NoError(t, err)
+	wsConf.Header.Set("Cookie", "ID="+token)
+	conn, err := websocket.DialConfig(wsConf)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := resty.New().R().SetCookie(&http.Cookie{Name: "ID", Value: token}).
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/ws"}`).
+		Post(srv.URL + "/api/shorten")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var ev models.StreamEvent
+	require.NoError(t, websocket.JSON.Receive(conn, &ev))
+	assert.Equal(t, models.WebhookCreated, ev.Event)
+	assert.Equal(t, "https://example.com/ws", ev.OriginalURL)
+}
+
+func TestHandler_FollowTransports(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := service.NewService(cfg, store)
+	defer s.Shutdown(context.Background())
+	var (
+		mu      sync.Mutex
+		follows []audit.Event
+	)
+	s.AddObserver(audit.ObserverFunc(func(_ context.Context, e audit.Event) error {
+		if e.Action == audit.Follow {
+			mu.Lock()
+			follows = append(follows, e)
+			mu.Unlock()
+		}
+		return nil
+	}))
+	srv := httptest.NewServer(newRouter(newHandler(s, am), logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	gs := grpc.NewServer()
+	pb.RegisterShortenerServiceServer(gs, newGRPC(s, am))
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+	defer gs.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	pair, err := s.AddLink(ctx, "https://example.com/", models.LinkOptions{
+		Rules: []models.RouteRule{{URL: "https://example.com/ru", Languages: []string{"ru"}}},
+	}, 31)
+	require.NoError(t, err)
+	hash := pair.Hash
+
+	transports := map[string]func() (string, error){
+		"http": func() (string, error) {
+			// Ошибка клиента означает только запрет следовать перенаправлению.
+			resp, _ := resty.New().SetRedirectPolicy(resty.NoRedirectPolicy()).R().
+				SetHeader("Accept-Language", "ru").Get(srv.URL + "/" + hash)
+			return resp.Header().Get("Location"), nil
+		},
+		"grpc": func() (string, error) {
+			ctx := metadata.AppendToOutgoingContext(ctx, "accept-language", "ru")
+			res, err := pb.NewShortenerServiceClient(conn).ExpandURL(ctx, &pb.URLExpandRequest{Id: hash})
+			return res.GetResult(), err
+		},
+		"connect": func() (string, error) {
+			req := connect.NewRequest(&pb.URLExpandRequest{Id: hash})
+			req.Header().Set("Accept-Language", "ru")
+			res, err := shortenerconnect.NewShortenerServiceClient(srv.Client(), srv.URL).ExpandURL(ctx, req)
+			if err != nil {
+				return "", err
+			}
+			return res.Msg.GetResult(), nil
+		},
+	}
+	for name, follow := range transports {
+		t.Run(name, func(t *testing.T) {
+			before, err := s.GetByHash(ctx, "", hash)
+			require.NoError(t, err)
+			target, err := follow()
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/ru", target, "routing rules apply")
+			after, err := s.GetByHash(ctx, "", hash)
+			require.NoError(t, err)
+			assert.Equal(t, before.Clicks+1, after.Clicks, "the click is counted")
+		})
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(follows) == len(transports)
+	}, 5*time.Second, 10*time.Millisecond, "every transport notifies observers")
+}
+
+func TestGRPC_Streams(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := service.NewService(cfg, store)
+	defer s.Shutdown(context.Background())
+	resolver := clientip.NewResolver(&clientip.Networks{})
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(clientIPInterceptor(resolver)),
+		grpc.StreamInterceptor(clientIPStreamInterceptor(resolver)),
+	)
+	h := newGRPC(s, am)
+	pb.RegisterShortenerServiceServer(gs, h)
+	lis := bufconn.Listen(1 << 20)
+	go gs.Serve(lis)
+	defer gs.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewShortenerServiceClient(conn)
+	token, _ := am.BuildJWT(15)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", token)
+
+	unauth, err := client.StreamUserURLs(context.Background(), &pb.StreamUserURLsRequest{})
+	require.NoError(t, err)
+	_, err = unauth.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "streams use the same auth as unary calls")
+
+	watch, err := client.WatchLinks(ctx)
+	require.NoError(t, err)
+	_, err = watch.Header()
+	require.NoError(t, err)
+
+	bulk, err := client.ShortenStream(ctx)
+	require.NoError(t, err)
+	for _, req := range []*pb.URLShortenRequest{
+		{Url: "https://example.com/grpc/1"},
+		{Url: "https://example.com/grpc/2", Redirect: &pb.RedirectOptions{Code: 200}},
+		{Url: "https://example.com/grpc/1"},
+		{Url: "https://example.com/grpc/3", Tags: []string{"bulk"}},
+	} {
+		require.NoError(t, bulk.Send(req))
+	}
+	res, err := bulk.CloseAndRecv()
+	require.NoError(t, err)
+	require.Len(t, res.GetResults(), 4)
+	assert.NotEmpty(t, res.GetResults()[0].GetResult())
+	assert.Equal(t, int32(codes.InvalidArgument), res.GetResults()[1].GetCode())
+	assert.NotEmpty(t, res.GetResults()[1].GetError())
+	assert.Equal(t, int32(codes.AlreadyExists), res.GetResults()[2].GetCode())
+	assert.Equal(t, res.GetResults()[0].GetResult(), res.GetResults()[2].GetResult(), "the existing link is returned")
+	assert.Equal(t, int32(3), res.GetResults()[3].GetIndex())
+	assert.Equal(t, int32(codes.OK), res.GetResults()[3].GetCode())
+
+	for _, want := range []string{"https://example.com/grpc/1", "https://example.com/grpc/3"} {
+		ev, err := watch.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, models.WebhookCreated, ev.GetEvent())
+		assert.Equal(t, want, ev.GetOriginalUrl())
+	}
+
+	list, err := client.StreamUserURLs(ctx, &pb.StreamUserURLsRequest{Sort: "clicks", Order: "asc"})
+	require.NoError(t, err)
+	var got []string
+	for {
+		u, err := list.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, u.GetOriginalUrl())
+	}
+	assert.ElementsMatch(t, []string{"https://example.com/grpc/1", "https://example.com/grpc/3"}, got)
+	list, err = client.StreamUserURLs(ctx, &pb.StreamUserURLsRequest{Tags: []string{"bulk"}})
+	require.NoError(t, err)
+	u, err := list.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/grpc/3", u.GetOriginalUrl())
+	_, err = list.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	list, err = client.StreamUserURLs(ctx, &pb.StreamUserURLsRequest{Sort: "title"})
+	require.NoError(t, err)
+	_, err = list.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	require.NoError(t, watch.Send(&pb.WatchLinksRequest{Events: []string{"visited"}}))
+	_, err = watch.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Остановка сервера завершает потоки событий.
+	watch, err = client.WatchLinks(ctx)
+	require.NoError(t, err)
+	_, err = watch.Header()
+	require.NoError(t, err)
+	h.closeStreams()
+	_, err = watch.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestConnect(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := service.NewService(cfg, store)
+	defer s.Shutdown(context.Background())
+	srv := httptest.NewServer(newRouter(newHandler(s, am), logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	token, _ := am.BuildJWT(21)
+
+	call := func(method, body, token string) (int, map[string]any) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/shortener.ShortenerService/"+method, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var res map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return resp.StatusCode, res
+	}
+
+	code, res := call("ShortenURL", `{"url":"https://example.com/connect"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "unauthenticated", res["code"])
+
+	code, res = call("ShortenURL", `{"url":"https://example.com/connect"}`, token)
+	require.Equal(t, http.StatusOK, code)
+	shortURL, _ := res["result"].(string)
+	require.NotEmpty(t, shortURL)
+
+	code, res = call("ShortenURL", `{"url":"https://example.com/connect"}`, token)
+	assert.Equal(t, http.StatusConflict, code, "duplicates map to the same status as the legacy API")
+	assert.Equal(t, "already_exists", res["code"])
+	assert.NotEmpty(t, res["details"], "the error reason is passed as google.rpc.ErrorInfo")
+
+	code, res = call("ShortenURL", `{"url":"https://example.com/connect/2","redirect":{"code":200}}`, token)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_argument", res["code"])
+
+	hash := shortURL[strings.LastIndex(shortURL, "/")+1:]
+	code, res = call("ExpandURL", `{"id":"`+hash+`"}`, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "https://example.com/connect", res["result"])
+
+	require.NoError(t, store.BatchDelete(context.Background(), repository.UserHash{UserID: 21, Hash: []string{hash}}))
+	code, res = call("ExpandURL", `{"id":"`+hash+`"}`, "")
+	assert.Equal(t, http.StatusNotFound, code, "deleted links are not expanded")
+	assert.Equal(t, "not_found", res["code"])
+
+	// Потоковые методы доступны сгенерированному клиенту Connect.
+	client := shortenerconnect.NewShortenerServiceClient(srv.Client(), srv.URL)
+	call2 := client.ShortenStream(context.Background())
+	call2.RequestHeader().Set("Authorization", token)
+	require.NoError(t, call2.Send(&pb.URLShortenRequest{Url: "https://example.com/connect/3"}))
+	require.NoError(t, call2.Send(&pb.URLShortenRequest{Url: "https://example.com/connect/3"}))
+	bulk, err := call2.CloseAndReceive()
+	require.NoError(t, err)
+	require.Len(t, bulk.Msg.GetResults(), 2)
+	assert.Equal(t, int32(codes.AlreadyExists), bulk.Msg.GetResults()[1].GetCode())
+
+	req := connect.NewRequest(&pb.StreamUserURLsRequest{})
+	req.Header().Set("Authorization", token)
+	stream, err := client.StreamUserURLs(context.Background(), req)
+	require.NoError(t, err)
+	var urls []string
+	for stream.Receive() {
+		urls = append(urls, stream.Msg().GetOriginalUrl())
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []string{"https://example.com/connect/3"}, urls, "deleted links are not listed")
+}
+
+func TestHandler_V2(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := service.NewService(cfg, store)
+	defer s.Shutdown(context.Background())
+	srv := httptest.NewServer(newRouter(newHandler(s, am), logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New()
+	token, _ := am.BuildJWT(31)
+	owner := &http.Cookie{Name: "ID", Value: token}
+	otherToken, _ := am.BuildJWT(32)
+	other := &http.Cookie{Name: "ID", Value: otherToken}
+
+	resp, err := client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/v2", "title": "V2", "tags": ["api"]}`).
+		Post(srv.URL + "/api/v2/links")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode(), resp.String())
+	var created models.Envelope[models.LinkResource]
+	require.NoError(t, json.Unmarshal(resp.Body(), &created))
+	link := created.Data
+	require.NotEmpty(t, link.ID)
+	assert.Equal(t, "/api/v2/links/"+link.ID, resp.Header().Get("Location"))
+	assert.Equal(t, "https://example.com/v2", link.OriginalURL)
+	assert.False(t, link.CreatedAt.IsZero())
+	assert.Equal(t, models.LinkMetadata{Title: "V2", Tags: []string{"api"}}, link.Metadata)
+
+	resp, err = client.R().SetCookie(other).SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "https://example.com/v2"}`).
+		Post(srv.URL + "/api/v2/links")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode(), "an existing link is returned with 200")
+	var existing models.Envelope[models.LinkResource]
+	require.NoError(t, json.Unmarshal(resp.Body(), &existing))
+	assert.Equal(t, link.ID, existing.Data.ID)
+	assert.Empty(t, existing.Data.Metadata.Title, "metadata of other users' links is hidden")
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"url": "not a url"}`).
+		Post(srv.URL + "/api/v2/links")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+
+	resp, err = client.R().SetCookie(owner).Get(srv.URL + "/api/v2/links/" + link.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.JSONEq(t, string(mustJSON(t, created)), resp.String())
+
+	resp, err = client.R().SetCookie(other).Get(srv.URL + "/api/v2/links/" + link.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode(), "links of other users are hidden")
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"mode": "best-effort", "items": [
+			{"correlation_id": "1", "original_url": "https://example.com/v2/1"},
+			{"correlation_id": "2", "original_url": "bad"}
+		]}`).
+		Post(srv.URL + "/api/v2/links/batch")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode(), resp.String())
+	var batch models.Envelope[[]models.BatchCreateResponse]
+	require.NoError(t, json.Unmarshal(resp.Body(), &batch))
+	require.Len(t, batch.Data, 2)
+	assert.Equal(t, models.BatchStatusCreated, batch.Data[0].Status)
+	assert.Equal(t, models.BatchStatusInvalid, batch.Data[1].Status)
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"items": [{"correlation_id": "3", "original_url": "bad"}]}`).
+		Post(srv.URL + "/api/v2/links/batch")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	var rejected models.Problem
+	require.NoError(t, json.Unmarshal(resp.Body(), &rejected))
+	assert.Equal(t, "batch_rejected", rejected.Code)
+	require.Len(t, rejected.Items, 1)
+	assert.Equal(t, "3", rejected.Items[0].CorrelationID)
+
+	resp, err = client.R().SetCookie(owner).Get(srv.URL + "/api/v2/links?limit=1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	var page models.PageEnvelope[models.LinkResource]
+	require.NoError(t, json.Unmarshal(resp.Body(), &page))
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, 1, page.Page.Limit)
+	require.NotEmpty(t, page.Page.NextCursor)
+
+	resp, err = client.R().SetCookie(owner).Get(srv.URL + "/api/v2/links?limit=1&cursor=" + page.Page.NextCursor)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	var next models.PageEnvelope[models.LinkResource]
+	require.NoError(t, json.Unmarshal(resp.Body(), &next))
+	require.Len(t, next.Data, 1)
+	assert.NotEqual(t, page.Data[0].ID, next.Data[0].ID)
+	assert.Empty(t, next.Page.NextCursor)
+
+	resp, err = client.R().SetCookie(other).Get(srv.URL + "/api/v2/links")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.JSONEq(t, `{"data": [], "page": {"limit": 100}}`, resp.String(), "an empty list is an empty page, not 204")
+
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetBody(`{"title": "V2 updated"}`).
+		Patch(srv.URL + "/api/v2/links/" + link.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode(), resp.String())
+	var updated models.Envelope[models.LinkResource]
+	require.NoError(t, json.Unmarshal(resp.Body(), &updated))
+	assert.Equal(t, "V2 updated", updated.Data.Metadata.Title)
+
+	resp, err = client.R().SetCookie(owner).Get(srv.URL + "/api/v2/links/" + link.ID + "/history")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	var history models.Envelope[[]models.LinkRevision]
+	require.NoError(t, json.Unmarshal(resp.Body(), &history))
+	assert.Len(t, history.Data, 1)
+
+	resp, err = client.R().SetCookie(other).Delete(srv.URL + "/api/v2/links/" + link.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	resp, err = client.R().SetCookie(owner).Delete(srv.URL + "/api/v2/links/" + link.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode(), resp.String())
+	var job models.Envelope[models.DeletionJob]
+	require.NoError(t, json.Unmarshal(resp.Body(), &job))
+	assert.Equal(t, fmt.Sprintf("/api/user/deletions/%d", job.Data.ID), resp.Header().Get("Location"))
+
+	resp, err = client.R().SetCookie(owner).Get(srv.URL + "/api/user/urls")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode(), "v1 keeps its format")
+	var v1 []models.LinkPair
+	require.NoError(t, json.Unmarshal(resp.Body(), &v1))
+	assert.NotEmpty(t, v1)
+}
+
+func TestHandler_Idempotency(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	conf := cfg
+	conf.Service.IdempotencyTTL = time.Hour
+	s := service.NewService(conf, store)
+	defer s.Shutdown(context.Background())
+	srv := httptest.NewServer(newRouter(newHandler(s, am), logger.InitMock(), &clientip.Networks{}, &clientip.Networks{}))
+	defer srv.Close()
+	client := resty.New()
+	token, _ := am.BuildJWT(41)
+	owner := &http.Cookie{Name: "ID", Value: token}
+
+	batch := func(key, body string) *resty.Response {
+		resp, err := client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+			SetHeader("Idempotency-Key", key).SetBody(body).
+			Post(srv.URL + "/api/shorten/batch")
+		require.NoError(t, err)
+		return resp
+	}
+	body := `[{"correlation_id": "1", "original_url": "https://example.com/idem/1"}]`
+	first := batch("batch-1", body)
+	require.Equal(t, http.StatusCreated, first.StatusCode(), first.String())
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	again := batch("batch-1", body)
+	require.Equal(t, http.StatusCreated, again.StatusCode())
+	assert.Equal(t, "true", again.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.String(), again.String(), "the first response is replayed, not reported as existing")
+	assert.Equal(t, "application/json", again.Header().Get("Content-Type"))
+
+	fresh := batch("batch-2", body)
+	assert.Contains(t, fresh.String(), models.BatchStatusExisting, "without the key the link is reported as existing")
+
+	conflict := batch("batch-1", `[{"correlation_id": "1", "original_url": "https://example.com/idem/2"}]`)
+	require.Equal(t, http.StatusBadRequest, conflict.StatusCode())
+	var p models.Problem
+	require.NoError(t, json.Unmarshal(conflict.Body(), &p))
+	assert.Equal(t, "idempotency_key_reused", p.Code)
+
+	resp, err := client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetHeader("Idempotency-Key", "link-1").SetBody(`{"url": "https://example.com/idem/v2"}`).
+		Post(srv.URL + "/api/v2/links")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	location := resp.Header().Get("Location")
+	resp, err = client.R().SetCookie(owner).SetHeader("Content-Type", "application/json").
+		SetHeader("Idempotency-Key", "link-1").SetBody(`{"url": "https://example.com/idem/v2"}`).
+		Post(srv.URL + "/api/v2/links")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	assert.Equal(t, location, resp.Header().Get("Location"))
+
+	call := func(key string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/shortener.ShortenerService/ShortenURL",
+			strings.NewReader(`{"url":"https://example.com/idem/rpc"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+	code, res := call("rpc-1")
+	require.Equal(t, http.StatusOK, code, res)
+	code, replayed := call("rpc-1")
+	assert.Equal(t, http.StatusOK, code, "the gRPC metadata key replays the first response")
+	assert.JSONEq(t, res, replayed)
+	code, _ = call("rpc-2")
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
+
+func TestErrorMapping(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		code   codes.Code
+		status int
+		reason string
+	}{
+		{fmt.Errorf("%w: title is too long", service.ErrInvalidLinkMeta), codes.InvalidArgument, http.StatusBadRequest, "invalid_link_meta"},
+		{repository.ErrNotFound, codes.NotFound, http.StatusNotFound, "not_found"},
+		{repository.ErrExhausted, codes.NotFound, http.StatusGone, "url_exhausted"},
+		{repository.ErrExistsURL, codes.AlreadyExists, http.StatusConflict, "url_exists"},
+		{service.ErrDomainForbidden, codes.PermissionDenied, http.StatusForbidden, "domain_forbidden"},
+		{service.ErrPasswordRequired, codes.Unauthenticated, http.StatusUnauthorized, "password_required"},
+		{service.ErrTooManyAttempts, codes.ResourceExhausted, http.StatusTooManyRequests, "too_many_attempts"},
+		{service.ErrStreamClosed, codes.Unavailable, http.StatusServiceUnavailable, "stream_closed"},
+		{errors.New("connection refused"), codes.Internal, http.StatusInternalServerError, ""},
+		{fmt.Errorf("save url: %w", errors.New(`ERROR: relation "urls" does not exist (SQLSTATE 42P01)`)),
+			codes.Internal, http.StatusInternalServerError, ""},
+	} {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			st := status.Convert(grpcError(tt.err))
+			assert.Equal(t, tt.code, st.Code())
+			var ce *connect.Error
+			require.ErrorAs(t, connectError(tt.err), &ce)
+			assert.Equal(t, connect.Code(tt.code), ce.Code())
+			if tt.code == codes.Internal {
+				assert.Equal(t, "internal error", st.Message(), "internal errors are not disclosed")
+				assert.Equal(t, "internal error", ce.Message())
+			} else {
+				assert.Equal(t, tt.err.Error(), st.Message())
+			}
+			var reason string
+			for _, d := range st.Details() {
+				if info, ok := d.(*errdetails.ErrorInfo); ok {
+					reason = info.GetReason()
+				}
+			}
+			assert.Equal(t, tt.reason, reason)
+
+			rec := httptest.NewRecorder()
+			httpError(rec, tt.err, "internal error")
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			var p models.Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, tt.reason, p.Code)
+			if tt.status == http.StatusInternalServerError {
+				assert.Equal(t, "internal error", p.Detail, "internal errors are not disclosed")
+				assert.Equal(t, "about:blank", p.Type)
+			} else {
+				assert.Equal(t, tt.err.Error(), p.Detail)
+			}
+		})
+	}
 }
@@ -4,13 +4,18 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/clientip"
 	"github.com/spitfy/urlshortener/internal/model"
 	"github.com/spitfy/urlshortener/internal/repository"
 
 	"log"
 	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -19,92 +24,210 @@ import (
 
 // Get обрабатывает запрос на получение оригинального URL по хешу
 // @Summary Получить оригинальный URL
-// @Description Перенаправляет на оригинальный URL по сокращенному хешу
+// @Description Перенаправляет на оригинальный URL по сокращенному хешу.
+// @Description Код ответа, передача query-параметров и UTM-метки задаются параметрами ссылки.
+// @Description Ссылка ищется в домене из заголовка Host; для неизвестного хеша домен может
+// @Description перенаправлять (302) на адрес-заглушку.
+// @Description Адрес назначения выбирается правилами ссылки (платформа, язык, страна, время, доля
+// @Description посетителей); если ни одно правило не подходит, используется оригинальный URL.
+// @Description Ссылка A/B-эксперимента ведет на вариант, закрепленный за посетителем cookie vid.
+// @Description Для защищенной паролем ссылки пароль передается в заголовке X-Link-Password;
+// @Description без него возвращается HTML-форма ввода пароля.
 // @Tags URL
 // @Param hash path string true "Хеш сокращенного URL"
+// @Param X-Link-Password header string false "Пароль защищенной ссылки"
+// @Success 301 {string} string "Постоянное перенаправление (если задано для ссылки)"
+// @Success 302 {string} string "Найдено (если задано для ссылки)"
 // @Success 307 {string} string "Перенаправление на оригинальный URL"
-// @Success 410 {string} string "URL был удален"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 401 {string} string "Неавторизованный доступ"
+// @Success 308 {string} string "Постоянное перенаправление (если задано для ссылки)"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 404 {object} model.Problem "URL не найден"
+// @Failure 410 {object} model.Problem "URL был удален или исчерпал лимит переходов"
+// @Failure 401 {string} string "Ссылка защищена паролем (HTML-форма)"
+// @Failure 403 {string} string "Неверный пароль (HTML-форма)"
+// @Failure 429 {object} model.Problem "Слишком много неверных паролей"
 // @Router /{hash} [get]
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.Header().Set("Allow", http.MethodGet)
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "method not allowed", http.StatusBadRequest)
 		return
 	}
+	h.follow(w, r, r.Header.Get(PasswordHeader))
+}
 
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
+// follow выполняет переход по короткой ссылке из запроса с паролем password.
+func (h *Handler) follow(w http.ResponseWriter, r *http.Request, password string) {
+	// Для анонимных посетителей ID пользователя в аудите равен 0.
+	userID, _ := r.Context().Value("userID").(int)
 
+	domain := h.service.DomainByHost(r.Host)
 	hash := chi.URLParam(r, "hash")
 	if len(hash) == 0 || len(hash) > service.CharCnt {
-		w.WriteHeader(http.StatusBadRequest)
+		notFound(w, r, domain, repository.ErrNotFound)
 		return
 	}
 
-	u, err := h.service.GetByHash(r.Context(), hash)
+	u, err := h.service.GetByHash(r.Context(), domain.Name, hash)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_, _ = w.Write([]byte(err.Error()))
+		notFound(w, r, domain, err)
 		return
 	}
 	if u.DeletedFlag {
-		w.WriteHeader(http.StatusGone)
+		httpError(w, service.ErrDeleted, "")
+		return
+	}
+	if u.Exhausted() {
+		httpError(w, repository.ErrExhausted, "")
+		return
+	}
+	// Форма пароля отправляется только для защищенных ссылок.
+	if r.Method == http.MethodPost && u.PasswordHash == "" {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.service.CheckPassword(r.Context(), u, password, clientip.String(r.Context())); err != nil {
+		passwordForm(w, r, hash, err)
 		return
 	}
 
-	h.service.NotifyObservers(r.Context(), audit.Event{
-		Timestamp: time.Now(),
-		Action:    audit.Follow,
-		UserID:    userID,
-		URL:       u.Link,
-	})
+	v := visitor(r)
+	if u.Experiment != 0 {
+		// Вариант эксперимента закрепляется за посетителем cookie.
+		v.ID = visitorID(w, r)
+	}
+	target, err := h.service.Follow(r.Context(), u, v, r.URL.Query(), userID)
+	if err != nil {
+		httpError(w, err, "could not follow URL")
+		return
+	}
+
+	status := domain.Redirect(u.Redirect)
+	if u.PasswordHash != "" {
+		// Перенаправление защищенной ссылки не должно кэшироваться, иначе
+		// повторный переход обойдет проверку пароля.
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method == http.MethodPost {
+			status = http.StatusSeeOther
+		}
+	}
+	w.Header().Add("Location", target)
+	w.WriteHeader(status)
+}
+
+// route возвращает ссылку с адресом назначения, выбранным правилами ссылки для посетителя.
+func (h *Handler) route(r *http.Request, u repository.URL) repository.URL {
+	u.Link = h.service.Destination(u, visitor(r))
+	return u
+}
+
+// visitor возвращает параметры посетителя, по которым выбирается адрес назначения.
+func visitor(r *http.Request) service.Visitor {
+	ip, _ := clientip.FromContext(r.Context())
+	return service.Visitor{
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		IP:             ip,
+		Time:           time.Now(),
+	}
+}
 
-	w.Header().Add("Location", u.Link)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+// notFound отвечает на запрос неизвестного хеша: перенаправляет на адрес-заглушку
+// домена, если он задан, иначе отвечает ошибкой err (404 для repository.ErrNotFound).
+func notFound(w http.ResponseWriter, r *http.Request, domain service.Domain, err error) {
+	if domain.NotFoundURL != "" && errors.Is(err, repository.ErrNotFound) {
+		http.Redirect(w, r, domain.NotFoundURL, http.StatusFound)
+		return
+	}
+	httpError(w, err, "could not get URL")
 }
 
-// GetByUserID возвращает все сокращенные URL пользователя
+// GetByUserID возвращает страницу сокращенных URL пользователя
 // @Summary Получить URL пользователя
-// @Description Возвращает все сокращенные URL, созданные текущим пользователем
+// @Description Возвращает сокращенные URL, созданные текущим пользователем, с поиском,
+// @Description фильтрами по тегам и недоступности адреса назначения и сортировкой. Удаленные ссылки не возвращаются.
+// @Description Если есть следующая страница, ее курсор передается в заголовке X-Next-Cursor.
 // @Tags User
 // @Produce json
-// @Success 200 {array} model.URL "Список сокращенных URL"
-// @Success 204 {string} string "Нет сохраненных URL"
-// @Failure 401 {string} string "Неавторизованный доступ"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Param q query string false "Слова для поиска в заголовке, заметке и оригинальном URL"
+// @Param tag query []string false "Теги, которые должны быть у ссылки (все перечисленные)" collectionFormat(multi)
+// @Param sort query string false "Поле сортировки" Enums(created, clicks)
+// @Param order query string false "Порядок сортировки" Enums(desc, asc)
+// @Param limit query int false "Размер страницы (1..1000, по умолчанию 100)"
+// @Param cursor query string false "Курсор следующей страницы из заголовка X-Next-Cursor"
+// @Param broken query bool false "Только ссылки, адрес назначения которых недоступен по результатам последней проверки"
+// @Success 200 {array} model.LinkPair "Список сокращенных URL"
+// @Success 204 {string} string "Нет подходящих URL"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Failure 400 {object} model.Problem "Некорректные параметры выборки"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
 // @Router /api/user/urls [get]
 func (h *Handler) GetByUserID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		httpProblem(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	userID, ok := r.Context().Value("userID").(int)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter, err := linkFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, err, "")
 		return
 	}
-	res, err := h.service.GetByUserID(r.Context(), userID)
+	page, err := h.service.ListUserLinks(r.Context(), userID, filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err, "could not list URLs")
 		return
 	}
-	if len(res) == 0 {
+	if len(page.Links) == 0 {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err = encodeJSONBuffered(w, res); err != nil {
-		http.Error(w, "encoding error", http.StatusInternalServerError)
+	if err = encodeJSONBuffered(w, page.Links); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
 		return
 	}
 }
 
+// linkFilter разбирает параметры списка ссылок из query-параметров запроса.
+// Теги передаются повторяющимся параметром tag или через запятую.
+func linkFilter(q url.Values) (model.LinkFilter, error) {
+	f := model.LinkFilter{
+		Query:  q.Get("q"),
+		Sort:   q.Get("sort"),
+		Order:  q.Get("order"),
+		Cursor: q.Get("cursor"),
+	}
+	for _, v := range q["tag"] {
+		f.Tags = append(f.Tags, strings.Split(v, ",")...)
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return f, fmt.Errorf("%w: limit: expected integer, got %q", service.ErrInvalidFilter, v)
+		}
+		f.Limit = n
+	}
+	if v := q.Get("broken"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("%w: broken: expected boolean, got %q", service.ErrInvalidFilter, v)
+		}
+		f.Broken = b
+	}
+	return f, nil
+}
+
 // Post создает новый сокращенный URL из текстового тела запроса
 // @Summary Сократить URL (текст)
 // @Description Создает новый сокращенный URL из текстового тела запроса
@@ -112,21 +235,22 @@ func (h *Handler) GetByUserID(w http.ResponseWriter, r *http.Request) {
 // @Accept text/plain
 // @Produce text/plain
 // @Param url body string true "Оригинальный URL для сокращения"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ"
 // @Success 201 {string} string "Создан новый сокращенный URL"
 // @Success 409 {string} string "URL уже был сокращен ранее"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 401 {string} string "Неавторизованный доступ"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
 // @Router / [post]
 func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", http.MethodPost)
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "method not allowed", http.StatusBadRequest)
 		return
 	}
 
 	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil || !allowedContent[mediaType] {
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "unsupported content type", http.StatusBadRequest)
 		return
 	}
 
@@ -136,16 +260,16 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	if err != nil || len(body) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	userID, ok := r.Context().Value("userID").(int)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	shortURL, err := h.service.Add(r.Context(), string(body), userID)
+	shortURL, err := h.service.Add(r.Context(), string(body), model.LinkOptions{}, userID)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrExistsURL) {
@@ -153,8 +277,8 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 			_, _ = w.Write([]byte(shortURL))
 			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
 		log.Printf("Error saving url: %v", err)
+		httpError(w, err, "could not shorten URL")
 		return
 	}
 
@@ -163,6 +287,8 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 		Action:    audit.Shorten,
 		UserID:    userID,
 		URL:       string(body),
+		IP:        clientip.String(r.Context()),
+		ShortURL:  shortURL,
 	})
 
 	w.WriteHeader(http.StatusCreated)
@@ -176,112 +302,173 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param request body model.Request true "Запрос на сокращение URL"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ"
 // @Success 201 {object} model.Response "Создан новый сокращенный URL"
 // @Success 409 {object} model.Response "URL уже был сокращен ранее"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 401 {string} string "Неавторизованный доступ"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Failure 400 {object} model.Problem "Некорректный запрос или неизвестный домен"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 403 {object} model.Problem "Домен недоступен пользователю"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
 // @Router /api/shorten [post]
 func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", http.MethodPost)
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		httpProblem(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil || mediaType != "application/json" {
-		http.Error(w, "invalid content-type", http.StatusBadRequest)
+		httpProblem(w, "invalid content-type", http.StatusBadRequest)
 		return
 	}
 
 	body, err := readBodyLimited(r.Body, 100*1024)
 	if err != nil {
-		http.Error(w, "invalid json body", http.StatusBadRequest)
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
 		return
 	}
 	var req model.Request
 	if err = json.Unmarshal(body, &req); err != nil {
-		http.Error(w, "invalid json body", http.StatusBadRequest)
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
 		return
 	}
 	userID, ok := r.Context().Value("userID").(int)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	shortURL, err := h.service.Add(r.Context(), req.URL, req.LinkOptions, userID)
+	// Для уже сокращенного URL в ответе 409 возвращается существующая ссылка.
+	if err != nil && !errors.Is(err, repository.ErrExistsURL) {
+		httpError(w, err, "could not shorten URL")
 		return
 	}
-	shortURL, err := h.service.Add(r.Context(), req.URL, userID)
 	res := model.Response{Result: shortURL}
 	w.Header().Set("Content-Type", "application/json")
-
-	switch {
-	case err == nil:
-		w.WriteHeader(http.StatusCreated)
-	case errors.Is(err, repository.ErrExistsURL):
+	if err != nil {
 		w.WriteHeader(http.StatusConflict)
-	default:
-		http.Error(w, "could not shorten URL", http.StatusInternalServerError)
+		if err := encodeJSONBuffered(w, res); err != nil {
+			httpProblem(w, "encoding error", http.StatusInternalServerError)
+		}
 		return
 	}
+	w.WriteHeader(http.StatusCreated)
 
 	h.service.NotifyObservers(r.Context(), audit.Event{
 		Timestamp: time.Now(),
 		Action:    audit.Shorten,
 		UserID:    userID,
 		URL:       req.URL,
+		IP:        clientip.String(r.Context()),
+		ShortURL:  shortURL,
 	})
 
 	if err := encodeJSONBuffered(w, res); err != nil {
-		http.Error(w, "encoding error", http.StatusInternalServerError)
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
 		return
 	}
 }
 
+// maxBatchSize ограничивает количество элементов в пакетном запросе.
+const maxBatchSize = 1000
+
+// maxBatchBodySize ограничивает размер тела пакетного запроса в байтах.
+const maxBatchBodySize = 1 << 20
+
 // BatchAdd создает несколько сокращенных URL из пакетного запроса
 // @Summary Пакетное сокращение URL
-// @Description Создает несколько сокращенных URL из пакетного запроса
+// @Description Создает несколько сокращенных URL из пакетного запроса за одно обращение к хранилищу.
+// @Description Для каждого элемента возвращается статус: created, existing или invalid.
+// @Description В режиме atomic (по умолчанию) пакет с невалидными элементами отклоняется целиком,
+// @Description в режиме best-effort сохраняются только валидные элементы.
 // @Tags URL
 // @Accept json
 // @Produce json
 // @Param request body []model.BatchCreateRequest true "Список URL для сокращения"
-// @Success 201 {array} model.BatchCreateResponse "Список созданных сокращенных URL"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 401 {string} string "Неавторизованный доступ"
-// @Failure 500 {string} string "Внутренняя ошибка сервера"
+// @Param mode query string false "Режим обработки" Enums(atomic, best-effort)
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ"
+// @Success 201 {array} model.BatchCreateResponse "Результаты по каждому элементу"
+// @Failure 400 {array} model.BatchCreateResponse "Некорректный запрос или список невалидных элементов"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 409 {object} model.Problem "Запрос с тем же ключом идемпотентности еще выполняется"
+// @Failure 413 {object} model.Problem "Превышен допустимый размер пакета"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
 // @Router /api/shorten/batch [post]
 func (h *Handler) BatchAdd(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", http.MethodPost)
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "method not allowed", http.StatusBadRequest)
 		return
 	}
 
 	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil || mediaType != "application/json" {
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "unsupported content type", http.StatusBadRequest)
+		return
+	}
+
+	mode := model.BatchMode(r.URL.Query().Get("mode"))
+	switch mode {
+	case "":
+		mode = model.BatchAtomic
+	case model.BatchAtomic, model.BatchBestEffort:
+	default:
+		httpProblem(w, "invalid mode: expected atomic or best-effort", http.StatusBadRequest)
 		return
 	}
 
 	var req []model.BatchCreateRequest
-	dec := json.NewDecoder(r.Body)
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
 	if err := dec.Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			httpProblem(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	if len(req) == 0 {
+		httpProblem(w, "empty batch", http.StatusBadRequest)
+		return
+	}
+	if len(req) > maxBatchSize {
+		httpProblem(w, fmt.Sprintf("batch too large: max %d items", maxBatchSize), http.StatusRequestEntityTooLarge)
 		return
 	}
 
 	userID, ok := r.Context().Value("userID").(int)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	batchResponse, err := h.service.BatchAdd(r.Context(), req, userID)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	batchResponse, err := h.service.BatchAdd(r.Context(), req, mode, userID)
+	status := http.StatusCreated
+	switch {
+	case errors.Is(err, service.ErrBatchRejected):
+		status = http.StatusBadRequest
+	case err != nil:
+		httpError(w, err, "could not shorten URLs")
 		return
 	}
 
+	for i, item := range batchResponse {
+		if item.Status != model.BatchStatusCreated {
+			continue
+		}
+		h.service.NotifyObservers(r.Context(), audit.Event{
+			Timestamp: time.Now(),
+			Action:    audit.Shorten,
+			UserID:    userID,
+			URL:       req[i].OriginalURL,
+			IP:        clientip.String(r.Context()),
+			ShortURL:  item.ShortURL,
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 
 	if err = encodeJSONBuffered(w, batchResponse); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -291,42 +478,248 @@ func (h *Handler) BatchAdd(w http.ResponseWriter, r *http.Request) {
 
 // Delete помечает URL как удаленные
 // @Summary Удалить URL
-// @Description Помечает указанные URL как удаленные (асинхронно)
+// @Description Ставит задание на удаление указанных URL в постоянную очередь (асинхронно).
+// @Description Статус задания доступен по адресу из заголовка Location.
 // @Tags URL
 // @Accept json
 // @Produce json
 // @Param request body []string true "Список хешей URL для удаления"
-// @Success 202 {string} string "Запрос на удаление принят"
-// @Failure 400 {string} string "Некорректный запрос"
-// @Failure 401 {string} string "Неавторизованный доступ"
+// @Param domain query string false "Домен ссылок; по умолчанию домен сервиса"
+// @Success 202 {object} model.DeletionJob "Задание на удаление принято"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
 // @Router /api/user/urls [delete]
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		w.Header().Set("Allow", http.MethodDelete)
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "method not allowed", http.StatusBadRequest)
 		return
 	}
 
 	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil || mediaType != "application/json" {
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "unsupported content type", http.StatusBadRequest)
 		return
 	}
 
 	var req []string
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
 		return
 	}
 
 	userID, ok := r.Context().Value("userID").(int)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	h.service.DeleteEnqueue(r.Context(), req, userID)
+	job, err := h.service.DeleteEnqueue(r.Context(), r.URL.Query().Get("domain"), req, userID)
+	if err != nil {
+		httpError(w, err, "could not enqueue deletion")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/user/deletions/%d", job.ID))
 	w.WriteHeader(http.StatusAccepted)
+	if err := encodeJSONBuffered(w, job); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Restore восстанавливает удаленные URL
+// @Summary Восстановить URL
+// @Description Восстанавливает удаленные ссылки текущего пользователя, если срок восстановления не истек.
+// @Description Ссылка не восстанавливается, если ее оригинальный URL уже сокращен заново.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body []string true "Список хешей URL для восстановления"
+// @Param domain query string false "Домен ссылок; по умолчанию домен сервиса"
+// @Success 200 {array} model.RestoreResult "Результат восстановления по каждой ссылке"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/urls/restore [post]
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		httpProblem(w, "invalid content-type", http.StatusBadRequest)
+		return
+	}
+
+	body, err := readBodyLimited(r.Body, 100*1024)
+	if err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	var req []string
+	if err := json.Unmarshal(body, &req); err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	res, err := h.service.Restore(r.Context(), r.URL.Query().Get("domain"), req, userID)
+	if err != nil {
+		httpError(w, err, "could not restore URLs")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSONBuffered(w, res); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetDeletion возвращает статус задания на удаление
+// @Summary Статус удаления
+// @Description Возвращает состояние задания на удаление URL текущего пользователя
+// @Tags User
+// @Produce json
+// @Param id path int true "Идентификатор задания"
+// @Success 200 {object} model.DeletionJob "Состояние задания"
+// @Failure 400 {object} model.Problem "Некорректный идентификатор"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Задание не найдено"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/deletions/{id} [get]
+func (h *Handler) GetDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httpProblem(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+	job, err := h.service.GetDeletion(r.Context(), id, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		notFoundProblem(w, "deletion job not found")
+		return
+	}
+	if err != nil {
+		httpError(w, err, "could not get deletion job")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSONBuffered(w, job); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Update редактирует ссылку пользователя
+// @Summary Редактировать ссылку
+// @Description Изменяет адрес назначения, заголовок, теги и параметры перенаправления ссылки.
+// @Description Предыдущее состояние сохраняется в истории изменений.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param hash path string true "Хеш сокращенного URL"
+// @Param request body model.LinkUpdate true "Изменяемые поля"
+// @Param domain query string false "Домен ссылок; по умолчанию домен сервиса"
+// @Success 200 {object} model.LinkPair "Ссылка после изменения"
+// @Success 409 {object} model.Response "Новый адрес уже был сокращен ранее"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/urls/{hash} [patch]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		httpProblem(w, "invalid content-type", http.StatusBadRequest)
+		return
+	}
+
+	body, err := readBodyLimited(r.Body, 100*1024)
+	if err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	var req model.LinkUpdate
+	if err = json.Unmarshal(body, &req); err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	link, err := h.service.Update(r.Context(), r.URL.Query().Get("domain"), chi.URLParam(r, "hash"), req, userID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		httpError(w, repository.ErrNotFound, "")
+		return
+	case errors.Is(err, repository.ErrExistsURL):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_ = encodeJSONBuffered(w, model.Response{Result: link.ShortURL})
+		return
+	case err != nil:
+		httpError(w, err, "could not update URL")
+		return
+	}
+
+	h.service.NotifyObservers(r.Context(), audit.Event{
+		Timestamp: time.Now(),
+		Action:    audit.Update,
+		UserID:    userID,
+		URL:       link.OriginalURL,
+		IP:        clientip.String(r.Context()),
+		ShortURL:  link.ShortURL,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSONBuffered(w, link); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetRevisions возвращает историю изменений ссылки пользователя
+// @Summary История изменений ссылки
+// @Description Возвращает предыдущие состояния ссылки, начиная с последнего изменения
+// @Tags User
+// @Produce json
+// @Param hash path string true "Хеш сокращенного URL"
+// @Param domain query string false "Домен ссылок; по умолчанию домен сервиса"
+// @Success 200 {array} model.LinkRevision "История изменений"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/urls/{hash}/history [get]
+func (h *Handler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	revs, err := h.service.GetRevisions(r.Context(), r.URL.Query().Get("domain"), chi.URLParam(r, "hash"), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		httpError(w, repository.ErrNotFound, "")
+		return
+	}
+	if err != nil {
+		httpError(w, err, "could not get URL history")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := encodeJSONBuffered(w, revs); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
 }
 
 // Ping проверяет доступность сервера
@@ -334,11 +727,11 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 // @Description Проверяет, что сервер работает и доступен
 // @Tags Health
 // @Success 200 {string} string "Сервер доступен"
-// @Failure 500 {string} string "Сервер недоступен"
+// @Failure 500 {object} model.Problem "Сервер недоступен"
 // @Router /ping [get]
 func (h *Handler) Ping(w http.ResponseWriter, _ *http.Request) {
 	if err := h.service.Ping(); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		httpProblem(w, "storage unavailable", http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -347,13 +740,13 @@ func (h *Handler) Ping(w http.ResponseWriter, _ *http.Request) {
 func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
 	stats, err := h.service.Stats(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		httpError(w, err, "could not get stats")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := encodeJSONBuffered(w, stats); err != nil {
-		http.Error(w, "encoding error", http.StatusInternalServerError)
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
 		return
 	}
 }
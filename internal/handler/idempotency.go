@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// idempotencyHeader — заголовок запроса с ключом идемпотентности.
+const idempotencyHeader = "Idempotency-Key"
+
+// idempotencyMetadata — ключ метаданных gRPC с ключом идемпотентности.
+const idempotencyMetadata = "idempotency-key"
+
+// replayedHeader отмечает ответ, повторенный для запроса с известным ключом идемпотентности.
+const replayedHeader = "Idempotent-Replayed"
+
+// storedHeaders — заголовки ответа, которые сохраняются вместе со статусом и телом.
+var storedHeaders = []string{"Content-Type", "Location"}
+
+// idempotent обрабатывает заголовок Idempotency-Key эндпоинтов создания ссылок:
+// ответ на первый запрос с ключом сохраняется, а повторы с тем же ключом и телом
+// получают его с заголовком Idempotent-Replayed без повторного выполнения next.
+// Запросы без ключа передаются next как есть. Пользователь определяется
+// middleware авторизации, поэтому idempotent вызывается после нее.
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		userID, ok := r.Context().Value("userID").(int)
+		if key == "" || !ok {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				httpProblem(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			httpProblem(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Разные эндпоинты и параметры (например, mode пакета) — разные запросы.
+		scope := r.Method + " " + r.URL.RequestURI()
+		rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		res, replayed, err := h.service.Idempotent(r.Context(), userID, key, scope, body, func() service.IdempotentResponse {
+			next(rec, r)
+			return rec.response()
+		})
+		if err != nil {
+			httpError(w, err, "could not process idempotency key")
+			return
+		}
+		if replayed {
+			for k, v := range res.Header {
+				w.Header().Set(k, v)
+			}
+			w.Header().Set(replayedHeader, "true")
+		} else {
+			for k, v := range rec.header {
+				w.Header()[k] = v
+			}
+		}
+		w.WriteHeader(res.Status)
+		_, _ = w.Write(res.Body)
+	}
+}
+
+// responseRecorder запоминает ответ обработчика, чтобы сохранить его
+// для повторов запроса и затем отправить клиенту.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status, r.wroteHeader = code, true
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+// response возвращает записанный ответ с заголовками storedHeaders.
+func (r *responseRecorder) response() service.IdempotentResponse {
+	header := make(map[string]string)
+	for _, k := range storedHeaders {
+		if v := r.header.Get(k); v != "" {
+			header[k] = v
+		}
+	}
+	return service.IdempotentResponse{Status: r.status, Header: header, Body: r.body.Bytes()}
+}
+
+// idempotentRPC выполняет вызов call с ключом идемпотентности из метаданных
+// idempotency-key так же, как idempotent для HTTP. Сохраняется сообщение ответа
+// либо статус gRPC с деталями; req отождествляет запрос вместе с методом method.
+// Результат повтора записывается в res.
+func idempotentRPC[Res proto.Message](
+	ctx context.Context,
+	svc ServiceShortener,
+	userID int,
+	method string,
+	req []byte,
+	res Res,
+	call func() (Res, error),
+) (Res, error) {
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(idempotencyMetadata); len(v) > 0 {
+			key = v[0]
+		}
+	}
+	if key == "" {
+		return call()
+	}
+	stored, _, err := svc.Idempotent(ctx, userID, key, method, req, func() service.IdempotentResponse {
+		out, err := call()
+		if err == nil {
+			if body, merr := proto.Marshal(out); merr == nil {
+				return service.IdempotentResponse{Status: http.StatusOK, Body: body}
+			}
+			err = grpcstatus.Error(codes.Internal, "could not encode response")
+		}
+		body, _ := proto.Marshal(grpcstatus.Convert(err).Proto())
+		return service.IdempotentResponse{Status: httpStatus(err), Body: body}
+	})
+	var zero Res
+	if err != nil {
+		return zero, grpcError(err)
+	}
+	if stored.Status != http.StatusOK {
+		st := &status.Status{}
+		if err := proto.Unmarshal(stored.Body, st); err != nil || st.GetCode() == int32(codes.OK) {
+			return zero, grpcstatus.Error(codes.Internal, "could not decode stored response")
+		}
+		return zero, grpcstatus.ErrorProto(st)
+	}
+	if err := proto.Unmarshal(stored.Body, res); err != nil {
+		return zero, grpcstatus.Error(codes.Internal, "could not decode stored response")
+	}
+	return res, nil
+}
@@ -1,48 +1,31 @@
 package middleware
 
 import (
-	"github.com/spitfy/urlshortener/internal/config"
-	"log"
-	"net"
 	"net/http"
-	"strings"
-)
-
-// TrustedSubnet создает middleware для проверки IP
-func TrustedSubnet(cfg *config.Config) func(next http.HandlerFunc) http.HandlerFunc {
-	var (
-		trustedNet *net.IPNet
-		err        error
-	)
 
-	if cfg.Handlers.TrustedSubnet != "" {
-		_, trustedNet, err = net.ParseCIDR(cfg.Handlers.TrustedSubnet)
-		if err != nil {
-			log.Printf("invalid trusted_subnet %s: %v", cfg.Handlers.TrustedSubnet, err)
-		}
-	}
+	"github.com/spitfy/urlshortener/internal/clientip"
+)
 
+// TrustedSubnet создает middleware для проверки IP клиента по списку доверенных подсетей.
+// Адрес клиента берется из контекста запроса (см. clientip.Middleware), поэтому
+// заголовки прокси учитываются только от доверенных прокси.
+// Пустой список подсетей запрещает доступ всем клиентам.
+func TrustedSubnet(trusted *clientip.Networks) func(next http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			if cfg.Handlers.TrustedSubnet == "" {
+			if trusted.Empty() {
 				http.Error(w, "Access forbidden", http.StatusForbidden)
 				return
 			}
 
-			clientIP := strings.TrimSpace(r.Header.Get("X-Real-IP"))
-			if clientIP == "" {
-				http.Error(w, "X-Real-IP header required", http.StatusForbidden)
-				return
-			}
-
-			ip := net.ParseIP(clientIP)
-			if ip == nil {
-				http.Error(w, "Invalid X-Real-IP format", http.StatusForbidden)
+			ip, ok := clientip.FromContext(r.Context())
+			if !ok {
+				http.Error(w, "Client IP unknown", http.StatusForbidden)
 				return
 			}
 
 			// Проверяем вхождение в подсеть
-			if !trustedNet.Contains(ip) {
+			if !trusted.Contains(ip) {
 				http.Error(w, "IP not in trusted subnet", http.StatusForbidden)
 				return
 			}
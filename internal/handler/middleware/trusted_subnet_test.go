@@ -1,26 +1,39 @@
 package middleware
 
 import (
-	"github.com/spitfy/urlshortener/internal/config"
-	config2 "github.com/spitfy/urlshortener/internal/handler/config"
-	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/spitfy/urlshortener/internal/clientip"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTrustedSubnet(t *testing.T) {
-	cfg := config.Config{Handlers: config2.Config{TrustedSubnet: "192.168.1.0/24"}}
-	mw := TrustedSubnet(&cfg)
+	trusted, err := clientip.NewNetworks("192.168.1.0/24, 2001:db8::/32")
+	require.NoError(t, err)
+	proxies, err := clientip.NewNetworks("10.0.0.1")
+	require.NoError(t, err)
+	h := clientip.Middleware(clientip.NewResolver(proxies))(
+		TrustedSubnet(trusted)(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	do := func(remote, realIP string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = remote
+		if realIP != "" {
+			req.Header.Set("X-Real-IP", realIP)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
 
-	req := httptest.NewRequest("GET", "/test", nil)
-	req.Header.Set("X-Real-IP", "192.168.1.100")
-	rr := httptest.NewRecorder()
-	mw(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rr, req)
-	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, http.StatusOK, do("192.168.1.100:5000", ""))
+	require.Equal(t, http.StatusOK, do("[2001:db8::1]:5000", ""))
+	require.Equal(t, http.StatusForbidden, do("172.16.0.1:5000", ""))
+	require.Equal(t, http.StatusForbidden, do("172.16.0.1:5000", "192.168.1.100"), "header from untrusted peer is ignored")
+	require.Equal(t, http.StatusOK, do("10.0.0.1:5000", "192.168.1.100"), "header from trusted proxy is used")
 
-	req.Header.Set("X-Real-IP", "10.0.0.1")
-	rr = httptest.NewRecorder()
-	mw(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rr, req)
-	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.NoError(t, trusted.Set(""))
+	require.Equal(t, http.StatusForbidden, do("192.168.1.100:5000", ""))
 }
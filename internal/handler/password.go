@@ -0,0 +1,103 @@
+// Package handler содержит форму ввода пароля защищенной ссылки.
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// PasswordHeader — заголовок, в котором API-клиенты передают пароль защищенной ссылки.
+const PasswordHeader = "X-Link-Password"
+
+// maxPasswordForm ограничивает размер тела запроса формы пароля.
+const maxPasswordForm = 4 << 10
+
+var passwordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Ссылка защищена паролем</title>
+</head>
+<body>
+<p>Ссылка защищена паролем.</p>
+{{if .Wrong}}<p><strong>Неверный пароль.</strong></p>
+{{end}}<form method="post" action="{{.Action}}">
+<input type="password" name="password" autofocus required>
+<button type="submit">Перейти</button>
+</form>
+</body>
+</html>
+`))
+
+// passwordData содержит данные для шаблона формы пароля.
+type passwordData struct {
+	Action string
+	Wrong  bool
+}
+
+// Unlock проверяет пароль из формы и перенаправляет на защищенную ссылку
+// @Summary Переход по защищенной ссылке
+// @Description Принимает пароль из HTML-формы и перенаправляет (303) на оригинальный URL.
+// @Description Query-параметры запроса передаются так же, как при GET-переходе.
+// @Tags URL
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param hash path string true "Хеш сокращенного URL"
+// @Param password formData string true "Пароль ссылки"
+// @Success 303 {string} string "Перенаправление на оригинальный URL"
+// @Failure 410 {object} model.Problem "URL был удален или исчерпал лимит переходов"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {string} string "Пароль не указан (HTML-форма)"
+// @Failure 403 {string} string "Неверный пароль (HTML-форма)"
+// @Failure 405 {object} model.Problem "Ссылка не защищена паролем"
+// @Failure 429 {object} model.Problem "Слишком много неверных паролей"
+// @Router /{hash} [post]
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordForm)
+	if err := r.ParseForm(); err != nil {
+		httpProblem(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	h.follow(w, r, r.PostForm.Get("password"))
+}
+
+// passwordForm отвечает на переход по защищенной ссылке без верного пароля:
+// 401 с формой пароля, 403 с формой и сообщением о неверном пароле
+// или 429 с Retry-After, если попытки исчерпаны.
+func passwordForm(w http.ResponseWriter, r *http.Request, hash string, err error) {
+	w.Header().Set("Cache-Control", "no-store")
+	var status int
+	switch {
+	case errors.Is(err, service.ErrTooManyAttempts):
+		w.Header().Set("Retry-After", strconv.Itoa(int(service.PasswordAttemptWindow.Seconds())))
+		httpError(w, err, "")
+		return
+	case errors.Is(err, service.ErrPasswordRequired):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrWrongPassword):
+		status = http.StatusForbidden
+	default:
+		httpError(w, err, "could not check password")
+		return
+	}
+
+	action := "/" + hash
+	if r.URL.RawQuery != "" {
+		action += "?" + r.URL.RawQuery
+	}
+	var buf bytes.Buffer
+	data := passwordData{Action: action, Wrong: status == http.StatusForbidden}
+	if err := passwordTemplate.Execute(&buf, data); err != nil {
+		httpProblem(w, "render error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
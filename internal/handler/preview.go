@@ -0,0 +1,96 @@
+// Package handler содержит страницу предварительного просмотра ссылки.
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// previewDelay задает задержку (в секундах) перед автоматическим переходом.
+const previewDelay = 5
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Delay}};url={{.Next}}">
+<title>Переход по ссылке</title>
+</head>
+<body>
+<p>Ссылка ведет на:</p>
+<p><strong>{{.Target}}</strong></p>
+<p>Переход произойдет автоматически через {{.Delay}} сек.</p>
+<p><a href="{{.Next}}">Перейти сейчас</a></p>
+</body>
+</html>
+`))
+
+// previewData содержит данные для шаблона страницы предпросмотра.
+type previewData struct {
+	Target string
+	Next   string
+	Delay  int
+}
+
+// Preview показывает промежуточную страницу с адресом назначения
+// @Summary Предпросмотр ссылки
+// @Description Показывает HTML-страницу с адресом назначения перед перенаправлением
+// @Tags URL
+// @Produce html
+// @Param hash path string true "Хеш сокращенного URL"
+// @Success 200 {string} string "HTML-страница предпросмотра"
+// @Failure 410 {object} model.Problem "URL был удален или исчерпал лимит переходов"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {string} string "Ссылка защищена паролем (HTML-форма)"
+// @Router /{hash}+ [get]
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	domain := h.service.DomainByHost(r.Host)
+	hash := chi.URLParam(r, "hash")
+	if len(hash) == 0 || len(hash) > service.CharCnt {
+		notFound(w, r, domain, nil)
+		return
+	}
+
+	u, err := h.service.GetByHash(r.Context(), domain.Name, hash)
+	if err != nil {
+		notFound(w, r, domain, err)
+		return
+	}
+	if u.DeletedFlag {
+		httpError(w, service.ErrDeleted, "")
+		return
+	}
+	if u.Exhausted() {
+		httpError(w, repository.ErrExhausted, "")
+		return
+	}
+	// Адрес назначения защищенной ссылки раскрывается только после ввода пароля.
+	if u.PasswordHash != "" {
+		passwordForm(w, r, hash, service.ErrPasswordRequired)
+		return
+	}
+
+	target, err := service.RedirectTarget(h.route(r, u), r.URL.Query())
+	if err != nil {
+		httpProblem(w, "invalid target url", http.StatusInternalServerError)
+		return
+	}
+
+	next := "/" + hash
+	if r.URL.RawQuery != "" {
+		next += "?" + r.URL.RawQuery
+	}
+
+	var buf bytes.Buffer
+	if err := previewTemplate.Execute(&buf, previewData{Target: target, Next: next, Delay: previewDelay}); err != nil {
+		httpProblem(w, "render error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,143 @@
+// Package handler содержит обработчики QR-кодов коротких ссылок.
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spitfy/urlshortener/internal/qrcode"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// qrMaxAge задает время (в секундах), на которое клиент может кэшировать QR-код.
+// QR-код кодирует короткий URL, а не адрес назначения, поэтому не меняется при редактировании ссылки.
+const qrMaxAge = "86400"
+
+// QRCode возвращает QR-код короткой ссылки
+// @Summary QR-код ссылки
+// @Description Возвращает QR-код полного сокращенного URL. Ссылка ищется в домене из заголовка Host.
+// @Description Ответ кэшируется по ETag: при совпадении If-None-Match возвращается 304.
+// @Tags URL
+// @Produce png
+// @Produce image/svg+xml
+// @Param hash path string true "Хеш сокращенного URL"
+// @Param format query string false "Формат изображения: png (по умолчанию) или svg"
+// @Param size query int false "Размер изображения в пикселях (64..2048, по умолчанию 256)"
+// @Param margin query int false "Свободная зона в модулях (0..16, по умолчанию 4)"
+// @Param level query string false "Уровень коррекции ошибок: L, M (по умолчанию), Q или H"
+// @Param fg query string false "Цвет модулей в формате RRGGBB (по умолчанию 000000)"
+// @Param bg query string false "Цвет фона в формате RRGGBB (по умолчанию ffffff)"
+// @Success 200 {file} file "Изображение QR-кода"
+// @Success 304 {string} string "Изображение не изменилось"
+// @Failure 410 {object} model.Problem "URL был удален или исчерпал лимит переходов"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Router /{hash}/qr [get]
+func (h *Handler) QRCode(w http.ResponseWriter, r *http.Request) {
+	domain := h.service.DomainByHost(r.Host)
+	hash := chi.URLParam(r, "hash")
+	if len(hash) == 0 || len(hash) > service.CharCnt {
+		httpError(w, repository.ErrNotFound, "")
+		return
+	}
+	u, err := h.service.GetByHash(r.Context(), domain.Name, hash)
+	if err != nil {
+		httpError(w, repository.ErrNotFound, "")
+		return
+	}
+	if u.DeletedFlag {
+		httpError(w, service.ErrDeleted, "")
+		return
+	}
+	if u.Exhausted() {
+		httpError(w, repository.ErrExhausted, "")
+		return
+	}
+	h.writeQR(w, r, domain.Name, hash, "public")
+}
+
+// UserQRCode возвращает QR-код ссылки текущего пользователя
+// @Summary QR-код ссылки пользователя
+// @Description Возвращает QR-код полного сокращенного URL ссылки текущего пользователя.
+// @Description Ответ кэшируется по ETag: при совпадении If-None-Match возвращается 304.
+// @Tags User
+// @Produce png
+// @Produce image/svg+xml
+// @Param hash path string true "Хеш сокращенного URL"
+// @Param domain query string false "Домен ссылок; по умолчанию домен сервиса"
+// @Param format query string false "Формат изображения: png (по умолчанию) или svg"
+// @Param size query int false "Размер изображения в пикселях (64..2048, по умолчанию 256)"
+// @Param margin query int false "Свободная зона в модулях (0..16, по умолчанию 4)"
+// @Param level query string false "Уровень коррекции ошибок: L, M (по умолчанию), Q или H"
+// @Param fg query string false "Цвет модулей в формате RRGGBB (по умолчанию 000000)"
+// @Param bg query string false "Цвет фона в формате RRGGBB (по умолчанию ffffff)"
+// @Success 200 {file} file "Изображение QR-кода"
+// @Success 304 {string} string "Изображение не изменилось"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Router /api/user/urls/{hash}/qr [get]
+func (h *Handler) UserQRCode(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	domain, hash := r.URL.Query().Get("domain"), chi.URLParam(r, "hash")
+	u, err := h.service.GetByHash(r.Context(), domain, hash)
+	if err != nil || u.UserID != userID || u.DeletedFlag {
+		httpError(w, repository.ErrNotFound, "")
+		return
+	}
+	h.writeQR(w, r, u.Domain, hash, "private")
+}
+
+// writeQR отдает QR-код сокращенного URL с параметрами из query-параметров запроса.
+// Изображение формируется, только если у клиента нет актуальной копии (If-None-Match).
+func (h *Handler) writeQR(w http.ResponseWriter, r *http.Request, domain, hash, cacheScope string) {
+	opts, err := qrcode.ParseOptions(r.URL.Query())
+	if err != nil {
+		httpProblem(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shortURL, err := h.service.ShortURL(domain, hash)
+	if err != nil {
+		httpError(w, err, "could not build short url")
+		return
+	}
+
+	etag := opts.ETag(shortURL)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheScope+", max-age="+qrMaxAge)
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	img, err := qrcode.Render(shortURL, opts)
+	if errors.Is(err, qrcode.ErrInvalidOptions) {
+		httpProblem(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httpProblem(w, "could not render qr code", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", opts.ContentType())
+	_, _ = w.Write(img)
+}
+
+// etagMatch проверяет, содержит ли заголовок If-None-Match указанный ETag
+// (слабое сравнение, допускается список значений и "*").
+func etagMatch(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
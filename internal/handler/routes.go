@@ -2,7 +2,10 @@
 package handler
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"github.com/spitfy/urlshortener/internal/clientip"
 	"github.com/spitfy/urlshortener/internal/gomodule"
 	"github.com/spitfy/urlshortener/internal/handler/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -17,29 +20,51 @@ import (
 	"github.com/spitfy/urlshortener/internal/config"
 )
 
+// HTTPServer — HTTP-сервер с компонентами, которые можно обновить без перезапуска.
+type HTTPServer struct {
+	*http.Server
+	trusted *clientip.Networks
+	proxies *clientip.Networks
+	certs   *CertReloader
+}
+
 // Serve запускает HTTP-сервер с обработчиками URL shortener API.
 // Принимает конфигурацию, сервис сокращения URL и логгер запросов.
 // Возвращает сервер и ошибку в случае неудачного запуска сервера.
-func Serve(cfg config.Config, service ServiceShortener, l RequestLogger, a *auth.Manager) (*http.Server, error) {
+// При включенном HTTPS сертификат загружается сразу, а сервер запускается
+// вызовом ListenAndServeTLS("", "").
+func Serve(cfg config.Config, service ServiceShortener, l RequestLogger, a *auth.Manager) (*HTTPServer, error) {
+	trusted, err := clientip.NewNetworks(cfg.Handlers.TrustedSubnet)
+	if err != nil {
+		return nil, fmt.Errorf("trusted subnet: %w", err)
+	}
+	proxies, err := clientip.NewNetworks(cfg.Handlers.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
 	h := newHandler(service, a)
-	router := newRouter(h, l, &cfg)
-
-	server := &http.Server{
-		Addr:         cfg.Handlers.ServerAddr,
-		Handler:      router,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	router := newRouter(h, l, trusted, proxies)
+
+	server := &HTTPServer{
+		Server: &http.Server{
+			Addr:         cfg.Handlers.ServerAddr,
+			Handler:      router,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		},
+		trusted: trusted,
+		proxies: proxies,
 	}
+	server.RegisterOnShutdown(h.closeStreams)
 
 	if cfg.Handlers.EnableHTTPS {
-		certFile, err := CertPath(cfg.Handlers.CertFile)
-		if err != nil {
-			return server, fmt.Errorf("certificate file not readable: %s — %w", certFile, err)
+		if server.certs, err = NewCertReloader(cfg.Handlers.CertFile, cfg.Handlers.KeyFile); err != nil {
+			return server, err
 		}
-		keyFile, err := CertPath(cfg.Handlers.KeyFile)
-		if err != nil {
-			return server, fmt.Errorf("key file not readable: %s — %w", keyFile, err)
+		if err = server.certs.ReloadDomains(cfg.Service.Domains); err != nil {
+			return server, err
 		}
+		server.TLSConfig = &tls.Config{GetCertificate: server.certs.GetCertificate}
 		httpsAddr := ":" + cfg.Handlers.HTTPSPort
 		server.Addr = httpsAddr
 		fmt.Printf("Starting HTTPS server on %s\n", httpsAddr)
@@ -48,12 +73,39 @@ func Serve(cfg config.Config, service ServiceShortener, l RequestLogger, a *auth
 	return server, nil
 }
 
+// Reload применяет обновляемые параметры конфигурации: доверенные подсети,
+// доверенные прокси и TLS-сертификаты сервера и доменов. Параметр, который
+// не удалось применить, сохраняет прежнее значение.
+func (s *HTTPServer) Reload(cfg config.Config) error {
+	var errs []error
+	if err := s.trusted.Set(cfg.Handlers.TrustedSubnet); err != nil {
+		errs = append(errs, fmt.Errorf("trusted subnet: %w", err))
+	}
+	if err := s.proxies.Set(cfg.Handlers.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trusted proxies: %w", err))
+	}
+	if s.certs != nil {
+		if err := s.certs.Reload(cfg.Handlers.CertFile, cfg.Handlers.KeyFile); err != nil {
+			errs = append(errs, err)
+		}
+		if err := s.certs.ReloadDomains(cfg.Service.Domains); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // newRouter создает новый маршрутизатор с обработчиками для:
 // - API сокращения URL
 // - Профилирования (pprof)
 // Добавляет middleware для аутентификации, сжатия и логирования.
-func newRouter(h *Handler, l RequestLogger, cfg *config.Config) *chi.Mux {
+// Пользователь создается только на эндпоинтах записи; публичное перенаправление
+// обрабатывает анонимных посетителей без создания пользователя.
+// Адрес клиента определяется один раз для каждого запроса с учетом доверенных
+// прокси proxies и доступен обработчикам через clientip.FromContext.
+func newRouter(h *Handler, l RequestLogger, trusted, proxies *clientip.Networks) *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(clientip.Middleware(clientip.NewResolver(proxies)))
 
 	r.Get("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 		wd, _ := os.Getwd()
@@ -65,16 +117,54 @@ func newRouter(h *Handler, l RequestLogger, cfg *config.Config) *chi.Mux {
 		httpSwagger.URL("/swagger.json"),
 	))
 
-	trustedSubnetMiddleware := middleware.TrustedSubnet(cfg)
+	trustedSubnetMiddleware := middleware.TrustedSubnet(trusted)
+
+	r.Get("/ping", gzipMiddleware(l.LogInfo(h.Ping)))
+	r.Get("/{hash}", h.optionalAuthMiddleware(gzipMiddleware(l.LogInfo(h.Get))))
+	r.Post("/{hash}", h.optionalAuthMiddleware(gzipMiddleware(l.LogInfo(h.Unlock))))
+	r.Get("/{hash}+", gzipMiddleware(l.LogInfo(h.Preview)))
+	// QR-коды отдаются без gzip: PNG уже сжат, а 304-ответы не должны иметь тела.
+	r.Get("/{hash}/qr", l.LogInfo(h.QRCode))
+	r.Get("/api/user/urls", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetByUserID))))
+	r.Delete("/api/user/urls", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.Delete))))
+	r.Post("/api/user/urls/restore", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.Restore))))
+	r.Patch("/api/user/urls/{hash}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.Update))))
+	r.Get("/api/user/urls/{hash}/history", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetRevisions))))
+	r.Get("/api/user/urls/{hash}/qr", h.requireAuthMiddleware(l.LogInfo(h.UserQRCode)))
+	r.Post("/api/user/experiments", h.authMiddleware(gzipMiddleware(l.LogInfo(h.CreateExperiment))))
+	r.Get("/api/user/experiments", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.ListExperiments))))
+	r.Get("/api/user/experiments/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetExperiment))))
+	r.Post("/api/user/webhooks", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.CreateWebhook))))
+	r.Get("/api/user/webhooks", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.ListWebhooks))))
+	r.Get("/api/user/webhooks/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetWebhook))))
+	r.Patch("/api/user/webhooks/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.UpdateWebhook))))
+	r.Delete("/api/user/webhooks/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.DeleteWebhook))))
+	r.Get("/api/user/webhooks/{id}/deliveries", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.ListDeliveries))))
+	r.Get("/api/user/stream", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.Stream))))
+	r.Get("/api/user/stream/ws", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.StreamWebSocket))))
+	r.Get("/api/user/deletions/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetDeletion))))
+	r.Post("/api/shorten/batch", h.authMiddleware(gzipMiddleware(l.LogInfo(h.idempotent(h.BatchAdd)))))
+	r.Post("/api/shorten", h.authMiddleware(gzipMiddleware(l.LogInfo(h.idempotent(h.ShortenURL)))))
+	r.Post("/api/internal/stats", gzipMiddleware(l.LogInfo(trustedSubnetMiddleware(h.Stats))))
+	r.Post("/", h.authMiddleware(gzipMiddleware(l.LogInfo(h.idempotent(h.Post)))))
+
+	// API v2: ответы в конвертах, пагинация курсором, ссылки с идентификатором и метаданными.
+	// Эндпоинты API v1 выше сохраняют прежний формат.
+	r.Route("/api/v2", func(r chi.Router) {
+		r.Post("/links", h.authMiddleware(gzipMiddleware(l.LogInfo(h.idempotent(h.CreateLink)))))
+		r.Get("/links", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.ListLinks))))
+		r.Post("/links/batch", h.authMiddleware(gzipMiddleware(l.LogInfo(h.idempotent(h.CreateLinks)))))
+		r.Get("/links/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetLink))))
+		r.Patch("/links/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.UpdateLink))))
+		r.Delete("/links/{id}", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.DeleteLink))))
+		r.Get("/links/{id}/history", h.requireAuthMiddleware(gzipMiddleware(l.LogInfo(h.GetLinkHistory))))
+	})
 
-	r.Get("/ping", h.authMiddleware(gzipMiddleware(l.LogInfo(h.Ping))))
-	r.Get("/{hash}", h.authMiddleware(gzipMiddleware(l.LogInfo(h.Get))))
-	r.Get("/api/user/urls", h.authMiddleware(gzipMiddleware(l.LogInfo(h.GetByUserID))))
-	r.Delete("/api/user/urls", h.authMiddleware(gzipMiddleware(l.LogInfo(h.Delete))))
-	r.Post("/api/shorten/batch", h.authMiddleware(gzipMiddleware(l.LogInfo(h.BatchAdd))))
-	r.Post("/api/shorten", h.authMiddleware(gzipMiddleware(l.LogInfo(h.ShortenURL))))
-	r.Post("/api/internal/stats", h.authMiddleware(gzipMiddleware(l.LogInfo(trustedSubnetMiddleware(h.Stats)))))
-	r.Post("/", h.authMiddleware(gzipMiddleware(l.LogInfo(h.Post))))
+	// ShortenerService из pkg/shortener.proto по протоколам Connect, gRPC-Web и
+	// HTTP/JSON (POST /shortener.ShortenerService/<метод>). Авторизация, как и в
+	// gRPC, — по заголовку Authorization. Сжатие выполняет сам Connect.
+	rpcPath, rpcHandler := newConnectHandler(h.rpc)
+	r.Handle(rpcPath+"*", l.LogInfo(rpcHandler.ServeHTTP))
 
 	r.Group(func(r chi.Router) {
 		r.Handle("/debug/pprof/*", http.HandlerFunc(pprof.Index))
@@ -0,0 +1,186 @@
+// Package handler содержит обработчики потоков событий ссылок.
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/service"
+)
+
+// streamHeartbeat — интервал служебных событий heartbeat в потоке без других событий.
+const streamHeartbeat = 15 * time.Second
+
+// closeStreams завершает открытые потоки событий, в том числе потоки Connect.
+// Вызывается при остановке HTTP-сервера: иначе Shutdown ждал бы окончания
+// бесконечных ответов.
+func (h *Handler) closeStreams() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+	h.rpc.closeStreams()
+}
+
+// subscribe открывает поток событий пользователя из контекста запроса
+// или отвечает ошибкой и возвращает nil.
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request) *service.Subscription {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	sub, err := h.service.Subscribe(userID)
+	if err != nil {
+		httpError(w, err, "could not open stream")
+		return nil
+	}
+	return sub
+}
+
+// streamEvents передает события подписки функции send, пока она не вернет ошибку,
+// клиент не отключится (done) или сервер не остановится. При отсутствии событий
+// отправляется событие heartbeat, а перед закрытием потока сервером — событие closed.
+func (h *Handler) streamEvents(sub *service.Subscription, done <-chan struct{}, send func(model.StreamEvent) error) {
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		var ev model.StreamEvent
+		select {
+		case <-done:
+			return
+		case <-h.done:
+			_ = send(model.StreamEvent{Event: model.StreamClosed, Timestamp: time.Now(), Error: service.ErrStreamClosed.Error()})
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					_ = send(model.StreamEvent{Event: model.StreamClosed, Timestamp: time.Now(), Error: err.Error()})
+				}
+				return
+			}
+			ev = e
+		case <-ticker.C:
+			ev = model.StreamEvent{Event: model.StreamHeartbeat, Timestamp: time.Now()}
+		}
+		if err := send(ev); err != nil {
+			return
+		}
+	}
+}
+
+// Stream отправляет события ссылок пользователя в формате Server-Sent Events
+// @Summary Поток событий ссылок (SSE)
+// @Description Передает в реальном времени события ссылок текущего пользователя: created, clicked, deleted, expired.
+// @Description Каждое событие отправляется как "event: <событие>" и "data: <model.StreamEvent в JSON>".
+// @Description При отсутствии событий каждые 15 секунд отправляется событие heartbeat. Клиент, не успевающий
+// @Description получать события, отключается событием closed и должен переподключиться.
+// @Tags Streams
+// @Produce text/event-stream
+// @Success 200 {object} model.StreamEvent "Поток событий"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 429 {object} model.Problem "Открыто слишком много потоков"
+// @Failure 503 {object} model.Problem "Сервер останавливается"
+// @Router /api/user/stream [get]
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	sub := h.subscribe(w, r)
+	if sub == nil {
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// Поток длится дольше WriteTimeout сервера.
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	h.streamEvents(sub, r.Context().Done(), func(ev model.StreamEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+}
+
+// hijackWriter позволяет golang.org/x/net/websocket захватить соединение
+// через обертки http.ResponseWriter, поддерживающие Unwrap.
+type hijackWriter struct {
+	http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// Hijack захватывает соединение исходного http.ResponseWriter.
+func (hw hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hw.rc.Hijack()
+}
+
+// checkStreamOrigin отклоняет WebSocket-соединения со сторонних сайтов: браузер
+// передает с ними cookie пользователя. Клиенты без заголовка Origin допускаются.
+func checkStreamOrigin(_ *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return fmt.Errorf("origin %q is not allowed", origin)
+	}
+	return nil
+}
+
+// StreamWebSocket отправляет события ссылок пользователя через WebSocket
+// @Summary Поток событий ссылок (WebSocket)
+// @Description Передает те же события, что и /api/user/stream, текстовыми сообщениями с model.StreamEvent в JSON.
+// @Description Сообщения клиента игнорируются. Соединения со сторонних сайтов (заголовок Origin) отклоняются.
+// @Tags Streams
+// @Success 101 {object} model.StreamEvent "Переключение на WebSocket"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 403 {object} model.Problem "Недопустимый Origin"
+// @Failure 429 {object} model.Problem "Открыто слишком много потоков"
+// @Failure 503 {object} model.Problem "Сервер останавливается"
+// @Router /api/user/stream/ws [get]
+func (h *Handler) StreamWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub := h.subscribe(w, r)
+	if sub == nil {
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// Тайм-ауты сервера сохраняются у захваченного соединения.
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	ws := websocket.Server{
+		Handshake: checkStreamOrigin,
+		Handler: func(conn *websocket.Conn) {
+			// Чтение нужно, чтобы обнаружить отключение клиента и ответить на ping.
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var msg []byte
+				for websocket.Message.Receive(conn, &msg) == nil {
+				}
+			}()
+			h.streamEvents(sub, closed, func(ev model.StreamEvent) error {
+				return websocket.JSON.Send(conn, ev)
+			})
+		},
+	}
+	ws.ServeHTTP(hijackWriter{ResponseWriter: w, rc: rc}, r)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/spitfy/urlshortener/internal/service"
+	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
+)
+
+// CertReloader отдает TLS-сертификат сервера и позволяет заменить его
+// без перезапуска: новые соединения получают сертификат, загруженный последним.
+// Для доменов коротких ссылок с собственным сертификатом он выбирается по SNI.
+type CertReloader struct {
+	cert    atomic.Pointer[tls.Certificate]
+	domains atomic.Pointer[map[string]*tls.Certificate]
+}
+
+// NewCertReloader загружает пару сертификат/ключ. Пути задаются относительно корня модуля.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	cr := &CertReloader{}
+	if err := cr.Reload(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Reload загружает новую пару сертификат/ключ. При ошибке остается прежний сертификат.
+// Пример:
+//
+//	if err := certs.Reload("cert/cert.pem", "cert/key.pem"); err != nil {
+//	    log.Println(err)
+//	}
+func (cr *CertReloader) Reload(certFile, keyFile string) error {
+	cert, err := loadCert(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	cr.cert.Store(cert)
+	return nil
+}
+
+// ReloadDomains загружает сертификаты доменов, для которых они заданы.
+// При ошибке остаются прежние сертификаты всех доменов.
+// Пример:
+//
+//	if err := certs.ReloadDomains(cfg.Service.Domains); err != nil {
+//	    log.Println(err)
+//	}
+func (cr *CertReloader) ReloadDomains(domains []serviceConf.Domain) error {
+	certs := make(map[string]*tls.Certificate, len(domains))
+	for _, d := range domains {
+		if d.CertFile == "" {
+			continue
+		}
+		cert, err := loadCert(d.CertFile, d.KeyFile)
+		if err != nil {
+			return fmt.Errorf("domain %s: %w", d.Name, err)
+		}
+		certs[service.HostName(d.Name)] = cert
+	}
+	cr.domains.Store(&certs)
+	return nil
+}
+
+// GetCertificate реализует tls.Config.GetCertificate: для домена с собственным
+// сертификатом возвращает его, для остальных — сертификат сервера.
+func (cr *CertReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if domains := cr.domains.Load(); domains != nil && hello != nil {
+		if cert, ok := (*domains)[service.HostName(hello.ServerName)]; ok {
+			return cert, nil
+		}
+	}
+	return cr.cert.Load(), nil
+}
+
+// loadCert загружает пару сертификат/ключ. Пути задаются относительно корня модуля.
+func loadCert(certFile, keyFile string) (*tls.Certificate, error) {
+	certPath, err := CertPath(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("certificate file not readable: %s — %w", certFile, err)
+	}
+	keyPath, err := CertPath(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("key file not readable: %s — %w", keyFile, err)
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	return &cert, nil
+}
@@ -0,0 +1,409 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/clientip"
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// API v2 отвечает данными в конверте {"data": ...}, списки — страницами с курсором
+// в конверте {"data": [...], "page": {...}}, ошибки — в формате application/problem+json.
+// Ссылка идентифицируется хешем (id) и доменом (query-параметр domain).
+
+// CreateLink создает ссылку
+// @Summary Создать ссылку
+// @Description Создает ссылку и возвращает ее со всеми метаданными. Если URL уже сокращен,
+// @Description возвращается существующая ссылка со статусом 200; метаданные чужой ссылки не раскрываются.
+// @Tags Links v2
+// @Accept json
+// @Produce json
+// @Param request body model.Request true "Запрос на сокращение URL"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ"
+// @Success 201 {object} model.Envelope[model.LinkResource] "Создана новая ссылка"
+// @Success 200 {object} model.Envelope[model.LinkResource] "URL уже был сокращен ранее"
+// @Header 201 {string} Location "Адрес ссылки в API v2"
+// @Failure 400 {object} model.Problem "Некорректный запрос или неизвестный домен"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 403 {object} model.Problem "Домен недоступен пользователю"
+// @Failure 409 {object} model.Problem "Запрос с тем же ключом идемпотентности еще выполняется"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links [post]
+func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	var req model.Request
+	if !decodeJSON(w, r, maxBodySize, &req) {
+		return
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	link, err := h.service.AddLink(r.Context(), req.URL, req.LinkOptions, userID)
+	if errors.Is(err, repository.ErrExistsURL) {
+		writeData(w, http.StatusOK, linkResource(link))
+		return
+	}
+	if err != nil {
+		httpError(w, err, "could not shorten URL")
+		return
+	}
+
+	h.service.NotifyObservers(r.Context(), audit.Event{
+		Timestamp: time.Now(),
+		Action:    audit.Shorten,
+		UserID:    userID,
+		URL:       link.OriginalURL,
+		IP:        clientip.String(r.Context()),
+		ShortURL:  link.ShortURL,
+	})
+
+	w.Header().Set("Location", linkLocation(link.Domain, link.Hash))
+	writeData(w, http.StatusCreated, linkResource(link))
+}
+
+// ListLinks возвращает страницу ссылок пользователя
+// @Summary Список ссылок
+// @Description Возвращает ссылки текущего пользователя с поиском, фильтрами и сортировкой.
+// @Description Курсор следующей страницы передается в page.next_cursor; пустой список — пустой массив data.
+// @Tags Links v2
+// @Produce json
+// @Param q query string false "Слова для поиска в заголовке, заметке и оригинальном URL"
+// @Param tag query []string false "Теги, которые должны быть у ссылки (все перечисленные)" collectionFormat(multi)
+// @Param sort query string false "Поле сортировки" Enums(created, clicks)
+// @Param order query string false "Порядок сортировки" Enums(desc, asc)
+// @Param limit query int false "Размер страницы (1..1000, по умолчанию 100)"
+// @Param cursor query string false "Курсор следующей страницы из page.next_cursor"
+// @Param broken query bool false "Только ссылки, адрес назначения которых недоступен по результатам последней проверки"
+// @Success 200 {object} model.PageEnvelope[model.LinkResource] "Страница ссылок"
+// @Failure 400 {object} model.Problem "Некорректные параметры выборки"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links [get]
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	filter, err := linkFilter(r.URL.Query())
+	if err != nil {
+		httpError(w, err, "")
+		return
+	}
+	page, err := h.service.ListUserLinks(r.Context(), userID, filter)
+	if err != nil {
+		httpError(w, err, "could not list URLs")
+		return
+	}
+	res := model.PageEnvelope[model.LinkResource]{
+		Data: make([]model.LinkResource, 0, len(page.Links)),
+		Page: model.PageInfo{NextCursor: page.NextCursor, Limit: filter.Limit},
+	}
+	if res.Page.Limit == 0 {
+		res.Page.Limit = service.DefaultPageSize
+	}
+	for _, link := range page.Links {
+		res.Data = append(res.Data, linkResource(link))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GetLink возвращает ссылку пользователя
+// @Summary Получить ссылку
+// @Description Возвращает ссылку текущего пользователя со всеми метаданными
+// @Tags Links v2
+// @Produce json
+// @Param id path string true "Идентификатор (хеш) ссылки"
+// @Param domain query string false "Домен ссылки; по умолчанию домен сервиса"
+// @Success 200 {object} model.Envelope[model.LinkResource] "Ссылка"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links/{id} [get]
+func (h *Handler) GetLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	link, err := h.service.GetLink(r.Context(), r.URL.Query().Get("domain"), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		httpError(w, err, "could not get URL")
+		return
+	}
+	writeData(w, http.StatusOK, linkResource(link))
+}
+
+// UpdateLink редактирует ссылку пользователя
+// @Summary Редактировать ссылку
+// @Description Изменяет адрес назначения, метаданные и параметры перенаправления ссылки.
+// @Description Предыдущее состояние сохраняется в истории изменений.
+// @Tags Links v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Идентификатор (хеш) ссылки"
+// @Param request body model.LinkUpdate true "Изменяемые поля"
+// @Param domain query string false "Домен ссылки; по умолчанию домен сервиса"
+// @Success 200 {object} model.Envelope[model.LinkResource] "Ссылка после изменения"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Failure 409 {object} model.Problem "Новый адрес уже был сокращен ранее"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links/{id} [patch]
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	var req model.LinkUpdate
+	if !decodeJSON(w, r, maxBodySize, &req) {
+		return
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	link, err := h.service.Update(r.Context(), r.URL.Query().Get("domain"), chi.URLParam(r, "id"), req, userID)
+	if errors.Is(err, repository.ErrExistsURL) {
+		httpError(w, fmt.Errorf("%w: %s", err, link.ShortURL), "")
+		return
+	}
+	if err != nil {
+		httpError(w, err, "could not update URL")
+		return
+	}
+
+	h.service.NotifyObservers(r.Context(), audit.Event{
+		Timestamp: time.Now(),
+		Action:    audit.Update,
+		UserID:    userID,
+		URL:       link.OriginalURL,
+		IP:        clientip.String(r.Context()),
+		ShortURL:  link.ShortURL,
+	})
+
+	writeData(w, http.StatusOK, linkResource(link))
+}
+
+// DeleteLink удаляет ссылку пользователя
+// @Summary Удалить ссылку
+// @Description Ставит задание на удаление ссылки в очередь (асинхронно).
+// @Description Статус задания доступен по адресу из заголовка Location.
+// @Tags Links v2
+// @Produce json
+// @Param id path string true "Идентификатор (хеш) ссылки"
+// @Param domain query string false "Домен ссылки; по умолчанию домен сервиса"
+// @Success 202 {object} model.Envelope[model.DeletionJob] "Задание на удаление принято"
+// @Header 202 {string} Location "Адрес статуса задания"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links/{id} [delete]
+func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	domain, hash := r.URL.Query().Get("domain"), chi.URLParam(r, "id")
+	// Очередь удаления пропускает чужие и несуществующие ссылки молча,
+	// поэтому наличие ссылки проверяется заранее.
+	if _, err := h.service.GetLink(r.Context(), domain, hash, userID); err != nil {
+		httpError(w, err, "could not get URL")
+		return
+	}
+	job, err := h.service.DeleteEnqueue(r.Context(), domain, []string{hash}, userID)
+	if err != nil {
+		httpError(w, err, "could not enqueue deletion")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/user/deletions/%d", job.ID))
+	writeData(w, http.StatusAccepted, job)
+}
+
+// GetLinkHistory возвращает историю изменений ссылки пользователя
+// @Summary История изменений ссылки
+// @Description Возвращает предыдущие состояния ссылки, начиная с последнего изменения
+// @Tags Links v2
+// @Produce json
+// @Param id path string true "Идентификатор (хеш) ссылки"
+// @Param domain query string false "Домен ссылки; по умолчанию домен сервиса"
+// @Success 200 {object} model.Envelope[[]model.LinkRevision] "История изменений"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Ссылка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links/{id}/history [get]
+func (h *Handler) GetLinkHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	revs, err := h.service.GetRevisions(r.Context(), r.URL.Query().Get("domain"), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		httpError(w, err, "could not get URL history")
+		return
+	}
+	if revs == nil {
+		revs = []model.LinkRevision{}
+	}
+	writeData(w, http.StatusOK, revs)
+}
+
+// CreateLinks создает несколько ссылок
+// @Summary Пакетное создание ссылок
+// @Description Создает несколько ссылок за одно обращение к хранилищу.
+// @Description Для каждого элемента возвращается статус: created, existing или invalid.
+// @Description В режиме atomic (по умолчанию) пакет с невалидными элементами отклоняется целиком:
+// @Description невалидные элементы перечисляются в поле items ответа об ошибке.
+// @Tags Links v2
+// @Accept json
+// @Produce json
+// @Param request body model.BatchRequest true "Ссылки для сокращения и режим обработки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом получает сохраненный ответ"
+// @Success 201 {object} model.Envelope[[]model.BatchCreateResponse] "Результаты по каждому элементу"
+// @Failure 400 {object} model.Problem "Некорректный запрос или пакет с невалидными элементами"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 409 {object} model.Problem "Запрос с тем же ключом идемпотентности еще выполняется"
+// @Failure 413 {object} model.Problem "Превышен допустимый размер пакета"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/v2/links/batch [post]
+func (h *Handler) CreateLinks(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchRequest
+	if !decodeJSON(w, r, maxBatchBodySize, &req) {
+		return
+	}
+	switch req.Mode {
+	case "":
+		req.Mode = model.BatchAtomic
+	case model.BatchAtomic, model.BatchBestEffort:
+	default:
+		httpProblem(w, "invalid mode: expected atomic or best-effort", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) == 0 {
+		httpProblem(w, "empty batch", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) > maxBatchSize {
+		httpProblem(w, fmt.Sprintf("batch too large: max %d items", maxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	res, err := h.service.BatchAdd(r.Context(), req.Items, req.Mode, userID)
+	if errors.Is(err, service.ErrBatchRejected) {
+		p := newProblem(http.StatusBadRequest, fault.ReasonOf(err), err.Error())
+		p.Items = res
+		sendProblem(w, p)
+		return
+	}
+	if err != nil {
+		httpError(w, err, "could not shorten URLs")
+		return
+	}
+
+	for i, item := range res {
+		if item.Status != model.BatchStatusCreated {
+			continue
+		}
+		h.service.NotifyObservers(r.Context(), audit.Event{
+			Timestamp: time.Now(),
+			Action:    audit.Shorten,
+			UserID:    userID,
+			URL:       req.Items[i].OriginalURL,
+			IP:        clientip.String(r.Context()),
+			ShortURL:  item.ShortURL,
+		})
+	}
+
+	writeData(w, http.StatusCreated, res)
+}
+
+// maxBodySize ограничивает размер тела запроса API v2 с одной ссылкой.
+const maxBodySize = 100 * 1024
+
+// decodeJSON разбирает JSON-тело запроса размером не более limit байт в v.
+// При ошибке отвечает 400 (413 для слишком большого тела) и возвращает false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, limit int64, v any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		httpProblem(w, "unsupported content type", http.StatusBadRequest)
+		return false
+	}
+	if err = json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			httpProblem(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeData отвечает статусом code и данными data в конверте API v2.
+func writeData[T any](w http.ResponseWriter, code int, data T) {
+	writeJSON(w, code, model.Envelope[T]{Data: data})
+}
+
+// writeJSON отвечает статусом code и телом v в формате JSON.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = encodeJSONBuffered(w, v)
+}
+
+// linkLocation возвращает адрес ссылки в API v2.
+func linkLocation(domain, hash string) string {
+	loc := "/api/v2/links/" + url.PathEscape(hash)
+	if domain != "" {
+		loc += "?domain=" + url.QueryEscape(domain)
+	}
+	return loc
+}
+
+// linkResource преобразует ссылку сервиса в модель API v2.
+func linkResource(l model.LinkPair) model.LinkResource {
+	tags := l.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return model.LinkResource{
+		ID:          l.Hash,
+		Domain:      l.Domain,
+		ShortURL:    l.ShortURL,
+		OriginalURL: l.OriginalURL,
+		CreatedAt:   l.CreatedAt,
+		Metadata: model.LinkMetadata{
+			Title: l.Title,
+			Tags:  tags,
+			Note:  l.Note,
+		},
+		Redirect:     l.Redirect,
+		Clicks:       l.Clicks,
+		Protected:    l.Protected,
+		ExperimentID: l.ExperimentID,
+		MaxClicks:    l.MaxClicks,
+		ClicksLeft:   l.ClicksLeft,
+		Rules:        l.Rules,
+		Broken:       l.Broken,
+		Health:       l.Health,
+	}
+}
@@ -0,0 +1,245 @@
+// Package handler содержит обработчики подписок на события ссылок.
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+)
+
+// readWebhookBody проверяет тип содержимого и разбирает JSON-тело запроса подписки в v.
+func readWebhookBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		httpProblem(w, "invalid content-type", http.StatusBadRequest)
+		return false
+	}
+	body, err := readBodyLimited(r.Body, 100*1024)
+	if err == nil {
+		err = json.Unmarshal(body, v)
+	}
+	if err != nil {
+		httpProblem(w, "invalid json body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// webhookID возвращает идентификатор подписки из пути запроса.
+func webhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httpProblem(w, "invalid webhook id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// webhookError отвечает на ошибку операции с подпиской.
+func webhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		notFoundProblem(w, "webhook not found")
+		return
+	}
+	httpError(w, err, "webhook operation failed")
+}
+
+// writeWebhookJSON отвечает JSON-представлением v с кодом status.
+func writeWebhookJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := encodeJSONBuffered(w, v); err != nil {
+		httpProblem(w, "encoding error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CreateWebhook создает подписку на события ссылок
+// @Summary Подписаться на события ссылок
+// @Description Создает подписку текущего пользователя на события его ссылок: created, clicked, deleted, expired.
+// @Description События отправляются POST-запросом с телом model.WebhookEvent и заголовками X-Webhook-Event,
+// @Description X-Webhook-Delivery, X-Webhook-Timestamp и X-Webhook-Signature (sha256=HMAC-SHA256 ключа подписи
+// @Description от строки "<timestamp>.<тело>"). Неудачные доставки повторяются с экспоненциальной задержкой.
+// @Description Ключ подписи возвращается только в этом ответе.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param request body model.WebhookRequest true "Параметры подписки"
+// @Success 201 {object} model.Webhook "Созданная подписка с ключом подписи"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/webhooks [post]
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req model.WebhookRequest
+	if !readWebhookBody(w, r, &req) {
+		return
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	hook, err := h.service.CreateWebhook(r.Context(), req, userID)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+	writeWebhookJSON(w, http.StatusCreated, hook)
+}
+
+// ListWebhooks возвращает подписки пользователя
+// @Summary Список подписок на события
+// @Description Возвращает подписки текущего пользователя в порядке создания (без ключей подписи)
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} model.Webhook "Подписки пользователя"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/webhooks [get]
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	list, err := h.service.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+	writeWebhookJSON(w, http.StatusOK, list)
+}
+
+// GetWebhook возвращает подписку пользователя
+// @Summary Подписка на события
+// @Description Возвращает подписку текущего пользователя (без ключа подписи)
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Идентификатор подписки"
+// @Success 200 {object} model.Webhook "Подписка"
+// @Failure 400 {object} model.Problem "Некорректный идентификатор"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Подписка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/webhooks/{id} [get]
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	hook, err := h.service.GetWebhook(r.Context(), id, userID)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+	writeWebhookJSON(w, http.StatusOK, hook)
+}
+
+// UpdateWebhook изменяет подписку пользователя
+// @Summary Изменить подписку на события
+// @Description Изменяет заданные поля подписки: адрес, события, ключ подписи, включение доставки.
+// @Description Новый ключ подписи возвращается в ответе.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Идентификатор подписки"
+// @Param request body model.WebhookUpdate true "Изменяемые поля"
+// @Success 200 {object} model.Webhook "Измененная подписка"
+// @Failure 400 {object} model.Problem "Некорректный запрос"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Подписка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/webhooks/{id} [patch]
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	var upd model.WebhookUpdate
+	if !readWebhookBody(w, r, &upd) {
+		return
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	hook, err := h.service.UpdateWebhook(r.Context(), id, upd, userID)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+	writeWebhookJSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhook удаляет подписку пользователя
+// @Summary Удалить подписку на события
+// @Description Удаляет подписку текущего пользователя вместе с журналом доставок
+// @Tags Webhooks
+// @Param id path int true "Идентификатор подписки"
+// @Success 204 "Подписка удалена"
+// @Failure 400 {object} model.Problem "Некорректный идентификатор"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Подписка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteWebhook(r.Context(), id, userID); err != nil {
+		webhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries возвращает журнал доставок подписки
+// @Summary Журнал доставок событий
+// @Description Возвращает последние 100 доставок событий подписке (новые первыми):
+// @Description статус, число попыток, ответ получателя и время следующей попытки.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Идентификатор подписки"
+// @Success 200 {array} model.WebhookDelivery "Доставки событий"
+// @Failure 400 {object} model.Problem "Некорректный идентификатор"
+// @Failure 401 {object} model.Problem "Неавторизованный доступ"
+// @Failure 404 {object} model.Problem "Подписка не найдена"
+// @Failure 500 {object} model.Problem "Внутренняя ошибка сервера"
+// @Router /api/user/webhooks/{id}/deliveries [get]
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		httpProblem(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.service.ListDeliveries(r.Context(), id, userID)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+	writeWebhookJSON(w, http.StatusOK, list)
+}
@@ -2,5 +2,5 @@
 package config
 
 type Config struct {
-	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+	LogLevel string `env:"LOG_LEVEL"`
 }
@@ -5,11 +5,13 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/spitfy/urlshortener/internal/clientip"
 	"go.uber.org/zap"
 )
 
 type Logger struct {
-	Log *zap.Logger
+	Log   *zap.Logger
+	level zap.AtomicLevel
 }
 
 type (
@@ -39,7 +41,17 @@ func Initialize(level string) (*Logger, error) {
 		return nil, err
 	}
 
-	return &Logger{Log: zl}, nil
+	return &Logger{Log: zl, level: lvl}, nil
+}
+
+// SetLevel меняет уровень логирования на лету, не пересоздавая логгер.
+// Пример:
+//
+//	if err := l.SetLevel("debug"); err != nil {
+//	    log.Println(err)
+//	}
+func (l *Logger) SetLevel(level string) error {
+	return l.level.UnmarshalText([]byte(level))
 }
 
 // LogInfo Сведения о запросах должны содержать URI, метод запроса и время, затраченное на его выполнение.
@@ -58,6 +70,7 @@ func (l *Logger) LogInfo(h http.HandlerFunc) http.HandlerFunc {
 		l.Log.Info("request log",
 			zap.String("url", r.URL.String()),
 			zap.String("method", r.Method),
+			zap.String("client_ip", clientip.String(r.Context())),
 			zap.Duration("duration", duration),
 			zap.Int("status", lw.responseData.status),
 			zap.Int("size", lw.responseData.size),
@@ -75,3 +88,15 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 	r.responseData.status = statusCode
 }
+
+// Unwrap возвращает исходный http.ResponseWriter, чтобы http.ResponseController
+// мог отправлять потоковые ответы и захватывать соединение.
+func (r *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush отправляет буферизованные данные клиенту. Нужен обработчикам, которые
+// проверяют поддержку http.Flusher приведением типа, а не через http.ResponseController.
+func (r *loggingResponseWriter) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
@@ -34,3 +34,14 @@ func TestInitialize(t *testing.T) {
 		})
 	}
 }
+
+func TestLogger_SetLevel(t *testing.T) {
+	l, err := Initialize("info")
+	assert.NoError(t, err)
+
+	assert.NoError(t, l.SetLevel("debug"))
+	assert.Equal(t, zap.DebugLevel, l.Log.Level())
+
+	assert.Error(t, l.SetLevel("loud"))
+	assert.Equal(t, zap.DebugLevel, l.Log.Level(), "invalid level must keep the current one")
+}
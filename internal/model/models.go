@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // Request представляет запрос на сокращение URL
 // @Schema(
 //
@@ -11,6 +13,8 @@ type Request struct {
 	// Оригинальный URL для сокращения
 	// Пример: "https://example.com/very/long/url/to/be/shortened"
 	URL string `json:"url"`
+
+	LinkOptions
 }
 
 // Response содержит результат сокращения URL
@@ -42,25 +46,189 @@ type Link struct {
 	// Сокращенный URL
 	ShortURL string `json:"short_url"`
 
+	// Домен ссылки; пустой для домена по умолчанию
+	Domain string `json:"domain,omitempty"`
+
 	// Оригинальный URL
 	OriginalURL string `json:"original_url"`
+
+	// Флаг удаления (soft delete)
+	IsDeleted bool `json:"is_deleted,omitempty"`
+
+	// Параметры перенаправления
+	Redirect RedirectOptions `json:"redirect,omitzero"`
+
+	// Идентификатор владельца ссылки
+	UserID int `json:"user_id,omitempty"`
+
+	// Заголовок ссылки
+	Title string `json:"title,omitempty"`
+
+	// Теги ссылки
+	Tags []string `json:"tags,omitempty"`
+
+	// Заметка владельца ссылки
+	Note string `json:"note,omitempty"`
+
+	// Время создания ссылки
+	CreatedAt time.Time `json:"created_at,omitzero"`
+
+	// Количество переходов по ссылке
+	Clicks int64 `json:"clicks,omitempty"`
+
+	// Bcrypt-хеш пароля ссылки
+	PasswordHash string `json:"password_hash,omitempty"`
+
+	// Допустимое число переходов по ссылке; 0 — без ограничения
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+
+	// Правила выбора адреса перенаправления
+	Rules []RouteRule `json:"rules,omitempty"`
+
+	// Эксперимент, трафик которого распределяет ссылка
+	Experiment *Experiment `json:"experiment,omitempty"`
+
+	// Результат последней проверки доступности адреса назначения
+	Health *LinkHealth `json:"health,omitempty"`
+
+	// Время удаления ссылки
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Время окончательного удаления ссылки
+	PurgedAt *time.Time `json:"purged_at,omitempty"`
+
+	// Хеш окончательно удаленной ссылки доступен для повторного использования
+	HashFree bool `json:"hash_free,omitempty"`
+
+	// История изменений ссылки
+	History []LinkRevision `json:"history,omitempty"`
 }
 
 // LinkPair представляет пару сокращенного и оригинального URL
+// с метаданными ссылки
 // @Schema(
 //
 //	example={
 //	    "short_url": "http://short.ly/abc123",
-//	    "original_url": "https://example.com/very-long-url"
+//	    "original_url": "https://example.com/very-long-url",
+//	    "title": "Весенняя распродажа",
+//	    "tags": ["promo"],
+//	    "clicks": 42
 //	}
 //
 // )
 type LinkPair struct {
+	// Хеш ссылки; в API v1 не передается
+	Hash string `json:"-"`
+
+	// Домен ссылки; пустой для домена по умолчанию. В API v1 не передается
+	Domain string `json:"-"`
+
 	// Сокращенный URL
 	ShortURL string `json:"short_url"`
 
 	// Оригинальный URL
 	OriginalURL string `json:"original_url"`
+
+	// Заголовок ссылки
+	Title string `json:"title,omitempty"`
+
+	// Теги ссылки
+	Tags []string `json:"tags,omitempty"`
+
+	// Заметка владельца ссылки
+	Note string `json:"note,omitempty"`
+
+	// Параметры перенаправления
+	Redirect RedirectOptions `json:"redirect,omitzero"`
+
+	// Время создания ссылки
+	CreatedAt time.Time `json:"created_at,omitzero"`
+
+	// Количество переходов по ссылке
+	Clicks int64 `json:"clicks,omitempty"`
+
+	// Ссылка защищена паролем
+	Protected bool `json:"protected,omitempty"`
+
+	// Идентификатор A/B-эксперимента, трафик которого распределяет ссылка
+	ExperimentID int64 `json:"experiment_id,omitempty"`
+
+	// Допустимое число переходов по ссылке; не задается для ссылок без ограничения
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+
+	// Оставшееся число переходов; не задается для ссылок без ограничения
+	ClicksLeft *int64 `json:"clicks_left,omitempty"`
+
+	// Правила выбора адреса перенаправления
+	Rules []RouteRule `json:"rules,omitempty"`
+
+	// Адрес назначения недоступен по результатам последней проверки
+	Broken bool `json:"broken,omitempty"`
+
+	// Результат последней проверки доступности адреса назначения; не задается, если проверки не было
+	Health *LinkHealth `json:"health,omitempty"`
+}
+
+// LinkHealth содержит результат проверки доступности адреса назначения ссылки.
+// @Schema(
+//
+//	example={
+//	    "status": 404,
+//	    "redirects": ["https://example.com/new-location"],
+//	    "broken": true,
+//	    "checked_at": "2025-01-01T12:00:00Z"
+//	}
+//
+// )
+type LinkHealth struct {
+	// HTTP-статус последнего ответа цепочки перенаправлений; 0, если ответ не получен
+	Status int `json:"status,omitempty"`
+
+	// Ошибка запроса: адрес не разрешается, соединение не установлено и т. п.
+	Error string `json:"error,omitempty"`
+
+	// Адреса, на которые перенаправлял сервер назначения, по порядку
+	Redirects []string `json:"redirects,omitempty"`
+
+	// Адрес недоступен: ответ не получен или получен статус 4xx/5xx (кроме 429)
+	Broken bool `json:"broken"`
+
+	// Время проверки
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// LinkFilter задает поиск, фильтрацию, сортировку и страницу ссылок пользователя.
+type LinkFilter struct {
+	// Слова для поиска в заголовке, заметке и оригинальном URL (все слова должны встречаться)
+	Query string
+
+	// Теги, которые должны быть у ссылки (все перечисленные)
+	Tags []string
+
+	// Поле сортировки: created (по умолчанию) или clicks
+	Sort string
+
+	// Порядок сортировки: desc (по умолчанию) или asc
+	Order string
+
+	// Курсор следующей страницы из предыдущего ответа
+	Cursor string
+
+	// Размер страницы; 0 — размер по умолчанию
+	Limit int
+
+	// Только ссылки, адрес назначения которых недоступен по результатам последней проверки
+	Broken bool
+}
+
+// LinkPage содержит страницу ссылок пользователя.
+type LinkPage struct {
+	// Ссылки страницы
+	Links []LinkPair
+
+	// Курсор следующей страницы; пустой для последней страницы
+	NextCursor string
 }
 
 // BatchCreateRequest представляет запрос на пакетное создание сокращенных URL
@@ -78,14 +246,34 @@ type BatchCreateRequest struct {
 
 	// Оригинальный URL для сокращения
 	OriginalURL string `json:"original_url"`
+
+	LinkOptions
 }
 
+// BatchMode определяет поведение пакетного сокращения при невалидных элементах.
+type BatchMode string
+
+const (
+	// BatchAtomic — пакет отклоняется целиком, если хотя бы один элемент невалиден (по умолчанию).
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort — сохраняются только валидные элементы, остальные помечаются как invalid.
+	BatchBestEffort BatchMode = "best-effort"
+)
+
+// Статусы элементов пакетного сокращения.
+const (
+	BatchStatusCreated  = "created"
+	BatchStatusExisting = "existing"
+	BatchStatusInvalid  = "invalid"
+)
+
 // BatchCreateResponse содержит результат пакетного создания сокращенных URL
 // @Schema(
 //
 //	example={
 //	    "correlation_id": "request-123",
-//	    "short_url": "http://short.ly/abc123"
+//	    "short_url": "http://short.ly/abc123",
+//	    "status": "created"
 //	}
 //
 // )
@@ -94,10 +282,739 @@ type BatchCreateResponse struct {
 	CorrelationID string `json:"correlation_id"`
 
 	// Сокращенный URL
-	ShortURL string `json:"short_url"`
+	ShortURL string `json:"short_url,omitempty"`
+
+	// Статус элемента: created, existing или invalid
+	Status string `json:"status"`
+
+	// Причина отклонения невалидного элемента
+	Error string `json:"error,omitempty"`
 }
 
+// Stats содержит статистику сервиса
 type Stats struct {
-	URLs  int `json:"urls"`
+	// Количество активных ссылок
+	URLs int `json:"urls"`
+
+	// Количество пользователей
 	Users int `json:"users"`
+
+	// Количество удаленных ссылок, которые еще можно восстановить
+	Deleted int `json:"deleted"`
+
+	// Количество окончательно удаленных ссылок
+	Purged int `json:"purged"`
+}
+
+// UTM содержит UTM-метки, добавляемые к адресу перенаправления
+// @Schema(
+//
+//	example={
+//	    "source": "newsletter",
+//	    "medium": "email",
+//	    "campaign": "spring_sale"
+//	}
+//
+// )
+type UTM struct {
+	Source   string `json:"source,omitempty"`   // utm_source
+	Medium   string `json:"medium,omitempty"`   // utm_medium
+	Campaign string `json:"campaign,omitempty"` // utm_campaign
+	Term     string `json:"term,omitempty"`     // utm_term
+	Content  string `json:"content,omitempty"`  // utm_content
+}
+
+// RedirectOptions описывает поведение перенаправления для ссылки
+// @Schema(
+//
+//	example={
+//	    "code": 301,
+//	    "pass_query": true,
+//	    "utm": {"source": "newsletter"}
+//	}
+//
+// )
+type RedirectOptions struct {
+	// HTTP-код перенаправления: 301, 302, 307 или 308 (по умолчанию 307)
+	Code int `json:"code,omitempty"`
+
+	// Передавать query-параметры запроса в адрес перенаправления
+	PassQuery bool `json:"pass_query,omitempty"`
+
+	// UTM-метки, добавляемые к адресу перенаправления
+	UTM UTM `json:"utm,omitzero"`
+}
+
+// Платформы посетителя, определяемые по User-Agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformDesktop = "desktop"
+)
+
+// RouteRule описывает правило выбора адреса перенаправления.
+// Правила ссылки проверяются по порядку; переход ведет на адрес первого правила,
+// все заданные условия которого выполнены, а если таких нет — на оригинальный URL.
+// @Schema(
+//
+//	example={
+//	    "url": "https://apps.apple.com/app/id123",
+//	    "platforms": ["ios"],
+//	    "countries": ["US", "CA"]
+//	}
+//
+// )
+type RouteRule struct {
+	// Адрес перенаправления при выполнении условий
+	URL string `json:"url"`
+
+	// Платформы посетителя: ios, android или desktop
+	Platforms []string `json:"platforms,omitempty"`
+
+	// Языки посетителя: сравниваются с наиболее предпочтительным языком из Accept-Language;
+	// "en" совпадает с "en-GB", "en-GB" — только с "en-GB"
+	Languages []string `json:"languages,omitempty"`
+
+	// Страны посетителя (ISO 3166-1 alpha-2), определяемые по IP-адресу
+	Countries []string `json:"countries,omitempty"`
+
+	// Период действия правила
+	Time *TimeWindow `json:"time,omitempty"`
+
+	// Доля посетителей в процентах (1..100). Доли правил ссылки следуют друг за другом:
+	// правила с долями 30 и 20 получают 30% и следующие 20% посетителей.
+	// Посетитель с тем же IP-адресом и User-Agent всегда попадает в одну долю
+	Percent int `json:"percent,omitempty"`
+}
+
+// TimeWindow задает период действия правила: интервал дат и (или) ежедневные часы.
+// @Schema(
+//
+//	example={
+//	    "from": "09:00",
+//	    "to": "18:00",
+//	    "location": "Europe/Moscow"
+//	}
+//
+// )
+type TimeWindow struct {
+	// Начало действия правила
+	Start time.Time `json:"start,omitzero"`
+
+	// Окончание действия правила (не включительно)
+	End time.Time `json:"end,omitzero"`
+
+	// Начало ежедневного интервала в формате ЧЧ:ММ
+	From string `json:"from,omitempty"`
+
+	// Конец ежедневного интервала в формате ЧЧ:ММ (не включительно); может быть меньше From
+	To string `json:"to,omitempty"`
+
+	// Часовой пояс ежедневного интервала (IANA); по умолчанию UTC
+	Location string `json:"location,omitempty"`
+}
+
+// LinkOptions содержит дополнительные параметры создаваемой ссылки
+type LinkOptions struct {
+	// Параметры перенаправления
+	Redirect RedirectOptions `json:"redirect,omitzero"`
+
+	// Домен короткой ссылки; по умолчанию домен сервиса
+	Domain string `json:"domain,omitempty"`
+
+	// Заголовок ссылки; если не задан, может быть загружен со страницы назначения
+	Title string `json:"title,omitempty"`
+
+	// Теги ссылки
+	Tags []string `json:"tags,omitempty"`
+
+	// Заметка владельца ссылки
+	Note string `json:"note,omitempty"`
+
+	// Пароль, который нужно ввести перед переходом по ссылке
+	Password string `json:"password,omitempty"`
+
+	// Допустимое число переходов; после него ссылка отвечает 410 Gone. 0 — без ограничения
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+
+	// Правила выбора адреса перенаправления
+	Rules []RouteRule `json:"rules,omitempty"`
+}
+
+// LinkUpdate представляет запрос на редактирование ссылки.
+// Незаданные (null) поля остаются без изменений.
+// @Schema(
+//
+//	example={
+//	    "original_url": "https://example.com/new-destination",
+//	    "title": "Новая страница",
+//	    "tags": ["promo", "spring"]
+//	}
+//
+// )
+type LinkUpdate struct {
+	// Новый оригинальный URL
+	OriginalURL *string `json:"original_url,omitempty"`
+
+	// Новый заголовок
+	Title *string `json:"title,omitempty"`
+
+	// Новый список тегов
+	Tags *[]string `json:"tags,omitempty"`
+
+	// Новая заметка
+	Note *string `json:"note,omitempty"`
+
+	// Новый пароль ссылки; пустая строка снимает защиту паролем
+	Password *string `json:"password,omitempty"`
+
+	// Новые параметры перенаправления
+	Redirect *RedirectOptions `json:"redirect,omitempty"`
+
+	// Новый список правил перенаправления; пустой список удаляет правила
+	Rules *[]RouteRule `json:"rules,omitempty"`
+}
+
+// LinkRevision содержит состояние ссылки до очередного редактирования
+// @Schema(
+//
+//	example={
+//	    "revision": 1,
+//	    "original_url": "https://example.com/old-destination",
+//	    "edited_at": "2025-01-01T12:00:00Z"
+//	}
+//
+// )
+type LinkRevision struct {
+	// Порядковый номер ревизии
+	Revision int `json:"revision"`
+
+	// Оригинальный URL до редактирования
+	OriginalURL string `json:"original_url"`
+
+	// Заголовок до редактирования
+	Title string `json:"title,omitempty"`
+
+	// Теги до редактирования
+	Tags []string `json:"tags,omitempty"`
+
+	// Заметка до редактирования
+	Note string `json:"note,omitempty"`
+
+	// Параметры перенаправления до редактирования
+	Redirect RedirectOptions `json:"redirect,omitzero"`
+
+	// Правила перенаправления до редактирования
+	Rules []RouteRule `json:"rules,omitempty"`
+
+	// Время редактирования
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// Статусы задания на удаление ссылок.
+const (
+	DeletionPending = "pending"
+	DeletionDone    = "done"
+)
+
+// DeletionJob описывает задание на удаление ссылок пользователя
+// @Schema(
+//
+//	example={
+//	    "id": 42,
+//	    "status": "pending",
+//	    "hashes": ["abc123", "def456"],
+//	    "created_at": "2025-01-01T12:00:00Z"
+//	}
+//
+// )
+type DeletionJob struct {
+	// Идентификатор задания
+	ID int64 `json:"id"`
+
+	// Идентификатор владельца ссылок
+	UserID int `json:"user_id,omitempty"`
+
+	// Домен удаляемых ссылок; пустой для домена по умолчанию
+	Domain string `json:"domain,omitempty"`
+
+	// Статус задания: pending или done
+	Status string `json:"status"`
+
+	// Хеши удаляемых ссылок
+	Hashes []string `json:"hashes"`
+
+	// Время постановки задания в очередь
+	CreatedAt time.Time `json:"created_at"`
+
+	// Время завершения задания
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Статусы восстановления удаленной ссылки.
+const (
+	RestoreRestored = "restored"
+	RestoreNotFound = "not_found"
+	RestoreConflict = "conflict"
+)
+
+// RestoreResult содержит результат восстановления одной ссылки
+// @Schema(
+//
+//	example={
+//	    "hash": "abc123",
+//	    "status": "restored"
+//	}
+//
+// )
+type RestoreResult struct {
+	// Хеш ссылки
+	Hash string `json:"hash"`
+
+	// Статус: restored, not_found (ссылка не найдена, не удалена или срок восстановления истек)
+	// или conflict (оригинальный URL уже сокращен заново)
+	Status string `json:"status"`
+}
+
+// ExperimentRequest представляет запрос на создание A/B-эксперимента:
+// короткой ссылки, распределяющей посетителей между вариантами по весам.
+// @Schema(
+//
+//	example={
+//	    "name": "Лендинг весенней распродажи",
+//	    "variants": [
+//	        {"name": "A", "url": "https://example.com/landing-a", "weight": 70},
+//	        {"name": "B", "url": "https://example.com/landing-b", "weight": 30}
+//	    ]
+//	}
+//
+// )
+type ExperimentRequest struct {
+	// Название эксперимента; становится заголовком ссылки
+	Name string `json:"name,omitempty"`
+
+	// Домен короткой ссылки; по умолчанию домен сервиса
+	Domain string `json:"domain,omitempty"`
+
+	// Варианты эксперимента (от 2 до 5)
+	Variants []Variant `json:"variants"`
+}
+
+// Variant описывает вариант A/B-эксперимента и число перенаправлений на него.
+// @Schema(
+//
+//	example={
+//	    "name": "A",
+//	    "url": "https://example.com/landing-a",
+//	    "weight": 70,
+//	    "redirects": 1250
+//	}
+//
+// )
+type Variant struct {
+	// Название варианта; по умолчанию A, B, C и т. д. по порядку
+	Name string `json:"name,omitempty"`
+
+	// Адрес перенаправления варианта
+	URL string `json:"url"`
+
+	// Вес варианта (1..1000): доля посетителей пропорциональна весу. По умолчанию 1
+	Weight int `json:"weight,omitempty"`
+
+	// Количество перенаправлений на вариант; при создании эксперимента не задается
+	Redirects int64 `json:"redirects"`
+}
+
+// Experiment содержит A/B-эксперимент пользователя и его результаты.
+// @Schema(
+//
+//	example={
+//	    "id": 7,
+//	    "name": "Лендинг весенней распродажи",
+//	    "short_url": "http://short.ly/abc123",
+//	    "variants": [
+//	        {"name": "A", "url": "https://example.com/landing-a", "weight": 70, "redirects": 1250},
+//	        {"name": "B", "url": "https://example.com/landing-b", "weight": 30, "redirects": 540}
+//	    ],
+//	    "redirects": 1790,
+//	    "created_at": "2025-01-01T12:00:00Z"
+//	}
+//
+// )
+type Experiment struct {
+	// Идентификатор эксперимента
+	ID int64 `json:"id"`
+
+	// Название эксперимента
+	Name string `json:"name,omitempty"`
+
+	// Короткая ссылка эксперимента
+	ShortURL string `json:"short_url,omitempty"`
+
+	// Варианты эксперимента с числом перенаправлений
+	Variants []Variant `json:"variants"`
+
+	// Общее количество перенаправлений
+	Redirects int64 `json:"redirects"`
+
+	// Время создания эксперимента
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// События ссылок, на которые можно подписаться.
+const (
+	WebhookCreated = "created" // Ссылка создана
+	WebhookClicked = "clicked" // Переходы по ссылке (агрегируются за период)
+	WebhookDeleted = "deleted" // Ссылка поставлена в очередь на удаление
+	WebhookExpired = "expired" // Исчерпан лимит переходов ссылки
+)
+
+// Статусы доставки события подписке.
+const (
+	DeliveryPending   = "pending"   // Ожидает отправки или повторной попытки
+	DeliveryDelivered = "delivered" // Получатель ответил кодом 2xx
+	DeliveryFailed    = "failed"    // Попытки доставки исчерпаны
+)
+
+// WebhookRequest содержит параметры новой подписки на события ссылок.
+// @Schema(
+//
+//	example={
+//	    "url": "https://hooks.example.com/shortener",
+//	    "events": ["created", "expired"]
+//	}
+//
+// )
+type WebhookRequest struct {
+	// Адрес получателя событий (http или https)
+	URL string `json:"url"`
+
+	// События подписки: created, clicked, deleted, expired; пустой список — все события
+	Events []string `json:"events,omitempty"`
+
+	// Ключ подписи HMAC-SHA256; если не задан, генерируется сервисом
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookUpdate содержит изменяемые поля подписки; незаданные поля не меняются.
+// @Schema(
+//
+//	example={
+//	    "active": false
+//	}
+//
+// )
+type WebhookUpdate struct {
+	// Новый адрес получателя
+	URL *string `json:"url,omitempty"`
+
+	// Новый список событий; пустой список — все события
+	Events *[]string `json:"events,omitempty"`
+
+	// Новый ключ подписи
+	Secret *string `json:"secret,omitempty"`
+
+	// Включение и отключение доставки
+	Active *bool `json:"active,omitempty"`
+}
+
+// Webhook содержит подписку пользователя на события его ссылок.
+// @Schema(
+//
+//	example={
+//	    "id": 3,
+//	    "url": "https://hooks.example.com/shortener",
+//	    "events": ["created", "expired"],
+//	    "active": true,
+//	    "secret": "Jx3kQ9...",
+//	    "created_at": "2025-01-01T12:00:00Z"
+//	}
+//
+// )
+type Webhook struct {
+	// Идентификатор подписки
+	ID int64 `json:"id"`
+
+	// Адрес получателя событий
+	URL string `json:"url"`
+
+	// События подписки
+	Events []string `json:"events"`
+
+	// Доставка включена
+	Active bool `json:"active"`
+
+	// Ключ подписи; возвращается только при создании подписки и смене ключа
+	Secret string `json:"secret,omitempty"`
+
+	// Время создания подписки
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookEvent — тело запроса, с которым событие отправляется подписке.
+// Запрос подписывается заголовком X-Webhook-Signature: sha256=HMAC-SHA256
+// ключа подписки от строки "<X-Webhook-Timestamp>.<тело запроса>" в шестнадцатеричном виде.
+// @Schema(
+//
+//	example={
+//	    "event": "clicked",
+//	    "ts": "2025-01-01T12:01:00Z",
+//	    "short_url": "http://short.ly/abc123",
+//	    "original_url": "https://example.com/page",
+//	    "clicks": 42
+//	}
+//
+// )
+type WebhookEvent struct {
+	// Событие: created, clicked, deleted или expired
+	Event string `json:"event"`
+
+	// Время события; для clicked — конец периода агрегирования
+	Timestamp time.Time `json:"ts"`
+
+	// Короткая ссылка
+	ShortURL string `json:"short_url,omitempty"`
+
+	// Оригинальный URL
+	OriginalURL string `json:"original_url,omitempty"`
+
+	// Количество переходов с предыдущего события clicked
+	Clicks int64 `json:"clicks,omitempty"`
+}
+
+// WebhookDelivery содержит запись журнала доставки события подписке.
+// @Schema(
+//
+//	example={
+//	    "id": 15,
+//	    "event": "created",
+//	    "payload": {"event": "created", "ts": "2025-01-01T12:00:00Z", "short_url": "http://short.ly/abc123", "original_url": "https://example.com/page"},
+//	    "status": "pending",
+//	    "attempts": 2,
+//	    "response_status": 503,
+//	    "created_at": "2025-01-01T12:00:00Z",
+//	    "last_attempt_at": "2025-01-01T12:01:00Z",
+//	    "next_attempt_at": "2025-01-01T12:03:00Z"
+//	}
+//
+// )
+type WebhookDelivery struct {
+	// Идентификатор доставки (заголовок X-Webhook-Delivery)
+	ID int64 `json:"id"`
+
+	// Событие
+	Event string `json:"event"`
+
+	// Отправляемое тело запроса
+	Payload WebhookEvent `json:"payload"`
+
+	// Статус: pending, delivered или failed
+	Status string `json:"status"`
+
+	// Количество выполненных попыток
+	Attempts int `json:"attempts"`
+
+	// Код ответа получателя на последнюю попытку
+	ResponseStatus int `json:"response_status,omitempty"`
+
+	// Ошибка последней попытки
+	Error string `json:"error,omitempty"`
+
+	// Время создания события
+	CreatedAt time.Time `json:"created_at"`
+
+	// Время последней попытки
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+
+	// Время следующей попытки для ожидающих доставок
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// Служебные события потока изменений ссылок.
+const (
+	// StreamHeartbeat отправляется при отсутствии других событий,
+	// чтобы соединение не закрывалось промежуточными прокси.
+	StreamHeartbeat = "heartbeat"
+	// StreamClosed — последнее событие потока, закрытого сервером; причина указывается в поле error.
+	// Клиент, не успевающий получать события, отключается и должен переподключиться.
+	StreamClosed = "closed"
+)
+
+// StreamEvent — событие потока изменений ссылок пользователя.
+// @Schema(
+//
+//	example={
+//	    "event": "clicked",
+//	    "ts": "2025-01-01T12:00:00Z",
+//	    "short_url": "http://short.ly/abc123",
+//	    "original_url": "https://example.com/page"
+//	}
+//
+// )
+type StreamEvent struct {
+	// Событие: created, clicked, deleted, expired, heartbeat или closed
+	Event string `json:"event"`
+
+	// Время события
+	Timestamp time.Time `json:"ts"`
+
+	// Короткая ссылка
+	ShortURL string `json:"short_url,omitempty"`
+
+	// Оригинальный URL
+	OriginalURL string `json:"original_url,omitempty"`
+
+	// Причина закрытия потока для события closed
+	Error string `json:"error,omitempty"`
+}
+
+// Problem — тело ответа об ошибке в формате RFC 9457 (application/problem+json)
+// @Schema(
+//
+//	example={
+//	    "type": "urn:urlshortener:problem:invalid_url",
+//	    "title": "Bad Request",
+//	    "status": 400,
+//	    "detail": "invalid url",
+//	    "code": "invalid_url"
+//	}
+//
+// )
+type Problem struct {
+	// URI типа ошибки; "about:blank", если у ошибки нет кода
+	Type string `json:"type"`
+
+	// Краткое описание статуса ответа
+	Title string `json:"title"`
+
+	// HTTP-статус ответа
+	Status int `json:"status"`
+
+	// Описание ошибки
+	Detail string `json:"detail,omitempty"`
+
+	// Машинно-читаемый код ошибки, например invalid_url
+	Code string `json:"code,omitempty"`
+
+	// Невалидные элементы отклоненного пакета (API v2)
+	Items []BatchCreateResponse `json:"items,omitempty"`
+}
+
+// LinkResource — ссылка в API v2: идентификатор, время создания и метаданные
+// передаются всегда.
+// @Schema(
+//
+//	example={
+//	    "id": "abc123",
+//	    "short_url": "http://short.ly/abc123",
+//	    "original_url": "https://example.com/very-long-url",
+//	    "created_at": "2025-01-01T12:00:00Z",
+//	    "metadata": {"title": "Весенняя распродажа", "tags": ["promo"]},
+//	    "clicks": 42
+//	}
+//
+// )
+type LinkResource struct {
+	// Идентификатор ссылки — хеш сокращенного URL; уникален в пределах домена
+	ID string `json:"id"`
+
+	// Домен ссылки; пустой для домена по умолчанию
+	Domain string `json:"domain,omitempty"`
+
+	// Сокращенный URL
+	ShortURL string `json:"short_url"`
+
+	// Оригинальный URL
+	OriginalURL string `json:"original_url"`
+
+	// Время создания ссылки
+	CreatedAt time.Time `json:"created_at"`
+
+	// Метаданные ссылки
+	Metadata LinkMetadata `json:"metadata"`
+
+	// Параметры перенаправления
+	Redirect RedirectOptions `json:"redirect,omitzero"`
+
+	// Количество переходов по ссылке
+	Clicks int64 `json:"clicks"`
+
+	// Ссылка защищена паролем
+	Protected bool `json:"protected"`
+
+	// Идентификатор A/B-эксперимента, трафик которого распределяет ссылка
+	ExperimentID int64 `json:"experiment_id,omitempty"`
+
+	// Допустимое число переходов по ссылке; не задается для ссылок без ограничения
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+
+	// Оставшееся число переходов; не задается для ссылок без ограничения
+	ClicksLeft *int64 `json:"clicks_left,omitempty"`
+
+	// Правила выбора адреса перенаправления
+	Rules []RouteRule `json:"rules,omitempty"`
+
+	// Адрес назначения недоступен по результатам последней проверки
+	Broken bool `json:"broken"`
+
+	// Результат последней проверки доступности адреса назначения; не задается, если проверки не было
+	Health *LinkHealth `json:"health,omitempty"`
+}
+
+// LinkMetadata содержит метаданные ссылки в API v2.
+type LinkMetadata struct {
+	// Заголовок ссылки
+	Title string `json:"title"`
+
+	// Теги ссылки
+	Tags []string `json:"tags"`
+
+	// Заметка владельца ссылки
+	Note string `json:"note"`
+}
+
+// Envelope — конверт успешного ответа API v2 с одним объектом или списком.
+type Envelope[T any] struct {
+	// Данные ответа
+	Data T `json:"data"`
+}
+
+// PageEnvelope — конверт страницы списка API v2.
+type PageEnvelope[T any] struct {
+	// Элементы страницы
+	Data []T `json:"data"`
+
+	// Параметры страницы
+	Page PageInfo `json:"page"`
+}
+
+// PageInfo описывает страницу списка API v2.
+// @Schema(
+//
+//	example={"next_cursor": "eyJjIjoiMjAyNS0wMS0wMVQxMjowMDowMFoifQ", "limit": 100}
+//
+// )
+type PageInfo struct {
+	// Курсор следующей страницы; не задается для последней страницы
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Размер страницы
+	Limit int `json:"limit"`
+}
+
+// BatchRequest представляет запрос на пакетное создание ссылок в API v2
+// @Schema(
+//
+//	example={
+//	    "mode": "best-effort",
+//	    "items": [{"correlation_id": "request-123", "original_url": "https://example.com/very-long-url"}]
+//	}
+//
+// )
+type BatchRequest struct {
+	// Режим обработки: atomic (по умолчанию) или best-effort
+	Mode BatchMode `json:"mode,omitempty"`
+
+	// Ссылки для сокращения
+	Items []BatchCreateRequest `json:"items"`
 }
@@ -0,0 +1,219 @@
+// Package qrcode формирует изображения QR-кодов для коротких ссылок.
+// Кодирование выполняется в процессе, без обращения к внешним сервисам.
+package qrcode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// Форматы изображения.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Ограничения параметров изображения.
+const (
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+)
+
+// ErrInvalidOptions возвращается при недопустимых параметрах изображения.
+var ErrInvalidOptions = errors.New("invalid qr options")
+
+// levels сопоставляет обозначения уровней коррекции ошибок уровням кодировщика.
+var levels = map[string]qr.Level{"L": qr.L, "M": qr.M, "Q": qr.Q, "H": qr.H}
+
+// Options задает параметры изображения QR-кода.
+type Options struct {
+	Format     string     // png или svg
+	Size       int        // Ширина и высота изображения в пикселях
+	Margin     int        // Ширина свободной зоны в модулях
+	Level      string     // Уровень коррекции ошибок: L, M, Q или H
+	Foreground color.RGBA // Цвет модулей
+	Background color.RGBA // Цвет фона
+}
+
+// DefaultOptions возвращает параметры по умолчанию: PNG 256×256, свободная зона
+// в 4 модуля, уровень коррекции M, черные модули на белом фоне.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       DefaultSize,
+		Margin:     DefaultMargin,
+		Level:      "M",
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// ParseOptions разбирает параметры изображения из query-параметров запроса:
+// format, size, margin, level, fg и bg (цвета в формате RRGGBB или RGB, с # или без).
+// Незаданные параметры принимают значения по умолчанию.
+// Пример:
+//
+//	opts, err := qrcode.ParseOptions(r.URL.Query()) // ?format=svg&size=512&fg=0a0a0a
+func ParseOptions(q url.Values) (Options, error) {
+	opts := DefaultOptions()
+	var errs []error
+	if v := q.Get("format"); v != "" {
+		opts.Format = strings.ToLower(v)
+		if opts.Format != FormatPNG && opts.Format != FormatSVG {
+			errs = append(errs, fmt.Errorf("format: expected png or svg, got %q", v))
+		}
+	}
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < MinSize || n > MaxSize {
+			errs = append(errs, fmt.Errorf("size: expected %d..%d, got %q", MinSize, MaxSize, v))
+		}
+		opts.Size = n
+	}
+	if v := q.Get("margin"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > MaxMargin {
+			errs = append(errs, fmt.Errorf("margin: expected 0..%d, got %q", MaxMargin, v))
+		}
+		opts.Margin = n
+	}
+	if v := q.Get("level"); v != "" {
+		opts.Level = strings.ToUpper(v)
+		if _, ok := levels[opts.Level]; !ok {
+			errs = append(errs, fmt.Errorf("level: expected L, M, Q or H, got %q", v))
+		}
+	}
+	for _, c := range []struct {
+		key string
+		dst *color.RGBA
+	}{{"fg", &opts.Foreground}, {"bg", &opts.Background}} {
+		if v := q.Get(c.key); v != "" {
+			rgba, err := parseColor(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", c.key, err))
+			}
+			*c.dst = rgba
+		}
+	}
+	if len(errs) > 0 {
+		return opts, fmt.Errorf("%w: %w", ErrInvalidOptions, errors.Join(errs...))
+	}
+	return opts, nil
+}
+
+// ContentType возвращает MIME-тип изображения.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// ETag возвращает сильный ETag изображения для текста и параметров:
+// одинаковые входные данные всегда дают одинаковое изображение.
+func (o Options) ETag(text string) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s|%d|%d|%s|%s|%s",
+		text, o.Format, o.Size, o.Margin, o.Level, hexColor(o.Foreground), hexColor(o.Background)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Render кодирует текст в QR-код и возвращает изображение в формате o.Format.
+// Пример:
+//
+//	img, err := qrcode.Render("https://sho.rt/abc", qrcode.DefaultOptions())
+func Render(text string, o Options) ([]byte, error) {
+	level, ok := levels[o.Level]
+	if !ok {
+		return nil, ErrInvalidOptions
+	}
+	code, err := qr.Encode(text, level)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr: %w", err)
+	}
+	if o.Format == FormatSVG {
+		return renderSVG(code, o), nil
+	}
+	return renderPNG(code, o)
+}
+
+// renderPNG рисует QR-код размером o.Size×o.Size пикселей.
+// Модули масштабируются пропорционально, поэтому размер изображения соблюдается точно.
+func renderPNG(code *qr.Code, o Options) ([]byte, error) {
+	modules := code.Size + 2*o.Margin
+	img := image.NewPaletted(image.Rect(0, 0, o.Size, o.Size), color.Palette{o.Background, o.Foreground})
+	for y := 0; y < o.Size; y++ {
+		my := y*modules/o.Size - o.Margin
+		for x := 0; x < o.Size; x++ {
+			mx := x*modules/o.Size - o.Margin
+			if code.Black(mx, my) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG рисует QR-код как векторное изображение: один path из модулей,
+// объединенных в горизонтальные отрезки.
+func renderSVG(code *qr.Code, o Options) []byte {
+	modules := code.Size + 2*o.Margin
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		o.Size, o.Size, modules, modules)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#%s"/>`, hexColor(o.Background))
+	fmt.Fprintf(&b, `<path fill="#%s" d="`, hexColor(o.Foreground))
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; {
+			if !code.Black(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < code.Size && code.Black(x, y) {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", start+o.Margin, y+o.Margin, x-start, x-start)
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return []byte(b.String())
+}
+
+// parseColor разбирает цвет в формате RRGGBB или RGB (с # или без).
+func parseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("expected RRGGBB or RGB color, got %q", s)
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("expected RRGGBB or RGB color, got %q", s)
+	}
+	return color.RGBA{R: v[0], G: v[1], B: v[2], A: 0xff}, nil
+}
+
+// hexColor записывает цвет в формате RRGGBB.
+func hexColor(c color.RGBA) string {
+	return hex.EncodeToString([]byte{c.R, c.G, c.B})
+}
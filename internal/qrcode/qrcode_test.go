@@ -0,0 +1,86 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultOptions(), opts)
+
+	opts, err = ParseOptions(url.Values{
+		"format": {"SVG"}, "size": {"512"}, "margin": {"0"}, "level": {"h"}, "fg": {"#0a0b0c"}, "bg": {"fff"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Options{
+		Format:     FormatSVG,
+		Size:       512,
+		Margin:     0,
+		Level:      "H",
+		Foreground: color.RGBA{R: 0x0a, G: 0x0b, B: 0x0c, A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}, opts)
+
+	_, err = ParseOptions(url.Values{
+		"format": {"gif"}, "size": {"10"}, "margin": {"-1"}, "level": {"X"}, "fg": {"black"},
+	})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	for _, key := range []string{"format:", "size:", "margin:", "level:", "fg:"} {
+		assert.Contains(t, err.Error(), key, "all errors are reported at once")
+	}
+}
+
+func TestRender_PNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size, opts.Margin = 100, 0
+	opts.Foreground = color.RGBA{R: 0xff, A: 0xff}
+	data, err := Render("http://localhost:8080/abcdefgh", opts)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+	assert.Equal(t, 100, img.Bounds().Dy())
+	r, g, b, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0, 0}, [3]uint32{r, g, b}, "finder pattern starts at the corner without margin")
+
+	opts.Margin = DefaultMargin
+	data, err = Render("http://localhost:8080/abcdefgh", opts)
+	require.NoError(t, err)
+	img, err = png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	r, g, b, _ = img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, b}, "margin uses background colour")
+}
+
+func TestRender_SVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatSVG
+	opts.Foreground = color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}
+	data, err := Render("http://localhost:8080/abcdefgh", opts)
+	require.NoError(t, err)
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, `width="256" height="256" viewBox="0 0 37 37"`, "29 modules plus margin on both sides")
+	assert.Contains(t, svg, `fill="#123456"`)
+	assert.Contains(t, svg, `d="M4 4h7v1h-7z`, "top row of the finder pattern")
+	assert.Equal(t, "image/svg+xml", opts.ContentType())
+}
+
+func TestOptions_ETag(t *testing.T) {
+	opts := DefaultOptions()
+	etag := opts.ETag("http://localhost:8080/abc")
+	assert.Equal(t, etag, DefaultOptions().ETag("http://localhost:8080/abc"))
+	assert.NotEqual(t, etag, opts.ETag("http://localhost:8080/abd"))
+	opts.Size = 512
+	assert.NotEqual(t, etag, opts.ETag("http://localhost:8080/abc"))
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
 	"github.com/spitfy/urlshortener/internal/model"
 
 	"github.com/jackc/pgerrcode"
@@ -18,14 +21,15 @@ import (
 // Пример создания:
 //
 //	conf := config.LoadConfig()
-//	store, err := newDBStore(conf)
+//	store, err := newDBStore(conf, DedupeGlobal)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
 //	defer store.Close()
 type DBStore struct {
-	conf *config.Config
-	pool PGXPooler
+	conf  *config.Config
+	pool  PGXPooler
+	scope DedupeScope
 }
 
 type PGXPooler interface {
@@ -42,11 +46,11 @@ type PGXPooler interface {
 // newDBStore создает новое подключение к БД и применяет миграции.
 // Пример:
 //
-//	store, err := newDBStore(config)
+//	store, err := newDBStore(config, DedupeGlobal)
 //	if err != nil {
 //	    // обработка ошибки подключения
 //	}
-func newDBStore(conf *config.Config) (*DBStore, error) {
+func newDBStore(conf *config.Config, scope DedupeScope) (*DBStore, error) {
 	if err := migrate(conf); err != nil {
 		return nil, err
 	}
@@ -60,8 +64,9 @@ func newDBStore(conf *config.Config) (*DBStore, error) {
 		return nil, err
 	}
 	return &DBStore{
-		conf,
-		pool,
+		conf:  conf,
+		pool:  pool,
+		scope: scope,
 	}, nil
 }
 
@@ -94,8 +99,8 @@ func (s *DBStore) Ping() error {
 	return nil
 }
 
-// Add добавляет URL в базу данных. При попытке добавить существующий URL
-// возвращает ErrExistsURL с сохраненным хешем.
+// Add добавляет URL в домен url.Domain. При попытке добавить URL, уже сокращенный
+// в этом домене в пределах области дедупликации, возвращает ErrExistsURL с сохраненным хешем.
 // Пример:
 //
 //	hash, err := store.Add(ctx, URL{
@@ -107,19 +112,17 @@ func (s *DBStore) Ping() error {
 //	}
 func (s *DBStore) Add(ctx context.Context, url URL, userID int) (string, error) {
 	_, err := s.pool.Exec(ctx,
-		`INSERT INTO urls (hash, original_url, user_id) VALUES ($1, $2, $3)`,
-		url.Hash, url.Link, userID,
+		`INSERT INTO urls (hash, original_url, user_id, redirect, dedupe_key, domain, title, tags, note,
+			password_hash, max_clicks, rules)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		url.Hash, url.Link, userID, url.Redirect, s.dedupeKey(url, userID), url.Domain,
+		url.Title, tagsOrEmpty(url.Tags), url.Note, url.PasswordHash, url.MaxClicks, rulesOrEmpty(url.Rules),
 	)
 
 	var pgErr *pgconn.PgError
 	switch {
 	case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation:
-		var hash string
-		err = s.pool.QueryRow(
-			ctx,
-			"SELECT hash FROM urls WHERE original_url=$1",
-			url.Link,
-		).Scan(&hash)
+		hash, err := s.existingHash(ctx, url.Domain, url.Link, userID)
 		if err != nil {
 			return url.Hash, err
 		}
@@ -131,17 +134,24 @@ func (s *DBStore) Add(ctx context.Context, url URL, userID int) (string, error)
 	}
 }
 
-// GetByHash возвращает URL по его хешу.
+// GetByHash возвращает URL по его хешу в домене.
 // Пример:
 //
-//	url, err := store.GetByHash(ctx, "abc123")
+//	url, err := store.GetByHash(ctx, "", "abc123")
 //	if err != nil {
 //	    // обработка ошибки
 //	}
-func (s *DBStore) GetByHash(ctx context.Context, hash string) (URL, error) {
-	var u URL
-	row := s.pool.QueryRow(ctx, "SELECT hash, original_url, is_deleted FROM urls WHERE hash = $1", hash)
-	err := row.Scan(&u.Hash, &u.Link, &u.DeletedFlag)
+func (s *DBStore) GetByHash(ctx context.Context, domain, hash string) (URL, error) {
+	u := URL{Domain: domain}
+	row := s.pool.QueryRow(ctx,
+		`SELECT hash, original_url, is_deleted, redirect, COALESCE(user_id, 0), title, tags, note, created_at, clicks,
+		password_hash, max_clicks, rules, COALESCE(experiment_id, 0), COALESCE(health, '{}')
+		FROM urls WHERE domain = $1 AND hash = $2`, domain, hash)
+	err := row.Scan(&u.Hash, &u.Link, &u.DeletedFlag, &u.Redirect, &u.UserID, &u.Title, &u.Tags,
+		&u.Note, &u.CreatedAt, &u.Clicks, &u.PasswordHash, &u.MaxClicks, &u.Rules, &u.Experiment, &u.Health)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrNotFound
+	}
 	if err != nil {
 		return u, err
 	}
@@ -159,22 +169,30 @@ func (s *DBStore) GetByHash(ctx context.Context, hash string) (URL, error) {
 //	    fmt.Println(url.Hash, url.Link)
 //	}
 func (s *DBStore) GetByUserID(ctx context.Context, userID int) ([]URL, error) {
-	rows, err := s.pool.Query(ctx, "SELECT original_url, hash FROM urls where user_id = $1", userID)
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+userURLColumns+" FROM urls where user_id = $1", userID)
 	if err != nil {
 		return nil, fmt.Errorf("error select data: %w", err)
 	}
-	defer rows.Close()
+	return scanUserURLs(rows, userID)
+}
 
+// userURLColumns — столбцы ссылки пользователя в порядке, ожидаемом scanUserURLs.
+const userURLColumns = "original_url, hash, domain, title, tags, note, redirect, created_at, clicks, " +
+	"password_hash, max_clicks, rules, COALESCE(experiment_id, 0), COALESCE(health, '{}')"
+
+// scanUserURLs считывает ссылки пользователя и закрывает rows.
+func scanUserURLs(rows pgx.Rows, userID int) ([]URL, error) {
+	defer rows.Close()
 	var res []URL
 	for rows.Next() {
-		var link, hash string
-		if err := rows.Scan(&link, &hash); err != nil {
+		u := URL{UserID: userID}
+		err := rows.Scan(&u.Link, &u.Hash, &u.Domain, &u.Title, &u.Tags, &u.Note, &u.Redirect, &u.CreatedAt, &u.Clicks,
+			&u.PasswordHash, &u.MaxClicks, &u.Rules, &u.Experiment, &u.Health)
+		if err != nil {
 			return nil, err
 		}
-		res = append(res, URL{
-			Hash: hash,
-			Link: link,
-		})
+		res = append(res, u)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -182,57 +200,222 @@ func (s *DBStore) GetByUserID(ctx context.Context, userID int) ([]URL, error) {
 	return res, nil
 }
 
-// BatchAdd добавляет несколько URL в рамках транзакции.
+// ListByUser возвращает страницу неудаленных ссылок пользователя, подходящих под запрос.
+// Поиск выполняется по GIN-индексу столбца search, фильтр по тегам — по GIN-индексу tags,
+// а постраничная выдача по курсору использует индексы (user_id, created_at, domain, hash)
+// и (user_id, clicks, domain, hash).
+// Пример:
+//
+//	urls, err := store.ListByUser(ctx, 1, LinkQuery{Search: "spring", Sort: SortClicks, Limit: 50})
+func (s *DBStore) ListByUser(ctx context.Context, userID int, q LinkQuery) ([]URL, error) {
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{"user_id = $1", "NOT is_deleted"}
+	if words := searchWords(q.Search); len(words) > 0 {
+		where = append(where, "search @@ plainto_tsquery('simple', "+arg(strings.Join(words, " "))+")")
+	}
+	if len(q.Tags) > 0 {
+		where = append(where, "tags @> "+arg(q.Tags))
+	}
+	if q.Broken {
+		where = append(where, "broken")
+	}
+
+	column, dir, op := "created_at", "DESC", "<"
+	if q.Sort == SortClicks {
+		column = "clicks"
+	}
+	if q.Asc {
+		dir, op = "ASC", ">"
+	}
+	if c := q.After; c != nil {
+		var v any = c.CreatedAt
+		if q.Sort == SortClicks {
+			v = c.Clicks
+		}
+		where = append(where, fmt.Sprintf("(%s, domain, hash) %s (%s, %s, %s)",
+			column, op, arg(v), arg(c.Domain), arg(c.Hash)))
+	}
+
+	sql := fmt.Sprintf("SELECT %s FROM urls WHERE %s ORDER BY %s %s, domain %s, hash %s",
+		userURLColumns, strings.Join(where, " AND "), column, dir, dir, dir)
+	if q.Limit > 0 {
+		sql += " LIMIT " + arg(q.Limit)
+	}
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error select data: %w", err)
+	}
+	res, err := scanUserURLs(rows, userID)
+	if res == nil && err == nil {
+		res = make([]URL, 0)
+	}
+	return res, err
+}
+
+// AddClick увеличивает счетчик переходов по ссылке домена. Лимит переходов
+// проверяется в том же условном UPDATE, поэтому параллельные переходы
+// не могут его превысить.
+// Пример:
+//
+//	err := store.AddClick(ctx, "", "abc123")
+func (s *DBStore) AddClick(ctx context.Context, domain, hash string) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE urls SET clicks = clicks + 1 WHERE domain = $1 AND hash = $2 AND NOT is_deleted
+		AND (max_clicks = 0 OR clicks < max_clicks)`, domain, hash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	var exhausted bool
+	err = s.pool.QueryRow(ctx,
+		"SELECT max_clicks > 0 FROM urls WHERE domain = $1 AND hash = $2 AND NOT is_deleted",
+		domain, hash).Scan(&exhausted)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !exhausted) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrExhausted
+}
+
+// SetTitle задает заголовок ссылки домена, только если он еще не задан.
+// Пример:
+//
+//	err := store.SetTitle(ctx, "", "abc123", "Example Domain")
+func (s *DBStore) SetTitle(ctx context.Context, domain, hash, title string) error {
+	_, err := s.pool.Exec(ctx,
+		"UPDATE urls SET title = $3 WHERE domain = $1 AND hash = $2 AND title = ''", domain, hash, title)
+	return err
+}
+
+// LinksToCheck возвращает ссылки, адрес назначения которых не проверялся с момента before.
+// Выборка использует частичный индекс по checked_at.
+// Пример:
+//
+//	urls, err := store.LinksToCheck(ctx, time.Now().Add(-24*time.Hour), 100)
+func (s *DBStore) LinksToCheck(ctx context.Context, before time.Time, limit int) ([]URL, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT domain, hash, original_url, COALESCE(health, '{}') FROM urls
+		WHERE NOT is_deleted AND (checked_at IS NULL OR checked_at < $1)
+		ORDER BY checked_at NULLS FIRST, id LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error select links to check: %w", err)
+	}
+	defer rows.Close()
+	res := make([]URL, 0)
+	for rows.Next() {
+		var u URL
+		if err := rows.Scan(&u.Domain, &u.Hash, &u.Link, &u.Health); err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+// SetHealth сохраняет результат проверки адреса назначения ссылки домена.
+// Пример:
+//
+//	err := store.SetHealth(ctx, "", "abc123", model.LinkHealth{Status: 404, Broken: true, CheckedAt: time.Now()})
+func (s *DBStore) SetHealth(ctx context.Context, domain, hash string, h model.LinkHealth) error {
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE urls SET health = $3, checked_at = $4, broken = $5 WHERE domain = $1 AND hash = $2",
+		domain, hash, h, h.CheckedAt, h.Broken)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// tagsOrEmpty заменяет nil пустым списком тегов: столбец tags не допускает NULL.
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// rulesOrEmpty заменяет nil пустым списком правил, чтобы в JSONB-колонку rules
+// записывался пустой массив, а не null.
+func rulesOrEmpty(rules []model.RouteRule) []model.RouteRule {
+	if rules == nil {
+		return []model.RouteRule{}
+	}
+	return rules
+}
+
+// BatchAdd добавляет несколько URL в рамках транзакции одним пакетом запросов.
+// Конфликты по индексу уникальности original_url в домене не прерывают транзакцию:
+// для таких URL возвращается хеш существующей ссылки с Exists = true.
 // Пример:
 //
 //	urls := []URL{
 //	    {Hash: "abc", Link: "https://example.com"},
 //	    {Hash: "def", Link: "https://example.org"},
 //	}
-//	err := store.BatchAdd(ctx, urls, 1)
+//	res, err := store.BatchAdd(ctx, urls, 1)
 //	if err != nil {
 //	    // обработка ошибки
 //	}
-func (s *DBStore) BatchAdd(ctx context.Context, urls []URL, userID int) error {
+func (s *DBStore) BatchAdd(ctx context.Context, urls []URL, userID int) (res []AddResult, err error) {
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
-			err := tx.Rollback(ctx)
-			if err != nil {
-				return
-			}
+			_ = tx.Rollback(ctx)
 		} else {
-			err := tx.Commit(ctx)
-			if err != nil {
-				return
-			}
+			err = tx.Commit(ctx)
 		}
 	}()
+
 	batch := &pgx.Batch{}
 	for _, url := range urls {
-		batch.Queue("INSERT INTO urls (hash, original_url, user_id) VALUES ($1, $2, $3)",
-			url.Hash, url.Link, userID)
+		batch.Queue(`WITH ins AS (
+				INSERT INTO urls (hash, original_url, user_id, redirect, dedupe_key, domain, title, tags, note,
+					password_hash, max_clicks, rules)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				ON CONFLICT (domain, original_url, dedupe_key) DO NOTHING
+				RETURNING hash
+			)
+			SELECT hash, false FROM ins
+			UNION ALL
+			SELECT hash, true FROM urls
+			WHERE domain = $6 AND original_url = $2 AND dedupe_key = $5 AND NOT EXISTS (SELECT 1 FROM ins)
+			LIMIT 1`,
+			url.Hash, url.Link, userID, url.Redirect, s.dedupeKey(url, userID), url.Domain,
+			url.Title, tagsOrEmpty(url.Tags), url.Note, url.PasswordHash, url.MaxClicks, rulesOrEmpty(url.Rules))
 	}
 
 	br := tx.SendBatch(ctx, batch)
-	defer func(br pgx.BatchResults) {
-		_ = br.Close()
-	}(br)
-
+	res = make([]AddResult, 0, len(urls))
 	for range urls {
-		_, err := br.Exec()
-		if err != nil {
-			return err
+		var r AddResult
+		if err = br.QueryRow().Scan(&r.Hash, &r.Exists); err != nil {
+			_ = br.Close()
+			return nil, err
 		}
+		res = append(res, r)
+	}
+	if err = br.Close(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return res, nil
 }
 
-// BatchDelete помечает URL как удаленные для указанного пользователя.
+// BatchDelete помечает URL домена uh.Domain как удаленные для указанного пользователя.
 // Пример:
 //
 //	err := store.BatchDelete(ctx, UserHash{
@@ -252,8 +435,12 @@ func (s *DBStore) BatchDelete(ctx context.Context, uh UserHash) (err error) {
 		}
 	}()
 
-	_, err = tx.Exec(ctx, "UPDATE urls SET is_deleted = true WHERE hash = ANY($1) AND user_id = $2",
-		uh.Hash, uh.UserID)
+	// Удаленная ссылка исключается из индекса уникальности (dedupe_key = NULL),
+	// чтобы повторное сокращение того же URL создавало новую ссылку.
+	_, err = tx.Exec(ctx,
+		`UPDATE urls SET is_deleted = true, deleted_at = CURRENT_TIMESTAMP, dedupe_key = NULL
+		WHERE hash = ANY($1) AND user_id = $2 AND domain = $3 AND NOT is_deleted`,
+		uh.Hash, uh.UserID, uh.Domain)
 	if err != nil {
 		return err
 	}
@@ -281,11 +468,794 @@ func (s *DBStore) CreateUser(ctx context.Context) (int, error) {
 func (s *DBStore) Stats(ctx context.Context) (model.Stats, error) {
 	var stats model.Stats
 	err := s.pool.QueryRow(ctx,
-		`select (SELECT count(1) from urls) as url, (select count(1) from users) as user`,
-	).Scan(&stats.URLs, &stats.Users)
+		`SELECT
+			(SELECT count(1) FROM urls WHERE NOT is_deleted) AS active,
+			(SELECT count(1) FROM urls WHERE is_deleted AND purged_at IS NULL) AS deleted,
+			(SELECT count(1) FROM url_purges) AS purged,
+			(SELECT count(1) FROM users) AS users`,
+	).Scan(&stats.URLs, &stats.Deleted, &stats.Purged, &stats.Users)
 
 	if err != nil {
 		return stats, err
 	}
 	return stats, nil
 }
+
+// Update изменяет ссылку пользователя в рамках транзакции: предыдущее состояние
+// записывается в url_revisions, затем обновляется строка в urls.
+// Если новый адрес нарушает уникальный индекс по original_url, возвращает
+// ErrExistsURL с хешем существующей ссылки.
+// Пример:
+//
+//	hash, err := store.Update(ctx, URL{Hash: "abc123", Link: "https://example.org"}, 1)
+//	if errors.Is(err, ErrExistsURL) {
+//	    log.Println("URL already shortened as:", hash)
+//	}
+func (s *DBStore) Update(ctx context.Context, url URL, userID int) (string, error) {
+	err := s.update(ctx, url, userID)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		hash, err := s.existingHash(ctx, url.Domain, url.Link, userID)
+		if err != nil {
+			return url.Hash, err
+		}
+		return hash, ErrExistsURL
+	}
+	return url.Hash, err
+}
+
+// dedupeKey возвращает ключ дедупликации ссылки пользователя. Защищенные паролем
+// и ограниченные ссылки не участвуют в дедупликации (NULL), как и при DedupeNone.
+func (s *DBStore) dedupeKey(url URL, userID int) *int {
+	if !url.deduplicated() {
+		return nil
+	}
+	return s.scope.Key(userID)
+}
+
+// existingHash возвращает хеш ссылки домена, с которой конфликтует URL пользователя
+// в пределах области дедупликации.
+func (s *DBStore) existingHash(ctx context.Context, domain, link string, userID int) (string, error) {
+	var hash string
+	err := s.pool.QueryRow(ctx,
+		"SELECT hash FROM urls WHERE original_url=$1 AND dedupe_key=$2 AND domain=$3",
+		link, s.scope.Key(userID), domain,
+	).Scan(&hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Нарушено не ограничение дедупликации, а уникальность хеша.
+		return "", ErrHashTaken
+	}
+	return hash, err
+}
+
+func (s *DBStore) update(ctx context.Context, url URL, userID int) (err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	var (
+		id  int
+		cur URL
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT id, original_url, title, tags, note, redirect, rules FROM urls
+		WHERE domain = $1 AND hash = $2 AND user_id = $3 AND NOT is_deleted FOR UPDATE`,
+		url.Domain, url.Hash, userID,
+	).Scan(&id, &cur.Link, &cur.Title, &cur.Tags, &cur.Note, &cur.Redirect, &cur.Rules)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO url_revisions (url_id, revision, original_url, title, tags, redirect, edited_by, note, rules)
+		VALUES ($1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM url_revisions WHERE url_id = $1), $2, $3, $4, $5, $6, $7, $8)`,
+		id, cur.Link, cur.Title, cur.Tags, cur.Redirect, userID, cur.Note, rulesOrEmpty(cur.Rules),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE urls SET original_url = $1, title = $2, tags = $3, redirect = $4, note = $6,
+		password_hash = $7, dedupe_key = $8, rules = $9,
+		health = CASE WHEN original_url = $1 THEN health END,
+		checked_at = CASE WHEN original_url = $1 THEN checked_at END,
+		broken = broken AND original_url = $1
+		WHERE id = $5`,
+		url.Link, url.Title, tagsOrEmpty(url.Tags), url.Redirect, id, url.Note, url.PasswordHash, s.dedupeKey(url, userID),
+		rulesOrEmpty(url.Rules),
+	)
+	return err
+}
+
+// GetRevisions возвращает историю изменений ссылки пользователя в домене (новые первыми).
+// Пример:
+//
+//	revs, err := store.GetRevisions(ctx, "", "abc123", 1)
+func (s *DBStore) GetRevisions(ctx context.Context, domain, hash string, userID int) ([]Revision, error) {
+	var id int
+	err := s.pool.QueryRow(ctx,
+		"SELECT id FROM urls WHERE domain = $1 AND hash = $2 AND user_id = $3", domain, hash, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT revision, original_url, title, tags, note, redirect, rules, edited_at
+		FROM url_revisions WHERE url_id = $1 ORDER BY revision DESC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error select revisions: %w", err)
+	}
+	defer rows.Close()
+
+	res := make([]Revision, 0)
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.Number, &r.Link, &r.Title, &r.Tags, &r.Note, &r.Redirect, &r.Rules, &r.EditedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// EnqueueDeletion сохраняет задание на удаление в таблицу deletion_jobs.
+// Пример:
+//
+//	job, err := store.EnqueueDeletion(ctx, UserHash{UserID: 1, Hash: []string{"abc123"}})
+func (s *DBStore) EnqueueDeletion(ctx context.Context, uh UserHash) (DeletionJob, error) {
+	job := DeletionJob{UserID: uh.UserID, Domain: uh.Domain, Hashes: uh.Hash, Status: model.DeletionPending}
+	err := s.pool.QueryRow(ctx,
+		"INSERT INTO deletion_jobs (user_id, hashes, domain) VALUES ($1, $2, $3) RETURNING id, created_at",
+		uh.UserID, uh.Hash, uh.Domain).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return DeletionJob{}, fmt.Errorf("error insert deletion job: %w", err)
+	}
+	return job, nil
+}
+
+// ProcessDeletions выбирает до limit ожидающих заданий и выполняет их
+// одним обновлением таблицы urls в рамках транзакции.
+// Задания блокируются через FOR UPDATE SKIP LOCKED, поэтому несколько
+// экземпляров сервиса могут обрабатывать очередь одновременно.
+// Пример:
+//
+//	n, deleted, err := store.ProcessDeletions(ctx, 100)
+func (s *DBStore) ProcessDeletions(ctx context.Context, limit int) (n int, deleted []URL, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			n, deleted = 0, nil
+		}
+	}()
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, user_id, hashes, domain FROM deletion_jobs
+		WHERE status = 'pending' ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error select deletion jobs: %w", err)
+	}
+	var (
+		ids     []int64
+		hashes  []string
+		users   []int
+		domains []string
+	)
+	for rows.Next() {
+		var (
+			id     int64
+			userID int
+			h      []string
+			domain string
+		)
+		if err = rows.Scan(&id, &userID, &h, &domain); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+		for _, hash := range h {
+			hashes = append(hashes, hash)
+			users = append(users, userID)
+			domains = append(domains, domain)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	rows, err = tx.Query(ctx,
+		`UPDATE urls AS u SET is_deleted = true, deleted_at = CURRENT_TIMESTAMP, dedupe_key = NULL
+		FROM unnest($1::text[], $2::int[], $3::text[]) AS d(hash, user_id, domain)
+		WHERE u.hash = d.hash AND u.user_id = d.user_id AND u.domain = d.domain AND NOT u.is_deleted
+		RETURNING u.hash, u.domain, u.original_url, u.user_id, u.deleted_at`,
+		hashes, users, domains)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error delete urls: %w", err)
+	}
+	for rows.Next() {
+		u := URL{DeletedFlag: true}
+		if err = rows.Scan(&u.Hash, &u.Domain, &u.Link, &u.UserID, &u.DeletedAt); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		deleted = append(deleted, u)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error delete urls: %w", err)
+	}
+	_, err = tx.Exec(ctx,
+		"UPDATE deletion_jobs SET status = 'done', completed_at = CURRENT_TIMESTAMP WHERE id = ANY($1)", ids)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error complete deletion jobs: %w", err)
+	}
+	return len(ids), deleted, nil
+}
+
+// GetDeletion возвращает задание на удаление пользователя.
+// Пример:
+//
+//	job, err := store.GetDeletion(ctx, 1, 1)
+func (s *DBStore) GetDeletion(ctx context.Context, id int64, userID int) (DeletionJob, error) {
+	job := DeletionJob{ID: id, UserID: userID}
+	err := s.pool.QueryRow(ctx,
+		"SELECT domain, hashes, status, created_at, completed_at FROM deletion_jobs WHERE id = $1 AND user_id = $2",
+		id, userID).Scan(&job.Domain, &job.Hashes, &job.Status, &job.CreatedAt, &job.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DeletionJob{}, ErrNotFound
+	}
+	if err != nil {
+		return DeletionJob{}, err
+	}
+	return job, nil
+}
+
+// Restore восстанавливает удаленные ссылки пользователя в рамках транзакции.
+// Ссылка восстанавливается, только если ее оригинальный URL не был сокращен заново
+// в пределах области дедупликации.
+// Пример:
+//
+//	res, err := store.Restore(ctx, UserHash{UserID: 1, Hash: []string{"abc123"}}, since)
+func (s *DBStore) Restore(ctx context.Context, uh UserHash, since time.Time) (res []model.RestoreResult, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	key := s.scope.Key(uh.UserID)
+	res = make([]model.RestoreResult, 0, len(uh.Hash))
+	for _, hash := range uh.Hash {
+		r := model.RestoreResult{Hash: hash, Status: model.RestoreRestored}
+		tag, err := tx.Exec(ctx,
+			`UPDATE urls AS u SET is_deleted = false, deleted_at = NULL,
+				dedupe_key = CASE WHEN u.password_hash = '' AND u.max_clicks = 0 AND u.experiment_id IS NULL THEN $3::int END
+			WHERE u.hash = $1 AND u.user_id = $2 AND u.domain = $5
+			AND u.is_deleted AND u.purged_at IS NULL AND u.deleted_at >= $4
+			AND (u.password_hash <> '' OR u.max_clicks > 0 OR u.experiment_id IS NOT NULL OR NOT EXISTS (SELECT 1 FROM urls o
+				WHERE o.domain = u.domain AND o.original_url = u.original_url AND o.dedupe_key = $3))`,
+			hash, uh.UserID, key, since, uh.Domain)
+		if err != nil {
+			return nil, fmt.Errorf("error restore url: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			var restorable bool
+			err = tx.QueryRow(ctx,
+				`SELECT EXISTS (SELECT 1 FROM urls WHERE hash = $1 AND user_id = $2 AND domain = $4
+				AND is_deleted AND purged_at IS NULL AND deleted_at >= $3)`,
+				hash, uh.UserID, since, uh.Domain).Scan(&restorable)
+			if err != nil {
+				return nil, err
+			}
+			r.Status = model.RestoreNotFound
+			if restorable {
+				r.Status = model.RestoreConflict
+			}
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// Purge окончательно удаляет ссылки, удаленные раньше before, и записывает их в url_purges.
+// Эксперименты ссылок удаляются вместе с вариантами. Если freeHash = false, строка остается в urls без данных ссылки (purged_at задан),
+// чтобы хеш не был выдан повторно; иначе строка удаляется.
+// Пример:
+//
+//	n, err := store.Purge(ctx, time.Now().Add(-30*24*time.Hour), false)
+func (s *DBStore) Purge(ctx context.Context, before time.Time, freeHash bool) (n int, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, hash, COALESCE(user_id, 0), domain FROM urls
+		WHERE is_deleted AND purged_at IS NULL AND deleted_at < $1
+		FOR UPDATE SKIP LOCKED`, before)
+	if err != nil {
+		return 0, fmt.Errorf("error select expired urls: %w", err)
+	}
+	var (
+		ids     []int
+		hashes  []string
+		users   []int
+		domains []string
+	)
+	for rows.Next() {
+		var (
+			id, userID   int
+			hash, domain string
+		)
+		if err = rows.Scan(&id, &hash, &userID, &domain); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		hashes = append(hashes, hash)
+		users = append(users, userID)
+		domains = append(domains, domain)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO url_purges (hash, user_id, domain) SELECT * FROM unnest($1::text[], $2::int[], $3::text[])",
+		hashes, users, domains)
+	if err != nil {
+		return 0, fmt.Errorf("error insert url purges: %w", err)
+	}
+	_, err = tx.Exec(ctx,
+		"DELETE FROM experiments WHERE id IN (SELECT experiment_id FROM urls WHERE id = ANY($1))", ids)
+	if err != nil {
+		return 0, fmt.Errorf("error purge experiments: %w", err)
+	}
+	if freeHash {
+		_, err = tx.Exec(ctx, "DELETE FROM urls WHERE id = ANY($1)", ids)
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM url_revisions WHERE url_id = ANY($1)", ids)
+		if err == nil {
+			_, err = tx.Exec(ctx,
+				`UPDATE urls SET purged_at = CURRENT_TIMESTAMP, original_url = '', title = '', tags = '{}', note = '', password_hash = '', redirect = '{}', rules = '[]',
+				health = NULL, checked_at = NULL, broken = false
+				WHERE id = ANY($1)`, ids)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error purge urls: %w", err)
+	}
+	return len(ids), nil
+}
+
+// AddExperiment создает эксперимент, его варианты и короткую ссылку в рамках транзакции.
+// Пример:
+//
+//	e, err := store.AddExperiment(ctx, Experiment{Hash: "abc123", Variants: variants}, 1)
+func (s *DBStore) AddExperiment(ctx context.Context, e Experiment, userID int) (_ Experiment, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Experiment{}, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	e.UserID = userID
+	err = tx.QueryRow(ctx,
+		"INSERT INTO experiments (user_id, domain, hash, name) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		userID, e.Domain, e.Hash, e.Name).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return Experiment{}, fmt.Errorf("error insert experiment: %w", err)
+	}
+	names := make([]string, 0, len(e.Variants))
+	urls := make([]string, 0, len(e.Variants))
+	weights := make([]int, 0, len(e.Variants))
+	for _, v := range e.Variants {
+		names = append(names, v.Name)
+		urls = append(urls, v.URL)
+		weights = append(weights, v.Weight)
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO experiment_variants (experiment_id, position, name, url, weight)
+		SELECT $1, v.ord - 1, v.name, v.url, v.weight
+		FROM unnest($2::text[], $3::text[], $4::int[]) WITH ORDINALITY AS v(name, url, weight, ord)`,
+		e.ID, names, urls, weights)
+	if err != nil {
+		return Experiment{}, fmt.Errorf("error insert experiment variants: %w", err)
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO urls (hash, original_url, user_id, dedupe_key, domain, title, tags, note, experiment_id, created_at)
+		VALUES ($1, $2, $3, NULL, $4, $5, '{}', '', $6, $7)`,
+		e.Hash, e.Variants[0].URL, userID, e.Domain, e.Name, e.ID, e.CreatedAt)
+	if err != nil {
+		return Experiment{}, err
+	}
+	return e, nil
+}
+
+// GetExperiment возвращает эксперимент с вариантами.
+// Пример:
+//
+//	e, err := store.GetExperiment(ctx, 7)
+func (s *DBStore) GetExperiment(ctx context.Context, id int64) (Experiment, error) {
+	list, err := s.queryExperiments(ctx, "e.id = $1", id)
+	if err != nil {
+		return Experiment{}, err
+	}
+	if len(list) == 0 {
+		return Experiment{}, ErrNotFound
+	}
+	return list[0], nil
+}
+
+// ListExperiments возвращает эксперименты пользователя (новые первыми).
+// Пример:
+//
+//	list, err := store.ListExperiments(ctx, 1)
+func (s *DBStore) ListExperiments(ctx context.Context, userID int) ([]Experiment, error) {
+	return s.queryExperiments(ctx, "e.user_id = $1", userID)
+}
+
+// queryExperiments выбирает эксперименты по условию where одним запросом
+// с вариантами, собранными в массивы в порядке создания.
+func (s *DBStore) queryExperiments(ctx context.Context, where string, arg any) ([]Experiment, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT e.id, e.user_id, e.domain, e.hash, e.name, e.created_at,
+			array_agg(v.name ORDER BY v.position), array_agg(v.url ORDER BY v.position),
+			array_agg(v.weight ORDER BY v.position), array_agg(v.redirects ORDER BY v.position)
+		FROM experiments e JOIN experiment_variants v ON v.experiment_id = e.id
+		WHERE `+where+`
+		GROUP BY e.id ORDER BY e.id DESC`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("error select experiments: %w", err)
+	}
+	defer rows.Close()
+	res := make([]Experiment, 0)
+	for rows.Next() {
+		var (
+			e         Experiment
+			names     []string
+			urls      []string
+			weights   []int
+			redirects []int64
+		)
+		err := rows.Scan(&e.ID, &e.UserID, &e.Domain, &e.Hash, &e.Name, &e.CreatedAt,
+			&names, &urls, &weights, &redirects)
+		if err != nil {
+			return nil, err
+		}
+		for i := range names {
+			e.Variants = append(e.Variants, Variant{Name: names[i], URL: urls[i], Weight: weights[i], Redirects: redirects[i]})
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// AddVariantRedirect увеличивает счетчик перенаправлений на вариант эксперимента.
+// Пример:
+//
+//	err := store.AddVariantRedirect(ctx, 7, 1)
+func (s *DBStore) AddVariantRedirect(ctx context.Context, id int64, variant int) error {
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE experiment_variants SET redirects = redirects + 1 WHERE experiment_id = $1 AND position = $2",
+		id, variant)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddWebhook сохраняет подписку в таблицу webhooks.
+// Пример:
+//
+//	w, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://hooks.example.com/", Active: true})
+func (s *DBStore) AddWebhook(ctx context.Context, w Webhook) (Webhook, error) {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO webhooks (user_id, url, secret, events, active) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		w.UserID, w.URL, w.Secret, tagsOrEmpty(w.Events), w.Active).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("error insert webhook: %w", err)
+	}
+	return w, nil
+}
+
+// webhookColumns — столбцы таблицы webhooks в порядке полей, читаемых scanWebhook.
+const webhookColumns = "id, user_id, url, secret, events, active, created_at"
+
+// scanWebhook читает подписку из строки результата запроса.
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var w Webhook
+	err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &w.Events, &w.Active, &w.CreatedAt)
+	return w, err
+}
+
+// GetWebhook возвращает подписку по идентификатору.
+// Пример:
+//
+//	w, err := store.GetWebhook(ctx, 3)
+func (s *DBStore) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	w, err := scanWebhook(s.pool.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	if err != nil {
+		return Webhook{}, err
+	}
+	return w, nil
+}
+
+// ListWebhooks возвращает подписки пользователя в порядке создания.
+// Пример:
+//
+//	list, err := store.ListWebhooks(ctx, 1)
+func (s *DBStore) ListWebhooks(ctx context.Context, userID int) ([]Webhook, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error select webhooks: %w", err)
+	}
+	defer rows.Close()
+	res := make([]Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, w)
+	}
+	return res, rows.Err()
+}
+
+// UpdateWebhook заменяет параметры подписки.
+// Пример:
+//
+//	err := store.UpdateWebhook(ctx, w)
+func (s *DBStore) UpdateWebhook(ctx context.Context, w Webhook) error {
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE webhooks SET url = $2, secret = $3, events = $4, active = $5 WHERE id = $1",
+		w.ID, w.URL, w.Secret, tagsOrEmpty(w.Events), w.Active)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWebhook удаляет подписку; доставки удаляются каскадно.
+// Пример:
+//
+//	err := store.DeleteWebhook(ctx, 3)
+func (s *DBStore) DeleteWebhook(ctx context.Context, id int64) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddDelivery сохраняет доставку события в таблицу webhook_deliveries.
+// Пример:
+//
+//	d, err := store.AddDelivery(ctx, Delivery{WebhookID: 3, Payload: event, Status: model.DeliveryPending})
+func (s *DBStore) AddDelivery(ctx context.Context, d Delivery) (Delivery, error) {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, payload, status, next_attempt_at) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		d.WebhookID, d.Payload, d.Status, d.NextAttemptAt).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("error insert webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+// deliveryColumns — столбцы таблицы webhook_deliveries в порядке полей, читаемых queryDeliveries.
+const deliveryColumns = `id, webhook_id, payload, status, attempts, response_status, error,
+	created_at, COALESCE(last_attempt_at, '0001-01-01'), next_attempt_at`
+
+// queryDeliveries выполняет запрос доставок и читает результат.
+func (s *DBStore) queryDeliveries(ctx context.Context, sql string, args ...any) ([]Delivery, error) {
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error select webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	res := make([]Delivery, 0)
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.Error,
+			&d.CreatedAt, &d.LastAttemptAt, &d.NextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// PendingDeliveries возвращает ожидающие доставки, время попытки которых наступило.
+// Пример:
+//
+//	list, err := store.PendingDeliveries(ctx, time.Now(), 100)
+func (s *DBStore) PendingDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	return s.queryDeliveries(ctx,
+		"SELECT "+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at, id LIMIT $2`, now, limit)
+}
+
+// UpdateDelivery сохраняет результат попытки доставки.
+// Пример:
+//
+//	err := store.UpdateDelivery(ctx, d)
+func (s *DBStore) UpdateDelivery(ctx context.Context, d Delivery) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = $3, response_status = $4, error = $5,
+		last_attempt_at = $6, next_attempt_at = $7 WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.ResponseStatus, d.Error, d.LastAttemptAt, d.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDeliveries возвращает последние доставки подписки (новые первыми).
+// Пример:
+//
+//	list, err := store.ListDeliveries(ctx, 3, 100)
+func (s *DBStore) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]Delivery, error) {
+	return s.queryDeliveries(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2",
+		webhookID, limit)
+}
+
+// PurgeDeliveries удаляет завершенные доставки, созданные раньше before.
+// Пример:
+//
+//	n, err := store.PurgeDeliveries(ctx, time.Now().Add(-7*24*time.Hour))
+func (s *DBStore) PurgeDeliveries(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.pool.Exec(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error purge webhook deliveries: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// AddIdempotencyKey сохраняет ключ идемпотентности в таблицу idempotency_keys.
+// Истекший ключ заменяется одним запросом; действующий возвращается вместе с ErrExistsKey.
+// Пример:
+//
+//	existing, err := store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 1, Key: "key", Fingerprint: fp, ExpiresAt: exp})
+func (s *DBStore) AddIdempotencyKey(ctx context.Context, k IdempotencyKey) (IdempotencyKey, error) {
+	// Действующий ключ может истечь или быть удален между вставкой и чтением,
+	// тогда вставка повторяется.
+	for range 3 {
+		now := time.Now()
+		err := s.pool.QueryRow(ctx,
+			`INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = 0,
+				header = NULL, body = NULL, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= $4
+			RETURNING created_at`,
+			k.UserID, k.Key, k.Fingerprint, now, k.ExpiresAt).Scan(&k.CreatedAt)
+		if err == nil {
+			k.Status, k.Header, k.Body = 0, nil, nil
+			return k, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return IdempotencyKey{}, fmt.Errorf("error insert idempotency key: %w", err)
+		}
+		existing := IdempotencyKey{UserID: k.UserID, Key: k.Key}
+		err = s.pool.QueryRow(ctx,
+			`SELECT fingerprint, status, header, COALESCE(body, ''), created_at, expires_at FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2 AND expires_at > $3`,
+			k.UserID, k.Key, now).Scan(&existing.Fingerprint, &existing.Status, &existing.Header, &existing.Body,
+			&existing.CreatedAt, &existing.ExpiresAt)
+		if err == nil {
+			return existing, ErrExistsKey
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return IdempotencyKey{}, fmt.Errorf("error select idempotency key: %w", err)
+		}
+	}
+	return IdempotencyKey{}, fmt.Errorf("error insert idempotency key %q: concurrent updates", k.Key)
+}
+
+// SetIdempotencyResponse сохраняет ответ для ключа идемпотентности.
+// Пример:
+//
+//	err := store.SetIdempotencyResponse(ctx, IdempotencyKey{UserID: 1, Key: "key", Status: 201, Body: body})
+func (s *DBStore) SetIdempotencyResponse(ctx context.Context, k IdempotencyKey) error {
+	tag, err := s.pool.Exec(ctx,
+		"UPDATE idempotency_keys SET status = $3, header = $4, body = $5 WHERE user_id = $1 AND key = $2",
+		k.UserID, k.Key, k.Status, k.Header, k.Body)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey удаляет ключ идемпотентности пользователя.
+// Пример:
+//
+//	err := store.DeleteIdempotencyKey(ctx, 1, "key")
+func (s *DBStore) DeleteIdempotencyKey(ctx context.Context, userID int, key string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2", userID, key)
+	return err
+}
+
+// PurgeIdempotencyKeys удаляет ключи идемпотентности, истекшие раньше before.
+// Пример:
+//
+//	n, err := store.PurgeIdempotencyKeys(ctx, time.Now())
+func (s *DBStore) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("error purge idempotency keys: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
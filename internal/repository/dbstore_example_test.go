@@ -64,14 +64,14 @@ func ExampleStorer_GetByHash() {
 	mockStorer := NewMockStorer(ctrl)
 
 	mockStorer.EXPECT().
-		GetByHash(gomock.Any(), "test456").
+		GetByHash(gomock.Any(), "", "test456").
 		Return(URL{
 			Hash: "test456",
 			Link: "https://test.com",
 		}, nil)
 
 	ctx := context.Background()
-	url, err := mockStorer.GetByHash(ctx, "test456")
+	url, err := mockStorer.GetByHash(ctx, "", "test456")
 
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -113,17 +113,21 @@ func ExampleStorer_BatchAdd() {
 
 	mockStorer.EXPECT().
 		BatchAdd(gomock.Any(), urls, 1).
-		Return(nil)
+		Return([]AddResult{{Hash: "abc"}, {Hash: "xyz", Exists: true}}, nil)
 
 	ctx := context.Background()
-	err := mockStorer.BatchAdd(ctx, urls, 1)
+	res, err := mockStorer.BatchAdd(ctx, urls, 1)
 
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Println("Batch URLs added successfully")
+		for _, r := range res {
+			fmt.Printf("%s exists=%v\n", r.Hash, r.Exists)
+		}
 	}
-	// Output: Batch URLs added successfully
+	// Output:
+	// abc exists=false
+	// xyz exists=true
 }
 
 func ExampleStorer_GetByUserID() {
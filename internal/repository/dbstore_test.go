@@ -3,12 +3,17 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/spitfy/urlshortener/internal/config"
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/service/fault"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockDB реализует интерфейс DB
@@ -147,11 +152,12 @@ func (m *MockRows) Scan(dest ...any) error {
 
 // MockTx для pgx.Tx
 type MockTx struct {
-	ExecFunc     func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
-	QueryFunc    func(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
-	QueryRowFunc func(ctx context.Context, sql string, args ...any) pgx.Row
-	CommitFunc   func(ctx context.Context) error
-	RollbackFunc func(ctx context.Context) error
+	ExecFunc      func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryFunc     func(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRowFunc  func(ctx context.Context, sql string, args ...any) pgx.Row
+	CommitFunc    func(ctx context.Context) error
+	RollbackFunc  func(ctx context.Context) error
+	SendBatchFunc func(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 }
 
 func (m *MockTx) Begin(ctx context.Context) (pgx.Tx, error) {
@@ -165,8 +171,10 @@ func (m *MockTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnN
 }
 
 func (m *MockTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
-	//TODO implement me
-	panic("implement me")
+	if m.SendBatchFunc != nil {
+		return m.SendBatchFunc(ctx, b)
+	}
+	return &MockBatchResults{}
 }
 
 func (m *MockTx) LargeObjects() pgx.LargeObjects {
@@ -346,4 +354,364 @@ func TestDBStore_Add(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "database connection failed")
 	})
+
+	t.Run("hash collision", func(t *testing.T) {
+		mockDB := &MockDB{
+			ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+				return pgconn.CommandTag{}, &pgconn.PgError{Code: "23505"}
+			},
+			QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+				return &MockRow{ScanFunc: func(dest ...any) error { return pgx.ErrNoRows }}
+			},
+		}
+		store := &DBStore{conf: &config.Config{}, pool: mockDB}
+
+		_, err := store.Add(ctx, URL{Hash: "taken", Link: "https://test.com"}, 1)
+		assert.ErrorIs(t, err, ErrHashTaken)
+		assert.NotErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func TestDBStore_GetByHashNotFound(t *testing.T) {
+	mockDB := &MockDB{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &MockRow{ScanFunc: func(dest ...any) error { return pgx.ErrNoRows }}
+		},
+	}
+	store := &DBStore{conf: &config.Config{}, pool: mockDB}
+
+	_, err := store.GetByHash(context.Background(), "", "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, fault.NotFound, fault.KindOf(err))
+}
+
+func TestDBStore_AddDedupeKey(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		scope    DedupeScope
+		password string
+		want     *int
+	}{
+		{"global", DedupeGlobal, "", new(int)},
+		{"user", DedupeUser, "", func() *int { v := 7; return &v }()},
+		{"none", DedupeNone, "", nil},
+		{"protected", DedupeGlobal, "$2a$10$hash", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDB{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+					assert.Contains(t, sql, "dedupe_key")
+					assert.Equal(t, tt.want, arguments[4])
+					return pgconn.NewCommandTag("INSERT 1"), nil
+				},
+			}
+			store := &DBStore{conf: &config.Config{}, pool: mockDB, scope: tt.scope}
+			_, err := store.Add(ctx, URL{Hash: "abc123", Link: "https://example.com", PasswordHash: tt.password}, 7)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDBStore_BatchAdd(t *testing.T) {
+	ctx := context.Background()
+	urls := []URL{
+		{Hash: "abc123", Link: "https://example.com"},
+		{Hash: "def456", Link: "https://example.org"},
+	}
+
+	t.Run("single round-trip with existing urls", func(t *testing.T) {
+		committed := false
+		results := []AddResult{{Hash: "abc123"}, {Hash: "old789", Exists: true}}
+		tx := &MockTx{
+			SendBatchFunc: func(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+				require.Equal(t, len(urls), b.Len())
+				assert.Contains(t, b.QueuedQueries[0].SQL, "ON CONFLICT (domain, original_url, dedupe_key) DO NOTHING")
+				i := 0
+				return &MockBatchResults{
+					QueryRowFunc: func() pgx.Row {
+						r := results[i]
+						i++
+						return &MockRow{ScanFunc: func(dest ...any) error {
+							*dest[0].(*string) = r.Hash
+							*dest[1].(*bool) = r.Exists
+							return nil
+						}}
+					},
+				}
+			},
+			CommitFunc: func(ctx context.Context) error {
+				committed = true
+				return nil
+			},
+		}
+		mockDB := &MockDB{BeginFunc: func(ctx context.Context) (pgx.Tx, error) { return tx, nil }}
+		store := &DBStore{conf: &config.Config{}, pool: mockDB, scope: DedupeGlobal}
+
+		res, err := store.BatchAdd(ctx, urls, 1)
+		require.NoError(t, err)
+		assert.Equal(t, results, res)
+		assert.True(t, committed)
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		rolledBack := false
+		tx := &MockTx{
+			SendBatchFunc: func(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+				return &MockBatchResults{
+					QueryRowFunc: func() pgx.Row {
+						return &MockRow{ScanFunc: func(dest ...any) error { return errors.New("db error") }}
+					},
+				}
+			},
+			RollbackFunc: func(ctx context.Context) error {
+				rolledBack = true
+				return nil
+			},
+		}
+		mockDB := &MockDB{BeginFunc: func(ctx context.Context) (pgx.Tx, error) { return tx, nil }}
+		store := &DBStore{conf: &config.Config{}, pool: mockDB, scope: DedupeGlobal}
+
+		_, err := store.BatchAdd(ctx, urls, 1)
+		assert.Error(t, err)
+		assert.True(t, rolledBack)
+	})
+}
+
+func TestDBStore_ProcessDeletions(t *testing.T) {
+	ctx := context.Background()
+	jobs := []struct {
+		id     int64
+		userID int
+		hashes []string
+		domain string
+	}{
+		{1, 7, []string{"abc", "def"}, ""},
+		{2, 8, []string{"ghi"}, "go.example"},
+	}
+	var execs []string
+	tx := &MockTx{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			if strings.Contains(sql, "UPDATE urls") {
+				assert.Contains(t, sql, "RETURNING")
+				assert.Equal(t, []string{"abc", "def", "ghi"}, args[0])
+				assert.Equal(t, []int{7, 7, 8}, args[1])
+				assert.Equal(t, []string{"", "", "go.example"}, args[2])
+				// Удалена только одна ссылка: остальные чужие или уже удалены.
+				i := -1
+				return &MockRows{
+					NextFunc: func() bool { i++; return i < 1 },
+					ScanFunc: func(dest ...any) error {
+						*dest[0].(*string) = "abc"
+						*dest[1].(*string) = ""
+						*dest[2].(*string) = "https://example.com"
+						*dest[3].(*int) = 7
+						*dest[4].(*time.Time) = time.Now()
+						return nil
+					},
+				}, nil
+			}
+			assert.Contains(t, sql, "FOR UPDATE SKIP LOCKED")
+			i := -1
+			return &MockRows{
+				NextFunc: func() bool { i++; return i < len(jobs) },
+				ScanFunc: func(dest ...any) error {
+					*dest[0].(*int64) = jobs[i].id
+					*dest[1].(*int) = jobs[i].userID
+					*dest[2].(*[]string) = jobs[i].hashes
+					*dest[3].(*string) = jobs[i].domain
+					return nil
+				},
+			}, nil
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			execs = append(execs, sql)
+			assert.Contains(t, sql, "UPDATE deletion_jobs")
+			assert.Equal(t, []int64{1, 2}, arguments[0])
+			return pgconn.NewCommandTag("UPDATE 2"), nil
+		},
+	}
+	mockDB := &MockDB{BeginFunc: func(ctx context.Context) (pgx.Tx, error) { return tx, nil }}
+	store := &DBStore{conf: &config.Config{}, pool: mockDB, scope: DedupeGlobal}
+
+	n, deleted, err := store.ProcessDeletions(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "abc", deleted[0].Hash)
+	assert.Equal(t, 7, deleted[0].UserID)
+	assert.Equal(t, "https://example.com", deleted[0].Link)
+	assert.Len(t, execs, 1, "jobs are completed with a single update")
+}
+
+func TestDBStore_Purge(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		freeHash bool
+		want     []string
+	}{
+		{"keep hash", false, []string{"INSERT INTO url_purges", "DELETE FROM experiments", "DELETE FROM url_revisions", "UPDATE urls SET purged_at"}},
+		{"free hash", true, []string{"INSERT INTO url_purges", "DELETE FROM experiments", "DELETE FROM urls"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var execs []string
+			tx := &MockTx{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+					i := -1
+					return &MockRows{
+						NextFunc: func() bool { i++; return i < 1 },
+						ScanFunc: func(dest ...any) error {
+							*dest[0].(*int) = 10
+							*dest[1].(*string) = "abc"
+							*dest[2].(*int) = 7
+							return nil
+						},
+					}, nil
+				},
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+					execs = append(execs, sql)
+					return pgconn.NewCommandTag("UPDATE 1"), nil
+				},
+			}
+			mockDB := &MockDB{BeginFunc: func(ctx context.Context) (pgx.Tx, error) { return tx, nil }}
+			store := &DBStore{conf: &config.Config{}, pool: mockDB, scope: DedupeGlobal}
+
+			n, err := store.Purge(ctx, time.Now(), tt.freeHash)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+			require.Len(t, execs, len(tt.want))
+			for i, prefix := range tt.want {
+				assert.Contains(t, execs[i], prefix)
+			}
+		})
+	}
+}
+
+func TestDBStore_ListByUser(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		q        LinkQuery
+		contains []string
+		args     []any
+	}{
+		{
+			name:     "defaults",
+			q:        LinkQuery{},
+			contains: []string{"WHERE user_id = $1 AND NOT is_deleted ORDER BY created_at DESC, domain DESC, hash DESC"},
+			args:     []any{1},
+		},
+		{
+			name: "search, tags and limit",
+			q:    LinkQuery{Search: "Spring-Sale!", Tags: []string{"promo"}, Limit: 10},
+			contains: []string{
+				"search @@ plainto_tsquery('simple', $2)",
+				"tags @> $3",
+				"LIMIT $4",
+			},
+			args: []any{1, "spring sale", []string{"promo"}, 10},
+		},
+		{
+			name: "clicks ascending after cursor",
+			q: LinkQuery{Sort: SortClicks, Asc: true,
+				After: &LinkCursor{CreatedAt: created, Clicks: 5, Domain: "go.example", Hash: "abc"}},
+			contains: []string{
+				"(clicks, domain, hash) > ($2, $3, $4)",
+				"ORDER BY clicks ASC, domain ASC, hash ASC",
+			},
+			args: []any{1, int64(5), "go.example", "abc"},
+		},
+		{
+			name:     "broken",
+			q:        LinkQuery{Broken: true},
+			contains: []string{"WHERE user_id = $1 AND NOT is_deleted AND broken ORDER BY"},
+			args:     []any{1},
+		},
+		{
+			name:     "created after cursor",
+			q:        LinkQuery{After: &LinkCursor{CreatedAt: created, Hash: "abc"}},
+			contains: []string{"(created_at, domain, hash) < ($2, $3, $4)"},
+			args:     []any{1, created, "", "abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDB{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+					for _, c := range tt.contains {
+						assert.Contains(t, sql, c)
+					}
+					assert.Equal(t, tt.args, args)
+					return &MockRows{}, nil
+				},
+			}
+			store := &DBStore{conf: &config.Config{}, pool: mockDB}
+			urls, err := store.ListByUser(ctx, 1, tt.q)
+			require.NoError(t, err)
+			assert.NotNil(t, urls)
+			assert.Empty(t, urls)
+		})
+	}
+}
+
+func TestDBStore_AddClick(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name    string
+		tag     string
+		limited error // Результат проверки лимита после неудачного UPDATE
+		want    error
+	}{
+		{"counted", "UPDATE 1", nil, nil},
+		{"not found", "UPDATE 0", pgx.ErrNoRows, ErrNotFound},
+		{"exhausted", "UPDATE 0", nil, ErrExhausted},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDB{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+					assert.Contains(t, sql, "clicks = clicks + 1")
+					assert.Contains(t, sql, "clicks < max_clicks")
+					assert.Equal(t, []any{"go.example", "abc"}, arguments)
+					return pgconn.NewCommandTag(tt.tag), nil
+				},
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+					return &MockRow{ScanFunc: func(dest ...any) error {
+						*dest[0].(*bool) = true
+						return tt.limited
+					}}
+				},
+			}
+			store := &DBStore{conf: &config.Config{}, pool: mockDB}
+			assert.ErrorIs(t, store.AddClick(ctx, "go.example", "abc"), tt.want)
+		})
+	}
+}
+
+func TestDBStore_SetHealth(t *testing.T) {
+	ctx := context.Background()
+	checked := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := model.LinkHealth{Status: 404, Broken: true, CheckedAt: checked}
+	for _, tt := range []struct {
+		tag  string
+		want error
+	}{
+		{"UPDATE 1", nil},
+		{"UPDATE 0", ErrNotFound},
+	} {
+		t.Run(tt.tag, func(t *testing.T) {
+			mockDB := &MockDB{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+					assert.Contains(t, sql, "SET health = $3, checked_at = $4, broken = $5")
+					assert.Equal(t, []any{"go.example", "abc", h, checked, true}, arguments)
+					return pgconn.NewCommandTag(tt.tag), nil
+				},
+			}
+			store := &DBStore{conf: &config.Config{}, pool: mockDB}
+			assert.ErrorIs(t, store.SetHealth(ctx, "go.example", "abc", h), tt.want)
+		})
+	}
 }
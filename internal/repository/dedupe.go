@@ -0,0 +1,49 @@
+package repository
+
+import "fmt"
+
+// DedupeScope определяет область уникальности оригинальных URL.
+type DedupeScope string
+
+const (
+	// DedupeGlobal — оригинальный URL уникален во всем сервисе (поведение по умолчанию).
+	DedupeGlobal DedupeScope = "global"
+	// DedupeUser — оригинальный URL уникален в пределах пользователя.
+	DedupeUser DedupeScope = "user"
+	// DedupeNone — дедупликация отключена, каждый запрос создает новую ссылку.
+	DedupeNone DedupeScope = "none"
+)
+
+// ParseDedupeScope разбирает область дедупликации из конфигурации.
+// Пустая строка соответствует DedupeGlobal.
+// Пример:
+//
+//	scope, err := ParseDedupeScope("user")
+func ParseDedupeScope(s string) (DedupeScope, error) {
+	switch DedupeScope(s) {
+	case "", DedupeGlobal:
+		return DedupeGlobal, nil
+	case DedupeUser, DedupeNone:
+		return DedupeScope(s), nil
+	default:
+		return "", fmt.Errorf("unknown dedupe scope %q: expected global, user or none", s)
+	}
+}
+
+// Key возвращает ключ дедупликации для ссылки пользователя.
+// Ссылки с одинаковым оригинальным URL и одинаковым ключом считаются дубликатами;
+// nil означает, что ссылка не участвует в дедупликации.
+// Пример:
+//
+//	key := DedupeUser.Key(42) // *key == 42
+func (d DedupeScope) Key(userID int) *int {
+	switch d {
+	case DedupeNone:
+		return nil
+	case DedupeUser:
+		return &userID
+	default:
+		key := 0
+		return &key
+	}
+}
@@ -1,12 +1,18 @@
 package repository
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/spitfy/urlshortener/internal/config"
 	"github.com/spitfy/urlshortener/internal/model"
@@ -14,16 +20,23 @@ import (
 
 // FileStore реализует хранилище URL в файле с in-memory кэшем.
 // Использует MemStore для быстрого доступа и синхронизирует данные с файлом.
+// Счетчики переходов и результаты проверок адресов назначения (SetHealth из MemStore)
+// не перезаписывают файл и сохраняются со следующим изменением или при закрытии.
+// Ключи идемпотентности действуют недолго и хранятся только в памяти (MemStore).
 // Пример создания:
 //
 //	conf := config.LoadConfig()
-//	store, err := newFileStore(conf)
+//	store, err := newFileStore(conf, DedupeGlobal)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
 //	defer store.Close()
 type FileStore struct {
-	file *os.File
+	file    *os.File
+	journal *os.File
+	hooks   *os.File // Журнал подписок и доставок событий
+	jmux    sync.Mutex
+	smux    sync.Mutex // Упорядочивает сохранения файла хранилища
 	*MemStore
 }
 
@@ -45,11 +58,11 @@ func NewMockStore() *FileStore {
 // newFileStore создает новое файловое хранилище с загрузкой данных из файла.
 // Пример:
 //
-//	store, err := newFileStore(config)
+//	store, err := newFileStore(config, DedupeGlobal)
 //	if err != nil {
 //	    // обработка ошибки
 //	}
-func newFileStore(config *config.Config) (*FileStore, error) {
+func newFileStore(config *config.Config, scope DedupeScope) (*FileStore, error) {
 	dir := filepath.Dir(config.FileStorage.FileStoragePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create storage directory: %w", err)
@@ -58,34 +71,161 @@ func newFileStore(config *config.Config) (*FileStore, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s: %w", config.FileStorage.FileStoragePath, err)
 	}
-	store := FileStore{
+	store := &FileStore{
 		file:     f,
-		MemStore: newMemStore(),
+		MemStore: newMemStoreWithScope(scope),
 	}
-	links, err := store.init()
-	if err != nil {
+	if err := store.init(); err != nil {
 		return nil, fmt.Errorf("failed to init store: %w", err)
 	}
-	store.s = links
+	if err := store.initJournal(); err != nil {
+		return nil, fmt.Errorf("failed to init deletion journal: %w", err)
+	}
+	if err := store.initWebhooks(); err != nil {
+		return nil, fmt.Errorf("failed to init webhook journal: %w", err)
+	}
+
+	return store, nil
+}
+
+// journalPath возвращает путь к журналу заданий на удаление.
+func (s *FileStore) journalPath() string {
+	return s.file.Name() + ".deletions"
+}
+
+// initJournal восстанавливает задания на удаление из журнала и сжимает его:
+// в журнале остается по одной записи с последним состоянием каждого задания.
+func (s *FileStore) initJournal() error {
+	jobs := make(map[int64]DeletionJob)
+	f, err := os.Open(s.journalPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if f != nil {
+		dec := json.NewDecoder(f)
+		for {
+			var j model.DeletionJob
+			if err := dec.Decode(&j); err == io.EOF {
+				break
+			} else if err != nil {
+				_ = f.Close()
+				return err
+			}
+			jobs[j.ID] = DeletionJob{
+				ID:          j.ID,
+				UserID:      j.UserID,
+				Domain:      j.Domain,
+				Hashes:      j.Hashes,
+				Status:      j.Status,
+				CreatedAt:   j.CreatedAt,
+				CompletedAt: j.CompletedAt,
+			}
+		}
+		_ = f.Close()
+	}
+	s.restoreJobs(jobs)
+
+	ids := make([]int64, 0, len(jobs))
+	for id := range jobs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, id := range ids {
+		if err := enc.Encode(deletionToModel(jobs[id])); err != nil {
+			return err
+		}
+	}
+	tmpPath := s.journalPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.journalPath()); err != nil {
+		return err
+	}
 
-	return &store, nil
+	s.journal, err = os.OpenFile(s.journalPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	return err
+}
+
+// appendJournal дописывает состояние заданий в журнал и сбрасывает его на диск.
+func (s *FileStore) appendJournal(jobs ...DeletionJob) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, j := range jobs {
+		if err := enc.Encode(deletionToModel(j)); err != nil {
+			return err
+		}
+	}
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	if _, err := s.journal.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.journal.Sync()
+}
+
+// deletionToModel преобразует задание на удаление в формат журнала.
+func deletionToModel(j DeletionJob) model.DeletionJob {
+	return model.DeletionJob{
+		ID:          j.ID,
+		UserID:      j.UserID,
+		Domain:      j.Domain,
+		Status:      j.Status,
+		Hashes:      j.Hashes,
+		CreatedAt:   j.CreatedAt,
+		CompletedAt: j.CompletedAt,
+	}
+}
+
+// experimentToModel преобразует эксперимент в формат файла хранилища.
+// Эксперимент хранится в записи своей ссылки, поэтому домен и хеш не сохраняются.
+func experimentToModel(e Experiment) *model.Experiment {
+	me := &model.Experiment{
+		ID:        e.ID,
+		Name:      e.Name,
+		Variants:  make([]model.Variant, 0, len(e.Variants)),
+		CreatedAt: e.CreatedAt,
+	}
+	for _, v := range e.Variants {
+		me.Variants = append(me.Variants, model.Variant(v))
+		me.Redirects += v.Redirects
+	}
+	return me
+}
+
+// experimentFromModel восстанавливает эксперимент ссылки k из формата файла хранилища.
+func experimentFromModel(me model.Experiment, k linkKey) Experiment {
+	e := Experiment{
+		Domain:    k.domain,
+		Hash:      k.hash,
+		Name:      me.Name,
+		Variants:  make([]Variant, 0, len(me.Variants)),
+		CreatedAt: me.CreatedAt,
+	}
+	for _, v := range me.Variants {
+		e.Variants = append(e.Variants, Variant(v))
+	}
+	return e
 }
 
 // GetByHash возвращает URL по хешу из in-memory кэша.
 // Пример:
 //
-//	url, err := store.GetByHash(ctx, "abc123")
+//	url, err := store.GetByHash(ctx, "", "abc123")
 //	if err != nil {
 //	    // обработка ошибки
 //	}
-func (s *FileStore) GetByHash(ctx context.Context, hash string) (URL, error) {
-	return s.MemStore.GetByHash(ctx, hash)
+func (s *FileStore) GetByHash(ctx context.Context, domain, hash string) (URL, error) {
+	return s.MemStore.GetByHash(ctx, domain, hash)
 }
 
-func (s *FileStore) init() (map[string]string, error) {
+// init загружает ссылки, историю изменений и последний ID пользователя из файла.
+func (s *FileStore) init() error {
 	_, err := s.file.Seek(0, io.SeekStart)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	var store LinkList
 	dec := json.NewDecoder(s.file)
@@ -93,13 +233,60 @@ func (s *FileStore) init() (map[string]string, error) {
 	if err == io.EOF {
 		store = nil
 	} else if err != nil {
-		return nil, err
+		return err
 	}
-	links := make(map[string]string, len(store))
 	for _, l := range store {
-		links[l.ShortURL] = l.OriginalURL
+		k := linkKey{l.Domain, l.ShortURL}
+		if l.PurgedAt != nil {
+			s.purged[k] = purgedLink{userID: l.UserID, purgedAt: *l.PurgedAt, free: l.HashFree}
+			s.lastUser = max(s.lastUser, l.UserID)
+			continue
+		}
+		u := URL{
+			Hash:         l.ShortURL,
+			Domain:       l.Domain,
+			Link:         l.OriginalURL,
+			DeletedFlag:  l.IsDeleted,
+			Redirect:     l.Redirect,
+			UserID:       l.UserID,
+			Title:        l.Title,
+			Tags:         l.Tags,
+			Note:         l.Note,
+			CreatedAt:    l.CreatedAt,
+			Clicks:       l.Clicks,
+			PasswordHash: l.PasswordHash,
+			MaxClicks:    l.MaxClicks,
+			Rules:        l.Rules,
+		}
+		if l.DeletedAt != nil {
+			u.DeletedAt = *l.DeletedAt
+		}
+		if l.Health != nil {
+			u.Health = *l.Health
+		}
+		if e := l.Experiment; e != nil {
+			u.Experiment = e.ID
+			s.addExperiment(experimentFromModel(*e, k), e.ID, l.UserID)
+		}
+		if origin, dedupe := s.origin(u, l.UserID); dedupe && !l.IsDeleted {
+			s.originals[origin] = l.ShortURL
+		}
+		s.s[k] = u
+		for _, r := range l.History {
+			s.revisions[k] = append(s.revisions[k], Revision{
+				Number:   r.Revision,
+				Link:     r.OriginalURL,
+				Title:    r.Title,
+				Tags:     r.Tags,
+				Note:     r.Note,
+				Redirect: r.Redirect,
+				Rules:    r.Rules,
+				EditedAt: r.EditedAt,
+			})
+		}
+		s.lastUser = max(s.lastUser, l.UserID)
 	}
-	return links, nil
+	return nil
 }
 
 // Add добавляет URL в хранилище с сохранением в файл.
@@ -132,11 +319,24 @@ func (s *FileStore) Ping() error {
 	return nil
 }
 
-// Close закрывает файловый дескриптор хранилища.
+// Close сохраняет накопленные счетчики переходов и результаты проверок адресов назначения
+// в файл и закрывает журналы заданий на удаление и подписок.
 // Пример:
 //
 //	defer store.Close()
-func (s *FileStore) Close() {}
+func (s *FileStore) Close() {
+	if s.file != nil {
+		if err := s.save(); err != nil {
+			log.Printf("failed to save store: %v", err)
+		}
+	}
+	if s.journal != nil {
+		_ = s.journal.Close()
+	}
+	if s.hooks != nil {
+		_ = s.hooks.Close()
+	}
+}
 
 // BatchAdd добавляет несколько URL с атомарным сохранением в файл.
 // Пример:
@@ -145,30 +345,83 @@ func (s *FileStore) Close() {}
 //	    {Hash: "abc", Link: "https://example.com"},
 //	    {Hash: "def", Link: "https://example.org"},
 //	}
-//	err := store.BatchAdd(ctx, urls, 1)
-func (s *FileStore) BatchAdd(ctx context.Context, urls []URL, userID int) error {
-	if err := s.MemStore.BatchAdd(ctx, urls, userID); err != nil {
-		return err
+//	res, err := store.BatchAdd(ctx, urls, 1)
+func (s *FileStore) BatchAdd(ctx context.Context, urls []URL, userID int) ([]AddResult, error) {
+	res, err := s.MemStore.BatchAdd(ctx, urls, userID)
+	if err != nil {
+		return nil, err
 	}
 	if err := s.save(); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return res, nil
 }
 
 // save сохраняет текущее состояние хранилища в файл
 func (s *FileStore) save() error {
+	// Снимок, запись и переименование выполняются под smux: иначе параллельные
+	// сохранения пишут в один временный файл, а более старый снимок может
+	// заменить более новый.
+	s.smux.Lock()
+	defer s.smux.Unlock()
+	s.mux.Lock()
 	store := make(LinkList, 0, len(s.s))
 	uuid := 1
-	for hash, l := range s.s {
+	for k, l := range s.s {
 		ml := model.Link{
-			UUID:        string(rune(uuid)),
-			ShortURL:    hash,
-			OriginalURL: l,
+			UUID:         string(rune(uuid)),
+			ShortURL:     k.hash,
+			Domain:       k.domain,
+			OriginalURL:  l.Link,
+			IsDeleted:    l.DeletedFlag,
+			Redirect:     l.Redirect,
+			UserID:       l.UserID,
+			Title:        l.Title,
+			Tags:         l.Tags,
+			Note:         l.Note,
+			CreatedAt:    l.CreatedAt,
+			Clicks:       l.Clicks,
+			PasswordHash: l.PasswordHash,
+			MaxClicks:    l.MaxClicks,
+			Rules:        l.Rules,
+		}
+		if l.DeletedFlag {
+			ml.DeletedAt = &l.DeletedAt
+		}
+		if !l.Health.CheckedAt.IsZero() {
+			ml.Health = &l.Health
+		}
+		if e, ok := s.experiments[l.Experiment]; ok {
+			ml.Experiment = experimentToModel(e)
+		}
+		for _, r := range s.revisions[k] {
+			ml.History = append(ml.History, model.LinkRevision{
+				Revision:    r.Number,
+				OriginalURL: r.Link,
+				Title:       r.Title,
+				Tags:        r.Tags,
+				Note:        r.Note,
+				Redirect:    r.Redirect,
+				Rules:       r.Rules,
+				EditedAt:    r.EditedAt,
+			})
 		}
 		store = append(store, ml)
 		uuid++
 	}
+	for k, p := range s.purged {
+		store = append(store, model.Link{
+			UUID:      string(rune(uuid)),
+			ShortURL:  k.hash,
+			Domain:    k.domain,
+			IsDeleted: true,
+			UserID:    p.userID,
+			PurgedAt:  &p.purgedAt,
+			HashFree:  p.free,
+		})
+		uuid++
+	}
+	s.mux.Unlock()
 	data, err := json.Marshal(store)
 	if err != nil {
 		return err
@@ -181,30 +434,335 @@ func (s *FileStore) save() error {
 	return os.Rename(tmpPath, s.file.Name())
 }
 
-// GetByUserID всегда возвращает пустой список (не реализовано для FileStore).
+// BatchDelete помечает URL пользователя как удаленные и сохраняет состояние в файл.
 // Пример:
 //
-//	urls, _ := store.GetByUserID(ctx, 1) // всегда []
-func (s *FileStore) GetByUserID(_ context.Context, _ int) ([]URL, error) {
-	return make([]URL, 0), nil
+//	_ = store.BatchDelete(ctx, UserHash{...})
+func (s *FileStore) BatchDelete(ctx context.Context, uh UserHash) (err error) {
+	if err := s.MemStore.BatchDelete(ctx, uh); err != nil {
+		return err
+	}
+	return s.save()
 }
 
-// CreateUser всегда возвращает -1 (не реализовано для FileStore).
+func (s *FileStore) Stats(ctx context.Context) (model.Stats, error) {
+	return s.MemStore.Stats(ctx)
+}
+
+// Update изменяет ссылку пользователя и сохраняет состояние в файл.
 // Пример:
 //
-//	userID, _ := store.CreateUser(ctx) // всегда -1
-func (s *FileStore) CreateUser(_ context.Context) (int, error) {
-	return -1, nil
+//	_, err := store.Update(ctx, URL{Hash: "abc", Link: "https://example.org"}, 1)
+func (s *FileStore) Update(ctx context.Context, url URL, userID int) (string, error) {
+	if hash, err := s.MemStore.Update(ctx, url, userID); err != nil {
+		return hash, err
+	}
+	return url.Hash, s.save()
 }
 
-// BatchDelete всегда возвращает nil (не реализовано для FileStore).
+// AddClick увеличивает счетчик переходов по ссылке. Чтобы переход не требовал
+// перезаписи файла, счетчик сохраняется вместе со следующим изменением хранилища
+// или при закрытии. Переход по ссылке с лимитом переходов сохраняется сразу,
+// чтобы после перезапуска лимит нельзя было превысить.
 // Пример:
 //
-//	_ = store.BatchDelete(ctx, UserHash{...})
-func (s *FileStore) BatchDelete(_ context.Context, _ UserHash) (err error) {
-	return nil
+//	_ = store.AddClick(ctx, "", "abc")
+func (s *FileStore) AddClick(_ context.Context, domain, hash string) error {
+	s.mux.Lock()
+	limited, err := s.addClick(linkKey{domain, hash})
+	s.mux.Unlock()
+	if err != nil || !limited {
+		return err
+	}
+	return s.save()
 }
 
-func (s *FileStore) Stats(ctx context.Context) (model.Stats, error) {
-	return s.MemStore.Stats(ctx)
+// AddExperiment создает эксперимент и его короткую ссылку и сохраняет состояние в файл.
+// Счетчики перенаправлений на варианты, как и счетчики переходов, сохраняются
+// вместе со следующим изменением хранилища или при закрытии.
+// Пример:
+//
+//	e, err := store.AddExperiment(ctx, Experiment{Hash: "abc", Variants: variants}, 1)
+func (s *FileStore) AddExperiment(ctx context.Context, e Experiment, userID int) (Experiment, error) {
+	e, err := s.MemStore.AddExperiment(ctx, e, userID)
+	if err != nil {
+		return Experiment{}, err
+	}
+	return e, s.save()
+}
+
+// SetTitle задает заголовок ссылки, если он еще не задан, и сохраняет состояние в файл.
+// Пример:
+//
+//	_ = store.SetTitle(ctx, "", "abc", "Example Domain")
+func (s *FileStore) SetTitle(ctx context.Context, domain, hash, title string) error {
+	if err := s.MemStore.SetTitle(ctx, domain, hash, title); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// EnqueueDeletion ставит задание на удаление в очередь и записывает его в журнал.
+// Пример:
+//
+//	job, err := store.EnqueueDeletion(ctx, UserHash{UserID: 1, Hash: []string{"abc"}})
+func (s *FileStore) EnqueueDeletion(ctx context.Context, uh UserHash) (DeletionJob, error) {
+	job, err := s.MemStore.EnqueueDeletion(ctx, uh)
+	if err != nil {
+		return DeletionJob{}, err
+	}
+	return job, s.appendJournal(job)
+}
+
+// ProcessDeletions выполняет ожидающие задания, сохраняет ссылки в файл
+// и только затем отмечает задания выполненными в журнале, поэтому
+// после сбоя незавершенные задания будут выполнены повторно.
+// Пример:
+//
+//	n, deleted, err := store.ProcessDeletions(ctx, 100)
+func (s *FileStore) ProcessDeletions(_ context.Context, limit int) (int, []URL, error) {
+	done, deleted := s.processDeletions(limit)
+	if len(done) == 0 {
+		return 0, nil, nil
+	}
+	if err := s.save(); err != nil {
+		return 0, nil, err
+	}
+	if err := s.appendJournal(done...); err != nil {
+		return 0, nil, err
+	}
+	return len(done), deleted, nil
+}
+
+// Restore восстанавливает удаленные ссылки пользователя и сохраняет состояние в файл.
+// Пример:
+//
+//	res, err := store.Restore(ctx, UserHash{UserID: 1, Hash: []string{"abc"}}, since)
+func (s *FileStore) Restore(ctx context.Context, uh UserHash, since time.Time) ([]model.RestoreResult, error) {
+	res, err := s.MemStore.Restore(ctx, uh, since)
+	if err != nil {
+		return nil, err
+	}
+	return res, s.save()
+}
+
+// Purge окончательно удаляет ссылки, удаленные раньше before, и сохраняет состояние в файл.
+// Пример:
+//
+//	n, err := store.Purge(ctx, time.Now().Add(-time.Hour), false)
+func (s *FileStore) Purge(ctx context.Context, before time.Time, freeHash bool) (int, error) {
+	n, err := s.MemStore.Purge(ctx, before, freeHash)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, s.save()
+}
+
+// webhookRecord — запись журнала подписок: состояние подписки или доставки
+// либо идентификатор удаленной подписки. Состояние объекта определяет его последняя запись.
+type webhookRecord struct {
+	Webhook  *Webhook  `json:"webhook,omitempty"`
+	Delivery *Delivery `json:"delivery,omitempty"`
+	Removed  int64     `json:"removed,omitempty"`
+}
+
+// webhooksPath возвращает путь к журналу подписок и доставок.
+func (s *FileStore) webhooksPath() string {
+	return s.file.Name() + ".webhooks"
+}
+
+// initWebhooks восстанавливает подписки и доставки из журнала и сжимает его.
+func (s *FileStore) initWebhooks() error {
+	f, err := os.Open(s.webhooksPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if f != nil {
+		dec := json.NewDecoder(f)
+		for {
+			var rec webhookRecord
+			if err := dec.Decode(&rec); err == io.EOF {
+				break
+			} else if err != nil {
+				_ = f.Close()
+				return err
+			}
+			s.restoreWebhook(rec)
+		}
+		_ = f.Close()
+	}
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	return s.compactWebhooks()
+}
+
+// restoreWebhook применяет запись журнала подписок к in-memory хранилищу.
+func (s *FileStore) restoreWebhook(rec webhookRecord) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	switch {
+	case rec.Webhook != nil:
+		s.webhooks[rec.Webhook.ID] = *rec.Webhook
+		s.lastWebhook = max(s.lastWebhook, rec.Webhook.ID)
+	case rec.Delivery != nil:
+		s.deliveries[rec.Delivery.ID] = *rec.Delivery
+		s.lastDelivery = max(s.lastDelivery, rec.Delivery.ID)
+	case rec.Removed != 0:
+		_ = s.deleteWebhook(rec.Removed)
+	}
+}
+
+// compactWebhooks перезаписывает журнал подписок текущим состоянием:
+// по одной записи на подписку и доставку. Вызывается под блокировкой jmux.
+func (s *FileStore) compactWebhooks() error {
+	s.mux.Lock()
+	recs := make([]webhookRecord, 0, len(s.webhooks)+len(s.deliveries))
+	for _, w := range s.webhooks {
+		recs = append(recs, webhookRecord{Webhook: &w})
+	}
+	for _, d := range s.deliveries {
+		recs = append(recs, webhookRecord{Delivery: &d})
+	}
+	s.mux.Unlock()
+	// Подписки записываются раньше доставок, внутри групп — по возрастанию идентификаторов.
+	slices.SortFunc(recs, func(a, b webhookRecord) int {
+		switch {
+		case a.Webhook != nil && b.Webhook != nil:
+			return cmp.Compare(a.Webhook.ID, b.Webhook.ID)
+		case a.Delivery != nil && b.Delivery != nil:
+			return cmp.Compare(a.Delivery.ID, b.Delivery.ID)
+		case a.Webhook != nil:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	tmpPath := s.webhooksPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.webhooksPath()); err != nil {
+		return err
+	}
+	if s.hooks != nil {
+		_ = s.hooks.Close()
+	}
+	var err error
+	s.hooks, err = os.OpenFile(s.webhooksPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	return err
+}
+
+// appendWebhooks дописывает записи в журнал подписок и сбрасывает его на диск.
+// Вызывается под блокировкой jmux, которая удерживается и на время изменения
+// in-memory хранилища, поэтому порядок записей совпадает с порядком изменений.
+func (s *FileStore) appendWebhooks(recs ...webhookRecord) error {
+	if s.hooks == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if _, err := s.hooks.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.hooks.Sync()
+}
+
+// AddWebhook сохраняет подписку и записывает ее в журнал.
+// Пример:
+//
+//	w, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://hooks.example.com/", Active: true})
+func (s *FileStore) AddWebhook(ctx context.Context, w Webhook) (Webhook, error) {
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	w, err := s.MemStore.AddWebhook(ctx, w)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return w, s.appendWebhooks(webhookRecord{Webhook: &w})
+}
+
+// UpdateWebhook заменяет параметры подписки и записывает ее в журнал.
+// Пример:
+//
+//	err := store.UpdateWebhook(ctx, w)
+func (s *FileStore) UpdateWebhook(_ context.Context, w Webhook) error {
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	s.mux.Lock()
+	w, err := s.updateWebhook(w)
+	s.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.appendWebhooks(webhookRecord{Webhook: &w})
+}
+
+// DeleteWebhook удаляет подписку и ее доставки и записывает удаление в журнал.
+// Пример:
+//
+//	err := store.DeleteWebhook(ctx, 1)
+func (s *FileStore) DeleteWebhook(ctx context.Context, id int64) error {
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	if err := s.MemStore.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+	return s.appendWebhooks(webhookRecord{Removed: id})
+}
+
+// AddDelivery сохраняет доставку события и записывает ее в журнал.
+// Пример:
+//
+//	d, err := store.AddDelivery(ctx, Delivery{WebhookID: 1, Status: model.DeliveryPending})
+func (s *FileStore) AddDelivery(ctx context.Context, d Delivery) (Delivery, error) {
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	d, err := s.MemStore.AddDelivery(ctx, d)
+	if err != nil {
+		return Delivery{}, err
+	}
+	return d, s.appendWebhooks(webhookRecord{Delivery: &d})
+}
+
+// UpdateDelivery сохраняет результат попытки доставки и записывает его в журнал.
+// Пример:
+//
+//	err := store.UpdateDelivery(ctx, d)
+func (s *FileStore) UpdateDelivery(_ context.Context, d Delivery) error {
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	s.mux.Lock()
+	d, err := s.updateDelivery(d)
+	s.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.appendWebhooks(webhookRecord{Delivery: &d})
+}
+
+// PurgeDeliveries удаляет завершенные доставки, созданные раньше before, и сжимает журнал.
+// Пример:
+//
+//	n, err := store.PurgeDeliveries(ctx, time.Now().Add(-7*24*time.Hour))
+func (s *FileStore) PurgeDeliveries(ctx context.Context, before time.Time) (int, error) {
+	s.jmux.Lock()
+	defer s.jmux.Unlock()
+	n, err := s.MemStore.PurgeDeliveries(ctx, before)
+	if err != nil || n == 0 || s.hooks == nil {
+		return n, err
+	}
+	return n, s.compactWebhooks()
 }
@@ -3,10 +3,14 @@ package repository
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/spitfy/urlshortener/internal/config"
+	"github.com/spitfy/urlshortener/internal/model"
 	repoConf "github.com/spitfy/urlshortener/internal/repository/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,7 +42,7 @@ func TestStore_Add(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			_, _ = store.Add(ctx, tt.link, -1)
-			assert.Equal(t, tt.want[tt.link.Hash], store.s[tt.link.Hash])
+			assert.Equal(t, tt.want[tt.link.Hash], store.s[linkKey{hash: tt.link.Hash}].Link)
 		})
 	}
 	if err := os.Remove(cfg.FileStorage.FileStoragePath); err != nil {
@@ -61,10 +65,239 @@ func TestNewStore(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got, err := newFileStore(&cfg); !reflect.DeepEqual((*got).s, tt.want.s) {
+			if got, err := newFileStore(&cfg, DedupeGlobal); !reflect.DeepEqual((*got).s, tt.want.s) {
 				require.NoError(t, err, "error creating store")
 				t.Errorf("NewFileStore() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestFileStore_DeletionJournal(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Config{FileStorage: repoConf.Config{FileStoragePath: filepath.Join(t.TempDir(), "links.json")}}
+
+	store, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	_, err = store.Add(ctx, URL{Hash: "JOURNAL1", Link: "https://example.com/journal"}, 1)
+	require.NoError(t, err)
+	job, err := store.EnqueueDeletion(ctx, UserHash{UserID: 1, Hash: []string{"JOURNAL1"}})
+	require.NoError(t, err)
+	store.Close()
+
+	// Задание переживает перезапуск и выполняется после него.
+	store, err = newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	got, err := store.GetDeletion(ctx, job.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeletionPending, got.Status)
+	n, deleted, err := store.ProcessDeletions(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "https://example.com/journal", deleted[0].Link)
+	store.Close()
+
+	store, err = newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer store.Close()
+	got, err = store.GetDeletion(ctx, job.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeletionDone, got.Status)
+	u, err := store.GetByHash(ctx, "", "JOURNAL1")
+	require.NoError(t, err)
+	assert.True(t, u.DeletedFlag)
+
+	next, err := store.EnqueueDeletion(ctx, UserHash{UserID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, job.ID+1, next.ID, "job IDs continue after restart")
+}
+
+func TestFileStore_LinkMetadata(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Config{FileStorage: repoConf.Config{FileStoragePath: filepath.Join(t.TempDir(), "links.json")}}
+
+	store, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	_, err = store.Add(ctx, URL{
+		Hash: "METADATA", Link: "https://example.com/", Tags: []string{"go"}, Note: "draft",
+		Rules: []model.RouteRule{{URL: "https://example.com/ru", Languages: []string{"ru"}}},
+	}, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.SetTitle(ctx, "", "METADATA", "Example Domain"))
+	require.NoError(t, store.AddClick(ctx, "", "METADATA"))
+	checked := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	health := model.LinkHealth{Status: 404, Redirects: []string{"https://example.com/old"}, Broken: true, CheckedAt: checked}
+	require.NoError(t, store.SetHealth(ctx, "", "METADATA", health))
+	// Счетчик переходов и результат проверки сохраняются при закрытии хранилища.
+	store.Close()
+
+	store, err = newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer store.Close()
+	u, err := store.GetByHash(ctx, "", "METADATA")
+	require.NoError(t, err)
+	assert.Equal(t, "Example Domain", u.Title)
+	assert.Equal(t, []string{"go"}, u.Tags)
+	assert.Equal(t, "draft", u.Note)
+	assert.Equal(t, int64(1), u.Clicks)
+	assert.Equal(t, []model.RouteRule{{URL: "https://example.com/ru", Languages: []string{"ru"}}}, u.Rules)
+	assert.Equal(t, health, u.Health)
+	assert.False(t, u.CreatedAt.IsZero())
+}
+
+func TestFileStore_AddClickLimit(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Config{FileStorage: repoConf.Config{FileStoragePath: filepath.Join(t.TempDir(), "links.json")}}
+
+	store, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer store.Close()
+	_, err = store.Add(ctx, URL{Hash: "ONETIME1", Link: "https://example.com/", MaxClicks: 1}, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.AddClick(ctx, "", "ONETIME1"))
+
+	// Переход по ограниченной ссылке сохраняется сразу, без закрытия хранилища.
+	reopened, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.ErrorIs(t, reopened.AddClick(ctx, "", "ONETIME1"), ErrExhausted)
+}
+
+func TestFileStore_AddClickConcurrent(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Config{FileStorage: repoConf.Config{FileStoragePath: filepath.Join(t.TempDir(), "links.json")}}
+
+	store, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer store.Close()
+	_, err = store.Add(ctx, URL{Hash: "LIMITED1", Link: "https://example.com/", MaxClicks: 1000}, 1)
+	require.NoError(t, err)
+
+	const clicks = 50
+	var wg sync.WaitGroup
+	for range clicks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, store.AddClick(ctx, "", "LIMITED1"))
+		}()
+	}
+	wg.Wait()
+
+	// Последнее сохранение содержит все переходы, а файл остается корректным.
+	reopened, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, int64(clicks), reopened.s[linkKey{"", "LIMITED1"}].Clicks)
+}
+
+func TestFileStore_Experiment(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Config{FileStorage: repoConf.Config{FileStoragePath: filepath.Join(t.TempDir(), "links.json")}}
+
+	store, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	e, err := store.AddExperiment(ctx, Experiment{
+		Hash: "EXPERIM1",
+		Name: "landing",
+		Variants: []Variant{
+			{Name: "A", URL: "https://example.com/a", Weight: 70},
+			{Name: "B", URL: "https://example.com/b", Weight: 30},
+		},
+	}, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.AddVariantRedirect(ctx, e.ID, 1))
+	assert.ErrorIs(t, store.AddVariantRedirect(ctx, e.ID, 2), ErrNotFound)
+	// Счетчики перенаправлений сохраняются при закрытии хранилища.
+	store.Close()
+
+	store, err = newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer store.Close()
+	u, err := store.GetByHash(ctx, "", "EXPERIM1")
+	require.NoError(t, err)
+	assert.Equal(t, e.ID, u.Experiment)
+	assert.Equal(t, "https://example.com/a", u.Link)
+	got, err := store.GetExperiment(ctx, e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, e.Name, got.Name)
+	assert.Equal(t, "EXPERIM1", got.Hash)
+	assert.Equal(t, int64(1), got.Variants[1].Redirects)
+
+	// Новому эксперименту выдается следующий идентификатор.
+	next, err := store.AddExperiment(ctx, Experiment{Hash: "EXPERIM2", Variants: e.Variants}, 1)
+	require.NoError(t, err)
+	assert.Greater(t, next.ID, e.ID)
+	list, err := store.ListExperiments(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, next.ID, list[0].ID)
+
+	// Эксперимент удаляется вместе со ссылкой.
+	require.NoError(t, store.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"EXPERIM1"}}))
+	_, err = store.Purge(ctx, time.Now().Add(time.Second), false)
+	require.NoError(t, err)
+	_, err = store.GetExperiment(ctx, e.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileStore_Webhooks(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Config{FileStorage: repoConf.Config{FileStoragePath: filepath.Join(t.TempDir(), "links.json")}}
+
+	store, err := newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	w, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://example.com/hook", Secret: "s", Events: []string{model.WebhookCreated}, Active: true})
+	require.NoError(t, err)
+	removed, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://example.com/old", Active: true})
+	require.NoError(t, err)
+	d, err := store.AddDelivery(ctx, Delivery{WebhookID: w.ID, Payload: model.WebhookEvent{Event: model.WebhookCreated}, Status: model.DeliveryPending})
+	require.NoError(t, err)
+	_, err = store.AddDelivery(ctx, Delivery{WebhookID: removed.ID, Status: model.DeliveryPending})
+	require.NoError(t, err)
+	_, err = store.AddDelivery(ctx, Delivery{WebhookID: 100, Status: model.DeliveryPending})
+	assert.ErrorIs(t, err, ErrNotFound)
+	d.Status, d.Attempts, d.ResponseStatus = model.DeliveryDelivered, 1, 200
+	require.NoError(t, store.UpdateDelivery(ctx, d))
+	w.Active = false
+	require.NoError(t, store.UpdateWebhook(ctx, w))
+	require.NoError(t, store.DeleteWebhook(ctx, removed.ID))
+	// Подписки и журнал доставок восстанавливаются из файла.
+	store.Close()
+
+	store, err = newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	defer store.Close()
+	list, err := store.ListWebhooks(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, w.URL, list[0].URL)
+	assert.Equal(t, "s", list[0].Secret)
+	assert.False(t, list[0].Active)
+	deliveries, err := store.ListDeliveries(ctx, w.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 200, deliveries[0].ResponseStatus)
+	assert.Equal(t, model.WebhookCreated, deliveries[0].Payload.Event)
+	pending, err := store.PendingDeliveries(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "deliveries of a deleted webhook are removed")
+
+	// Новой подписке выдается следующий идентификатор.
+	next, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://example.com/new"})
+	require.NoError(t, err)
+	assert.Greater(t, next.ID, removed.ID)
+
+	n, err := store.PurgeDeliveries(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	store.Close()
+	store, err = newFileStore(&conf, DedupeGlobal)
+	require.NoError(t, err)
+	deliveries, err = store.ListDeliveries(ctx, w.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+	store.Close()
+}
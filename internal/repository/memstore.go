@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"github.com/spitfy/urlshortener/internal/model"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 )
 
 // MemStore реализует хранилище URL в памяти с синхронизацией доступа.
@@ -12,58 +17,160 @@ import (
 //
 //	store := newMemStore()
 type MemStore struct {
-	mux *sync.Mutex
-	s   map[string]string
+	mux       *sync.Mutex
+	s         map[linkKey]URL
+	revisions map[linkKey][]Revision
+	lastUser  int
+	scope     DedupeScope
+	originals map[originKey]string
+	jobs      map[int64]DeletionJob
+	pending   []int64
+	lastJob   int64
+	purged    map[linkKey]purgedLink
+
+	experiments    map[int64]Experiment
+	lastExperiment int64
+
+	webhooks     map[int64]Webhook
+	lastWebhook  int64
+	deliveries   map[int64]Delivery
+	lastDelivery int64
+
+	idempotency map[idempotencyKey]IdempotencyKey
+}
+
+// idempotencyKey идентифицирует ключ идемпотентности: значение уникально для пользователя.
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+// linkKey идентифицирует ссылку: хеш уникален в пределах домена.
+type linkKey struct {
+	domain string
+	hash   string
+}
+
+// purgedLink описывает окончательно удаленную ссылку.
+type purgedLink struct {
+	userID   int
+	purgedAt time.Time
+	free     bool // хеш доступен для повторного использования
+}
+
+// originKey — ключ индекса уникальности оригинальных URL в домене.
+type originKey struct {
+	domain string
+	link   string
+	key    int
 }
 
-// newMemStore создает новый экземпляр MemStore.
+// newMemStore создает новый экземпляр MemStore с глобальной дедупликацией.
 // Пример:
 //
 //	store := newMemStore()
 func newMemStore() *MemStore {
+	return newMemStoreWithScope(DedupeGlobal)
+}
+
+// newMemStoreWithScope создает новый экземпляр MemStore с заданной областью дедупликации.
+// Пример:
+//
+//	store := newMemStoreWithScope(DedupeUser)
+func newMemStoreWithScope(scope DedupeScope) *MemStore {
 	return &MemStore{
-		mux: &sync.Mutex{},
-		s:   make(map[string]string),
+		mux:       &sync.Mutex{},
+		s:         make(map[linkKey]URL),
+		revisions: make(map[linkKey][]Revision),
+		scope:     scope,
+		originals: make(map[originKey]string),
+		jobs:      make(map[int64]DeletionJob),
+		purged:    make(map[linkKey]purgedLink),
+
+		experiments: make(map[int64]Experiment),
+
+		webhooks:   make(map[int64]Webhook),
+		deliveries: make(map[int64]Delivery),
+
+		idempotency: make(map[idempotencyKey]IdempotencyKey),
+	}
+}
+
+// origin возвращает ключ индекса уникальности для ссылки пользователя в домене.
+// Второе значение false означает, что ссылка не участвует в дедупликации:
+// при DedupeNone, а также для защищенных паролем и ограниченных ссылок.
+func (s *MemStore) origin(u URL, userID int) (originKey, bool) {
+	key := s.scope.Key(userID)
+	if key == nil || !u.deduplicated() {
+		return originKey{}, false
 	}
+	return originKey{domain: u.Domain, link: u.Link, key: *key}, true
+}
+
+// hashTaken сообщает, занят ли хеш действующей или окончательно удаленной ссылкой.
+// Вызывается под блокировкой.
+func (s *MemStore) hashTaken(k linkKey) bool {
+	if _, ok := s.s[k]; ok {
+		return true
+	}
+	p, ok := s.purged[k]
+	return ok && !p.free
 }
 
 // Add добавляет URL в хранилище.
-// Возвращает ошибку если хеш уже существует.
+// Возвращает ошибку если хеш уже существует и ErrExistsURL с хешем
+// существующей ссылки, если URL уже сокращен в пределах области дедупликации.
 // Пример:
 //
 //	hash, err := store.Add(ctx, URL{Hash: "abc", Link: "https://example.com"}, 1)
 //	if err != nil {
 //	    // обработка ошибки
 //	}
-func (s *MemStore) Add(_ context.Context, url URL, _ int) (hash string, err error) {
+func (s *MemStore) Add(_ context.Context, url URL, userID int) (hash string, err error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
-	if _, ok := s.s[url.Hash]; ok {
-		return url.Hash, fmt.Errorf("wrong hash: '%s', already exists", url.Hash)
+	if s.hashTaken(linkKey{url.Domain, url.Hash}) {
+		return url.Hash, fmt.Errorf("%w: %q", ErrHashTaken, url.Hash)
+	}
+	return s.add(url, userID)
+}
+
+// add сохраняет ссылку с учетом дедупликации. Вызывается под блокировкой.
+func (s *MemStore) add(url URL, userID int) (string, error) {
+	origin, dedupe := s.origin(url, userID)
+	if existing, ok := s.originals[origin]; dedupe && ok {
+		return existing, ErrExistsURL
+	}
+	url.UserID = userID
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now()
+	}
+	s.s[linkKey{url.Domain, url.Hash}] = url
+	if dedupe {
+		s.originals[origin] = url.Hash
 	}
-	s.s[url.Hash] = url.Link
 	return url.Hash, nil
 }
 
-// GetByHash возвращает URL по его хешу.
+// GetByHash возвращает URL по его хешу в домене.
 // Возвращает ошибку если URL не найден.
 // Пример:
 //
-//	url, err := store.GetByHash(ctx, "abc")
+//	url, err := store.GetByHash(ctx, "", "abc")
 //	if err != nil {
 //	    // обработка ошибки
 //	}
-func (s *MemStore) GetByHash(_ context.Context, hash string) (URL, error) {
+func (s *MemStore) GetByHash(_ context.Context, domain, hash string) (URL, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
-	link, ok := s.s[hash]
+	u, ok := s.s[linkKey{domain, hash}]
 	if !ok {
-		return URL{}, fmt.Errorf("data not found for n = %s", hash)
+		if p, ok := s.purged[linkKey{domain, hash}]; ok && !p.free {
+			return URL{Hash: hash, Domain: domain, UserID: p.userID, DeletedFlag: true}, nil
+		}
+		return URL{}, ErrNotFound
 	}
-	return URL{
-		Link: link,
-		Hash: hash,
-	}, nil
+	return u, nil
 }
 
 // Ping всегда возвращает nil (для совместимости с интерфейсом Storer).
@@ -76,51 +183,781 @@ func (s *MemStore) Ping() error {
 	return nil
 }
 
-// GetByUserID возвращает пустой список URL (in-memory хранилище не поддерживает пользовательские URL).
+// GetByUserID возвращает все URL пользователя, отсортированные по домену и хешу.
 // Пример:
 //
-//	links, _ := store.GetByUserID(ctx, 1) // всегда возвращает пустой slice
-func (s *MemStore) GetByUserID(_ context.Context, _ int) ([]URL, error) {
-	return make([]URL, 0), nil
+//	links, _ := store.GetByUserID(ctx, 1)
+func (s *MemStore) GetByUserID(_ context.Context, userID int) ([]URL, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]URL, 0)
+	for _, u := range s.s {
+		if u.UserID == userID {
+			res = append(res, u)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Domain != res[j].Domain {
+			return res[i].Domain < res[j].Domain
+		}
+		return res[i].Hash < res[j].Hash
+	})
+	return res, nil
+}
+
+// ListByUser возвращает страницу неудаленных ссылок пользователя, подходящих под запрос.
+// Пример:
+//
+//	links, _ := store.ListByUser(ctx, 1, LinkQuery{Tags: []string{"promo"}, Limit: 20})
+func (s *MemStore) ListByUser(_ context.Context, userID int, q LinkQuery) ([]URL, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	words := searchWords(q.Search)
+	res := make([]URL, 0)
+	for _, u := range s.s {
+		if u.UserID == userID && !u.DeletedFlag && q.match(u, words) && q.after(u) {
+			res = append(res, u)
+		}
+	}
+	slices.SortFunc(res, q.compare)
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[:q.Limit]
+	}
+	return res, nil
+}
+
+// AddClick увеличивает счетчик переходов по ссылке.
+// Для ссылки с исчерпанным лимитом переходов возвращает ErrExhausted.
+// Пример:
+//
+//	_ = store.AddClick(ctx, "", "abc")
+func (s *MemStore) AddClick(_ context.Context, domain, hash string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.addClick(linkKey{domain, hash})
+	return err
+}
+
+// addClick увеличивает счетчик переходов и сообщает, ограничена ли ссылка.
+// Вызывается под блокировкой.
+func (s *MemStore) addClick(k linkKey) (bool, error) {
+	u, ok := s.s[k]
+	if !ok || u.DeletedFlag {
+		return false, ErrNotFound
+	}
+	if u.Exhausted() {
+		return true, ErrExhausted
+	}
+	u.Clicks++
+	s.s[k] = u
+	return u.MaxClicks > 0, nil
+}
+
+// LinksToCheck возвращает неудаленные ссылки, не проверявшиеся с момента before.
+// Пример:
+//
+//	urls, _ := store.LinksToCheck(ctx, time.Now().Add(-time.Hour), 100)
+func (s *MemStore) LinksToCheck(_ context.Context, before time.Time, limit int) ([]URL, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]URL, 0)
+	for _, u := range s.s {
+		if !u.DeletedFlag && u.Health.CheckedAt.Before(before) {
+			res = append(res, u)
+		}
+	}
+	slices.SortFunc(res, func(a, b URL) int {
+		return cmp.Or(a.Health.CheckedAt.Compare(b.Health.CheckedAt),
+			cmp.Compare(a.Domain, b.Domain), cmp.Compare(a.Hash, b.Hash))
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// SetHealth сохраняет результат проверки адреса назначения ссылки.
+// Пример:
+//
+//	_ = store.SetHealth(ctx, "", "abc", model.LinkHealth{Status: 200, CheckedAt: time.Now()})
+func (s *MemStore) SetHealth(_ context.Context, domain, hash string, h model.LinkHealth) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	k := linkKey{domain, hash}
+	u, ok := s.s[k]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Health = h
+	s.s[k] = u
+	return nil
+}
+
+// SetTitle задает заголовок ссылки, если он еще не задан.
+// Пример:
+//
+//	_ = store.SetTitle(ctx, "", "abc", "Example Domain")
+func (s *MemStore) SetTitle(_ context.Context, domain, hash, title string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	k := linkKey{domain, hash}
+	u, ok := s.s[k]
+	if !ok {
+		return ErrNotFound
+	}
+	if u.Title == "" {
+		u.Title = title
+		s.s[k] = u
+	}
+	return nil
 }
 
 func (s *MemStore) Close() {}
 
 // BatchAdd добавляет несколько URL в хранилище атомарно.
-// Возвращает первую ошибку, если добавление не удалось.
+// Если хотя бы один хеш уже занят, ни одна ссылка не сохраняется.
 // Пример:
 //
 //	urls := []URL{
 //	    {Hash: "abc", Link: "https://example.com"},
 //	    {Hash: "def", Link: "https://example.org"},
 //	}
-//	err := store.BatchAdd(ctx, urls, 1)
-func (s *MemStore) BatchAdd(ctx context.Context, urls []URL, userID int) error {
+//	res, err := store.BatchAdd(ctx, urls, 1)
+func (s *MemStore) BatchAdd(_ context.Context, urls []URL, userID int) ([]AddResult, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	hashes := make(map[linkKey]bool, len(urls))
 	for _, u := range urls {
-		if _, err := s.Add(ctx, u, userID); err != nil {
-			return err
+		k := linkKey{u.Domain, u.Hash}
+		if s.hashTaken(k) || hashes[k] {
+			return nil, fmt.Errorf("%w: %q", ErrHashTaken, u.Hash)
 		}
+		hashes[k] = true
 	}
-	return nil
+
+	res := make([]AddResult, 0, len(urls))
+	for _, u := range urls {
+		hash, err := s.add(u, userID)
+		res = append(res, AddResult{Hash: hash, Exists: err != nil})
+	}
+	return res, nil
 }
 
-// CreateUser всегда возвращает -1 (in-memory хранилище не поддерживает пользователей).
+// CreateUser выдает следующий по порядку ID пользователя.
 // Пример:
 //
-//	userID, _ := store.CreateUser(ctx) // всегда возвращает -1
+//	userID, _ := store.CreateUser(ctx)
 func (s *MemStore) CreateUser(_ context.Context) (int, error) {
-	return -1, nil
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.lastUser++
+	return s.lastUser, nil
 }
 
-// BatchDelete всегда возвращает nil (in-memory хранилище не поддерживает удаление).
+// BatchDelete помечает URL пользователя как удаленные и исключает их из дедупликации.
+// Ссылки других пользователей не изменяются.
 // Пример:
 //
 //	err := store.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"abc"}})
-func (s *MemStore) BatchDelete(_ context.Context, _ UserHash) (err error) {
+func (s *MemStore) BatchDelete(_ context.Context, uh UserHash) (err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.delete(uh)
 	return nil
 }
 
+// delete помечает ссылки владельца в домене как удаленные и возвращает их.
+// Вызывается под блокировкой.
+func (s *MemStore) delete(uh UserHash) []URL {
+	now := time.Now()
+	var deleted []URL
+	for _, hash := range uh.Hash {
+		k := linkKey{uh.Domain, hash}
+		u, ok := s.s[k]
+		if !ok || u.UserID != uh.UserID || u.DeletedFlag {
+			continue
+		}
+		if origin, dedupe := s.origin(u, u.UserID); dedupe && s.originals[origin] == hash {
+			delete(s.originals, origin)
+		}
+		u.DeletedFlag = true
+		u.DeletedAt = now
+		s.s[k] = u
+		deleted = append(deleted, u)
+	}
+	return deleted
+}
+
+// EnqueueDeletion ставит задание на удаление ссылок пользователя в очередь.
+// Пример:
+//
+//	job, _ := store.EnqueueDeletion(ctx, UserHash{UserID: 1, Hash: []string{"abc"}})
+func (s *MemStore) EnqueueDeletion(_ context.Context, uh UserHash) (DeletionJob, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.lastJob++
+	job := DeletionJob{
+		ID:        s.lastJob,
+		UserID:    uh.UserID,
+		Domain:    uh.Domain,
+		Hashes:    slices.Clone(uh.Hash),
+		Status:    model.DeletionPending,
+		CreatedAt: time.Now(),
+	}
+	s.jobs[job.ID] = job
+	s.pending = append(s.pending, job.ID)
+	return job, nil
+}
+
+// ProcessDeletions выполняет до limit ожидающих заданий на удаление.
+// Пример:
+//
+//	n, deleted, _ := store.ProcessDeletions(ctx, 100)
+func (s *MemStore) ProcessDeletions(_ context.Context, limit int) (int, []URL, error) {
+	done, deleted := s.processDeletions(limit)
+	return len(done), deleted, nil
+}
+
+// processDeletions выполняет до limit ожидающих заданий и возвращает их в новом
+// состоянии вместе с удаленными ссылками.
+func (s *MemStore) processDeletions(limit int) ([]DeletionJob, []URL) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	n := min(limit, len(s.pending))
+	done := make([]DeletionJob, 0, n)
+	var deleted []URL
+	now := time.Now()
+	for _, id := range s.pending[:n] {
+		job := s.jobs[id]
+		deleted = append(deleted, s.delete(UserHash{UserID: job.UserID, Domain: job.Domain, Hash: job.Hashes})...)
+		job.Status = model.DeletionDone
+		job.CompletedAt = &now
+		s.jobs[id] = job
+		done = append(done, job)
+	}
+	s.pending = s.pending[n:]
+	return done, deleted
+}
+
+// GetDeletion возвращает задание на удаление пользователя.
+// Пример:
+//
+//	job, err := store.GetDeletion(ctx, 1, 1)
+func (s *MemStore) GetDeletion(_ context.Context, id int64, userID int) (DeletionJob, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return DeletionJob{}, ErrNotFound
+	}
+	return job, nil
+}
+
+// restoreJobs восстанавливает задания из журнала и очередь ожидающих заданий.
+// Вызывается при инициализации хранилища.
+func (s *MemStore) restoreJobs(jobs map[int64]DeletionJob) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for id, job := range jobs {
+		s.jobs[id] = job
+		s.lastJob = max(s.lastJob, id)
+		if job.Status == model.DeletionPending {
+			s.pending = append(s.pending, id)
+		}
+	}
+	slices.Sort(s.pending)
+}
+
 // Stats статистика по количеству ссылок в сервисе
 func (s *MemStore) Stats(_ context.Context) (model.Stats, error) {
-	return model.Stats{URLs: len(s.s), Users: 1}, nil
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	stats := model.Stats{Users: s.lastUser, Purged: len(s.purged)}
+	for _, u := range s.s {
+		if u.DeletedFlag {
+			stats.Deleted++
+		} else {
+			stats.URLs++
+		}
+	}
+	return stats, nil
+}
+
+// Restore восстанавливает ссылки пользователя, удаленные не ранее since.
+// Пример:
+//
+//	res, _ := store.Restore(ctx, UserHash{UserID: 1, Hash: []string{"abc"}}, since)
+func (s *MemStore) Restore(_ context.Context, uh UserHash, since time.Time) ([]model.RestoreResult, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]model.RestoreResult, 0, len(uh.Hash))
+	for _, hash := range uh.Hash {
+		r := model.RestoreResult{Hash: hash, Status: model.RestoreNotFound}
+		k := linkKey{uh.Domain, hash}
+		u, ok := s.s[k]
+		if ok && u.UserID == uh.UserID && u.DeletedFlag && !u.DeletedAt.Before(since) {
+			origin, dedupe := s.origin(u, u.UserID)
+			if _, exists := s.originals[origin]; dedupe && exists {
+				r.Status = model.RestoreConflict
+			} else {
+				if dedupe {
+					s.originals[origin] = hash
+				}
+				u.DeletedFlag = false
+				u.DeletedAt = time.Time{}
+				s.s[k] = u
+				r.Status = model.RestoreRestored
+			}
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// Purge окончательно удаляет ссылки, удаленные раньше before.
+// Пример:
+//
+//	n, _ := store.Purge(ctx, time.Now().Add(-time.Hour), false)
+func (s *MemStore) Purge(_ context.Context, before time.Time, freeHash bool) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	now := time.Now()
+	n := 0
+	for k, u := range s.s {
+		if !u.DeletedFlag || !u.DeletedAt.Before(before) {
+			continue
+		}
+		delete(s.s, k)
+		delete(s.revisions, k)
+		delete(s.experiments, u.Experiment)
+		s.purged[k] = purgedLink{userID: u.UserID, purgedAt: now, free: freeHash}
+		n++
+	}
+	return n, nil
+}
+
+// Update изменяет ссылку пользователя, сохраняя предыдущее состояние в истории.
+// Пример:
+//
+//	_, err := store.Update(ctx, URL{Hash: "abc", Link: "https://example.org"}, 1)
+func (s *MemStore) Update(_ context.Context, url URL, userID int) (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	k := linkKey{url.Domain, url.Hash}
+	cur, ok := s.s[k]
+	if !ok || cur.UserID != userID || cur.DeletedFlag {
+		return url.Hash, ErrNotFound
+	}
+	prev, wasDeduped := s.origin(cur, userID)
+	next, dedupe := s.origin(url, userID)
+	if dedupe && (!wasDeduped || prev != next) {
+		if existing, ok := s.originals[next]; ok {
+			return existing, ErrExistsURL
+		}
+	}
+	if wasDeduped && s.originals[prev] == url.Hash {
+		delete(s.originals, prev)
+	}
+	if dedupe {
+		s.originals[next] = url.Hash
+	}
+	revs := s.revisions[k]
+	s.revisions[k] = append(revs, Revision{
+		Number:   len(revs) + 1,
+		Link:     cur.Link,
+		Title:    cur.Title,
+		Tags:     cur.Tags,
+		Note:     cur.Note,
+		Redirect: cur.Redirect,
+		Rules:    cur.Rules,
+		EditedAt: time.Now(),
+	})
+	if cur.Link != url.Link {
+		cur.Health = model.LinkHealth{}
+	}
+	cur.Link = url.Link
+	cur.Title = url.Title
+	cur.Tags = url.Tags
+	cur.Note = url.Note
+	cur.PasswordHash = url.PasswordHash
+	cur.Redirect = url.Redirect
+	cur.Rules = url.Rules
+	s.s[k] = cur
+	return url.Hash, nil
+}
+
+// GetRevisions возвращает историю изменений ссылки пользователя в домене (новые первыми).
+// Пример:
+//
+//	revs, err := store.GetRevisions(ctx, "", "abc", 1)
+func (s *MemStore) GetRevisions(_ context.Context, domain, hash string, userID int) ([]Revision, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	k := linkKey{domain, hash}
+	cur, ok := s.s[k]
+	if !ok || cur.UserID != userID {
+		return nil, ErrNotFound
+	}
+	res := slices.Clone(s.revisions[k])
+	slices.Reverse(res)
+	return res, nil
+}
+
+// AddExperiment создает эксперимент и его короткую ссылку.
+// Пример:
+//
+//	e, err := store.AddExperiment(ctx, Experiment{Hash: "abc", Variants: variants}, 1)
+func (s *MemStore) AddExperiment(_ context.Context, e Experiment, userID int) (Experiment, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.hashTaken(linkKey{e.Domain, e.Hash}) {
+		return Experiment{}, fmt.Errorf("%w: %q", ErrHashTaken, e.Hash)
+	}
+	s.lastExperiment++
+	e = s.addExperiment(e, s.lastExperiment, userID)
+	_, err := s.add(URL{
+		Link:       e.Variants[0].URL,
+		Hash:       e.Hash,
+		Domain:     e.Domain,
+		Title:      e.Name,
+		Experiment: e.ID,
+		CreatedAt:  e.CreatedAt,
+	}, userID)
+	return e, err
+}
+
+// addExperiment сохраняет эксперимент с идентификатором id. Вызывается под блокировкой.
+func (s *MemStore) addExperiment(e Experiment, id int64, userID int) Experiment {
+	e.ID = id
+	e.UserID = userID
+	e.Variants = slices.Clone(e.Variants)
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	s.experiments[id] = e
+	s.lastExperiment = max(s.lastExperiment, id)
+	return e
+}
+
+// GetExperiment возвращает эксперимент по идентификатору.
+// Пример:
+//
+//	e, err := store.GetExperiment(ctx, 1)
+func (s *MemStore) GetExperiment(_ context.Context, id int64) (Experiment, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e, ok := s.experiments[id]
+	if !ok {
+		return Experiment{}, ErrNotFound
+	}
+	e.Variants = slices.Clone(e.Variants)
+	return e, nil
+}
+
+// ListExperiments возвращает эксперименты пользователя (новые первыми).
+// Пример:
+//
+//	list, _ := store.ListExperiments(ctx, 1)
+func (s *MemStore) ListExperiments(_ context.Context, userID int) ([]Experiment, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]Experiment, 0)
+	for _, e := range s.experiments {
+		if e.UserID == userID {
+			e.Variants = slices.Clone(e.Variants)
+			res = append(res, e)
+		}
+	}
+	slices.SortFunc(res, func(a, b Experiment) int { return cmp.Compare(b.ID, a.ID) })
+	return res, nil
+}
+
+// AddVariantRedirect увеличивает счетчик перенаправлений на вариант эксперимента.
+// Пример:
+//
+//	_ = store.AddVariantRedirect(ctx, 1, 0)
+func (s *MemStore) AddVariantRedirect(_ context.Context, id int64, variant int) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e, ok := s.experiments[id]
+	if !ok || variant < 0 || variant >= len(e.Variants) {
+		return ErrNotFound
+	}
+	// Варианты не копируются при чтении из карты, поэтому счетчик
+	// увеличивается в общем срезе эксперимента.
+	e.Variants[variant].Redirects++
+	return nil
+}
+
+// AddWebhook сохраняет подписку на события ссылок.
+// Пример:
+//
+//	w, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://hooks.example.com/", Active: true})
+func (s *MemStore) AddWebhook(_ context.Context, w Webhook) (Webhook, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.lastWebhook++
+	w.ID = s.lastWebhook
+	w.Events = slices.Clone(w.Events)
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	s.webhooks[w.ID] = w
+	return w, nil
+}
+
+// GetWebhook возвращает подписку по идентификатору.
+// Пример:
+//
+//	w, err := store.GetWebhook(ctx, 1)
+func (s *MemStore) GetWebhook(_ context.Context, id int64) (Webhook, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	w, ok := s.webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	w.Events = slices.Clone(w.Events)
+	return w, nil
+}
+
+// ListWebhooks возвращает подписки пользователя в порядке создания.
+// Пример:
+//
+//	list, _ := store.ListWebhooks(ctx, 1)
+func (s *MemStore) ListWebhooks(_ context.Context, userID int) ([]Webhook, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]Webhook, 0)
+	for _, w := range s.webhooks {
+		if w.UserID == userID {
+			w.Events = slices.Clone(w.Events)
+			res = append(res, w)
+		}
+	}
+	slices.SortFunc(res, func(a, b Webhook) int { return cmp.Compare(a.ID, b.ID) })
+	return res, nil
+}
+
+// UpdateWebhook заменяет параметры подписки.
+// Пример:
+//
+//	err := store.UpdateWebhook(ctx, w)
+func (s *MemStore) UpdateWebhook(_ context.Context, w Webhook) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.updateWebhook(w)
+	return err
+}
+
+// updateWebhook заменяет параметры подписки и возвращает ее новое состояние.
+// Вызывается под блокировкой.
+func (s *MemStore) updateWebhook(w Webhook) (Webhook, error) {
+	cur, ok := s.webhooks[w.ID]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	cur.URL = w.URL
+	cur.Secret = w.Secret
+	cur.Events = slices.Clone(w.Events)
+	cur.Active = w.Active
+	s.webhooks[w.ID] = cur
+	return cur, nil
+}
+
+// DeleteWebhook удаляет подписку и ее доставки.
+// Пример:
+//
+//	err := store.DeleteWebhook(ctx, 1)
+func (s *MemStore) DeleteWebhook(_ context.Context, id int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.deleteWebhook(id)
+}
+
+// deleteWebhook удаляет подписку и ее доставки. Вызывается под блокировкой.
+func (s *MemStore) deleteWebhook(id int64) error {
+	if _, ok := s.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.webhooks, id)
+	for did, d := range s.deliveries {
+		if d.WebhookID == id {
+			delete(s.deliveries, did)
+		}
+	}
+	return nil
+}
+
+// AddDelivery сохраняет доставку события подписке.
+// Пример:
+//
+//	d, err := store.AddDelivery(ctx, Delivery{WebhookID: 1, Status: model.DeliveryPending})
+func (s *MemStore) AddDelivery(_ context.Context, d Delivery) (Delivery, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.webhooks[d.WebhookID]; !ok {
+		return Delivery{}, ErrNotFound
+	}
+	s.lastDelivery++
+	d.ID = s.lastDelivery
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	s.deliveries[d.ID] = d
+	return d, nil
+}
+
+// PendingDeliveries возвращает ожидающие доставки, время попытки которых наступило.
+// Пример:
+//
+//	list, _ := store.PendingDeliveries(ctx, time.Now(), 100)
+func (s *MemStore) PendingDeliveries(_ context.Context, now time.Time, limit int) ([]Delivery, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]Delivery, 0)
+	for _, d := range s.deliveries {
+		if d.Status == model.DeliveryPending && !d.NextAttemptAt.After(now) {
+			res = append(res, d)
+		}
+	}
+	slices.SortFunc(res, func(a, b Delivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// UpdateDelivery сохраняет результат попытки доставки.
+// Пример:
+//
+//	err := store.UpdateDelivery(ctx, d)
+func (s *MemStore) UpdateDelivery(_ context.Context, d Delivery) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.updateDelivery(d)
+	return err
+}
+
+// updateDelivery сохраняет результат попытки доставки и возвращает ее новое состояние.
+// Вызывается под блокировкой.
+func (s *MemStore) updateDelivery(d Delivery) (Delivery, error) {
+	cur, ok := s.deliveries[d.ID]
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+	cur.Status = d.Status
+	cur.Attempts = d.Attempts
+	cur.ResponseStatus = d.ResponseStatus
+	cur.Error = d.Error
+	cur.LastAttemptAt = d.LastAttemptAt
+	cur.NextAttemptAt = d.NextAttemptAt
+	s.deliveries[d.ID] = cur
+	return cur, nil
+}
+
+// ListDeliveries возвращает последние доставки подписки (новые первыми).
+// Пример:
+//
+//	list, _ := store.ListDeliveries(ctx, 1, 100)
+func (s *MemStore) ListDeliveries(_ context.Context, webhookID int64, limit int) ([]Delivery, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := make([]Delivery, 0)
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			res = append(res, d)
+		}
+	}
+	slices.SortFunc(res, func(a, b Delivery) int { return cmp.Compare(b.ID, a.ID) })
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// PurgeDeliveries удаляет завершенные доставки, созданные раньше before.
+// Пример:
+//
+//	n, _ := store.PurgeDeliveries(ctx, time.Now().Add(-7*24*time.Hour))
+func (s *MemStore) PurgeDeliveries(_ context.Context, before time.Time) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	n := 0
+	for id, d := range s.deliveries {
+		if d.Status != model.DeliveryPending && d.CreatedAt.Before(before) {
+			delete(s.deliveries, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// AddIdempotencyKey сохраняет ключ идемпотентности, если у пользователя нет действующего
+// ключа с тем же значением; иначе возвращает его и ErrExistsKey.
+// Пример:
+//
+//	existing, err := store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 1, Key: "key", ExpiresAt: exp})
+func (s *MemStore) AddIdempotencyKey(_ context.Context, k IdempotencyKey) (IdempotencyKey, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	id := idempotencyKey{userID: k.UserID, key: k.Key}
+	if existing, ok := s.idempotency[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		return existing, ErrExistsKey
+	}
+	k.Status, k.Header, k.Body = 0, nil, nil
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+	s.idempotency[id] = k
+	return k, nil
+}
+
+// SetIdempotencyResponse сохраняет ответ для ключа идемпотентности.
+// Пример:
+//
+//	err := store.SetIdempotencyResponse(ctx, IdempotencyKey{UserID: 1, Key: "key", Status: 201, Body: body})
+func (s *MemStore) SetIdempotencyResponse(_ context.Context, k IdempotencyKey) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	id := idempotencyKey{userID: k.UserID, key: k.Key}
+	stored, ok := s.idempotency[id]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status = k.Status
+	stored.Header = maps.Clone(k.Header)
+	stored.Body = slices.Clone(k.Body)
+	s.idempotency[id] = stored
+	return nil
+}
+
+// DeleteIdempotencyKey удаляет ключ идемпотентности пользователя.
+// Пример:
+//
+//	err := store.DeleteIdempotencyKey(ctx, 1, "key")
+func (s *MemStore) DeleteIdempotencyKey(_ context.Context, userID int, key string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.idempotency, idempotencyKey{userID: userID, key: key})
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет ключи идемпотентности, истекшие раньше before.
+// Пример:
+//
+//	n, _ := store.PurgeIdempotencyKeys(ctx, time.Now())
+func (s *MemStore) PurgeIdempotencyKeys(_ context.Context, before time.Time) (int, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	n := 0
+	for id, k := range s.idempotency {
+		if k.ExpiresAt.Before(before) {
+			delete(s.idempotency, id)
+			n++
+		}
+	}
+	return n, nil
 }
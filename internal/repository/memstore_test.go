@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spitfy/urlshortener/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemStore_DedupeScope(t *testing.T) {
+	const link = "https://example.com/"
+	tests := []struct {
+		name        string
+		scope       DedupeScope
+		sameUser    error
+		otherUser   error
+		afterDelete error
+	}{
+		{"global", DedupeGlobal, ErrExistsURL, ErrExistsURL, nil},
+		{"user", DedupeUser, ErrExistsURL, nil, nil},
+		{"none", DedupeNone, nil, nil, nil},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStoreWithScope(tt.scope)
+			_, err := store.Add(ctx, URL{Hash: "AAAAAAAA", Link: link}, 1)
+			require.NoError(t, err)
+
+			hash, err := store.Add(ctx, URL{Hash: "BBBBBBBB", Link: link}, 1)
+			assert.ErrorIs(t, err, tt.sameUser)
+			if tt.sameUser != nil {
+				assert.Equal(t, "AAAAAAAA", hash, "existing hash must be returned")
+			}
+
+			_, err = store.Add(ctx, URL{Hash: "CCCCCCCC", Link: link}, 2)
+			assert.ErrorIs(t, err, tt.otherUser)
+
+			require.NoError(t, store.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"AAAAAAAA"}}))
+			_, err = store.Add(ctx, URL{Hash: "DDDDDDDD", Link: link}, 1)
+			assert.ErrorIs(t, err, tt.afterDelete, "deleted link must not be reused")
+		})
+	}
+}
+
+func TestMemStore_BatchDeleteOwnLinksOnly(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStoreWithScope(DedupeUser)
+	_, _ = store.Add(ctx, URL{Hash: "OWNED001", Link: "https://a.example/"}, 1)
+	_, _ = store.Add(ctx, URL{Hash: "FOREIGN1", Link: "https://b.example/"}, 2)
+
+	require.NoError(t, store.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"OWNED001", "FOREIGN1"}}))
+
+	owned, _ := store.GetByHash(ctx, "", "OWNED001")
+	foreign, _ := store.GetByHash(ctx, "", "FOREIGN1")
+	assert.True(t, owned.DeletedFlag)
+	assert.False(t, foreign.DeletedFlag)
+
+	links, err := store.GetByUserID(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, links, 1)
+}
+
+func TestMemStore_Domains(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStoreWithScope(DedupeGlobal)
+	_, err := store.Add(ctx, URL{Hash: "SAMEHASH", Link: "https://a.example/"}, 1)
+	require.NoError(t, err)
+	_, err = store.Add(ctx, URL{Hash: "SAMEHASH", Domain: "go.example", Link: "https://a.example/"}, 1)
+	require.NoError(t, err, "hash and url are unique per domain")
+	_, err = store.Add(ctx, URL{Hash: "SAMEHASH", Domain: "go.example", Link: "https://b.example/"}, 1)
+	assert.Error(t, err)
+
+	u, err := store.GetByHash(ctx, "go.example", "SAMEHASH")
+	require.NoError(t, err)
+	assert.Equal(t, "go.example", u.Domain)
+
+	require.NoError(t, store.BatchDelete(ctx, UserHash{UserID: 1, Domain: "go.example", Hash: []string{"SAMEHASH"}}))
+	u, _ = store.GetByHash(ctx, "", "SAMEHASH")
+	assert.False(t, u.DeletedFlag, "deletion is limited to the domain")
+	u, _ = store.GetByHash(ctx, "go.example", "SAMEHASH")
+	assert.True(t, u.DeletedFlag)
+}
+
+func TestParseDedupeScope(t *testing.T) {
+	scope, err := ParseDedupeScope("")
+	require.NoError(t, err)
+	assert.Equal(t, DedupeGlobal, scope)
+
+	_, err = ParseDedupeScope("tenant")
+	assert.Error(t, err)
+}
+
+func TestMemStore_BatchAdd(t *testing.T) {
+	ctx := context.Background()
+	s := newMemStore()
+	_, err := s.Add(ctx, URL{Hash: "old", Link: "https://example.com/a"}, 1)
+	require.NoError(t, err)
+
+	res, err := s.BatchAdd(ctx, []URL{
+		{Hash: "h1", Link: "https://example.com/a"},
+		{Hash: "h2", Link: "https://example.com/b"},
+		{Hash: "h3", Link: "https://example.com/b"},
+	}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []AddResult{
+		{Hash: "old", Exists: true},
+		{Hash: "h2"},
+		{Hash: "h2", Exists: true},
+	}, res)
+
+	_, err = s.BatchAdd(ctx, []URL{
+		{Hash: "h4", Link: "https://example.com/c"},
+		{Hash: "h2", Link: "https://example.com/d"},
+	}, 1)
+	assert.Error(t, err, "hash collision rejects the whole batch")
+	_, err = s.GetByHash(ctx, "", "h4")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemStore_DeletionJobs(t *testing.T) {
+	ctx := context.Background()
+	s := newMemStore()
+	_, _ = s.Add(ctx, URL{Hash: "a", Link: "https://example.com/a"}, 1)
+	_, _ = s.Add(ctx, URL{Hash: "b", Link: "https://example.com/b"}, 2)
+
+	j1, err := s.EnqueueDeletion(ctx, UserHash{UserID: 1, Hash: []string{"a"}})
+	require.NoError(t, err)
+	j2, err := s.EnqueueDeletion(ctx, UserHash{UserID: 2, Hash: []string{"b", "a", "missing"}})
+	require.NoError(t, err)
+	assert.Equal(t, model.DeletionPending, j1.Status)
+
+	_, err = s.GetDeletion(ctx, j1.ID, 2)
+	assert.ErrorIs(t, err, ErrNotFound, "job of another user is not visible")
+
+	n, deleted, err := s.ProcessDeletions(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "pending jobs are coalesced into one pass")
+	require.Len(t, deleted, 2, "links of other users and missing links are skipped")
+	for _, u := range deleted {
+		assert.Equal(t, map[string]int{"a": 1, "b": 2}[u.Hash], u.UserID)
+	}
+
+	for _, j := range []DeletionJob{j1, j2} {
+		got, err := s.GetDeletion(ctx, j.ID, j.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, model.DeletionDone, got.Status)
+		assert.NotNil(t, got.CompletedAt)
+		u, _ := s.GetByHash(ctx, "", j.Hashes[0])
+		assert.True(t, u.DeletedFlag)
+	}
+
+	n, deleted, _ = s.ProcessDeletions(ctx, 10)
+	assert.Zero(t, n)
+	assert.Empty(t, deleted)
+}
+
+func TestMemStore_RestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	s := newMemStore()
+	_, _ = s.Add(ctx, URL{Hash: "a", Link: "https://example.com/a"}, 1)
+	_, _ = s.Add(ctx, URL{Hash: "b", Link: "https://example.com/b"}, 1)
+	require.NoError(t, s.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"a", "b"}}))
+	since := time.Now().Add(-time.Hour)
+
+	// Оригинальный URL ссылки b сокращен заново после удаления.
+	_, err := s.Add(ctx, URL{Hash: "b2", Link: "https://example.com/b"}, 1)
+	require.NoError(t, err)
+
+	res, err := s.Restore(ctx, UserHash{UserID: 1, Hash: []string{"a", "b", "missing"}}, since)
+	require.NoError(t, err)
+	assert.Equal(t, []model.RestoreResult{
+		{Hash: "a", Status: model.RestoreRestored},
+		{Hash: "b", Status: model.RestoreConflict},
+		{Hash: "missing", Status: model.RestoreNotFound},
+	}, res)
+
+	res, _ = s.Restore(ctx, UserHash{UserID: 1, Hash: []string{"b"}}, time.Now().Add(time.Hour))
+	assert.Equal(t, model.RestoreNotFound, res[0].Status, "grace period expired")
+
+	stats, _ := s.Stats(ctx)
+	assert.Equal(t, model.Stats{URLs: 2, Deleted: 1}, stats)
+
+	n, err := s.Purge(ctx, time.Now().Add(time.Hour), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	stats, _ = s.Stats(ctx)
+	assert.Equal(t, model.Stats{URLs: 2, Purged: 1}, stats)
+
+	u, err := s.GetByHash(ctx, "", "b")
+	require.NoError(t, err)
+	assert.True(t, u.DeletedFlag, "purged link stays gone")
+	_, err = s.Add(ctx, URL{Hash: "b", Link: "https://example.com/c"}, 1)
+	assert.Error(t, err, "purged hash is reserved")
+}
+
+func TestMemStore_PurgeFreeHash(t *testing.T) {
+	ctx := context.Background()
+	s := newMemStore()
+	_, _ = s.Add(ctx, URL{Hash: "a", Link: "https://example.com/a"}, 1)
+	require.NoError(t, s.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"a"}}))
+
+	n, err := s.Purge(ctx, time.Now().Add(time.Hour), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = s.GetByHash(ctx, "", "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Add(ctx, URL{Hash: "a", Link: "https://example.com/new"}, 2)
+	assert.NoError(t, err, "purged hash can be reused")
+}
+
+func TestMemStore_ListByUser(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStoreWithScope(DedupeNone)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	links := []URL{
+		{Hash: "AAAAAAAA", Link: "https://shop.example/spring-sale", Title: "Spring sale", Tags: []string{"promo", "spring"}},
+		{Hash: "BBBBBBBB", Link: "https://blog.example/go", Title: "Go blog", Note: "read later", Tags: []string{"go"}},
+		{Hash: "CCCCCCCC", Link: "https://shop.example/winter", Tags: []string{"promo"}},
+		{Hash: "DDDDDDDD", Link: "https://other.example/", Title: "Spring sale"},
+	}
+	for i, u := range links {
+		u.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		userID := 1
+		if u.Hash == "DDDDDDDD" {
+			userID = 2
+		}
+		_, err := store.Add(ctx, u, userID)
+		require.NoError(t, err)
+	}
+	for range 3 {
+		require.NoError(t, store.AddClick(ctx, "", "BBBBBBBB"))
+	}
+	require.NoError(t, store.AddClick(ctx, "", "AAAAAAAA"))
+	assert.ErrorIs(t, store.AddClick(ctx, "", "MISSING1"), ErrNotFound)
+
+	hashes := func(urls []URL) []string {
+		res := make([]string, 0, len(urls))
+		for _, u := range urls {
+			res = append(res, u.Hash)
+		}
+		return res
+	}
+	tests := []struct {
+		name string
+		q    LinkQuery
+		want []string
+	}{
+		{"newest first", LinkQuery{}, []string{"CCCCCCCC", "BBBBBBBB", "AAAAAAAA"}},
+		{"oldest first", LinkQuery{Asc: true}, []string{"AAAAAAAA", "BBBBBBBB", "CCCCCCCC"}},
+		{"by clicks", LinkQuery{Sort: SortClicks}, []string{"BBBBBBBB", "AAAAAAAA", "CCCCCCCC"}},
+		{"search title", LinkQuery{Search: "SPRING"}, []string{"AAAAAAAA"}},
+		{"search note and url", LinkQuery{Search: "later blog"}, []string{"BBBBBBBB"}},
+		{"search words only", LinkQuery{Search: "sal"}, []string{}},
+		{"tags", LinkQuery{Tags: []string{"promo"}}, []string{"CCCCCCCC", "AAAAAAAA"}},
+		{"all tags", LinkQuery{Tags: []string{"promo", "spring"}}, []string{"AAAAAAAA"}},
+		{"limit", LinkQuery{Limit: 2}, []string{"CCCCCCCC", "BBBBBBBB"}},
+		{"after cursor", LinkQuery{After: &LinkCursor{CreatedAt: start.Add(time.Hour), Hash: "BBBBBBBB"}}, []string{"AAAAAAAA"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := store.ListByUser(ctx, 1, tt.q)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, hashes(urls))
+		})
+	}
+
+	require.NoError(t, store.BatchDelete(ctx, UserHash{UserID: 1, Hash: []string{"CCCCCCCC"}}))
+	urls, err := store.ListByUser(ctx, 1, LinkQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BBBBBBBB", "AAAAAAAA"}, hashes(urls), "deleted links are not listed")
+}
+
+func TestMemStore_AddClickLimit(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	_, _ = store.Add(ctx, URL{Hash: "LIMITED1", Link: "https://example.com/", MaxClicks: 10}, 1)
+	_, err := store.Add(ctx, URL{Hash: "LIMITED2", Link: "https://example.com/", MaxClicks: 10}, 1)
+	require.NoError(t, err, "limited links are never deduplicated")
+
+	var wg sync.WaitGroup
+	var ok atomic.Int64
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if store.AddClick(ctx, "", "LIMITED1") == nil {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(10), ok.Load())
+	assert.ErrorIs(t, store.AddClick(ctx, "", "LIMITED1"), ErrExhausted)
+
+	u, _ := store.GetByHash(ctx, "", "LIMITED1")
+	assert.True(t, u.Exhausted())
+	assert.Equal(t, int64(10), u.Clicks)
+}
+
+func TestMemStore_SetTitle(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	_, _ = store.Add(ctx, URL{Hash: "UNTITLED", Link: "https://a.example/"}, 1)
+	_, _ = store.Add(ctx, URL{Hash: "TITLED01", Link: "https://b.example/", Title: "Mine"}, 1)
+
+	require.NoError(t, store.SetTitle(ctx, "", "UNTITLED", "Fetched"))
+	require.NoError(t, store.SetTitle(ctx, "", "TITLED01", "Fetched"))
+	assert.ErrorIs(t, store.SetTitle(ctx, "", "MISSING1", "Fetched"), ErrNotFound)
+
+	u, _ := store.GetByHash(ctx, "", "UNTITLED")
+	assert.Equal(t, "Fetched", u.Title)
+	u, _ = store.GetByHash(ctx, "", "TITLED01")
+	assert.Equal(t, "Mine", u.Title, "title set by user must not be overwritten")
+	revs, _ := store.GetRevisions(ctx, "", "UNTITLED", 1)
+	assert.Empty(t, revs)
+}
+
+func TestMemStore_IdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	k := IdempotencyKey{UserID: 1, Key: "key", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)}
+
+	_, err := store.AddIdempotencyKey(ctx, k)
+	require.NoError(t, err)
+	_, err = store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 2, Key: "key", ExpiresAt: k.ExpiresAt})
+	require.NoError(t, err, "keys are scoped to the user")
+
+	require.NoError(t, store.SetIdempotencyResponse(ctx, IdempotencyKey{UserID: 1, Key: "key", Status: 201, Body: []byte("ok")}))
+	existing, err := store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 1, Key: "key", Fingerprint: "b", ExpiresAt: k.ExpiresAt})
+	assert.ErrorIs(t, err, ErrExistsKey)
+	assert.Equal(t, "a", existing.Fingerprint)
+	assert.Equal(t, 201, existing.Status)
+	assert.Equal(t, []byte("ok"), existing.Body)
+	assert.ErrorIs(t, store.SetIdempotencyResponse(ctx, IdempotencyKey{UserID: 3, Key: "key"}), ErrNotFound)
+
+	_, err = store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 3, Key: "old", ExpiresAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	replaced, err := store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 3, Key: "old", Fingerprint: "c", ExpiresAt: k.ExpiresAt})
+	require.NoError(t, err, "an expired key is replaced")
+	assert.Equal(t, "c", replaced.Fingerprint)
+
+	_, err = store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 4, Key: "old", ExpiresAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	n, err := store.PurgeIdempotencyKeys(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, store.DeleteIdempotencyKey(ctx, 1, "key"))
+	_, err = store.AddIdempotencyKey(ctx, k)
+	assert.NoError(t, err)
+}
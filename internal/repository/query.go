@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Поля сортировки ссылок пользователя.
+const (
+	SortCreated = "created" // По времени создания
+	SortClicks  = "clicks"  // По количеству переходов
+)
+
+// LinkQuery задает выборку ссылок пользователя: поиск, фильтр по тегам,
+// сортировку и позицию, после которой начинается страница.
+// Пример:
+//
+//	q := LinkQuery{Search: "spring sale", Tags: []string{"promo"}, Sort: SortClicks, Limit: 50}
+type LinkQuery struct {
+	Search string      // Слова, которые должны встречаться в заголовке, заметке или адресе ссылки
+	Tags   []string    // Теги, которые должны быть у ссылки (все перечисленные)
+	Broken bool        // Только ссылки, адрес назначения которых недоступен
+	Sort   string      // Поле сортировки: SortCreated (по умолчанию) или SortClicks
+	Asc    bool        // Сортировка по возрастанию; по умолчанию — по убыванию
+	After  *LinkCursor // Позиция последней ссылки предыдущей страницы; nil — с начала
+	Limit  int         // Максимальное количество ссылок; 0 — без ограничения
+}
+
+// LinkCursor описывает позицию ссылки в выборке: значения полей сортировки.
+// Домен и хеш однозначно упорядочивают ссылки с одинаковым значением поля сортировки.
+// Пример:
+//
+//	next := CursorOf(urls[len(urls)-1])
+type LinkCursor struct {
+	CreatedAt time.Time // Время создания ссылки
+	Clicks    int64     // Количество переходов по ссылке
+	Domain    string    // Домен ссылки
+	Hash      string    // Хеш ссылки
+}
+
+// CursorOf возвращает позицию ссылки в выборке.
+func CursorOf(u URL) LinkCursor {
+	return LinkCursor{CreatedAt: u.CreatedAt, Clicks: u.Clicks, Domain: u.Domain, Hash: u.Hash}
+}
+
+// compare сравнивает ссылки в порядке выборки.
+func (q LinkQuery) compare(a, b URL) int {
+	var c int
+	if q.Sort == SortClicks {
+		c = cmp.Compare(a.Clicks, b.Clicks)
+	} else {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	c = cmp.Or(c, cmp.Compare(a.Domain, b.Domain), cmp.Compare(a.Hash, b.Hash))
+	if !q.Asc {
+		return -c
+	}
+	return c
+}
+
+// match сообщает, подходит ли ссылка под поиск и фильтры по тегам и доступности.
+// words — слова поиска, полученные searchWords.
+func (q LinkQuery) match(u URL, words []string) bool {
+	if q.Broken && !u.Health.Broken {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !slices.Contains(u.Tags, tag) {
+			return false
+		}
+	}
+	if len(words) == 0 {
+		return true
+	}
+	text := searchWords(u.Title + " " + u.Note + " " + u.Link)
+	for _, w := range words {
+		if !slices.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// after сообщает, следует ли ссылка в выборке за позицией q.After.
+func (q LinkQuery) after(u URL) bool {
+	if q.After == nil {
+		return true
+	}
+	c := q.After
+	return q.compare(URL{CreatedAt: c.CreatedAt, Clicks: c.Clicks, Domain: c.Domain, Hash: c.Hash}, u) < 0
+}
+
+// searchWords разбивает текст на слова в нижнем регистре: словом считается
+// последовательность букв и цифр. Так же индексируется текст в PostgreSQL,
+// поэтому поиск во всех хранилищах находит одни и те же ссылки.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
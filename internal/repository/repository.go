@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"time"
+
 	"github.com/spitfy/urlshortener/internal/model"
 
 	"github.com/spitfy/urlshortener/internal/config"
+	"github.com/spitfy/urlshortener/internal/service/fault"
 )
 
 // URL представляет структуру для хранения информации о сокращенной ссылке.
@@ -17,9 +19,165 @@ import (
 //	    DeletedFlag: false,
 //	}
 type URL struct {
-	Link        string // Оригинальный URL
-	Hash        string // Сокращенный идентификатор
-	DeletedFlag bool   // Флаг удаления (soft delete)
+	Link         string                // Оригинальный URL
+	Hash         string                // Сокращенный идентификатор (уникален в пределах домена)
+	Domain       string                // Домен ссылки; пустой для домена по умолчанию
+	DeletedFlag  bool                  // Флаг удаления (soft delete)
+	Redirect     model.RedirectOptions // Параметры перенаправления
+	UserID       int                   // Идентификатор владельца ссылки
+	Title        string                // Заголовок ссылки
+	Tags         []string              // Теги ссылки
+	Note         string                // Заметка владельца ссылки
+	CreatedAt    time.Time             // Время создания ссылки
+	Clicks       int64                 // Количество переходов по ссылке
+	PasswordHash string                // Bcrypt-хеш пароля ссылки; пустой, если пароль не задан
+	MaxClicks    int64                 // Допустимое число переходов; 0 — без ограничения
+	Rules        []model.RouteRule     // Правила выбора адреса перенаправления
+	Experiment   int64                 // Идентификатор A/B-эксперимента ссылки; 0 — обычная ссылка
+	Health       model.LinkHealth      // Результат последней проверки адреса назначения; нулевой, если проверки не было
+	DeletedAt    time.Time             // Время удаления (для удаленных ссылок)
+}
+
+// Exhausted сообщает, исчерпан ли лимит переходов по ссылке.
+// Пример:
+//
+//	if u.Exhausted() {
+//	    w.WriteHeader(http.StatusGone)
+//	}
+func (u URL) Exhausted() bool {
+	return u.MaxClicks > 0 && u.Clicks >= u.MaxClicks
+}
+
+// deduplicated сообщает, участвует ли ссылка в дедупликации. Защищенные паролем,
+// ограниченные по числу переходов ссылки и ссылки экспериментов всегда создаются
+// заново: иначе пользователь получил бы чужой пароль, общий с другими лимит
+// или чужой эксперимент.
+func (u URL) deduplicated() bool {
+	return u.PasswordHash == "" && u.MaxClicks == 0 && u.Experiment == 0
+}
+
+// Experiment описывает A/B-эксперимент: короткую ссылку Domain/Hash,
+// распределяющую посетителей между вариантами по весам.
+// Пример:
+//
+//	e := Experiment{
+//	    Hash: "abc123",
+//	    Name: "landing",
+//	    Variants: []Variant{
+//	        {Name: "A", URL: "https://example.com/a", Weight: 70},
+//	        {Name: "B", URL: "https://example.com/b", Weight: 30},
+//	    },
+//	}
+type Experiment struct {
+	ID        int64     // Идентификатор эксперимента
+	UserID    int       // Идентификатор владельца эксперимента
+	Domain    string    // Домен короткой ссылки эксперимента
+	Hash      string    // Хеш короткой ссылки эксперимента
+	Name      string    // Название эксперимента
+	Variants  []Variant // Варианты в порядке создания
+	CreatedAt time.Time // Время создания эксперимента
+}
+
+// Variant описывает вариант эксперимента.
+// Пример:
+//
+//	v := Variant{Name: "A", URL: "https://example.com/a", Weight: 70}
+type Variant struct {
+	Name      string // Название варианта
+	URL       string // Адрес перенаправления
+	Weight    int    // Вес варианта
+	Redirects int64  // Количество перенаправлений на вариант
+}
+
+// Webhook описывает подписку пользователя на события его ссылок.
+// Пример:
+//
+//	w := Webhook{
+//	    UserID: 1,
+//	    URL:    "https://hooks.example.com/shortener",
+//	    Secret: "s3cr3t",
+//	    Events: []string{model.WebhookCreated},
+//	    Active: true,
+//	}
+type Webhook struct {
+	ID        int64     // Идентификатор подписки
+	UserID    int       // Идентификатор владельца подписки
+	URL       string    // Адрес получателя событий
+	Secret    string    // Ключ подписи HMAC-SHA256
+	Events    []string  // События подписки (model.WebhookCreated и т. д.)
+	Active    bool      // Доставка включена
+	CreatedAt time.Time // Время создания подписки
+}
+
+// Delivery описывает доставку события подписке и ее состояние.
+// Пример:
+//
+//	d := Delivery{
+//	    WebhookID:     3,
+//	    Payload:       model.WebhookEvent{Event: model.WebhookCreated, ShortURL: "http://localhost:8080/abc"},
+//	    Status:        model.DeliveryPending,
+//	    NextAttemptAt: time.Now(),
+//	}
+type Delivery struct {
+	ID             int64              // Идентификатор доставки
+	WebhookID      int64              // Идентификатор подписки
+	Payload        model.WebhookEvent // Отправляемое событие
+	Status         string             // Статус (model.DeliveryPending, model.DeliveryDelivered, model.DeliveryFailed)
+	Attempts       int                // Количество выполненных попыток
+	ResponseStatus int                // Код ответа на последнюю попытку
+	Error          string             // Ошибка последней попытки
+	CreatedAt      time.Time          // Время создания
+	LastAttemptAt  time.Time          // Время последней попытки; нулевое, если попыток не было
+	NextAttemptAt  time.Time          // Время следующей попытки
+}
+
+// IdempotencyKey описывает ключ идемпотентности запроса пользователя и сохраненный
+// ответ на первый запрос с этим ключом.
+// Пример:
+//
+//	k := IdempotencyKey{
+//	    UserID:      1,
+//	    Key:         "6b1f0c1e-3c1a-4d8e-9a51-0c2f3e4d5a6b",
+//	    Fingerprint: "9f86d081884c7d65...",
+//	    ExpiresAt:   time.Now().Add(24 * time.Hour),
+//	}
+type IdempotencyKey struct {
+	UserID      int               // Идентификатор пользователя
+	Key         string            // Ключ из запроса
+	Fingerprint string            // Хеш метода и тела запроса
+	Status      int               // HTTP-статус ответа; 0, пока первый запрос выполняется
+	Header      map[string]string // Сохраненные заголовки ответа
+	Body        []byte            // Тело ответа
+	CreatedAt   time.Time         // Время первого запроса
+	ExpiresAt   time.Time         // Время, после которого ключ можно использовать заново
+}
+
+// Revision содержит состояние ссылки до ее редактирования.
+// Пример:
+//
+//	rev := Revision{
+//	    Number:   1,
+//	    Link:     "https://example.com/old",
+//	    EditedAt: time.Now(),
+//	}
+type Revision struct {
+	Number   int                   // Порядковый номер ревизии
+	Link     string                // Оригинальный URL до редактирования
+	Title    string                // Заголовок до редактирования
+	Tags     []string              // Теги до редактирования
+	Note     string                // Заметка до редактирования
+	Redirect model.RedirectOptions // Параметры перенаправления до редактирования
+	Rules    []model.RouteRule     // Правила перенаправления до редактирования
+	EditedAt time.Time             // Время редактирования
+}
+
+// AddResult содержит результат добавления одной ссылки в пакете.
+// Пример:
+//
+//	res := AddResult{Hash: "abc123", Exists: true}
+type AddResult struct {
+	Hash   string // Хеш созданной или существующей ссылки
+	Exists bool   // URL уже был сокращен ранее
 }
 
 // UserHash содержит информацию о пользователе и хешах для пакетных операций.
@@ -31,13 +189,46 @@ type URL struct {
 //	}
 type UserHash struct {
 	UserID int      // Идентификатор пользователя
+	Domain string   // Домен ссылок; пустой для домена по умолчанию
 	Hash   []string // Список хешей для операций
 }
 
-// ErrExistsURL возвращается при попытке добавить уже существующий URL.
-var ErrExistsURL = errors.New("URL already exists")
+// DeletionJob описывает сохраненное задание на удаление ссылок пользователя.
+// Пример:
+//
+//	job := DeletionJob{
+//	    ID:     1,
+//	    UserID: 1,
+//	    Hashes: []string{"abc123"},
+//	    Status: model.DeletionPending,
+//	}
+type DeletionJob struct {
+	ID          int64      // Идентификатор задания
+	UserID      int        // Идентификатор владельца ссылок
+	Domain      string     // Домен удаляемых ссылок
+	Hashes      []string   // Хеши удаляемых ссылок
+	Status      string     // Статус задания (model.DeletionPending, model.DeletionDone)
+	CreatedAt   time.Time  // Время постановки в очередь
+	CompletedAt *time.Time // Время завершения (nil для незавершенных заданий)
+}
+
+var (
+	// ErrExistsURL возвращается при попытке добавить уже существующий URL.
+	ErrExistsURL = fault.New(fault.Conflict, "url_exists", "URL already exists")
+	// ErrNotFound возвращается, если ссылка не найдена или не принадлежит пользователю.
+	ErrNotFound = fault.New(fault.NotFound, "not_found", "URL not found")
+	// ErrExhausted возвращается при переходе по ссылке с исчерпанным лимитом переходов.
+	ErrExhausted = fault.New(fault.Gone, "url_exhausted", "URL click limit exhausted")
+	// ErrHashTaken возвращается, если в домене уже есть ссылка или эксперимент с таким хешем.
+	ErrHashTaken = fault.New(fault.Conflict, "hash_taken", "hash already exists")
+	// ErrExistsKey возвращается, если у пользователя есть действующий ключ идемпотентности с тем же значением.
+	ErrExistsKey = fault.New(fault.Conflict, "idempotency_key_exists", "idempotency key already exists")
+)
 
 // Storer определяет интерфейс для работы с хранилищем URL.
+// Об ошибках, зависящих от данных, все реализации сообщают типизированными
+// ошибками (ErrNotFound, ErrExistsURL, ErrExhausted, ErrHashTaken), а не
+// ошибками конкретного хранилища, например pgx.ErrNoRows.
 // Реализации:
 //   - DBStore (PostgreSQL)
 //   - FileStore (файловое хранилище)
@@ -45,16 +236,17 @@ var ErrExistsURL = errors.New("URL already exists")
 //
 //go:generate mockgen -destination=storer_mock.go -package=order github.com/spitfy/urlshortener/internal/repository Storer
 type Storer interface {
-	// Add добавляет новую ссылку в хранилище.
-	// Возвращает ErrExistsURL если URL уже существует.
+	// Add добавляет новую ссылку в домен url.Domain.
+	// Возвращает ErrExistsURL и хеш существующей ссылки, если URL уже сокращен в этом домене
+	// в пределах области дедупликации (глобально, для пользователя или никогда).
 	// Пример:
 	//   hash, err := store.Add(ctx, URL{...}, userID)
 	Add(ctx context.Context, url URL, userID int) (hash string, err error)
 
-	// GetByHash возвращает URL по его хешу.
+	// GetByHash возвращает URL по его хешу в домене (пустой домен — домен по умолчанию).
 	// Пример:
-	//   url, err := store.GetByHash(ctx, "abc123")
-	GetByHash(ctx context.Context, hash string) (URL, error)
+	//   url, err := store.GetByHash(ctx, "", "abc123")
+	GetByHash(ctx context.Context, domain, hash string) (URL, error)
 
 	// Close освобождает ресурсы хранилища.
 	// Пример:
@@ -68,11 +260,15 @@ type Storer interface {
 	//   }
 	Ping() error
 
-	// BatchAdd добавляет несколько URL атомарно.
+	// BatchAdd добавляет несколько URL атомарно за одно обращение к хранилищу.
+	// Для каждого URL возвращает результат в том же порядке: хеш созданной ссылки
+	// либо хеш существующей ссылки с Exists = true, если URL уже сокращен
+	// (в том числе повторно в пределах пакета).
+	// При ошибке ни одна ссылка из пакета не сохраняется.
 	// Пример:
 	//   urls := []URL{...}
-	//   err := store.BatchAdd(ctx, urls, userID)
-	BatchAdd(ctx context.Context, urls []URL, userID int) error
+	//   res, err := store.BatchAdd(ctx, urls, userID)
+	BatchAdd(ctx context.Context, urls []URL, userID int) ([]AddResult, error)
 
 	// BatchDelete помечает URL как удаленные для указанного пользователя.
 	// Пример:
@@ -84,15 +280,207 @@ type Storer interface {
 	//   urls, err := store.GetByUserID(ctx, 1)
 	GetByUserID(ctx context.Context, userID int) ([]URL, error)
 
+	// ListByUser возвращает до q.Limit неудаленных ссылок пользователя, подходящих
+	// под поиск и фильтр по тегам, в порядке q.Sort, начиная после позиции q.After.
+	// Пример:
+	//   urls, err := store.ListByUser(ctx, 1, LinkQuery{Search: "spring sale", Tags: []string{"promo"}, Limit: 50})
+	ListByUser(ctx context.Context, userID int, q LinkQuery) ([]URL, error)
+
+	// AddClick увеличивает счетчик переходов по ссылке домена. Для ссылки с лимитом
+	// переходов проверка лимита и увеличение счетчика выполняются атомарно.
+	// Возвращает ErrNotFound, если ссылка не найдена или удалена,
+	// и ErrExhausted, если лимит переходов исчерпан.
+	// Пример:
+	//   err := store.AddClick(ctx, "", "abc123")
+	AddClick(ctx context.Context, domain, hash string) error
+
+	// LinksToCheck возвращает до limit неудаленных ссылок, адрес назначения которых
+	// не проверялся с момента before: сначала никогда не проверявшиеся, затем проверенные раньше других.
+	// Пример:
+	//   urls, err := store.LinksToCheck(ctx, time.Now().Add(-24*time.Hour), 100)
+	LinksToCheck(ctx context.Context, before time.Time, limit int) ([]URL, error)
+
+	// SetHealth сохраняет результат проверки адреса назначения ссылки домена.
+	// Изменение не попадает в историю ревизий.
+	// Пример:
+	//   err := store.SetHealth(ctx, "", "abc123", model.LinkHealth{Status: 404, Broken: true, CheckedAt: time.Now()})
+	SetHealth(ctx context.Context, domain, hash string, h model.LinkHealth) error
+
+	// SetTitle задает заголовок ссылки домена, только если он еще не задан.
+	// Изменение не попадает в историю ревизий.
+	// Пример:
+	//   err := store.SetTitle(ctx, "", "abc123", "Example Domain")
+	SetTitle(ctx context.Context, domain, hash, title string) error
+
 	// CreateUser создает нового пользователя и возвращает его ID.
 	// Пример:
 	//   userID, err := store.CreateUser(ctx)
 	CreateUser(ctx context.Context) (int, error)
 
+	// Stats возвращает количество активных, удаленных и окончательно удаленных ссылок
+	// и количество пользователей.
+	// Пример:
+	//   stats, err := store.Stats(ctx)
 	Stats(ctx context.Context) (model.Stats, error)
+
+	// Update изменяет адрес, заголовок, теги, заметку и параметры перенаправления ссылки
+	// пользователя. Предыдущее состояние сохраняется в истории ревизий.
+	// При смене адреса результат проверки его доступности сбрасывается.
+	// Возвращает ErrNotFound, если ссылка не найдена или принадлежит другому пользователю,
+	// и ErrExistsURL с хешем существующей ссылки, если новый адрес уже сокращен.
+	// Пример:
+	//   hash, err := store.Update(ctx, URL{Hash: "abc123", Link: "https://example.org"}, 1)
+	Update(ctx context.Context, url URL, userID int) (hash string, err error)
+
+	// GetRevisions возвращает историю изменений ссылки пользователя в домене (новые первыми).
+	// Пример:
+	//   revs, err := store.GetRevisions(ctx, "", "abc123", 1)
+	GetRevisions(ctx context.Context, domain, hash string, userID int) ([]Revision, error)
+
+	// EnqueueDeletion сохраняет задание на удаление ссылок пользователя
+	// со статусом model.DeletionPending. Задание переживает перезапуск сервиса.
+	// Пример:
+	//   job, err := store.EnqueueDeletion(ctx, UserHash{UserID: 1, Hash: []string{"abc123"}})
+	EnqueueDeletion(ctx context.Context, uh UserHash) (DeletionJob, error)
+
+	// ProcessDeletions выполняет до limit ожидающих заданий на удаление
+	// одним массовым обновлением и помечает их выполненными.
+	// Возвращает количество обработанных заданий и ссылки, которые были удалены:
+	// чужие, несуществующие и уже удаленные ссылки заданий в них не входят.
+	// Пример:
+	//   n, deleted, err := store.ProcessDeletions(ctx, 100)
+	ProcessDeletions(ctx context.Context, limit int) (n int, deleted []URL, err error)
+
+	// GetDeletion возвращает задание на удаление пользователя.
+	// Возвращает ErrNotFound, если задание не найдено или принадлежит другому пользователю.
+	// Пример:
+	//   job, err := store.GetDeletion(ctx, 1, 1)
+	GetDeletion(ctx context.Context, id int64, userID int) (DeletionJob, error)
+
+	// Restore восстанавливает удаленные ссылки пользователя, удаленные не ранее since.
+	// Для каждого хеша возвращает статус восстановления; ссылка, оригинальный URL
+	// которой уже сокращен заново, не восстанавливается (model.RestoreConflict).
+	// Пример:
+	//   res, err := store.Restore(ctx, UserHash{UserID: 1, Hash: []string{"abc123"}}, since)
+	Restore(ctx context.Context, uh UserHash, since time.Time) ([]model.RestoreResult, error)
+
+	// AddExperiment создает эксперимент пользователя и его короткую ссылку e.Domain/e.Hash,
+	// ведущую на первый вариант. Ссылка не участвует в дедупликации.
+	// Возвращает эксперимент с присвоенным идентификатором и временем создания.
+	// Пример:
+	//   e, err := store.AddExperiment(ctx, Experiment{Hash: "abc123", Variants: variants}, 1)
+	AddExperiment(ctx context.Context, e Experiment, userID int) (Experiment, error)
+
+	// GetExperiment возвращает эксперимент по идентификатору.
+	// Возвращает ErrNotFound, если эксперимент не найден.
+	// Пример:
+	//   e, err := store.GetExperiment(ctx, 7)
+	GetExperiment(ctx context.Context, id int64) (Experiment, error)
+
+	// ListExperiments возвращает эксперименты пользователя (новые первыми).
+	// Пример:
+	//   list, err := store.ListExperiments(ctx, 1)
+	ListExperiments(ctx context.Context, userID int) ([]Experiment, error)
+
+	// AddVariantRedirect увеличивает счетчик перенаправлений на вариант эксперимента
+	// с порядковым номером variant (с нуля).
+	// Возвращает ErrNotFound, если эксперимент или вариант не найден.
+	// Пример:
+	//   err := store.AddVariantRedirect(ctx, 7, 1)
+	AddVariantRedirect(ctx context.Context, id int64, variant int) error
+
+	// AddWebhook сохраняет подписку пользователя w.UserID на события ссылок.
+	// Пример:
+	//   w, err := store.AddWebhook(ctx, Webhook{UserID: 1, URL: "https://hooks.example.com/", Active: true})
+	AddWebhook(ctx context.Context, w Webhook) (Webhook, error)
+
+	// GetWebhook возвращает подписку по идентификатору.
+	// Возвращает ErrNotFound, если подписка не найдена.
+	// Пример:
+	//   w, err := store.GetWebhook(ctx, 3)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+
+	// ListWebhooks возвращает подписки пользователя в порядке создания.
+	// Пример:
+	//   list, err := store.ListWebhooks(ctx, 1)
+	ListWebhooks(ctx context.Context, userID int) ([]Webhook, error)
+
+	// UpdateWebhook заменяет адрес, ключ, события и признак активности подписки w.ID.
+	// Возвращает ErrNotFound, если подписка не найдена.
+	// Пример:
+	//   err := store.UpdateWebhook(ctx, w)
+	UpdateWebhook(ctx context.Context, w Webhook) error
+
+	// DeleteWebhook удаляет подписку вместе с журналом ее доставок.
+	// Возвращает ErrNotFound, если подписка не найдена.
+	// Пример:
+	//   err := store.DeleteWebhook(ctx, 3)
+	DeleteWebhook(ctx context.Context, id int64) error
+
+	// AddDelivery сохраняет доставку события подписке.
+	// Пример:
+	//   d, err := store.AddDelivery(ctx, Delivery{WebhookID: 3, Payload: event, Status: model.DeliveryPending})
+	AddDelivery(ctx context.Context, d Delivery) (Delivery, error)
+
+	// PendingDeliveries возвращает до limit ожидающих доставок, время попытки которых
+	// наступило к моменту now, в порядке этого времени.
+	// Пример:
+	//   list, err := store.PendingDeliveries(ctx, time.Now(), 100)
+	PendingDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+
+	// UpdateDelivery сохраняет результат попытки доставки d.ID: статус, число попыток,
+	// ответ получателя и время следующей попытки.
+	// Пример:
+	//   err := store.UpdateDelivery(ctx, d)
+	UpdateDelivery(ctx context.Context, d Delivery) error
+
+	// ListDeliveries возвращает до limit последних доставок подписки (новые первыми).
+	// Пример:
+	//   list, err := store.ListDeliveries(ctx, 3, 100)
+	ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]Delivery, error)
+
+	// PurgeDeliveries удаляет завершенные доставки, созданные раньше before.
+	// Возвращает количество удаленных доставок.
+	// Пример:
+	//   n, err := store.PurgeDeliveries(ctx, time.Now().Add(-7*24*time.Hour))
+	PurgeDeliveries(ctx context.Context, before time.Time) (int, error)
+
+	// AddIdempotencyKey сохраняет ключ идемпотентности k без ответа. Если у пользователя
+	// есть действующий (с ExpiresAt позже текущего времени) ключ с тем же значением,
+	// он не изменяется и возвращается вместе с ErrExistsKey; истекший ключ заменяется.
+	// Пример:
+	//   existing, err := store.AddIdempotencyKey(ctx, IdempotencyKey{UserID: 1, Key: "key", Fingerprint: fp, ExpiresAt: exp})
+	AddIdempotencyKey(ctx context.Context, k IdempotencyKey) (IdempotencyKey, error)
+
+	// SetIdempotencyResponse сохраняет статус, заголовки и тело ответа для ключа k.UserID/k.Key.
+	// Возвращает ErrNotFound, если ключ не найден.
+	// Пример:
+	//   err := store.SetIdempotencyResponse(ctx, IdempotencyKey{UserID: 1, Key: "key", Status: 201, Body: body})
+	SetIdempotencyResponse(ctx context.Context, k IdempotencyKey) error
+
+	// DeleteIdempotencyKey удаляет ключ идемпотентности пользователя; отсутствие ключа не считается ошибкой.
+	// Пример:
+	//   err := store.DeleteIdempotencyKey(ctx, 1, "key")
+	DeleteIdempotencyKey(ctx context.Context, userID int, key string) error
+
+	// PurgeIdempotencyKeys удаляет ключи идемпотентности, истекшие раньше before.
+	// Возвращает количество удаленных ключей.
+	// Пример:
+	//   n, err := store.PurgeIdempotencyKeys(ctx, time.Now())
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error)
+
+	// Purge окончательно удаляет ссылки, удаленные раньше before.
+	// Если freeHash = false, хеш остается занятым и ссылка отдает 410 Gone,
+	// иначе хеш может быть выдан новой ссылке. Эксперименты удаленных ссылок удаляются вместе с ними.
+	// Возвращает количество удаленных ссылок.
+	// Пример:
+	//   n, err := store.Purge(ctx, time.Now().Add(-30*24*time.Hour), false)
+	Purge(ctx context.Context, before time.Time, freeHash bool) (int, error)
 }
 
 // CreateStore создает соответствующую реализацию Storer на основе конфигурации.
+// Область дедупликации оригинальных URL задается conf.DedupeScope и действует
+// для любого хранилища.
 // Приоритет выбора хранилища:
 //  1. PostgreSQL (если указан DSN)
 //  2. Файловое хранилище (если указан путь)
@@ -106,11 +494,15 @@ type Storer interface {
 //	}
 //	defer store.Close()
 func CreateStore(conf *config.Config) (Storer, error) {
+	scope, err := ParseDedupeScope(conf.DedupeScope)
+	if err != nil {
+		return nil, err
+	}
 	if conf.DB.DatabaseDsn != "" {
-		return newDBStore(conf)
+		return newDBStore(conf, scope)
 	}
 	if conf.FileStorage.FileStoragePath != "" {
-		return newFileStore(conf)
+		return newFileStore(conf, scope)
 	}
-	return newMemStore(), nil
+	return newMemStoreWithScope(scope), nil
 }
@@ -7,8 +7,10 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	model "github.com/spitfy/urlshortener/internal/model"
 )
 
 // MockStorer is a mock of Storer interface.
@@ -49,14 +51,103 @@ func (mr *MockStorerMockRecorder) Add(arg0, arg1, arg2 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockStorer)(nil).Add), arg0, arg1, arg2)
 }
 
-// BatchAdd mocks base method.
-func (m *MockStorer) BatchAdd(arg0 context.Context, arg1 []URL, arg2 int) error {
+// AddClick mocks base method.
+func (m *MockStorer) AddClick(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BatchAdd", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "AddClick", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// AddClick indicates an expected call of AddClick.
+func (mr *MockStorerMockRecorder) AddClick(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClick", reflect.TypeOf((*MockStorer)(nil).AddClick), arg0, arg1, arg2)
+}
+
+// AddDelivery mocks base method.
+func (m *MockStorer) AddDelivery(arg0 context.Context, arg1 Delivery) (Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDelivery", arg0, arg1)
+	ret0, _ := ret[0].(Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDelivery indicates an expected call of AddDelivery.
+func (mr *MockStorerMockRecorder) AddDelivery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDelivery", reflect.TypeOf((*MockStorer)(nil).AddDelivery), arg0, arg1)
+}
+
+// AddExperiment mocks base method.
+func (m *MockStorer) AddExperiment(arg0 context.Context, arg1 Experiment, arg2 int) (Experiment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddExperiment", arg0, arg1, arg2)
+	ret0, _ := ret[0].(Experiment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddExperiment indicates an expected call of AddExperiment.
+func (mr *MockStorerMockRecorder) AddExperiment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddExperiment", reflect.TypeOf((*MockStorer)(nil).AddExperiment), arg0, arg1, arg2)
+}
+
+// AddIdempotencyKey mocks base method.
+func (m *MockStorer) AddIdempotencyKey(arg0 context.Context, arg1 IdempotencyKey) (IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddIdempotencyKey", arg0, arg1)
+	ret0, _ := ret[0].(IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddIdempotencyKey indicates an expected call of AddIdempotencyKey.
+func (mr *MockStorerMockRecorder) AddIdempotencyKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddIdempotencyKey", reflect.TypeOf((*MockStorer)(nil).AddIdempotencyKey), arg0, arg1)
+}
+
+// AddVariantRedirect mocks base method.
+func (m *MockStorer) AddVariantRedirect(arg0 context.Context, arg1 int64, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVariantRedirect", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVariantRedirect indicates an expected call of AddVariantRedirect.
+func (mr *MockStorerMockRecorder) AddVariantRedirect(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVariantRedirect", reflect.TypeOf((*MockStorer)(nil).AddVariantRedirect), arg0, arg1, arg2)
+}
+
+// AddWebhook mocks base method.
+func (m *MockStorer) AddWebhook(arg0 context.Context, arg1 Webhook) (Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWebhook", arg0, arg1)
+	ret0, _ := ret[0].(Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWebhook indicates an expected call of AddWebhook.
+func (mr *MockStorerMockRecorder) AddWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWebhook", reflect.TypeOf((*MockStorer)(nil).AddWebhook), arg0, arg1)
+}
+
+// BatchAdd mocks base method.
+func (m *MockStorer) BatchAdd(arg0 context.Context, arg1 []URL, arg2 int) ([]AddResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchAdd", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]AddResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // BatchAdd indicates an expected call of BatchAdd.
 func (mr *MockStorerMockRecorder) BatchAdd(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
@@ -104,19 +195,62 @@ func (mr *MockStorerMockRecorder) CreateUser(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStorer)(nil).CreateUser), arg0)
 }
 
+// DeleteIdempotencyKey mocks base method.
+func (m *MockStorer) DeleteIdempotencyKey(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyKey indicates an expected call of DeleteIdempotencyKey.
+func (mr *MockStorerMockRecorder) DeleteIdempotencyKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKey", reflect.TypeOf((*MockStorer)(nil).DeleteIdempotencyKey), arg0, arg1, arg2)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockStorer) DeleteWebhook(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockStorerMockRecorder) DeleteWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockStorer)(nil).DeleteWebhook), arg0, arg1)
+}
+
+// EnqueueDeletion mocks base method.
+func (m *MockStorer) EnqueueDeletion(arg0 context.Context, arg1 UserHash) (DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueDeletion", arg0, arg1)
+	ret0, _ := ret[0].(DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueDeletion indicates an expected call of EnqueueDeletion.
+func (mr *MockStorerMockRecorder) EnqueueDeletion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueDeletion", reflect.TypeOf((*MockStorer)(nil).EnqueueDeletion), arg0, arg1)
+}
+
 // GetByHash mocks base method.
-func (m *MockStorer) GetByHash(arg0 context.Context, arg1 string) (URL, error) {
+func (m *MockStorer) GetByHash(arg0 context.Context, arg1, arg2 string) (URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByHash", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetByHash", arg0, arg1, arg2)
 	ret0, _ := ret[0].(URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByHash indicates an expected call of GetByHash.
-func (mr *MockStorerMockRecorder) GetByHash(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) GetByHash(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*MockStorer)(nil).GetByHash), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*MockStorer)(nil).GetByHash), arg0, arg1, arg2)
 }
 
 // GetByUserID mocks base method.
@@ -134,6 +268,156 @@ func (mr *MockStorerMockRecorder) GetByUserID(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockStorer)(nil).GetByUserID), arg0, arg1)
 }
 
+// GetDeletion mocks base method.
+func (m *MockStorer) GetDeletion(arg0 context.Context, arg1 int64, arg2 int) (DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletion", arg0, arg1, arg2)
+	ret0, _ := ret[0].(DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletion indicates an expected call of GetDeletion.
+func (mr *MockStorerMockRecorder) GetDeletion(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletion", reflect.TypeOf((*MockStorer)(nil).GetDeletion), arg0, arg1, arg2)
+}
+
+// GetExperiment mocks base method.
+func (m *MockStorer) GetExperiment(arg0 context.Context, arg1 int64) (Experiment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExperiment", arg0, arg1)
+	ret0, _ := ret[0].(Experiment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExperiment indicates an expected call of GetExperiment.
+func (mr *MockStorerMockRecorder) GetExperiment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExperiment", reflect.TypeOf((*MockStorer)(nil).GetExperiment), arg0, arg1)
+}
+
+// GetRevisions mocks base method.
+func (m *MockStorer) GetRevisions(arg0 context.Context, arg1, arg2 string, arg3 int) ([]Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevisions", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevisions indicates an expected call of GetRevisions.
+func (mr *MockStorerMockRecorder) GetRevisions(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevisions", reflect.TypeOf((*MockStorer)(nil).GetRevisions), arg0, arg1, arg2, arg3)
+}
+
+// GetWebhook mocks base method.
+func (m *MockStorer) GetWebhook(arg0 context.Context, arg1 int64) (Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", arg0, arg1)
+	ret0, _ := ret[0].(Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockStorerMockRecorder) GetWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockStorer)(nil).GetWebhook), arg0, arg1)
+}
+
+// LinksToCheck mocks base method.
+func (m *MockStorer) LinksToCheck(arg0 context.Context, arg1 time.Time, arg2 int) ([]URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinksToCheck", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinksToCheck indicates an expected call of LinksToCheck.
+func (mr *MockStorerMockRecorder) LinksToCheck(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinksToCheck", reflect.TypeOf((*MockStorer)(nil).LinksToCheck), arg0, arg1, arg2)
+}
+
+// ListByUser mocks base method.
+func (m *MockStorer) ListByUser(arg0 context.Context, arg1 int, arg2 LinkQuery) ([]URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockStorerMockRecorder) ListByUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockStorer)(nil).ListByUser), arg0, arg1, arg2)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockStorer) ListDeliveries(arg0 context.Context, arg1 int64, arg2 int) ([]Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockStorerMockRecorder) ListDeliveries(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockStorer)(nil).ListDeliveries), arg0, arg1, arg2)
+}
+
+// ListExperiments mocks base method.
+func (m *MockStorer) ListExperiments(arg0 context.Context, arg1 int) ([]Experiment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListExperiments", arg0, arg1)
+	ret0, _ := ret[0].([]Experiment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListExperiments indicates an expected call of ListExperiments.
+func (mr *MockStorerMockRecorder) ListExperiments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListExperiments", reflect.TypeOf((*MockStorer)(nil).ListExperiments), arg0, arg1)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockStorer) ListWebhooks(arg0 context.Context, arg1 int) ([]Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", arg0, arg1)
+	ret0, _ := ret[0].([]Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockStorerMockRecorder) ListWebhooks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockStorer)(nil).ListWebhooks), arg0, arg1)
+}
+
+// PendingDeliveries mocks base method.
+func (m *MockStorer) PendingDeliveries(arg0 context.Context, arg1 time.Time, arg2 int) ([]Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingDeliveries", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingDeliveries indicates an expected call of PendingDeliveries.
+func (mr *MockStorerMockRecorder) PendingDeliveries(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingDeliveries", reflect.TypeOf((*MockStorer)(nil).PendingDeliveries), arg0, arg1, arg2)
+}
+
 // Ping mocks base method.
 func (m *MockStorer) Ping() error {
 	m.ctrl.T.Helper()
@@ -147,3 +431,179 @@ func (mr *MockStorerMockRecorder) Ping() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorer)(nil).Ping))
 }
+
+// ProcessDeletions mocks base method.
+func (m *MockStorer) ProcessDeletions(arg0 context.Context, arg1 int) (int, []URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessDeletions", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].([]URL)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ProcessDeletions indicates an expected call of ProcessDeletions.
+func (mr *MockStorerMockRecorder) ProcessDeletions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessDeletions", reflect.TypeOf((*MockStorer)(nil).ProcessDeletions), arg0, arg1)
+}
+
+// Purge mocks base method.
+func (m *MockStorer) Purge(arg0 context.Context, arg1 time.Time, arg2 bool) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorerMockRecorder) Purge(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorer)(nil).Purge), arg0, arg1, arg2)
+}
+
+// PurgeDeliveries mocks base method.
+func (m *MockStorer) PurgeDeliveries(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeliveries", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeliveries indicates an expected call of PurgeDeliveries.
+func (mr *MockStorerMockRecorder) PurgeDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeliveries", reflect.TypeOf((*MockStorer)(nil).PurgeDeliveries), arg0, arg1)
+}
+
+// PurgeIdempotencyKeys mocks base method.
+func (m *MockStorer) PurgeIdempotencyKeys(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeIdempotencyKeys", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeIdempotencyKeys indicates an expected call of PurgeIdempotencyKeys.
+func (mr *MockStorerMockRecorder) PurgeIdempotencyKeys(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeIdempotencyKeys", reflect.TypeOf((*MockStorer)(nil).PurgeIdempotencyKeys), arg0, arg1)
+}
+
+// Restore mocks base method.
+func (m *MockStorer) Restore(arg0 context.Context, arg1 UserHash, arg2 time.Time) ([]model.RestoreResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.RestoreResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorerMockRecorder) Restore(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorer)(nil).Restore), arg0, arg1, arg2)
+}
+
+// SetHealth mocks base method.
+func (m *MockStorer) SetHealth(arg0 context.Context, arg1, arg2 string, arg3 model.LinkHealth) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHealth", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHealth indicates an expected call of SetHealth.
+func (mr *MockStorerMockRecorder) SetHealth(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHealth", reflect.TypeOf((*MockStorer)(nil).SetHealth), arg0, arg1, arg2, arg3)
+}
+
+// SetIdempotencyResponse mocks base method.
+func (m *MockStorer) SetIdempotencyResponse(arg0 context.Context, arg1 IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIdempotencyResponse", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetIdempotencyResponse indicates an expected call of SetIdempotencyResponse.
+func (mr *MockStorerMockRecorder) SetIdempotencyResponse(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdempotencyResponse", reflect.TypeOf((*MockStorer)(nil).SetIdempotencyResponse), arg0, arg1)
+}
+
+// SetTitle mocks base method.
+func (m *MockStorer) SetTitle(arg0 context.Context, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTitle", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTitle indicates an expected call of SetTitle.
+func (mr *MockStorerMockRecorder) SetTitle(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTitle", reflect.TypeOf((*MockStorer)(nil).SetTitle), arg0, arg1, arg2, arg3)
+}
+
+// Stats mocks base method.
+func (m *MockStorer) Stats(arg0 context.Context) (model.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", arg0)
+	ret0, _ := ret[0].(model.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockStorerMockRecorder) Stats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockStorer)(nil).Stats), arg0)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 URL, arg2 int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1, arg2)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockStorer) UpdateDelivery(arg0 context.Context, arg1 Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockStorerMockRecorder) UpdateDelivery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockStorer)(nil).UpdateDelivery), arg0, arg1)
+}
+
+// UpdateWebhook mocks base method.
+func (m *MockStorer) UpdateWebhook(arg0 context.Context, arg1 Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhook", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebhook indicates an expected call of UpdateWebhook.
+func (mr *MockStorerMockRecorder) UpdateWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhook", reflect.TypeOf((*MockStorer)(nil).UpdateWebhook), arg0, arg1)
+}
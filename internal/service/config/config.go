@@ -1,6 +1,54 @@
 // Package config содержит конфигурацию сервисного слоя.
 package config
 
+import "time"
+
 type Config struct {
 	ServerURL string `env:"BASE_URL"`
+	// RetentionPeriod задает срок, в течение которого удаленную ссылку можно восстановить.
+	RetentionPeriod time.Duration `env:"RETENTION_PERIOD"`
+	// PurgeInterval задает период запуска окончательного удаления; 0 отключает его.
+	PurgeInterval time.Duration `env:"PURGE_INTERVAL"`
+	// PurgeFreeHash разрешает повторно использовать хеши окончательно удаленных ссылок.
+	PurgeFreeHash bool `env:"PURGE_FREE_HASH"`
+	// FetchTitles включает фоновую загрузку заголовка со страницы назначения
+	// для ссылок, созданных без заголовка.
+	FetchTitles bool `env:"FETCH_TITLES"`
+	// GeoIPDB — путь к базе MaxMind DB для правил перенаправления по странам.
+	GeoIPDB string `env:"GEOIP_DB"`
+	// HealthCheckInterval задает, как часто проверяется доступность адресов назначения; 0 отключает проверку.
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL"`
+	// HealthCheckConcurrency — количество одновременных проверок адресов назначения.
+	HealthCheckConcurrency int `env:"HEALTH_CHECK_CONCURRENCY"`
+	// HealthCheckHostDelay — минимальный интервал между запросами проверки к одному хосту.
+	HealthCheckHostDelay time.Duration `env:"HEALTH_CHECK_HOST_DELAY"`
+	// WebhookClickInterval — период, за который переходы по ссылке объединяются
+	// в одно событие clicked подписок; 0 — событие на каждый переход.
+	WebhookClickInterval time.Duration `env:"WEBHOOK_CLICK_INTERVAL"`
+	// IdempotencyTTL — срок хранения ответов на запросы с ключом идемпотентности;
+	// 0 отключает обработку ключей.
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL"`
+	// AllowPrivateTargets разрешает подпискам, загрузке заголовков и проверке доступности
+	// обращаться к непубличным адресам (loopback, частные и link-local сети).
+	AllowPrivateTargets bool `env:"ALLOW_PRIVATE_TARGETS"`
+	// Domains задает дополнительные домены коротких ссылок (только в файле конфигурации).
+	// Домен по умолчанию определяется ServerURL.
+	Domains []Domain
+}
+
+// Domain описывает домен коротких ссылок и его настройки.
+type Domain struct {
+	// Name — имя хоста, по заголовку Host которого выбирается домен, например go.brand-a.com.
+	Name string
+	// BaseURL — адрес, к которому добавляется хеш короткой ссылки; по умолчанию https://<Name>.
+	BaseURL string
+	// RedirectCode — код перенаправления для ссылок домена без собственного кода.
+	RedirectCode int
+	// NotFoundURL — адрес, на который перенаправляются запросы неизвестных хешей.
+	NotFoundURL string
+	// CertFile и KeyFile — TLS-сертификат домена (выбирается по SNI).
+	CertFile string
+	KeyFile  string
+	// Users — пользователи, которым разрешено создавать ссылки в домене; пустой список разрешает всем.
+	Users []int
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// ErrUnknownDomain возвращается, если запрошенный домен не зарегистрирован.
+var ErrUnknownDomain = fault.New(fault.Invalid, "unknown_domain", "unknown domain")
+
+// ErrDomainForbidden возвращается, если пользователю не разрешено создавать ссылки в домене.
+var ErrDomainForbidden = fault.New(fault.Forbidden, "domain_forbidden", "domain is not allowed for user")
+
+// Domain описывает домен коротких ссылок с его настройками.
+// Домен по умолчанию имеет пустое имя, его адрес задается ServerURL.
+type Domain struct {
+	Name         string // Имя хоста; пустое для домена по умолчанию
+	BaseURL      string // Адрес, к которому добавляется хеш короткой ссылки
+	RedirectCode int    // Код перенаправления по умолчанию; 0 — 307 Temporary Redirect
+	NotFoundURL  string // Адрес перенаправления для неизвестных хешей; пустой — ответ с ошибкой
+	users        map[int]bool
+}
+
+// Redirect возвращает HTTP-код перенаправления для ссылки домена.
+// Код, заданный в ссылке, имеет приоритет над кодом домена.
+func (d Domain) Redirect(opts model.RedirectOptions) int {
+	if opts.Code == 0 && d.RedirectCode != 0 {
+		return d.RedirectCode
+	}
+	return RedirectCode(opts)
+}
+
+// allows сообщает, может ли пользователь создавать ссылки в домене.
+func (d Domain) allows(userID int) bool {
+	return len(d.users) == 0 || d.users[userID]
+}
+
+// domains — реестр доменов коротких ссылок.
+type domains struct {
+	def    Domain
+	byName map[string]Domain
+}
+
+// newDomains строит реестр доменов: домен по умолчанию определяется serverURL,
+// дополнительные домены — списком из конфигурации.
+func newDomains(serverURL string, list []serviceConf.Domain) domains {
+	r := domains{
+		def:    Domain{BaseURL: serverURL},
+		byName: make(map[string]Domain, len(list)),
+	}
+	if u, err := url.Parse(serverURL); err == nil {
+		r.def.Name = HostName(u.Host)
+	}
+	for _, d := range list {
+		name := HostName(d.Name)
+		base := d.BaseURL
+		if base == "" {
+			base = "https://" + name
+		}
+		users := make(map[int]bool, len(d.Users))
+		for _, id := range d.Users {
+			users[id] = true
+		}
+		r.byName[name] = Domain{
+			Name:         name,
+			BaseURL:      base,
+			RedirectCode: d.RedirectCode,
+			NotFoundURL:  d.NotFoundURL,
+			users:        users,
+		}
+	}
+	return r
+}
+
+// lookup возвращает домен по имени. Пустое имя и имя хоста ServerURL
+// соответствуют домену по умолчанию.
+func (r domains) lookup(name string) (Domain, bool) {
+	name = HostName(name)
+	if name == "" || name == r.def.Name {
+		return Domain{BaseURL: r.def.BaseURL}, true
+	}
+	d, ok := r.byName[name]
+	return d, ok
+}
+
+// HostName приводит имя хоста к каноническому виду: без порта, завершающей точки
+// и в нижнем регистре. По нему сопоставляются домены из конфигурации, заголовка Host
+// и имени сервера TLS (SNI).
+// Пример:
+//
+//	name := HostName("Go.Brand.com.:443") // "go.brand.com"
+func HostName(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
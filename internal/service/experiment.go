@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// Ограничения A/B-экспериментов.
+const (
+	MinVariants      = 2    // Минимальное количество вариантов
+	MaxVariants      = 5    // Максимальное количество вариантов
+	MaxVariantWeight = 1000 // Максимальный вес варианта
+	MaxVariantName   = 64   // Максимальная длина названия варианта в символах
+)
+
+// ErrInvalidExperiment возвращается при недопустимых параметрах эксперимента,
+// а также при попытке изменить адрес назначения или правила ссылки эксперимента.
+var ErrInvalidExperiment = fault.New(fault.Invalid, "invalid_experiment", "invalid experiment")
+
+// CreateExperiment создает A/B-эксперимент пользователя: короткую ссылку
+// в домене req.Domain, распределяющую посетителей между вариантами по весам.
+// Для недопустимых параметров возвращается ErrInvalidExperiment.
+// Пример:
+//
+//	e, err := s.CreateExperiment(ctx, model.ExperimentRequest{
+//	    Name: "landing",
+//	    Variants: []model.Variant{
+//	        {URL: "https://example.com/a", Weight: 70},
+//	        {URL: "https://example.com/b", Weight: 30},
+//	    },
+//	}, userID)
+func (s *Service) CreateExperiment(ctx context.Context, req model.ExperimentRequest, userID int) (model.Experiment, error) {
+	variants, err := validateVariants(req.Variants)
+	if err != nil {
+		return model.Experiment{}, err
+	}
+	if utf8.RuneCountInString(req.Name) > MaxTitleLen {
+		return model.Experiment{}, fmt.Errorf("%w: name is longer than %d characters", ErrInvalidExperiment, MaxTitleLen)
+	}
+	domain, err := s.userDomain(req.Domain, userID)
+	if err != nil {
+		return model.Experiment{}, err
+	}
+	e, err := s.store.AddExperiment(ctx, repository.Experiment{
+		Domain:   domain.Name,
+		Hash:     RandString(CharCnt),
+		Name:     req.Name,
+		Variants: variants,
+	}, userID)
+	if err != nil {
+		return model.Experiment{}, err
+	}
+	return s.experiment(e)
+}
+
+// GetExperiment возвращает эксперимент пользователя с числом перенаправлений на каждый вариант.
+// Возвращает repository.ErrNotFound, если эксперимент принадлежит другому пользователю.
+func (s *Service) GetExperiment(ctx context.Context, id int64, userID int) (model.Experiment, error) {
+	e, err := s.store.GetExperiment(ctx, id)
+	if err != nil {
+		return model.Experiment{}, err
+	}
+	if e.UserID != userID {
+		return model.Experiment{}, repository.ErrNotFound
+	}
+	return s.experiment(e)
+}
+
+// ListExperiments возвращает эксперименты пользователя (новые первыми).
+func (s *Service) ListExperiments(ctx context.Context, userID int) ([]model.Experiment, error) {
+	list, err := s.store.ListExperiments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Experiment, 0, len(list))
+	for _, e := range list {
+		me, err := s.experiment(e)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, me)
+	}
+	return res, nil
+}
+
+// Assign выбирает вариант эксперимента для посетителя visitor, учитывает
+// перенаправление на него и возвращает адрес варианта.
+// Один и тот же посетитель всегда получает один и тот же вариант.
+// Пример:
+//
+//	link, err := s.Assign(ctx, u.Experiment, visitorID)
+func (s *Service) Assign(ctx context.Context, id int64, visitor string) (string, error) {
+	e, err := s.store.GetExperiment(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	i := pickVariant(e.ID, visitor, e.Variants)
+	if err := s.store.AddVariantRedirect(ctx, e.ID, i); err != nil {
+		return "", err
+	}
+	return e.Variants[i].URL, nil
+}
+
+// pickVariant возвращает номер варианта посетителя: хеш FNV-1a идентификаторов
+// эксперимента и посетителя попадает в диапазон одного из вариантов, длина
+// которого пропорциональна весу. Выбор детерминирован, поэтому распределение
+// можно проверить на заданном наборе посетителей.
+func pickVariant(id int64, visitor string, variants []repository.Variant) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.FormatInt(id, 10) + "|" + visitor))
+	n := int(h.Sum32() % uint32(total))
+	for i, v := range variants {
+		if n < v.Weight {
+			return i
+		}
+		n -= v.Weight
+	}
+	return len(variants) - 1
+}
+
+// validateVariants проверяет варианты эксперимента и задает значения по умолчанию:
+// вес 1 и названия A, B, C и т. д. по порядку.
+func validateVariants(variants []model.Variant) ([]repository.Variant, error) {
+	if len(variants) < MinVariants || len(variants) > MaxVariants {
+		return nil, fmt.Errorf("%w: expected %d..%d variants, got %d",
+			ErrInvalidExperiment, MinVariants, MaxVariants, len(variants))
+	}
+	res := make([]repository.Variant, 0, len(variants))
+	seen := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		if !isURL(v.URL) {
+			return nil, fmt.Errorf("%w: variant %d: invalid url", ErrInvalidExperiment, i+1)
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+		if v.Weight < 0 || v.Weight > MaxVariantWeight {
+			return nil, fmt.Errorf("%w: variant %d: weight must be in 1..%d", ErrInvalidExperiment, i+1, MaxVariantWeight)
+		}
+		if v.Name == "" {
+			v.Name = string(rune('A' + i))
+		}
+		if utf8.RuneCountInString(v.Name) > MaxVariantName {
+			return nil, fmt.Errorf("%w: variant %d: name is longer than %d characters", ErrInvalidExperiment, i+1, MaxVariantName)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("%w: duplicate variant name %q", ErrInvalidExperiment, v.Name)
+		}
+		seen[v.Name] = true
+		res = append(res, repository.Variant{Name: v.Name, URL: v.URL, Weight: v.Weight})
+	}
+	return res, nil
+}
+
+// experiment преобразует эксперимент хранилища в модель ответа.
+func (s *Service) experiment(e repository.Experiment) (model.Experiment, error) {
+	shortURL, err := s.makeURL(e.Domain, e.Hash)
+	if err != nil {
+		return model.Experiment{}, err
+	}
+	res := model.Experiment{
+		ID:        e.ID,
+		Name:      e.Name,
+		ShortURL:  shortURL,
+		Variants:  make([]model.Variant, 0, len(e.Variants)),
+		CreatedAt: e.CreatedAt,
+	}
+	for _, v := range e.Variants {
+		res.Variants = append(res.Variants, model.Variant(v))
+		res.Redirects += v.Redirects
+	}
+	return res, nil
+}
@@ -0,0 +1,87 @@
+// Package fault содержит типизированные ошибки сервисного слоя.
+// Каждая ошибка относится к одному из видов Kind, по которому транспорт выбирает
+// код ответа, и имеет машинно-читаемый код Reason. Пакет не зависит от других
+// пакетов приложения, поэтому ошибки возвращают и сервис, и хранилища.
+package fault
+
+import "errors"
+
+// Kind — вид ошибки.
+type Kind int
+
+// Виды ошибок.
+const (
+	Internal    Kind = iota // Внутренняя ошибка; вид нетипизированных ошибок
+	NotFound                // Объект не найден
+	Gone                    // Объект больше недоступен
+	Conflict                // Объект уже существует или конфликтует с другим
+	Invalid                 // Некорректные входные данные
+	Forbidden               // Операция запрещена
+	RateLimited             // Превышен лимит запросов или ресурсов
+	Unavailable             // Сервис временно недоступен
+)
+
+var kindNames = [...]string{
+	Internal:    "internal",
+	NotFound:    "not_found",
+	Gone:        "gone",
+	Conflict:    "conflict",
+	Invalid:     "invalid",
+	Forbidden:   "forbidden",
+	RateLimited: "rate_limited",
+	Unavailable: "unavailable",
+}
+
+// String возвращает название вида ошибки, например "not_found".
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return kindNames[Internal]
+	}
+	return kindNames[k]
+}
+
+// Error — типизированная ошибка. Значения создаются функцией New и сравниваются
+// через errors.Is; подробности добавляются оборачиванием: fmt.Errorf("%w: ...", err).
+type Error struct {
+	Kind   Kind   // Вид ошибки
+	Reason string // Машинно-читаемый код, например "invalid_url"
+	msg    string
+}
+
+// New создает ошибку вида kind с кодом reason и текстом msg.
+// Пример:
+//
+//	var ErrInvalidURL = fault.New(fault.Invalid, "invalid_url", "invalid url")
+func New(kind Kind, reason, msg string) error {
+	return &Error{Kind: kind, Reason: reason, msg: msg}
+}
+
+// Error возвращает текст ошибки.
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// KindOf возвращает вид первой типизированной ошибки в цепочке err
+// или Internal, если таких ошибок нет.
+// Пример:
+//
+//	if fault.KindOf(err) == fault.NotFound {
+//	    // ответ 404
+//	}
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// ReasonOf возвращает код первой типизированной ошибки в цепочке err
+// или пустую строку, если таких ошибок нет.
+func ReasonOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ""
+}
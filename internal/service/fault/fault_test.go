@@ -0,0 +1,29 @@
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindOf(t *testing.T) {
+	errMissing := New(NotFound, "missing", "not found")
+	wrapped := fmt.Errorf("get link: %w", fmt.Errorf("%w: abc", errMissing))
+
+	assert.ErrorIs(t, wrapped, errMissing)
+	assert.Equal(t, NotFound, KindOf(wrapped))
+	assert.Equal(t, "missing", ReasonOf(wrapped))
+	assert.Equal(t, "get link: not found: abc", wrapped.Error())
+
+	plain := errors.New("boom")
+	assert.Equal(t, Internal, KindOf(plain), "untyped errors are internal")
+	assert.Empty(t, ReasonOf(plain))
+	assert.Equal(t, Internal, KindOf(nil))
+}
+
+func TestKind_String(t *testing.T) {
+	assert.Equal(t, "rate_limited", RateLimited.String())
+	assert.Equal(t, "internal", Kind(100).String())
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+)
+
+// Параметры проверки доступности адресов назначения.
+const (
+	// healthCheckTimeout ограничивает время одного запроса проверки.
+	healthCheckTimeout = 10 * time.Second
+	// healthMaxRedirects — максимальная длина цепочки перенаправлений.
+	healthMaxRedirects = 10
+	// healthBatchSize — количество ссылок, выбираемых из хранилища за раз.
+	healthBatchSize = 100
+	// healthHostsPrune — размер таблицы хостов, после которого из нее удаляются устаревшие записи.
+	healthHostsPrune = 1024
+)
+
+// healthClient возвращает клиент проверки адресов назначения. Перенаправления
+// не выполняются автоматически, чтобы записать цепочку целиком.
+func (s *Service) healthClient() *http.Client {
+	return &http.Client{
+		Transport: s.outboundTransport(),
+		Timeout:   healthCheckTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// hostLimiter разделяет запросы к одному хосту заданным интервалом.
+// Нулевое значение готово к использованию.
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait резервирует для запроса к host ближайшее свободное время
+// и ждет его наступления или отмены ctx.
+func (l *hostLimiter) wait(ctx context.Context, host string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	now := time.Now()
+	l.mu.Lock()
+	if l.next == nil {
+		l.next = make(map[string]time.Time)
+	}
+	if len(l.next) >= healthHostsPrune {
+		for h, at := range l.next {
+			if at.Before(now) {
+				delete(l.next, h)
+			}
+		}
+	}
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(delay)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runHealthChecker периодически проверяет адреса назначения ссылок.
+// Остановка сервиса прерывает текущую проверку.
+func (s *Service) runHealthChecker(interval time.Duration) {
+	defer s.workers.Done()
+	ctx, cancel := s.stopContext()
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.CheckLinks(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("health check error: %v", err)
+			}
+		}
+	}
+}
+
+// CheckLinks проверяет адреса назначения ссылок, не проверявшиеся в течение
+// HealthCheckInterval, и сохраняет результаты. Одновременно выполняется не более
+// HealthCheckConcurrency проверок. Возвращает количество проверенных ссылок.
+// Пример:
+//
+//	n, err := s.CheckLinks(ctx)
+func (s *Service) CheckLinks(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.config.Service.HealthCheckInterval)
+	workers := max(s.config.Service.HealthCheckConcurrency, 1)
+	total := 0
+	for {
+		urls, err := s.store.LinksToCheck(ctx, before, healthBatchSize)
+		if err != nil {
+			return total, err
+		}
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+		sem := make(chan struct{}, workers)
+		for _, u := range urls {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				h := s.checkLink(ctx, u.Link)
+				if ctx.Err() != nil {
+					return
+				}
+				err := s.store.SetHealth(ctx, u.Domain, u.Hash, h)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("set health %s: %w", u.Hash, err))
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		total += len(urls)
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		if len(errs) > 0 {
+			return total, errors.Join(errs...)
+		}
+		if len(urls) < healthBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// checkLink проверяет адрес назначения, следуя перенаправлениям вручную.
+// Адрес считается недоступным, если ответ не получен или получен статус
+// 4xx/5xx, кроме 429 (сервер жив, но ограничивает запросы). Результат виден
+// владельцу ссылки, поэтому вместо текста сетевой ошибки сохраняется ее описание,
+// а перенаправление на непубличный адрес не записывается в цепочку.
+func (s *Service) checkLink(ctx context.Context, link string) model.LinkHealth {
+	var h model.LinkHealth
+	client := s.healthClient()
+	target := link
+	for {
+		status, location, err := s.probe(ctx, client, target)
+		if err != nil {
+			if errors.Is(err, errPrivateAddress) && len(h.Redirects) > 0 {
+				h.Redirects = h.Redirects[:len(h.Redirects)-1]
+			}
+			h.Error = outboundError(err)
+			break
+		}
+		h.Status = status
+		if location == "" || !isRedirectStatus(status) {
+			break
+		}
+		if len(h.Redirects) == healthMaxRedirects {
+			h.Error = fmt.Sprintf("stopped after %d redirects", healthMaxRedirects)
+			break
+		}
+		base, err := url.Parse(target)
+		if err != nil {
+			h.Error = "invalid url"
+			break
+		}
+		next, err := base.Parse(location)
+		if err != nil {
+			h.Error = fmt.Sprintf("invalid redirect location %q", location)
+			break
+		}
+		target = next.String()
+		h.Redirects = append(h.Redirects, target)
+	}
+	h.Broken = h.Error != "" || h.Status >= 400 && h.Status != http.StatusTooManyRequests
+	h.CheckedAt = time.Now()
+	return h
+}
+
+// probe запрашивает адрес методом HEAD, а если сервер его не поддерживает, — методом GET.
+// Возвращает код ответа и заголовок Location; тело ответа не читается.
+func (s *Service) probe(ctx context.Context, client *http.Client, target string) (int, string, error) {
+	status, location, err := s.request(ctx, client, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		return s.request(ctx, client, http.MethodGet, target)
+	}
+	return status, location, err
+}
+
+// request выполняет один запрос проверки с учетом интервала между запросами к хосту.
+func (s *Service) request(ctx context.Context, client *http.Client, method, target string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := s.hosts.wait(ctx, req.URL.Host, s.config.Service.HealthCheckHostDelay); err != nil {
+		return 0, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+// isRedirectStatus сообщает, является ли код ответа перенаправлением с заголовком Location.
+func isRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// maxIdempotencyKeyLen ограничивает длину ключа идемпотентности.
+const maxIdempotencyKeyLen = 255
+
+// statusClientClosedRequest — статус ответа на запрос, отмененный клиентом.
+const statusClientClosedRequest = 499
+
+var (
+	// ErrInvalidIdempotencyKey возвращается для пустого, слишком длинного или
+	// содержащего непечатаемые символы ключа идемпотентности.
+	ErrInvalidIdempotencyKey = fault.New(fault.Invalid, "invalid_idempotency_key", "invalid idempotency key")
+	// ErrIdempotencyKeyReused возвращается, если ключ идемпотентности уже использован
+	// для другого запроса: с другим телом или на другом эндпоинте.
+	ErrIdempotencyKeyReused = fault.New(fault.Invalid, "idempotency_key_reused", "idempotency key reused with a different request")
+	// ErrIdempotencyKeyInProgress возвращается, если первый запрос с ключом еще выполняется.
+	ErrIdempotencyKeyInProgress = fault.New(fault.Conflict, "idempotency_key_in_progress", "request with this idempotency key is in progress")
+)
+
+// IdempotentResponse — ответ на запрос с ключом идемпотентности, который сохраняется
+// и повторяется для запросов с тем же ключом.
+type IdempotentResponse struct {
+	Status int               // HTTP-статус ответа
+	Header map[string]string // Заголовки ответа, которые нужно повторить
+	Body   []byte            // Тело ответа
+}
+
+// Idempotent выполняет запрос do с ключом идемпотентности key пользователя userID.
+// Ответ первого запроса сохраняется на срок config.Service.IdempotencyTTL, а запросы
+// с тем же ключом получают его без выполнения do (replayed = true). Запрос
+// отождествляется методом scope и телом payload: другой запрос с тем же ключом
+// отклоняется ошибкой ErrIdempotencyKeyReused, а повтор, пришедший до завершения
+// первого, — ErrIdempotencyKeyInProgress. Ответы со статусом 5xx и 499 (запрос
+// отменен клиентом) не сохраняются, чтобы запрос можно было повторить. Пустой
+// ключ или нулевой срок хранения означают обычное выполнение do.
+// Пример:
+//
+//	res, replayed, err := s.Idempotent(ctx, userID, key, "POST /api/shorten", body, func() IdempotentResponse {
+//	    return IdempotentResponse{Status: http.StatusCreated, Body: []byte(`{"result":"http://short.ly/abc123"}`)}
+//	})
+func (s *Service) Idempotent(
+	ctx context.Context,
+	userID int,
+	key, scope string,
+	payload []byte,
+	do func() IdempotentResponse,
+) (res IdempotentResponse, replayed bool, err error) {
+	ttl := s.config.Service.IdempotencyTTL
+	if key == "" || ttl <= 0 {
+		return do(), false, nil
+	}
+	if !validIdempotencyKey(key) {
+		return IdempotentResponse{}, false, ErrInvalidIdempotencyKey
+	}
+	h := sha256.New()
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write(payload)
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	existing, err := s.store.AddIdempotencyKey(ctx, repository.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(ttl),
+	})
+	switch {
+	case errors.Is(err, repository.ErrExistsKey):
+		if existing.Fingerprint != fingerprint {
+			return IdempotentResponse{}, false, ErrIdempotencyKeyReused
+		}
+		if existing.Status == 0 {
+			return IdempotentResponse{}, false, ErrIdempotencyKeyInProgress
+		}
+		return IdempotentResponse{Status: existing.Status, Header: existing.Header, Body: existing.Body}, true, nil
+	case err != nil:
+		return IdempotentResponse{}, false, err
+	}
+
+	res = do()
+	// Ответ сохраняется и при отмене запроса клиентом: он может повторить запрос.
+	ctx = context.WithoutCancel(ctx)
+	if res.Status >= http.StatusInternalServerError || res.Status == statusClientClosedRequest {
+		if err := s.store.DeleteIdempotencyKey(ctx, userID, key); err != nil {
+			log.Printf("delete idempotency key: %v", err)
+		}
+		return res, false, nil
+	}
+	err = s.store.SetIdempotencyResponse(ctx, repository.IdempotencyKey{
+		UserID: userID,
+		Key:    key,
+		Status: res.Status,
+		Header: res.Header,
+		Body:   res.Body,
+	})
+	if err != nil {
+		log.Printf("save idempotent response: %v", err)
+	}
+	return res, false, nil
+}
+
+// validIdempotencyKey проверяет, что ключ непустой, не длиннее maxIdempotencyKeyLen
+// и состоит из печатаемых символов ASCII.
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// runIdempotencyPurger периодически удаляет истекшие ключи идемпотентности.
+func (s *Service) runIdempotencyPurger(interval time.Duration) {
+	defer s.workers.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.store.PurgeIdempotencyKeys(context.Background(), time.Now()); err != nil {
+				log.Printf("purge idempotency keys error: %v", err)
+			}
+		}
+	}
+}
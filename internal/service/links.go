@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// Ограничения метаданных ссылки.
+const (
+	MaxTitleLen = 512  // Максимальная длина заголовка в символах
+	MaxNoteLen  = 4096 // Максимальная длина заметки в символах
+	MaxTags     = 32   // Максимальное количество тегов
+	MaxTagLen   = 64   // Максимальная длина тега в символах
+)
+
+// Размеры страницы списка ссылок пользователя.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// ErrInvalidLinkMeta возвращается при недопустимых заголовке, тегах или заметке ссылки.
+var ErrInvalidLinkMeta = fault.New(fault.Invalid, "invalid_link_meta", "invalid link metadata")
+
+// ErrInvalidFilter возвращается при недопустимых параметрах списка ссылок.
+var ErrInvalidFilter = fault.New(fault.Invalid, "invalid_filter", "invalid link filter")
+
+// validateMaxClicks проверяет лимит переходов создаваемой ссылки.
+func validateMaxClicks(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("%w: max_clicks must not be negative", ErrInvalidLinkMeta)
+	}
+	return nil
+}
+
+// linkMeta проверяет заголовок и заметку ссылки и нормализует теги:
+// пробелы по краям отбрасываются, пустые теги и повторы удаляются.
+func linkMeta(title string, tags []string, note string) ([]string, error) {
+	if utf8.RuneCountInString(title) > MaxTitleLen {
+		return nil, fmt.Errorf("%w: title is longer than %d characters", ErrInvalidLinkMeta, MaxTitleLen)
+	}
+	if utf8.RuneCountInString(note) > MaxNoteLen {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidLinkMeta, MaxNoteLen)
+	}
+	return normalizeTags(tags)
+}
+
+// normalizeTags проверяет и нормализует список тегов.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLen {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidLinkMeta, tag, MaxTagLen)
+		}
+		seen[tag] = true
+		res = append(res, tag)
+	}
+	if len(res) > MaxTags {
+		return nil, fmt.Errorf("%w: more than %d tags", ErrInvalidLinkMeta, MaxTags)
+	}
+	return res, nil
+}
+
+// cursor — содержимое курсора страницы. Поле сортировки и порядок сохраняются,
+// чтобы курсор нельзя было применить к выборке с другой сортировкой.
+type cursor struct {
+	Sort      string    `json:"s"`
+	Asc       bool      `json:"a,omitempty"`
+	CreatedAt time.Time `json:"t,omitzero"`
+	Clicks    int64     `json:"c,omitempty"`
+	Domain    string    `json:"d,omitempty"`
+	Hash      string    `json:"h"`
+}
+
+// encodeCursor возвращает непрозрачный курсор позиции ссылки в выборке.
+func encodeCursor(q repository.LinkQuery, u repository.URL) string {
+	c := repository.CursorOf(u)
+	data, _ := json.Marshal(cursor{
+		Sort:      q.Sort,
+		Asc:       q.Asc,
+		CreatedAt: c.CreatedAt.UTC(),
+		Clicks:    c.Clicks,
+		Domain:    c.Domain,
+		Hash:      c.Hash,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор, выданный для выборки q.
+func decodeCursor(q repository.LinkQuery, s string) (*repository.LinkCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	var c cursor
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Hash == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+	if c.Sort != q.Sort || c.Asc != q.Asc {
+		return nil, fmt.Errorf("%w: cursor was issued for another sort order", ErrInvalidFilter)
+	}
+	return &repository.LinkCursor{CreatedAt: c.CreatedAt, Clicks: c.Clicks, Domain: c.Domain, Hash: c.Hash}, nil
+}
+
+// linkQuery преобразует параметры списка ссылок в запрос к хранилищу.
+func linkQuery(f model.LinkFilter) (repository.LinkQuery, error) {
+	q := repository.LinkQuery{Search: f.Query, Broken: f.Broken, Sort: repository.SortCreated, Limit: DefaultPageSize}
+	var errs []error
+	switch f.Sort {
+	case "", repository.SortCreated:
+	case repository.SortClicks:
+		q.Sort = repository.SortClicks
+	default:
+		errs = append(errs, fmt.Errorf("sort: expected created or clicks, got %q", f.Sort))
+	}
+	switch f.Order {
+	case "", "desc":
+	case "asc":
+		q.Asc = true
+	default:
+		errs = append(errs, fmt.Errorf("order: expected asc or desc, got %q", f.Order))
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		errs = append(errs, fmt.Errorf("limit: expected 1..%d, got %d", MaxPageSize, f.Limit))
+	} else if f.Limit > 0 {
+		q.Limit = f.Limit
+	}
+	for _, tag := range f.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			q.Tags = append(q.Tags, tag)
+		}
+	}
+	if len(errs) > 0 {
+		return q, fmt.Errorf("%w: %w", ErrInvalidFilter, errors.Join(errs...))
+	}
+	if f.Cursor != "" {
+		after, err := decodeCursor(q, f.Cursor)
+		if err != nil {
+			return q, err
+		}
+		q.After = after
+	}
+	return q, nil
+}
+
+// ListUserLinks возвращает страницу ссылок пользователя с поиском по заголовку,
+// заметке и адресу, фильтрами по тегам и недоступности адреса назначения
+// и сортировкой по времени создания или переходам.
+// Для продолжения выдачи курсор NextCursor передается в следующем запросе
+// с теми же параметрами сортировки.
+// Для недопустимых параметров возвращается ErrInvalidFilter.
+func (s *Service) ListUserLinks(ctx context.Context, userID int, f model.LinkFilter) (model.LinkPage, error) {
+	q, err := linkQuery(f)
+	if err != nil {
+		return model.LinkPage{}, err
+	}
+	limit := q.Limit
+	q.Limit++
+	urls, err := s.store.ListByUser(ctx, userID, q)
+	if err != nil {
+		return model.LinkPage{}, err
+	}
+
+	var page model.LinkPage
+	if len(urls) > limit {
+		urls = urls[:limit]
+		page.NextCursor = encodeCursor(q, urls[limit-1])
+	}
+	page.Links = make([]model.LinkPair, 0, len(urls))
+	for _, u := range urls {
+		link, err := s.linkPair(u)
+		if err != nil {
+			return model.LinkPage{}, err
+		}
+		page.Links = append(page.Links, link)
+	}
+	return page, nil
+}
+
+// GetLink возвращает ссылку пользователя по домену и хешу. Для чужой, удаленной
+// или несуществующей ссылки возвращается repository.ErrNotFound.
+// Пример:
+//
+//	link, err := s.GetLink(ctx, "", "abc123", userID)
+//	if errors.Is(err, repository.ErrNotFound) {
+//	    // ответ 404
+//	}
+func (s *Service) GetLink(ctx context.Context, domain, hash string, userID int) (model.LinkPair, error) {
+	u, err := s.store.GetByHash(ctx, s.domainName(domain), hash)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && (u.UserID != userID || u.DeletedFlag)) {
+		return model.LinkPair{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return model.LinkPair{}, err
+	}
+	return s.linkPair(u)
+}
+
+// CountClick учитывает переход по ссылке домена. Для ссылки с исчерпанным
+// лимитом переходов возвращает repository.ErrExhausted: переход по ней запрещен.
+// Прочие ошибки учета не должны мешать перенаправлению, поэтому они только
+// записываются в журнал.
+func (s *Service) CountClick(ctx context.Context, domain, hash string) error {
+	err := s.store.AddClick(ctx, s.domainName(domain), hash)
+	if errors.Is(err, repository.ErrExhausted) {
+		return err
+	}
+	if err != nil {
+		log.Printf("count click %s: %v", hash, err)
+	}
+	return nil
+}
+
+// linkPair преобразует ссылку хранилища в модель ответа.
+func (s *Service) linkPair(u repository.URL) (model.LinkPair, error) {
+	shortURL, err := s.makeURL(u.Domain, u.Hash)
+	if err != nil {
+		return model.LinkPair{}, err
+	}
+	var left *int64
+	if u.MaxClicks > 0 {
+		n := max(u.MaxClicks-u.Clicks, 0)
+		left = &n
+	}
+	var health *model.LinkHealth
+	if !u.Health.CheckedAt.IsZero() {
+		h := u.Health
+		health = &h
+	}
+	return model.LinkPair{
+		Hash:         u.Hash,
+		Domain:       u.Domain,
+		ShortURL:     shortURL,
+		OriginalURL:  u.Link,
+		Title:        u.Title,
+		Tags:         u.Tags,
+		Note:         u.Note,
+		Redirect:     u.Redirect,
+		CreatedAt:    u.CreatedAt,
+		Clicks:       u.Clicks,
+		Protected:    u.PasswordHash != "",
+		ExperimentID: u.Experiment,
+		MaxClicks:    u.MaxClicks,
+		ClicksLeft:   left,
+		Rules:        u.Rules,
+		Broken:       u.Health.Broken,
+		Health:       health,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress возвращается при попытке соединения с непубличным адресом.
+var errPrivateAddress = errors.New("destination address is not public")
+
+// reservedPrefixes — служебные сети, не покрытые проверками netip.Addr.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // «эта» сеть
+	netip.MustParsePrefix("100.64.0.0/10"),  // адреса провайдерского NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // служебные назначения IETF
+	netip.MustParsePrefix("198.18.0.0/15"),  // тестирование производительности
+	netip.MustParsePrefix("240.0.0.0/4"),    // зарезервированные и широковещательный адреса
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64 с встроенным адресом IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // локальный NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4 с встроенным адресом IPv4
+	netip.MustParsePrefix("2001:db8::/32"),  // документация
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("100::/64"),       // сброс трафика
+}
+
+// Транспорты запросов по адресам, заданным пользователями: подпискам на события,
+// страницам назначения при загрузке заголовков и проверке доступности.
+// Прокси из окружения не используется: проверялся бы адрес прокси, а не назначения.
+var (
+	publicTransport  = newOutboundTransport(publicDialControl)
+	privateTransport = newOutboundTransport(nil)
+)
+
+// newOutboundTransport возвращает транспорт, вызывающий control перед установкой
+// каждого соединения.
+func newOutboundTransport(control func(network, address string, c syscall.RawConn) error) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: control}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// outboundTransport возвращает транспорт запросов по адресам пользователей.
+// Соединения с непубличными адресами отклоняются, если не задан AllowPrivateTargets.
+func (s *Service) outboundTransport() http.RoundTripper {
+	if s.config.Service.AllowPrivateTargets {
+		return privateTransport
+	}
+	return publicTransport
+}
+
+// publicDialControl отклоняет соединение с непубличным адресом. Вызывается
+// для уже разрешенного адреса, поэтому подмена DNS не позволяет обойти проверку.
+func publicDialControl(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(ap.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// publicAddr сообщает, является ли адрес публичным адресом интернета:
+// не loopback, не частным, не link-local, не multicast и не служебным.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// outboundError возвращает описание ошибки исходящего запроса, которое можно
+// показать пользователю: без адресов и текста системных ошибок.
+func outboundError(err error) string {
+	var (
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+	switch {
+	case errors.Is(err, errPrivateAddress):
+		return "destination address is not allowed"
+	case errors.As(err, &dnsErr):
+		return "could not resolve host"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "connection failed"
+	}
+}
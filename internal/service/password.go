@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// Ограничения паролей ссылок.
+const (
+	// MaxPasswordLen — максимальная длина пароля в байтах (ограничение bcrypt).
+	MaxPasswordLen = 72
+	// MaxPasswordAttempts — число неверных паролей, после которого попытки
+	// с одного IP-адреса для ссылки блокируются до конца окна PasswordAttemptWindow.
+	MaxPasswordAttempts = 5
+	// PasswordAttemptWindow — окно подсчета неверных паролей.
+	PasswordAttemptWindow = 15 * time.Minute
+)
+
+// ErrPasswordRequired возвращается при переходе по защищенной ссылке без пароля.
+var ErrPasswordRequired = fault.New(fault.Forbidden, "password_required", "link password required")
+
+// ErrWrongPassword возвращается при неверном пароле ссылки.
+var ErrWrongPassword = fault.New(fault.Forbidden, "wrong_password", "wrong link password")
+
+// ErrTooManyAttempts возвращается, если с IP-адреса введено слишком много неверных паролей ссылки.
+var ErrTooManyAttempts = fault.New(fault.RateLimited, "too_many_attempts", "too many password attempts")
+
+// ErrInvalidPassword возвращается при недопустимом пароле создаваемой или редактируемой ссылки.
+var ErrInvalidPassword = fault.New(fault.Invalid, "invalid_password", "invalid link password")
+
+// hashPassword возвращает bcrypt-хеш пароля ссылки; для пустого пароля — пустую строку.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > MaxPasswordLen {
+		return "", fmt.Errorf("%w: password is longer than %d bytes", ErrInvalidPassword, MaxPasswordLen)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword проверяет пароль перехода по ссылке с IP-адреса ip.
+// Для ссылок без пароля проверка всегда успешна. Пустой пароль дает
+// ErrPasswordRequired и не считается попыткой; неверный — ErrWrongPassword.
+// После MaxPasswordAttempts неверных паролей за PasswordAttemptWindow
+// возвращается ErrTooManyAttempts, пока окно не истечет.
+// Пример:
+//
+//	err := s.CheckPassword(ctx, u, r.Header.Get("X-Link-Password"), clientip.String(ctx))
+func (s *Service) CheckPassword(_ context.Context, u repository.URL, password, ip string) error {
+	if u.PasswordHash == "" {
+		return nil
+	}
+	key := u.Domain + "/" + u.Hash + "/" + ip
+	if s.attempts.blocked(key) {
+		return ErrTooManyAttempts
+	}
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		if s.attempts.fail(key) {
+			return ErrTooManyAttempts
+		}
+		return ErrWrongPassword
+	}
+	s.attempts.reset(key)
+	return nil
+}
+
+// attemptLimiter считает неверные пароли по ссылке и IP-адресу.
+// Нулевое значение готово к использованию.
+type attemptLimiter struct {
+	mu      sync.Mutex
+	entries map[string]*attempts
+	swept   time.Time
+}
+
+// attempts — неверные пароли в текущем окне.
+type attempts struct {
+	count int
+	since time.Time
+}
+
+// blocked сообщает, исчерпаны ли попытки для ключа.
+func (l *attemptLimiter) blocked(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.entry(key, time.Now())
+	return a != nil && a.count >= MaxPasswordAttempts
+}
+
+// fail учитывает неверный пароль и сообщает, исчерпаны ли попытки.
+func (l *attemptLimiter) fail(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	a := l.entry(key, now)
+	if a == nil {
+		if l.entries == nil {
+			l.entries = make(map[string]*attempts)
+		}
+		a = &attempts{since: now}
+		l.entries[key] = a
+	}
+	a.count++
+	return a.count >= MaxPasswordAttempts
+}
+
+// reset сбрасывает счетчик после верного пароля.
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// entry возвращает действующую запись ключа. Просроченные записи удаляются
+// не чаще раза в окно, чтобы карта не росла неограниченно.
+// Вызывается под l.mu.
+func (l *attemptLimiter) entry(key string, now time.Time) *attempts {
+	if now.Sub(l.swept) >= PasswordAttemptWindow {
+		for k, a := range l.entries {
+			if now.Sub(a.since) >= PasswordAttemptWindow {
+				delete(l.entries, k)
+			}
+		}
+		l.swept = now
+	}
+	a, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+	if now.Sub(a.since) >= PasswordAttemptWindow {
+		delete(l.entries, key)
+		return nil
+	}
+	return a
+}
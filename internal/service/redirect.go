@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// ErrInvalidRedirect возвращается при недопустимых параметрах перенаправления.
+var ErrInvalidRedirect = fault.New(fault.Invalid, "invalid_redirect", "invalid redirect options")
+
+// allowedRedirectCodes содержит допустимые коды перенаправления.
+var allowedRedirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// validateRedirect проверяет параметры перенаправления.
+// Нулевой код допустим и означает код по умолчанию.
+func validateRedirect(opts model.RedirectOptions) error {
+	if opts.Code != 0 && !allowedRedirectCodes[opts.Code] {
+		return ErrInvalidRedirect
+	}
+	return nil
+}
+
+// RedirectCode возвращает HTTP-код перенаправления для ссылки.
+// Если код не задан, используется 307 Temporary Redirect.
+func RedirectCode(opts model.RedirectOptions) int {
+	if opts.Code == 0 {
+		return http.StatusTemporaryRedirect
+	}
+	return opts.Code
+}
+
+// RedirectTarget формирует итоговый адрес перенаправления для ссылки.
+// При включенном PassQuery параметры запроса объединяются с параметрами
+// оригинального URL (значения из запроса имеют приоритет), затем добавляются
+// UTM-метки, если соответствующие параметры еще не заданы.
+// Если дополнительных параметров нет, оригинальный URL возвращается без изменений.
+func RedirectTarget(u repository.URL, query url.Values) (string, error) {
+	opts := u.Redirect
+	if (!opts.PassQuery || len(query) == 0) && opts.UTM == (model.UTM{}) {
+		return u.Link, nil
+	}
+
+	target, err := url.Parse(u.Link)
+	if err != nil {
+		return "", err
+	}
+	q := target.Query()
+	if opts.PassQuery {
+		for k, v := range query {
+			q[k] = v
+		}
+	}
+	setUTM(q, opts.UTM)
+	target.RawQuery = q.Encode()
+	return target.String(), nil
+}
+
+// setUTM добавляет UTM-метки, не перезаписывая уже заданные значения.
+func setUTM(q url.Values, utm model.UTM) {
+	params := []struct {
+		key   string
+		value string
+	}{
+		{"utm_source", utm.Source},
+		{"utm_medium", utm.Medium},
+		{"utm_campaign", utm.Campaign},
+		{"utm_term", utm.Term},
+		{"utm_content", utm.Content},
+	}
+	for _, p := range params {
+		if p.value != "" && !q.Has(p.key) {
+			q.Set(p.key, p.value)
+		}
+	}
+}
+
+// Follow выполняет переход посетителя v по ссылке u и возвращает адрес перенаправления.
+// Ссылка должна быть уже проверена вызывающим: не удалена, не исчерпана, пароль верен.
+// Адрес назначения выбирается вариантом эксперимента и правилами ссылки, к нему
+// добавляются параметры query и UTM-метки (см. RedirectTarget). Переход засчитывается,
+// а наблюдатели получают событие audit.Follow и, для последнего допустимого перехода
+// ограниченной ссылки, audit.Expire. userID — пользователь, выполнивший переход
+// (0 для анонимных посетителей). Перенаправление HTTP и раскрытие ссылки по gRPC
+// и Connect используют этот метод, поэтому переходы по ним учитываются одинаково.
+// Пример:
+//
+//	target, err := s.Follow(ctx, u, Visitor{UserAgent: r.UserAgent(), IP: ip, Time: time.Now()}, r.URL.Query(), userID)
+func (s *Service) Follow(ctx context.Context, u repository.URL, v Visitor, query url.Values, userID int) (string, error) {
+	if u.Experiment != 0 {
+		// Вариант эксперимента закрепляется за посетителем и заменяет адрес ссылки.
+		link, err := s.Assign(ctx, u.Experiment, v.ID)
+		if err != nil {
+			return "", err
+		}
+		u.Link = link
+	}
+	dest := u
+	dest.Link = s.Destination(u, v)
+	target, err := RedirectTarget(dest, query)
+	if err != nil {
+		return "", err
+	}
+
+	// Переход по ограниченной ссылке засчитывается до перенаправления:
+	// параллельный переход мог исчерпать лимит после чтения ссылки.
+	if err := s.CountClick(ctx, u.Domain, u.Hash); err != nil {
+		return "", err
+	}
+	var ip string
+	if v.IP.IsValid() {
+		ip = v.IP.String()
+	}
+	shortURL, _ := s.ShortURL(u.Domain, u.Hash)
+	s.NotifyObservers(ctx, audit.Event{
+		Timestamp: time.Now(),
+		Action:    audit.Follow,
+		UserID:    userID,
+		URL:       u.Link,
+		IP:        ip,
+		OwnerID:   u.UserID,
+		ShortURL:  shortURL,
+	})
+	if u.MaxClicks > 0 && u.Clicks+1 >= u.MaxClicks {
+		// Счетчик прочитан до перехода, поэтому при параллельных последних
+		// переходах событие может быть отправлено повторно.
+		s.NotifyObservers(ctx, audit.Event{
+			Timestamp: time.Now(),
+			Action:    audit.Expire,
+			UserID:    userID,
+			URL:       u.Link,
+			OwnerID:   u.UserID,
+			ShortURL:  shortURL,
+		})
+	}
+	return target, nil
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// MaxRules — максимальное количество правил перенаправления ссылки.
+const MaxRules = 32
+
+// clockLayout — формат времени ежедневного интервала правила.
+const clockLayout = "15:04"
+
+// ErrInvalidRules возвращается при недопустимых правилах перенаправления.
+var ErrInvalidRules = fault.New(fault.Invalid, "invalid_rules", "invalid routing rules")
+
+// CountryLookup определяет страну (ISO 3166-1 alpha-2) по IP-адресу;
+// для неизвестных адресов возвращает пустую строку.
+type CountryLookup interface {
+	Country(ip netip.Addr) string
+}
+
+// Visitor описывает посетителя короткой ссылки: по нему выбирается правило перенаправления.
+// Пример:
+//
+//	v := Visitor{UserAgent: r.UserAgent(), AcceptLanguage: r.Header.Get("Accept-Language"), Time: time.Now()}
+type Visitor struct {
+	UserAgent      string     // Заголовок User-Agent
+	AcceptLanguage string     // Заголовок Accept-Language
+	IP             netip.Addr // Адрес клиента
+	Time           time.Time  // Время перехода
+	ID             string     // Идентификатор, за которым закрепляется вариант эксперимента
+}
+
+// SetGeoIP задает базу, по которой определяется страна посетителя для правил
+// с условием countries. Без базы такие правила отклоняются при создании ссылки
+// и не срабатывают при переходе.
+func (s *Service) SetGeoIP(geo CountryLookup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.geo = geo
+}
+
+// geoIP возвращает базу определения страны.
+func (s *Service) geoIP() CountryLookup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.geo
+}
+
+// Destination возвращает адрес перенаправления ссылки для посетителя: адрес первого
+// правила, все условия которого выполнены, или оригинальный URL, если таких правил нет.
+// Пример:
+//
+//	dest := s.Destination(u, Visitor{UserAgent: "Mozilla/5.0 (iPhone; ...)", Time: time.Now()})
+func (s *Service) Destination(u repository.URL, v Visitor) string {
+	if len(u.Rules) == 0 {
+		return u.Link
+	}
+	var (
+		country   string
+		lang      = preferredLanguage(v.AcceptLanguage)
+		platform  = Platform(v.UserAgent)
+		countryOK bool
+		offset    int
+	)
+	for _, r := range u.Rules {
+		ok := (len(r.Platforms) == 0 || slices.Contains(r.Platforms, platform)) &&
+			(len(r.Languages) == 0 || languageMatch(r.Languages, lang)) &&
+			timeMatch(r.Time, v.Time)
+		if ok && len(r.Countries) > 0 {
+			if !countryOK {
+				if geo := s.geoIP(); geo != nil {
+					country = geo.Country(v.IP)
+				}
+				countryOK = true
+			}
+			ok = country != "" && slices.Contains(r.Countries, country)
+		}
+		if r.Percent > 0 {
+			// Доля правила занимает следующий отрезок шкалы 0..99 независимо от
+			// остальных условий, чтобы доли правил не зависели друг от друга.
+			lo := offset
+			offset += r.Percent
+			if ok {
+				b := bucket(u, v)
+				ok = lo <= b && b < offset
+			}
+		}
+		if ok {
+			return r.URL
+		}
+	}
+	return u.Link
+}
+
+// validateRules проверяет правила перенаправления и нормализует их условия:
+// платформы и языки приводятся к нижнему регистру, страны — к верхнему.
+func (s *Service) validateRules(rules []model.RouteRule) ([]model.RouteRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > MaxRules {
+		return nil, fmt.Errorf("%w: more than %d rules", ErrInvalidRules, MaxRules)
+	}
+	res := make([]model.RouteRule, 0, len(rules))
+	percent := 0
+	var errs []error
+	for i, r := range rules {
+		r, err := normalizeRule(r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+			continue
+		}
+		if len(r.Countries) > 0 && s.geoIP() == nil {
+			errs = append(errs, fmt.Errorf("rule %d: countries require a GeoIP database", i+1))
+		}
+		percent += r.Percent
+		res = append(res, r)
+	}
+	if percent > 100 {
+		errs = append(errs, fmt.Errorf("percentages add up to %d, more than 100", percent))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRules, errors.Join(errs...))
+	}
+	return res, nil
+}
+
+// normalizeRule проверяет и нормализует одно правило.
+func normalizeRule(r model.RouteRule) (model.RouteRule, error) {
+	if !isURL(r.URL) {
+		return r, fmt.Errorf("invalid url %q", r.URL)
+	}
+	if len(r.Platforms) == 0 && len(r.Languages) == 0 && len(r.Countries) == 0 && r.Time == nil && r.Percent == 0 {
+		return r, errors.New("no conditions")
+	}
+	platforms := make([]string, 0, len(r.Platforms))
+	for _, p := range r.Platforms {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != model.PlatformIOS && p != model.PlatformAndroid && p != model.PlatformDesktop {
+			return r, fmt.Errorf("platform: expected ios, android or desktop, got %q", p)
+		}
+		platforms = append(platforms, p)
+	}
+	languages := make([]string, 0, len(r.Languages))
+	for _, l := range r.Languages {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if !validLanguage(l) {
+			return r, fmt.Errorf("invalid language %q", l)
+		}
+		languages = append(languages, l)
+	}
+	countries := make([]string, 0, len(r.Countries))
+	for _, c := range r.Countries {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if len(c) != 2 || c[0] < 'A' || c[0] > 'Z' || c[1] < 'A' || c[1] > 'Z' {
+			return r, fmt.Errorf("country: expected ISO 3166-1 alpha-2 code, got %q", c)
+		}
+		countries = append(countries, c)
+	}
+	if r.Percent < 0 || r.Percent > 100 {
+		return r, fmt.Errorf("percent: expected 1..100, got %d", r.Percent)
+	}
+	if r.Time != nil {
+		if err := validateWindow(*r.Time); err != nil {
+			return r, err
+		}
+	}
+	r.Platforms = nilIfEmpty(platforms)
+	r.Languages = nilIfEmpty(languages)
+	r.Countries = nilIfEmpty(countries)
+	return r, nil
+}
+
+// validateWindow проверяет период действия правила.
+func validateWindow(w model.TimeWindow) error {
+	if !w.Start.IsZero() && !w.End.IsZero() && !w.Start.Before(w.End) {
+		return errors.New("time: start must be before end")
+	}
+	if (w.From == "") != (w.To == "") {
+		return errors.New("time: from and to must be set together")
+	}
+	if w.From != "" {
+		from, err := time.Parse(clockLayout, w.From)
+		if err != nil {
+			return fmt.Errorf("time: from: expected HH:MM, got %q", w.From)
+		}
+		to, err := time.Parse(clockLayout, w.To)
+		if err != nil {
+			return fmt.Errorf("time: to: expected HH:MM, got %q", w.To)
+		}
+		if from.Equal(to) {
+			return errors.New("time: from and to must differ")
+		}
+	}
+	if w.Location != "" {
+		if _, err := location(w.Location); err != nil {
+			return fmt.Errorf("time: unknown location %q", w.Location)
+		}
+	}
+	return nil
+}
+
+// timeMatch сообщает, попадает ли время в период действия правила.
+func timeMatch(w *model.TimeWindow, t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	if !w.Start.IsZero() && t.Before(w.Start) {
+		return false
+	}
+	if !w.End.IsZero() && !t.Before(w.End) {
+		return false
+	}
+	if w.From == "" {
+		return true
+	}
+	loc, err := location(w.Location)
+	if err != nil {
+		return false
+	}
+	from, _ := time.Parse(clockLayout, w.From)
+	to, _ := time.Parse(clockLayout, w.To)
+	local := t.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	lo, hi := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+	if lo < hi {
+		return lo <= m && m < hi
+	}
+	// Интервал переходит через полночь, например 22:00–06:00.
+	return m >= lo || m < hi
+}
+
+// locations кэширует загруженные часовые пояса: правила проверяются при каждом переходе.
+var locations sync.Map
+
+// location возвращает часовой пояс по имени IANA; пустое имя означает UTC.
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Platform определяет платформу посетителя по заголовку User-Agent:
+// model.PlatformIOS, model.PlatformAndroid или model.PlatformDesktop.
+// Для прочих клиентов (роботы, консольные утилиты) возвращается пустая строка.
+// Пример:
+//
+//	Platform("Mozilla/5.0 (Linux; Android 14; Pixel 8) ...") // "android"
+func Platform(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return model.PlatformIOS
+	case strings.Contains(ua, "Android"):
+		return model.PlatformAndroid
+	case strings.Contains(ua, "Mobile"):
+		return ""
+	case strings.Contains(ua, "Windows NT"), strings.Contains(ua, "Macintosh"),
+		strings.Contains(ua, "X11"), strings.Contains(ua, "CrOS"):
+		return model.PlatformDesktop
+	}
+	return ""
+}
+
+// preferredLanguage возвращает наиболее предпочтительный язык из заголовка
+// Accept-Language в нижнем регистре; при равных весах — первый из перечисленных.
+func preferredLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
+
+// languageMatch сообщает, совпадает ли язык посетителя с одним из языков правила:
+// язык правила совпадает с равным ему языком и с его уточнениями ("en" — с "en-gb").
+func languageMatch(languages []string, lang string) bool {
+	if lang == "" {
+		return false
+	}
+	for _, l := range languages {
+		if lang == l || strings.HasPrefix(lang, l+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// validLanguage проверяет языковой тег: подтеги из латинских букв и цифр через дефис.
+func validLanguage(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, sub := range strings.Split(tag, "-") {
+		if sub == "" || len(sub) > 8 {
+			return false
+		}
+		for _, c := range sub {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// bucket возвращает долю посетителя (0..99) для правил с процентами. Доля вычисляется
+// по ссылке, адресу и User-Agent, поэтому посетитель при повторных переходах
+// попадает на тот же адрес.
+func bucket(u repository.URL, v Visitor) int {
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%s/%s|%s|%s", u.Domain, u.Hash, v.IP, v.UserAgent)
+	return int(h.Sum32() % 100)
+}
+
+// nilIfEmpty заменяет пустой список nil, чтобы незаданные условия не попадали в JSON.
+func nilIfEmpty(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
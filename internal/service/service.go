@@ -9,14 +9,15 @@ import (
 	"log"
 	"math/big"
 	"net/url"
-	"runtime"
 	"sync"
+	"time"
 
 	"github.com/spitfy/urlshortener/internal/audit"
 	"github.com/spitfy/urlshortener/internal/model"
 
 	"github.com/spitfy/urlshortener/internal/config"
 	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
 )
 
 const (
@@ -24,6 +25,16 @@ const (
 	CharCnt = 8
 )
 
+// ErrInvalidURL возвращается, если переданная строка не является валидным URL.
+var ErrInvalidURL = fault.New(fault.Invalid, "invalid_url", "invalid url")
+
+// ErrDeleted описывает удаленную ссылку: хранилище возвращает ее с DeletedFlag,
+// а транспорт отвечает этой ошибкой.
+var ErrDeleted = fault.New(fault.Gone, "url_deleted", "URL deleted")
+
+// ErrBatchRejected возвращается, если пакет в режиме atomic содержит невалидные элементы.
+var ErrBatchRejected = fault.New(fault.Invalid, "batch_rejected", "batch rejected: contains invalid items")
+
 // RandString генерирует случайную строку заданной длины из набора символов chars.
 func RandString(n int) string {
 	b := make([]byte, n)
@@ -34,91 +45,432 @@ func RandString(n int) string {
 	return string(b)
 }
 
+// Параметры обработки очереди заданий на удаление.
+const (
+	// deleteBatchSize — максимальное число заданий, объединяемых в одно массовое обновление.
+	deleteBatchSize = 100
+	// deletePollInterval — период опроса очереди, если новых заданий не поступало.
+	deletePollInterval = time.Second
+)
+
 // Service реализует бизнес-логику сервиса сокращения URL.
 type Service struct {
-	store     repository.Storer
-	config    config.Config
-	deleteQ   chan repository.UserHash
-	observers []audit.Observer
-	mu        sync.Mutex
+	store      repository.Storer
+	config     config.Config
+	deleteWake chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	workers    sync.WaitGroup
+	observers  []audit.Observer
+	mu         sync.Mutex
+	domains    domains
+	attempts   attemptLimiter
+	geo        CountryLookup
+	hosts      hostLimiter
+	titles     titleQueue
+	hookWake   chan struct{}
+	clicks     clickCounter
+	streams    streamHub
 }
 
-// NewService создает новый экземпляр Service.
+// NewService создает новый экземпляр Service и запускает обработчики очереди удаления
+// и доставки событий подпискам и, если заданы cfg.Service.PurgeInterval, cfg.Service.HealthCheckInterval
+// и cfg.Service.IdempotencyTTL, окончательное удаление просроченных ссылок, проверку адресов назначения
+// и удаление истекших ключей идемпотентности.
 func NewService(cfg config.Config, store repository.Storer) *Service {
 	s := &Service{
-		store:   store,
-		config:  cfg,
-		deleteQ: make(chan repository.UserHash, 100),
+		store:      store,
+		config:     cfg,
+		deleteWake: make(chan struct{}, 1),
+		hookWake:   make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		domains:    newDomains(cfg.Service.ServerURL, cfg.Service.Domains),
+	}
+
+	s.workers.Add(2)
+	go s.runDeleteWorker()
+	go s.runWebhookWorker()
+
+	if cfg.Service.PurgeInterval > 0 {
+		s.workers.Add(1)
+		go s.runPurger(cfg.Service.PurgeInterval)
 	}
 
-	maxProcs := runtime.GOMAXPROCS(0)
-	for i := 0; i < maxProcs; i++ {
-		go s.runDeleteWorker()
+	if cfg.Service.HealthCheckInterval > 0 {
+		s.workers.Add(1)
+		go s.runHealthChecker(cfg.Service.HealthCheckInterval)
+	}
+
+	if cfg.Service.IdempotencyTTL > 0 {
+		s.workers.Add(1)
+		go s.runIdempotencyPurger(min(cfg.Service.IdempotencyTTL, time.Hour))
 	}
 
 	return s
 }
 
-// runDeleteWorker обрабатывает задачи на удаление URL из хранилища.
+// runDeleteWorker обрабатывает сохраненные задания на удаление URL.
+// Задания, накопившиеся к моменту пробуждения, объединяются в массовые обновления.
+// После остановки сервиса оставшиеся задания обрабатываются до конца.
 func (s *Service) runDeleteWorker() {
-	for uh := range s.deleteQ {
-		if err := s.store.BatchDelete(context.Background(), uh); err != nil {
+	defer s.workers.Done()
+	ticker := time.NewTicker(deletePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			s.drainDeletions()
+			return
+		case <-s.deleteWake:
+		case <-ticker.C:
+		}
+		s.drainDeletions()
+	}
+}
+
+// drainDeletions обрабатывает задания на удаление, пока очередь не опустеет.
+// Событие audit.Delete отправляется только для ссылок, которые действительно
+// были удалены, поэтому чужие и несуществующие хеши заданий событий не порождают.
+func (s *Service) drainDeletions() {
+	for {
+		n, deleted, err := s.store.ProcessDeletions(context.Background(), deleteBatchSize)
+		if err != nil {
 			log.Printf("batch delete error: %v", err)
+			return
+		}
+		for _, u := range deleted {
+			shortURL, _ := s.ShortURL(u.Domain, u.Hash)
+			s.NotifyObservers(context.Background(), audit.Event{
+				Timestamp: time.Now(),
+				Action:    audit.Delete,
+				UserID:    u.UserID,
+				URL:       u.Link,
+				OwnerID:   u.UserID,
+				ShortURL:  shortURL,
+			})
+		}
+		if n < deleteBatchSize {
+			return
 		}
 	}
 }
 
-// DeleteEnqueue добавляет хеши URL в очередь на удаление.
-func (s *Service) DeleteEnqueue(_ context.Context, hashes []string, userID int) {
-	s.deleteQ <- repository.UserHash{
+// runPurger периодически окончательно удаляет ссылки, срок восстановления которых истек.
+func (s *Service) runPurger(interval time.Duration) {
+	defer s.workers.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.Purge(context.Background()); err != nil {
+				log.Printf("purge error: %v", err)
+			}
+		}
+	}
+}
+
+// Purge окончательно удаляет ссылки, удаленные раньше срока восстановления.
+// Возвращает количество удаленных ссылок.
+func (s *Service) Purge(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.config.Service.RetentionPeriod)
+	return s.store.Purge(ctx, before, s.config.Service.PurgeFreeHash)
+}
+
+// Restore восстанавливает удаленные ссылки пользователя в домене, если срок восстановления не истек.
+func (s *Service) Restore(ctx context.Context, domain string, hashes []string, userID int) ([]model.RestoreResult, error) {
+	since := time.Now().Add(-s.config.Service.RetentionPeriod)
+	return s.store.Restore(ctx, repository.UserHash{UserID: userID, Domain: s.domainName(domain), Hash: hashes}, since)
+}
+
+// stopContext возвращает контекст, отменяемый при остановке сервиса.
+func (s *Service) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Shutdown останавливает фоновые обработчики, предварительно выполнив все
+// ожидающие задания на удаление. Незавершенные к истечению ctx задания
+// остаются в хранилище и будут выполнены после перезапуска.
+// Открытые потоки событий закрываются с ошибкой ErrStreamClosed.
+// Повторный вызов, например при одновременной ошибке сервера и сигнале,
+// только дожидается остановки обработчиков.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.streams.close()
+	})
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeleteEnqueue сохраняет задание на удаление URL домена и возвращает его для отслеживания статуса.
+// Вызов не блокируется обработкой очереди.
+func (s *Service) DeleteEnqueue(ctx context.Context, domain string, hashes []string, userID int) (model.DeletionJob, error) {
+	job, err := s.store.EnqueueDeletion(ctx, repository.UserHash{
 		UserID: userID,
+		Domain: s.domainName(domain),
 		Hash:   hashes,
+	})
+	if err != nil {
+		return model.DeletionJob{}, err
+	}
+	select {
+	case s.deleteWake <- struct{}{}:
+	default:
 	}
+	return deletionToModel(job), nil
 }
 
-// Add создает сокращенный URL для заданной ссылки.
-func (s *Service) Add(ctx context.Context, link string, userID int) (string, error) {
-	if !isURL(link) {
-		return "", errors.New("invalid url")
+// GetDeletion возвращает состояние задания на удаление пользователя.
+func (s *Service) GetDeletion(ctx context.Context, id int64, userID int) (model.DeletionJob, error) {
+	job, err := s.store.GetDeletion(ctx, id, userID)
+	if err != nil {
+		return model.DeletionJob{}, err
 	}
+	return deletionToModel(job), nil
+}
 
-	hash := RandString(CharCnt)
-	u := repository.URL{Link: link, Hash: hash}
-	hash, err := s.store.Add(ctx, u, userID)
+// deletionToModel преобразует задание на удаление в модель ответа.
+func deletionToModel(job repository.DeletionJob) model.DeletionJob {
+	return model.DeletionJob{
+		ID:          job.ID,
+		Domain:      job.Domain,
+		Status:      job.Status,
+		Hashes:      job.Hashes,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+}
 
+// Add создает сокращенный URL для заданной ссылки с дополнительными параметрами.
+// Ссылка создается в домене opts.Domain (по умолчанию — в домене сервиса);
+// для незарегистрированного домена возвращается ErrUnknownDomain, для домена,
+// недоступного пользователю, — ErrDomainForbidden.
+// Если заголовок не задан и включена загрузка заголовков, он загружается
+// со страницы назначения в фоне.
+func (s *Service) Add(ctx context.Context, link string, opts model.LinkOptions, userID int) (string, error) {
+	domain, hash, err := s.add(ctx, link, opts, userID)
 	if err != nil && !errors.Is(err, repository.ErrExistsURL) {
 		return "", err
 	}
-	shortURL, errMakeURL := s.makeURL(hash)
+	shortURL, errMakeURL := s.makeURL(domain, hash)
 	if errMakeURL != nil {
 		return "", errMakeURL
 	}
+	return shortURL, err
+}
+
+// AddLink создает ссылку так же, как Add, и возвращает ее со всеми метаданными.
+// Если URL уже сокращен, возвращается существующая ссылка и repository.ErrExistsURL;
+// метаданные чужой ссылки не раскрываются: заполнены только хеш, домен и адреса.
+// Пример:
+//
+//	link, err := s.AddLink(ctx, "https://example.com", model.LinkOptions{Title: "Пример"}, userID)
+//	if err != nil && !errors.Is(err, repository.ErrExistsURL) {
+//	    return err
+//	}
+//	fmt.Println(link.Hash, link.ShortURL, link.CreatedAt)
+func (s *Service) AddLink(ctx context.Context, link string, opts model.LinkOptions, userID int) (model.LinkPair, error) {
+	domain, hash, err := s.add(ctx, link, opts, userID)
+	if err != nil && !errors.Is(err, repository.ErrExistsURL) {
+		return model.LinkPair{}, err
+	}
+	u, errGet := s.store.GetByHash(ctx, domain, hash)
+	if errGet != nil {
+		return model.LinkPair{}, errGet
+	}
+	if u.UserID != userID {
+		u = repository.URL{Hash: hash, Domain: domain, Link: u.Link}
+	}
+	res, errMakeURL := s.linkPair(u)
+	if errMakeURL != nil {
+		return model.LinkPair{}, errMakeURL
+	}
+	return res, err
+}
+
+// add проверяет параметры и сохраняет ссылку. Возвращает домен и хеш новой
+// ссылки или, вместе с repository.ErrExistsURL, существующей.
+func (s *Service) add(ctx context.Context, link string, opts model.LinkOptions, userID int) (domainName, hash string, err error) {
+	if !isURL(link) {
+		return "", "", ErrInvalidURL
+	}
+	if err := validateRedirect(opts.Redirect); err != nil {
+		return "", "", err
+	}
+	tags, err := linkMeta(opts.Title, opts.Tags, opts.Note)
+	if err != nil {
+		return "", "", err
+	}
+	if err := validateMaxClicks(opts.MaxClicks); err != nil {
+		return "", "", err
+	}
+	rules, err := s.validateRules(opts.Rules)
+	if err != nil {
+		return "", "", err
+	}
+	domain, err := s.userDomain(opts.Domain, userID)
+	if err != nil {
+		return "", "", err
+	}
+	passwordHash, err := hashPassword(opts.Password)
+	if err != nil {
+		return "", "", err
+	}
+
+	u := repository.URL{
+		Link:     link,
+		Hash:     RandString(CharCnt),
+		Domain:   domain.Name,
+		Redirect: opts.Redirect,
+		Title:    opts.Title,
+		Tags:     tags,
+		Note:     opts.Note,
+
+		PasswordHash: passwordHash,
+		MaxClicks:    opts.MaxClicks,
+		Rules:        rules,
+	}
+	hash, err = s.store.Add(ctx, u, userID)
 	if errors.Is(err, repository.ErrExistsURL) {
-		return shortURL, repository.ErrExistsURL
+		return domain.Name, hash, err
 	}
-	return shortURL, nil
+	if err != nil {
+		return "", "", err
+	}
+	if u.Title == "" && s.config.Service.FetchTitles {
+		s.fetchTitleAsync(u.Domain, hash, link)
+	}
+	return domain.Name, hash, nil
 }
 
-// BatchAdd создает несколько сокращенных URL для списка ссылок.
+// BatchAdd создает несколько сокращенных URL для списка ссылок за одно обращение к хранилищу.
+// Для каждого элемента возвращается статус: created, existing или invalid.
+// В режиме BatchAtomic при наличии невалидных элементов ничего не сохраняется:
+// возвращаются только невалидные элементы и ErrBatchRejected.
+// В режиме BatchBestEffort сохраняются валидные элементы.
 func (s *Service) BatchAdd(
 	ctx context.Context,
 	req []model.BatchCreateRequest,
+	mode model.BatchMode,
 	userID int,
 ) ([]model.BatchCreateResponse, error) {
-	res := make([]model.BatchCreateResponse, 0, len(req))
-	for _, r := range req {
-		shortURL, err := s.Add(ctx, r.OriginalURL, userID)
+	res := make([]model.BatchCreateResponse, len(req))
+	urls := make([]repository.URL, 0, len(req))
+	idx := make([]int, 0, len(req))
+	invalid := make([]model.BatchCreateResponse, 0)
+	for i, r := range req {
+		res[i].CorrelationID = r.CorrelationID
+		err := ErrInvalidURL
+		if isURL(r.OriginalURL) {
+			err = validateRedirect(r.Redirect)
+		}
+		var tags []string
+		if err == nil {
+			tags, err = linkMeta(r.Title, r.Tags, r.Note)
+		}
+		if err == nil {
+			err = validateMaxClicks(r.MaxClicks)
+		}
+		var rules []model.RouteRule
+		if err == nil {
+			rules, err = s.validateRules(r.Rules)
+		}
+		var domain Domain
+		if err == nil {
+			domain, err = s.userDomain(r.Domain, userID)
+		}
+		var passwordHash string
+		if err == nil {
+			passwordHash, err = hashPassword(r.Password)
+		}
+		if err != nil {
+			res[i].Status = model.BatchStatusInvalid
+			res[i].Error = err.Error()
+			invalid = append(invalid, res[i])
+			continue
+		}
+		urls = append(urls, repository.URL{
+			Link:     r.OriginalURL,
+			Hash:     RandString(CharCnt),
+			Domain:   domain.Name,
+			Redirect: r.Redirect,
+			Title:    r.Title,
+			Tags:     tags,
+			Note:     r.Note,
+
+			PasswordHash: passwordHash,
+			MaxClicks:    r.MaxClicks,
+			Rules:        rules,
+		})
+		idx = append(idx, i)
+	}
+
+	if len(invalid) > 0 && mode != model.BatchBestEffort {
+		return invalid, ErrBatchRejected
+	}
+	if len(urls) == 0 {
+		return res, nil
+	}
+
+	added, err := s.store.BatchAdd(ctx, urls, userID)
+	if err != nil {
+		return nil, err
+	}
+	for j, a := range added {
+		i := idx[j]
+		shortURL, err := s.makeURL(urls[j].Domain, a.Hash)
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, model.BatchCreateResponse{CorrelationID: r.CorrelationID, ShortURL: shortURL})
+		res[i].ShortURL = shortURL
+		res[i].Status = model.BatchStatusCreated
+		if a.Exists {
+			res[i].Status = model.BatchStatusExisting
+		} else if urls[j].Title == "" && s.config.Service.FetchTitles {
+			s.fetchTitleAsync(urls[j].Domain, a.Hash, urls[j].Link)
+		}
 	}
 	return res, nil
 }
 
-// GetByHash возвращает оригинальный URL по его хешу.
-func (s *Service) GetByHash(ctx context.Context, hash string) (repository.URL, error) {
-	return s.store.GetByHash(ctx, hash)
+// GetByHash возвращает оригинальный URL по его хешу в домене.
+func (s *Service) GetByHash(ctx context.Context, domain, hash string) (repository.URL, error) {
+	return s.store.GetByHash(ctx, s.domainName(domain), hash)
+}
+
+// ShortURL возвращает полный сокращенный URL ссылки домена.
+func (s *Service) ShortURL(domain, hash string) (string, error) {
+	return s.makeURL(s.domainName(domain), hash)
+}
+
+// DomainByHost возвращает домен, обслуживающий хост из заголовка Host.
+// Для незарегистрированных хостов возвращается домен по умолчанию.
+func (s *Service) DomainByHost(host string) Domain {
+	if d, ok := s.domains.lookup(host); ok {
+		return d
+	}
+	d, _ := s.domains.lookup("")
+	return d
 }
 
 // Ping проверяет доступность хранилища.
@@ -134,13 +486,98 @@ func (s *Service) GetByUserID(ctx context.Context, id int) ([]model.LinkPair, er
 	}
 	res := make([]model.LinkPair, 0, len(links))
 	for _, u := range links {
-		ShortURL, err := s.makeURL(u.Hash)
+		link, err := s.linkPair(u)
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, model.LinkPair{
-			ShortURL:    ShortURL,
-			OriginalURL: u.Link,
+		res = append(res, link)
+	}
+	return res, nil
+}
+
+// Update редактирует ссылку пользователя: адрес назначения, заголовок, теги, заметку,
+// пароль, параметры и правила перенаправления. Незаданные поля запроса остаются без изменений;
+// пустой пароль снимает защиту ссылки. Адрес и правила ссылки эксперимента
+// задаются его вариантами и не редактируются (ErrInvalidExperiment).
+// Возвращает repository.ErrNotFound, если ссылка не принадлежит пользователю,
+// и repository.ErrExistsURL с сокращенным URL существующей ссылки, если новый
+// адрес уже сокращен в домене ссылки.
+func (s *Service) Update(ctx context.Context, domain, hash string, upd model.LinkUpdate, userID int) (model.LinkPair, error) {
+	u, err := s.store.GetByHash(ctx, s.domainName(domain), hash)
+	if err != nil || u.UserID != userID || u.DeletedFlag {
+		return model.LinkPair{}, repository.ErrNotFound
+	}
+	if u.Experiment != 0 && (upd.OriginalURL != nil || upd.Rules != nil) {
+		return model.LinkPair{}, fmt.Errorf("%w: destinations of an experiment link are set by its variants", ErrInvalidExperiment)
+	}
+
+	if upd.OriginalURL != nil {
+		if !isURL(*upd.OriginalURL) {
+			return model.LinkPair{}, ErrInvalidURL
+		}
+		u.Link = *upd.OriginalURL
+	}
+	if upd.Title != nil {
+		u.Title = *upd.Title
+	}
+	if upd.Tags != nil {
+		u.Tags = *upd.Tags
+	}
+	if upd.Note != nil {
+		u.Note = *upd.Note
+	}
+	if u.Tags, err = linkMeta(u.Title, u.Tags, u.Note); err != nil {
+		return model.LinkPair{}, err
+	}
+	if upd.Redirect != nil {
+		if err := validateRedirect(*upd.Redirect); err != nil {
+			return model.LinkPair{}, err
+		}
+		u.Redirect = *upd.Redirect
+	}
+	if upd.Rules != nil {
+		if u.Rules, err = s.validateRules(*upd.Rules); err != nil {
+			return model.LinkPair{}, err
+		}
+	}
+	if upd.Password != nil {
+		if u.PasswordHash, err = hashPassword(*upd.Password); err != nil {
+			return model.LinkPair{}, err
+		}
+	}
+
+	existing, err := s.store.Update(ctx, u, userID)
+	if errors.Is(err, repository.ErrExistsURL) {
+		shortURL, errMakeURL := s.makeURL(u.Domain, existing)
+		if errMakeURL != nil {
+			return model.LinkPair{}, errMakeURL
+		}
+		return model.LinkPair{Hash: existing, Domain: u.Domain, ShortURL: shortURL, OriginalURL: u.Link}, err
+	}
+	if err != nil {
+		return model.LinkPair{}, err
+	}
+
+	return s.linkPair(u)
+}
+
+// GetRevisions возвращает историю изменений ссылки пользователя в домене (новые первыми).
+func (s *Service) GetRevisions(ctx context.Context, domain, hash string, userID int) ([]model.LinkRevision, error) {
+	revs, err := s.store.GetRevisions(ctx, s.domainName(domain), hash, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.LinkRevision, 0, len(revs))
+	for _, r := range revs {
+		res = append(res, model.LinkRevision{
+			Revision:    r.Number,
+			OriginalURL: r.Link,
+			Title:       r.Title,
+			Tags:        r.Tags,
+			Note:        r.Note,
+			Redirect:    r.Redirect,
+			Rules:       r.Rules,
+			EditedAt:    r.EditedAt,
 		})
 	}
 	return res, nil
@@ -154,9 +591,34 @@ func (s *Service) CreateUser(ctx context.Context) (int, error) {
 	return id, nil
 }
 
-// makeURL формирует полный сокращенный URL на основе хеша.
-func (s *Service) makeURL(hash string) (string, error) {
-	addr, err := url.JoinPath(s.config.Service.ServerURL, hash)
+// userDomain возвращает домен, в котором пользователь создает ссылку.
+func (s *Service) userDomain(name string, userID int) (Domain, error) {
+	d, ok := s.domains.lookup(name)
+	if !ok {
+		return Domain{}, ErrUnknownDomain
+	}
+	if !d.allows(userID) {
+		return Domain{}, ErrDomainForbidden
+	}
+	return d, nil
+}
+
+// domainName приводит имя домена к виду, в котором он хранится в хранилище:
+// домен по умолчанию хранится с пустым именем.
+func (s *Service) domainName(name string) string {
+	if d, ok := s.domains.lookup(name); ok {
+		return d.Name
+	}
+	return HostName(name)
+}
+
+// makeURL формирует полный сокращенный URL на основе домена и хеша.
+func (s *Service) makeURL(domain, hash string) (string, error) {
+	base := s.config.Service.ServerURL
+	if d, ok := s.domains.lookup(domain); ok && d.BaseURL != "" {
+		base = d.BaseURL
+	}
+	addr, err := url.JoinPath(base, hash)
 	if err != nil {
 		return "", fmt.Errorf("can't create short url: %w", err)
 	}
@@ -176,12 +638,24 @@ func (s *Service) AddObserver(observer audit.Observer) {
 	s.observers = append(s.observers, observer)
 }
 
-// NotifyObservers уведомляет всех наблюдателей о событии.
+// SetObservers заменяет всех наблюдателей аудита, например при перечитывании конфигурации.
+// События, отправленные до замены, доставляются прежним наблюдателям.
+func (s *Service) SetObservers(observers []audit.Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append([]audit.Observer(nil), observers...)
+}
+
+// NotifyObservers уведомляет всех наблюдателей о событии, в том числе
+// подписки владельца ссылки (см. CreateWebhook) и его открытые потоки (см. Subscribe).
 func (s *Service) NotifyObservers(ctx context.Context, event audit.Event) {
+	s.publishStream(event)
+
 	s.mu.Lock()
-	observers := make([]audit.Observer, len(s.observers))
+	observers := make([]audit.Observer, len(s.observers), len(s.observers)+1)
 	copy(observers, s.observers)
 	s.mu.Unlock()
+	observers = append(observers, audit.ObserverFunc(s.notifyWebhooks))
 
 	for _, observer := range observers {
 		go func() {
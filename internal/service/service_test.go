@@ -1,14 +1,30 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/model"
 
 	serviceConf "github.com/spitfy/urlshortener/internal/service/config"
 
 	"github.com/spitfy/urlshortener/internal/config"
 	"github.com/spitfy/urlshortener/internal/repository"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestService_makeURL(t *testing.T) {
@@ -30,7 +46,7 @@ func TestService_makeURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			link, err := s.makeURL(tt.hash)
+			link, err := s.makeURL("", tt.hash)
 
 			assert.NoError(t, err, "Error making url")
 			assert.Equal(t, tt.want, link, "Wrong url")
@@ -85,3 +101,1183 @@ func BenchmarkRandString(b *testing.B) {
 		})
 	}
 }
+
+func TestRedirectTarget(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   repository.URL
+		query url.Values
+		want  string
+	}{
+		{
+			name:  "verbatim",
+			url:   repository.URL{Link: "https://example.com/path?b=2&a=1"},
+			query: url.Values{"x": {"1"}},
+			want:  "https://example.com/path?b=2&a=1",
+		},
+		{
+			name: "pass query merges with target",
+			url: repository.URL{
+				Link:     "https://example.com/path?a=1&b=2",
+				Redirect: model.RedirectOptions{PassQuery: true},
+			},
+			query: url.Values{"b": {"3"}, "c": {"4"}},
+			want:  "https://example.com/path?a=1&b=3&c=4",
+		},
+		{
+			name: "utm does not override existing params",
+			url: repository.URL{
+				Link: "https://example.com/?utm_source=site",
+				Redirect: model.RedirectOptions{
+					UTM: model.UTM{Source: "mail", Campaign: "sale"},
+				},
+			},
+			want: "https://example.com/?utm_campaign=sale&utm_source=site",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RedirectTarget(tt.url, tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRedirectCode(t *testing.T) {
+	assert.Equal(t, http.StatusTemporaryRedirect, RedirectCode(model.RedirectOptions{}))
+	assert.Equal(t, http.StatusMovedPermanently, RedirectCode(model.RedirectOptions{Code: http.StatusMovedPermanently}))
+	assert.NoError(t, validateRedirect(model.RedirectOptions{Code: http.StatusPermanentRedirect}))
+	assert.ErrorIs(t, validateRedirect(model.RedirectOptions{Code: http.StatusOK}), ErrInvalidRedirect)
+}
+
+func TestService_Update(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	_, err = store.Add(ctx, repository.URL{Hash: "EDITME01", Link: "https://example.com/old"}, 1)
+	require.NoError(t, err)
+
+	newLink, title := "https://example.com/new", "New"
+	tags := []string{"promo"}
+	link, err := s.Update(ctx, "", "EDITME01", model.LinkUpdate{OriginalURL: &newLink, Title: &title, Tags: &tags}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, newLink, link.OriginalURL)
+	assert.Equal(t, title, link.Title)
+	assert.Equal(t, tags, link.Tags)
+
+	_, err = s.Update(ctx, "", "EDITME01", model.LinkUpdate{Title: &title}, 2)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "only the owner can edit the link")
+
+	invalid := "not a url"
+	_, err = s.Update(ctx, "", "EDITME01", model.LinkUpdate{OriginalURL: &invalid}, 1)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	revs, err := s.GetRevisions(ctx, "", "EDITME01", 1)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, 1, revs[0].Revision)
+	assert.Equal(t, "https://example.com/old", revs[0].OriginalURL)
+}
+
+func TestService_AddLink(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	link, err := s.AddLink(ctx, "https://example.com/link", model.LinkOptions{Title: "Link", Note: "note"}, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, link.Hash)
+	assert.Equal(t, "Link", link.Title)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	got, err := s.GetLink(ctx, "", link.Hash, 1)
+	require.NoError(t, err)
+	assert.Equal(t, link, got)
+
+	existing, err := s.AddLink(ctx, "https://example.com/link", model.LinkOptions{}, 2)
+	assert.ErrorIs(t, err, repository.ErrExistsURL)
+	assert.Equal(t, link.Hash, existing.Hash)
+	assert.Equal(t, link.ShortURL, existing.ShortURL)
+	assert.Empty(t, existing.Title, "metadata of other users' links is hidden")
+	assert.Empty(t, existing.Note)
+
+	_, err = s.GetLink(ctx, "", link.Hash, 2)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "only the owner can get the link")
+	_, err = s.GetLink(ctx, "", "MISSING1", 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	require.NoError(t, store.BatchDelete(ctx, repository.UserHash{UserID: 1, Hash: []string{link.Hash}}))
+	_, err = s.GetLink(ctx, "", link.Hash, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "deleted links are hidden")
+}
+
+func TestService_Idempotent(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL, IdempotencyTTL: time.Hour}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	calls := 0
+	do := func(status int) func() IdempotentResponse {
+		return func() IdempotentResponse {
+			calls++
+			return IdempotentResponse{Status: status, Header: map[string]string{"Content-Type": "text/plain"}, Body: []byte{byte(calls)}}
+		}
+	}
+
+	first, replayed, err := s.Idempotent(ctx, 1, "key", "POST /", []byte("a"), do(201))
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	again, replayed, err := s.Idempotent(ctx, 1, "key", "POST /", []byte("a"), do(201))
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first, again)
+	assert.Equal(t, 1, calls, "a replayed request is not executed")
+
+	_, _, err = s.Idempotent(ctx, 1, "key", "POST /", []byte("b"), do(201))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	_, _, err = s.Idempotent(ctx, 1, "key", "POST /batch", []byte("a"), do(201))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused, "the key is bound to the endpoint")
+	_, replayed, err = s.Idempotent(ctx, 2, "key", "POST /", []byte("a"), do(201))
+	require.NoError(t, err)
+	assert.False(t, replayed, "keys are scoped to the user")
+
+	_, _, err = s.Idempotent(ctx, 1, "slow", "POST /", nil, func() IdempotentResponse {
+		_, _, err := s.Idempotent(ctx, 1, "slow", "POST /", nil, do(201))
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+		return IdempotentResponse{Status: 201}
+	})
+	require.NoError(t, err)
+
+	calls = 0
+	_, _, err = s.Idempotent(ctx, 1, "failed", "POST /", nil, do(500))
+	require.NoError(t, err)
+	_, replayed, err = s.Idempotent(ctx, 1, "failed", "POST /", nil, do(500))
+	require.NoError(t, err)
+	assert.False(t, replayed, "server errors are not stored")
+	assert.Equal(t, 2, calls)
+
+	_, _, err = s.Idempotent(ctx, 1, "bad\nkey", "POST /", nil, do(201))
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+	_, replayed, err = s.Idempotent(ctx, 1, "", "POST /", nil, do(201))
+	require.NoError(t, err)
+	assert.False(t, replayed, "requests without a key are executed as is")
+}
+
+func TestService_Domains(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{
+		ServerURL: "http://localhost:8080",
+		Domains: []serviceConf.Domain{
+			{Name: "Go.Brand.example", RedirectCode: http.StatusMovedPermanently, NotFoundURL: "https://brand.example/"},
+			{Name: "vip.example", BaseURL: "https://vip.example/s", Users: []int{1}},
+		},
+	}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg, domains: newDomains(cfg.Service.ServerURL, cfg.Service.Domains)}
+	ctx := context.Background()
+
+	short, err := s.Add(ctx, "https://example.com/", model.LinkOptions{Domain: "go.brand.example"}, 2)
+	require.NoError(t, err)
+	u, err := url.Parse(short)
+	require.NoError(t, err)
+	assert.Equal(t, "go.brand.example", u.Host)
+
+	link, err := s.GetByHash(ctx, "GO.BRAND.EXAMPLE:443", u.Path[1:])
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/", link.Link)
+	_, err = s.GetByHash(ctx, "", u.Path[1:])
+	assert.Error(t, err, "hash is scoped to its domain")
+
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{Domain: "vip.example"}, 2)
+	assert.ErrorIs(t, err, ErrDomainForbidden)
+	short, err = s.Add(ctx, "https://example.com/", model.LinkOptions{Domain: "vip.example"}, 1)
+	require.NoError(t, err)
+	assert.Contains(t, short, "https://vip.example/s/")
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{Domain: "unknown.example"}, 1)
+	assert.ErrorIs(t, err, ErrUnknownDomain)
+
+	d := s.DomainByHost("go.brand.example:8443")
+	assert.Equal(t, http.StatusMovedPermanently, d.Redirect(model.RedirectOptions{}))
+	assert.Equal(t, http.StatusFound, d.Redirect(model.RedirectOptions{Code: http.StatusFound}))
+	assert.Equal(t, "https://brand.example/", d.NotFoundURL)
+	assert.Equal(t, d, s.DomainByHost("Go.Brand.example."), "a trailing dot names the same host")
+	assert.Equal(t, "go.brand.example", HostName("Go.Brand.example.:443"), "the same name is used for SNI")
+	assert.Empty(t, s.DomainByHost("localhost:8080").Name)
+	assert.Empty(t, s.DomainByHost("other.example").Name, "unknown hosts use the default domain")
+}
+
+func TestService_BatchAdd(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	_, err = store.Add(ctx, repository.URL{Hash: "EXISTS01", Link: "https://example.com/exists"}, 1)
+	require.NoError(t, err)
+
+	req := []model.BatchCreateRequest{
+		{CorrelationID: "1", OriginalURL: "https://example.com/new"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/exists"},
+		{CorrelationID: "3", OriginalURL: "not a url"},
+	}
+
+	res, err := s.BatchAdd(ctx, req, model.BatchAtomic, 1)
+	assert.ErrorIs(t, err, ErrBatchRejected)
+	require.Len(t, res, 1)
+	assert.Equal(t, "3", res[0].CorrelationID)
+	assert.Equal(t, model.BatchStatusInvalid, res[0].Status)
+	links, _ := store.GetByUserID(ctx, 1)
+	assert.Len(t, links, 1, "atomic batch must not store anything")
+
+	res, err = s.BatchAdd(ctx, req, model.BatchBestEffort, 1)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	assert.Equal(t, model.BatchStatusCreated, res[0].Status)
+	assert.Equal(t, model.BatchStatusExisting, res[1].Status)
+	assert.Equal(t, config.DefaultServerURL+"/EXISTS01", res[1].ShortURL)
+	assert.Equal(t, model.BatchStatusInvalid, res[2].Status)
+	assert.Empty(t, res[2].ShortURL)
+}
+
+func TestService_ShutdownDrainsDeletions(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := NewService(config.Config{}, store)
+	events := make(chanObserver, 4)
+	s.AddObserver(events)
+	ctx := context.Background()
+
+	_, err = store.Add(ctx, repository.URL{Hash: "DELETE01", Link: "https://example.com/delete"}, 1)
+	require.NoError(t, err)
+	_, err = store.Add(ctx, repository.URL{Hash: "DELETE02", Link: "https://example.com/other"}, 2)
+	require.NoError(t, err)
+	job, err := s.DeleteEnqueue(ctx, "", []string{"DELETE01", "DELETE02", "MISSING1"}, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Shutdown(ctx))
+	require.NoError(t, s.Shutdown(ctx), "repeated shutdown does not panic")
+	got, err := s.GetDeletion(ctx, job.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeletionDone, got.Status)
+	u, _ := store.GetByHash(ctx, "", "DELETE01")
+	assert.True(t, u.DeletedFlag)
+
+	// Событие отправляется только для удаленной ссылки пользователя.
+	select {
+	case e := <-events:
+		assert.Equal(t, audit.Delete, e.Action)
+		assert.Equal(t, 1, e.OwnerID)
+		assert.Equal(t, "https://example.com/delete", e.URL)
+		shortURL, _ := s.ShortURL("", "DELETE01")
+		assert.Equal(t, shortURL, e.ShortURL)
+	case <-time.After(time.Second):
+		t.Fatal("deleted link was not reported")
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event for %s", e.ShortURL)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestService_RestoreRetention(t *testing.T) {
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	cfg := config.Config{Service: serviceConf.Config{RetentionPeriod: time.Hour}}
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	_, err = store.Add(ctx, repository.URL{Hash: "RESTORE1", Link: "https://example.com/restore"}, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.BatchDelete(ctx, repository.UserHash{UserID: 1, Hash: []string{"RESTORE1"}}))
+
+	res, err := s.Restore(ctx, "", []string{"RESTORE1"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, model.RestoreNotFound, res[0].Status, "only the owner can restore the link")
+
+	n, err := s.Purge(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "links within the grace period are kept")
+
+	res, err = s.Restore(ctx, "", []string{"RESTORE1"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.RestoreRestored, res[0].Status)
+
+	require.NoError(t, store.BatchDelete(ctx, repository.UserHash{UserID: 1, Hash: []string{"RESTORE1"}}))
+	s.config.Service.RetentionPeriod = 0
+	n, err = s.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	stats, _ := s.Stats(ctx)
+	assert.Equal(t, 1, stats.Purged)
+}
+
+// chanObserver передает полученные события в канал.
+type chanObserver chan audit.Event
+
+func (o chanObserver) Notify(_ context.Context, event audit.Event) error {
+	o <- event
+	return nil
+}
+
+func TestService_SetObservers(t *testing.T) {
+	s := &Service{}
+	before, after := make(chanObserver, 1), make(chanObserver, 1)
+	s.AddObserver(before)
+	s.SetObservers([]audit.Observer{after})
+
+	s.NotifyObservers(context.Background(), audit.Event{Action: audit.Shorten, URL: "https://example.com"})
+	select {
+	case e := <-after:
+		assert.Equal(t, "https://example.com", e.URL)
+	case <-time.After(time.Second):
+		t.Fatal("new observer was not notified")
+	}
+	assert.Empty(t, before, "replaced observer must not be notified")
+}
+
+func TestService_ListUserLinks(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	for i := range 5 {
+		_, err := s.Add(ctx, fmt.Sprintf("https://example.com/%d", i),
+			model.LinkOptions{Title: fmt.Sprintf("Page %d", i), Tags: []string{" promo ", "", "promo"}}, 1)
+		require.NoError(t, err)
+	}
+	_, err = s.Add(ctx, "https://example.com/x", model.LinkOptions{Tags: make([]string, 0)}, 2)
+	require.NoError(t, err)
+
+	var (
+		seen   []string
+		cursor string
+	)
+	for range 3 {
+		page, err := s.ListUserLinks(ctx, 1, model.LinkFilter{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		for _, l := range page.Links {
+			seen = append(seen, l.Title)
+			assert.Equal(t, []string{"promo"}, l.Tags, "tags are trimmed and deduplicated")
+		}
+		cursor = page.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Len(t, seen, 5, "pages must cover all links without repeats")
+	assert.ElementsMatch(t, []string{"Page 0", "Page 1", "Page 2", "Page 3", "Page 4"}, seen)
+	assert.Empty(t, cursor, "last page has no cursor")
+
+	page, err := s.ListUserLinks(ctx, 1, model.LinkFilter{Query: "page 3"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "https://example.com/3", page.Links[0].OriginalURL)
+
+	first, err := s.ListUserLinks(ctx, 1, model.LinkFilter{Limit: 1})
+	require.NoError(t, err)
+	for _, f := range []model.LinkFilter{
+		{Sort: "title"},
+		{Order: "up"},
+		{Limit: MaxPageSize + 1},
+		{Cursor: "not-a-cursor"},
+		{Cursor: first.NextCursor, Sort: repository.SortClicks},
+	} {
+		_, err := s.ListUserLinks(ctx, 1, f)
+		assert.ErrorIs(t, err, ErrInvalidFilter, "filter %+v", f)
+	}
+}
+
+func TestService_LinkMeta(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	long := strings.Repeat("я", MaxTitleLen+1)
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{Title: long}, 1)
+	assert.ErrorIs(t, err, ErrInvalidLinkMeta)
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{Tags: []string{strings.Repeat("t", MaxTagLen+1)}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidLinkMeta)
+
+	short, err := s.Add(ctx, "https://example.com/", model.LinkOptions{Note: "first"}, 1)
+	require.NoError(t, err)
+	hash := short[strings.LastIndex(short, "/")+1:]
+	note := "second"
+	link, err := s.Update(ctx, "", hash, model.LinkUpdate{Note: &note}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "second", link.Note)
+	_, err = s.Update(ctx, "", hash, model.LinkUpdate{Note: &long}, 1)
+	assert.NoError(t, err, "notes may be longer than titles")
+	revs, err := s.GetRevisions(ctx, "", hash, 1)
+	require.NoError(t, err)
+	require.Len(t, revs, 2)
+	assert.Equal(t, "first", revs[1].Note)
+
+	s.CountClick(ctx, "", hash)
+	page, err := s.ListUserLinks(ctx, 1, model.LinkFilter{Sort: repository.SortClicks})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, int64(1), page.Links[0].Clicks)
+}
+
+func TestService_LinkPassword(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{Password: strings.Repeat("p", MaxPasswordLen+1)}, 1)
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	first, err := s.Add(ctx, "https://example.com/", model.LinkOptions{Password: "secret"}, 1)
+	require.NoError(t, err)
+	second, err := s.Add(ctx, "https://example.com/", model.LinkOptions{Password: "secret"}, 1)
+	require.NoError(t, err, "protected links are never deduplicated")
+	assert.NotEqual(t, first, second)
+	public, err := s.Add(ctx, "https://example.com/", model.LinkOptions{}, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, public)
+
+	hash := first[strings.LastIndex(first, "/")+1:]
+	u, err := s.GetByHash(ctx, "", hash)
+	require.NoError(t, err)
+	assert.NotContains(t, u.PasswordHash, "secret")
+
+	assert.ErrorIs(t, s.CheckPassword(ctx, u, "", "10.0.0.1"), ErrPasswordRequired)
+	assert.NoError(t, s.CheckPassword(ctx, u, "secret", "10.0.0.1"))
+	for range MaxPasswordAttempts - 1 {
+		assert.ErrorIs(t, s.CheckPassword(ctx, u, "wrong", "10.0.0.1"), ErrWrongPassword)
+	}
+	assert.ErrorIs(t, s.CheckPassword(ctx, u, "wrong", "10.0.0.1"), ErrTooManyAttempts)
+	assert.ErrorIs(t, s.CheckPassword(ctx, u, "secret", "10.0.0.1"), ErrTooManyAttempts,
+		"the right password is rejected until the window expires")
+	assert.NoError(t, s.CheckPassword(ctx, u, "secret", "10.0.0.2"), "attempts are counted per IP")
+
+	links, err := s.GetByUserID(ctx, 1)
+	require.NoError(t, err)
+	protected := 0
+	for _, l := range links {
+		if l.Protected {
+			protected++
+		}
+	}
+	assert.Equal(t, 2, protected)
+
+	empty, other := "", "https://example.org/"
+	link, err := s.Update(ctx, "", hash, model.LinkUpdate{Password: &empty}, 1)
+	assert.ErrorIs(t, err, repository.ErrExistsURL, "an unprotected link joins deduplication")
+	assert.Equal(t, public, link.ShortURL)
+	link, err = s.Update(ctx, "", hash, model.LinkUpdate{Password: &empty, OriginalURL: &other}, 1)
+	require.NoError(t, err)
+	assert.False(t, link.Protected)
+	u, err = s.GetByHash(ctx, "", hash)
+	require.NoError(t, err)
+	assert.NoError(t, s.CheckPassword(ctx, u, "", "10.0.0.1"))
+}
+
+func TestService_MaxClicks(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{MaxClicks: -1}, 1)
+	assert.ErrorIs(t, err, ErrInvalidLinkMeta)
+
+	short, err := s.Add(ctx, "https://example.com/", model.LinkOptions{MaxClicks: 2}, 1)
+	require.NoError(t, err)
+	_, err = s.Add(ctx, "https://example.com/", model.LinkOptions{}, 1)
+	require.NoError(t, err)
+	hash := short[strings.LastIndex(short, "/")+1:]
+
+	assert.NoError(t, s.CountClick(ctx, "", hash))
+	assert.NoError(t, s.CountClick(ctx, "", hash))
+	assert.ErrorIs(t, s.CountClick(ctx, "", hash), repository.ErrExhausted)
+	assert.NoError(t, s.CountClick(ctx, "", "MISSING1"), "errors of unlimited links are only logged")
+
+	page, err := s.ListUserLinks(ctx, 1, model.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 2)
+	for _, l := range page.Links {
+		if l.ShortURL != short {
+			assert.Nil(t, l.ClicksLeft)
+			continue
+		}
+		assert.Equal(t, int64(2), l.MaxClicks)
+		require.NotNil(t, l.ClicksLeft)
+		assert.Equal(t, int64(0), *l.ClicksLeft)
+	}
+}
+
+func TestService_FetchTitle(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprint(w, "<html><head><title>\n  Example &amp; Co\n</title></head><body><title>x</title></body></html>")
+	}))
+	defer target.Close()
+
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL,
+		FetchTitles: true, AllowPrivateTargets: true}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg, stop: make(chan struct{})}
+	ctx := context.Background()
+
+	_, err = s.Add(ctx, target.URL+"/fetched", model.LinkOptions{}, 1)
+	require.NoError(t, err)
+	_, err = s.Add(ctx, target.URL+"/own", model.LinkOptions{Title: "Own"}, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.Shutdown(ctx), "shutdown waits for title fetching")
+
+	page, err := s.ListUserLinks(ctx, 1, model.LinkFilter{Order: "asc"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 2)
+	assert.Equal(t, "Example & Co", page.Links[0].Title)
+	assert.Equal(t, "Own", page.Links[1].Title)
+}
+
+func TestService_FetchTitleLimits(t *testing.T) {
+	var (
+		mu                 sync.Mutex
+		active, peak, hits int
+	)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, "<title>Page</title>")
+	}))
+	defer target.Close()
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Без AllowPrivateTargets страницы по локальным адресам не загружаются.
+	s := &Service{store: store, config: config.Config{Service: serviceConf.Config{
+		ServerURL: config.DefaultServerURL, FetchTitles: true}}, stop: make(chan struct{})}
+	_, err = s.Add(ctx, target.URL+"/private", model.LinkOptions{}, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.Shutdown(ctx))
+	assert.Zero(t, hits)
+
+	s = &Service{store: store, config: config.Config{Service: serviceConf.Config{
+		ServerURL: config.DefaultServerURL, FetchTitles: true, AllowPrivateTargets: true}}, stop: make(chan struct{})}
+	req := make([]model.BatchCreateRequest, 20)
+	for i := range req {
+		req[i] = model.BatchCreateRequest{CorrelationID: strconv.Itoa(i), OriginalURL: fmt.Sprintf("%s/%d", target.URL, i)}
+	}
+	_, err = s.BatchAdd(ctx, req, model.BatchAtomic, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.Shutdown(ctx))
+	assert.Equal(t, len(req), hits)
+	assert.LessOrEqual(t, peak, titleConcurrency, "fetches are limited")
+}
+
+func TestService_CheckLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	target := httptest.NewServer(mux)
+	defer target.Close()
+	closed := httptest.NewServer(mux)
+	closed.Close()
+
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL,
+		HealthCheckInterval: time.Hour, HealthCheckConcurrency: 2, AllowPrivateTargets: true}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	links := []string{target.URL + "/ok", target.URL + "/moved", target.URL + "/get-only", target.URL + "/busy", closed.URL + "/down"}
+	for _, link := range links {
+		_, err = s.Add(ctx, link, model.LinkOptions{}, 1)
+		require.NoError(t, err)
+	}
+
+	n, err := s.CheckLinks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(links), n)
+	n, err = s.CheckLinks(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "checked links are skipped until the interval passes")
+
+	page, err := s.ListUserLinks(ctx, 1, model.LinkFilter{Order: "asc"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, len(links))
+	health := make(map[string]*model.LinkHealth)
+	for _, l := range page.Links {
+		require.NotNil(t, l.Health, l.OriginalURL)
+		assert.Equal(t, l.Health.Broken, l.Broken)
+		assert.False(t, l.Health.CheckedAt.IsZero())
+		health[l.OriginalURL] = l.Health
+	}
+	assert.Equal(t, model.LinkHealth{Status: http.StatusOK}, withoutTime(health[links[0]]))
+	assert.Equal(t, model.LinkHealth{Status: http.StatusGone, Redirects: []string{target.URL + "/gone"}, Broken: true},
+		withoutTime(health[links[1]]))
+	assert.Equal(t, model.LinkHealth{Status: http.StatusOK}, withoutTime(health[links[2]]), "falls back to GET")
+	assert.Equal(t, model.LinkHealth{Status: http.StatusTooManyRequests}, withoutTime(health[links[3]]))
+	assert.True(t, health[links[4]].Broken)
+	assert.Zero(t, health[links[4]].Status)
+	assert.NotEmpty(t, health[links[4]].Error)
+
+	page, err = s.ListUserLinks(ctx, 1, model.LinkFilter{Order: "asc", Broken: true})
+	require.NoError(t, err)
+	var broken []string
+	for _, l := range page.Links {
+		broken = append(broken, l.OriginalURL)
+	}
+	assert.Equal(t, []string{links[1], links[4]}, broken)
+
+	fixed := target.URL + "/ok?fixed"
+	hash := page.Links[0].ShortURL[strings.LastIndex(page.Links[0].ShortURL, "/")+1:]
+	_, err = s.Update(ctx, "", hash, model.LinkUpdate{OriginalURL: &fixed}, 1)
+	require.NoError(t, err)
+	page, err = s.ListUserLinks(ctx, 1, model.LinkFilter{Broken: true})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1, "changing the destination resets its health")
+	assert.Equal(t, links[4], page.Links[0].OriginalURL)
+}
+
+func TestService_checkLinkPrivate(t *testing.T) {
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+	s := &Service{}
+	for _, link := range []string{target.URL, "http://10.0.0.1/", "http://169.254.169.254/latest/meta-data/"} {
+		h := s.checkLink(context.Background(), link)
+		assert.True(t, h.Broken, link)
+		assert.Zero(t, h.Status, link)
+		assert.Empty(t, h.Redirects, link)
+		assert.Equal(t, "destination address is not allowed", h.Error, link)
+	}
+	assert.Zero(t, hits.Load(), "private destinations are not contacted")
+}
+
+func TestHostLimiter(t *testing.T) {
+	var l hostLimiter
+	ctx := context.Background()
+	start := time.Now()
+	for range 3 {
+		require.NoError(t, l.wait(ctx, "a.example", 20*time.Millisecond))
+	}
+	require.NoError(t, l.wait(ctx, "b.example", 20*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, l.wait(cancelled, "a.example", time.Hour), context.Canceled)
+}
+
+// withoutTime возвращает результат проверки без времени проверки для сравнения.
+func withoutTime(h *model.LinkHealth) model.LinkHealth {
+	res := *h
+	res.CheckedAt = time.Time{}
+	return res
+}
+
+// fakeGeo определяет страну по IP-адресу из таблицы.
+type fakeGeo map[string]string
+
+func (g fakeGeo) Country(ip netip.Addr) string {
+	return g[ip.String()]
+}
+
+func TestService_Destination(t *testing.T) {
+	s := &Service{}
+	s.SetGeoIP(fakeGeo{"1.2.3.4": "US", "5.6.7.8": "RU"})
+	const (
+		iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+		windows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	)
+	rules, err := s.validateRules([]model.RouteRule{
+		{URL: "https://apps.apple.com/app", Platforms: []string{"iOS"}},
+		{URL: "https://play.google.com/app", Platforms: []string{"android"}},
+		{URL: "https://example.com/ru", Languages: []string{"RU"}},
+		{URL: "https://example.com/us", Countries: []string{"us"}},
+		{URL: "https://example.com/night", Time: &model.TimeWindow{From: "22:00", To: "06:00", Location: "Europe/Moscow"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ios"}, rules[0].Platforms)
+	assert.Equal(t, []string{"US"}, rules[3].Countries)
+	u := repository.URL{Hash: "ROUTED01", Link: "https://example.com/", Rules: rules}
+	noon := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC) // 12:00 MSK
+
+	tests := []struct {
+		name string
+		v    Visitor
+		want string
+	}{
+		{"ios", Visitor{UserAgent: iPhone, AcceptLanguage: "ru", Time: noon}, "https://apps.apple.com/app"},
+		{"android", Visitor{UserAgent: android, Time: noon}, "https://play.google.com/app"},
+		{"preferred language", Visitor{UserAgent: windows, AcceptLanguage: "en;q=0.5, ru-RU", Time: noon}, "https://example.com/ru"},
+		{"less preferred language", Visitor{UserAgent: windows, AcceptLanguage: "en, ru;q=0.8", Time: noon}, "https://example.com/"},
+		{"country", Visitor{UserAgent: windows, IP: netip.MustParseAddr("1.2.3.4"), Time: noon}, "https://example.com/us"},
+		{"other country", Visitor{UserAgent: windows, IP: netip.MustParseAddr("5.6.7.8"), Time: noon}, "https://example.com/"},
+		{"night window", Visitor{UserAgent: windows, Time: noon.Add(12 * time.Hour)}, "https://example.com/night"},
+		{"fallback", Visitor{UserAgent: "curl/8.0", Time: noon}, "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Destination(u, tt.v))
+		})
+	}
+}
+
+func TestService_DestinationPercent(t *testing.T) {
+	s := &Service{}
+	rules, err := s.validateRules([]model.RouteRule{
+		{URL: "https://example.com/a", Percent: 30},
+		{URL: "https://example.com/b", Percent: 20},
+		{URL: "https://example.com/campaign", Time: &model.TimeWindow{
+			Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	})
+	require.NoError(t, err)
+	u := repository.URL{Hash: "SPLIT001", Link: "https://example.com/", Rules: rules}
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	counts := map[string]int{}
+	for i := range 2000 {
+		v := Visitor{UserAgent: fmt.Sprintf("agent-%d", i), Time: now}
+		dest := s.Destination(u, v)
+		assert.Equal(t, dest, s.Destination(u, v), "a visitor always gets the same destination")
+		counts[dest]++
+	}
+	assert.InDelta(t, 600, counts["https://example.com/a"], 100)
+	assert.InDelta(t, 400, counts["https://example.com/b"], 100)
+	assert.InDelta(t, 1000, counts["https://example.com/"], 100)
+
+	in := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	for i := range 100 {
+		v := Visitor{UserAgent: fmt.Sprintf("agent-%d", i), Time: in}
+		if dest := s.Destination(u, v); dest == "https://example.com/" {
+			t.Fatalf("visitor outside of splits must get the campaign page during the campaign")
+		}
+	}
+}
+
+func TestService_ValidateRules(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		rule model.RouteRule
+	}{
+		{"invalid url", model.RouteRule{URL: "not a url", Platforms: []string{"ios"}}},
+		{"no conditions", model.RouteRule{URL: "https://example.com/"}},
+		{"unknown platform", model.RouteRule{URL: "https://example.com/", Platforms: []string{"symbian"}}},
+		{"invalid language", model.RouteRule{URL: "https://example.com/", Languages: []string{"en_US"}}},
+		{"invalid country", model.RouteRule{URL: "https://example.com/", Countries: []string{"USA"}}},
+		{"countries without geoip", model.RouteRule{URL: "https://example.com/", Countries: []string{"US"}}},
+		{"percent", model.RouteRule{URL: "https://example.com/", Percent: 101}},
+		{"half window", model.RouteRule{URL: "https://example.com/", Time: &model.TimeWindow{From: "09:00"}}},
+		{"clock", model.RouteRule{URL: "https://example.com/", Time: &model.TimeWindow{From: "9am", To: "18:00"}}},
+		{"location", model.RouteRule{URL: "https://example.com/", Time: &model.TimeWindow{From: "09:00", To: "18:00", Location: "Mars/Olympus"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.validateRules([]model.RouteRule{tt.rule})
+			assert.ErrorIs(t, err, ErrInvalidRules)
+		})
+	}
+
+	_, err := s.validateRules([]model.RouteRule{
+		{URL: "https://example.com/a", Percent: 60},
+		{URL: "https://example.com/b", Percent: 50},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRules, "percentages must not exceed 100 in total")
+}
+
+func TestService_Rules(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	rules := []model.RouteRule{{URL: "https://apps.apple.com/app", Platforms: []string{"ios"}}}
+	short, err := s.Add(ctx, "https://example.com/", model.LinkOptions{Rules: rules}, 1)
+	require.NoError(t, err)
+	hash := short[strings.LastIndex(short, "/")+1:]
+
+	empty := []model.RouteRule{}
+	link, err := s.Update(ctx, "", hash, model.LinkUpdate{Rules: &empty}, 1)
+	require.NoError(t, err)
+	assert.Empty(t, link.Rules)
+	revs, err := s.GetRevisions(ctx, "", hash, 1)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, rules, revs[0].Rules)
+}
+
+func TestPlatform(t *testing.T) {
+	assert.Equal(t, model.PlatformIOS, Platform("Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)"))
+	assert.Equal(t, model.PlatformAndroid, Platform("Mozilla/5.0 (Linux; Android 14; Pixel 8)"))
+	assert.Equal(t, model.PlatformDesktop, Platform("Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)"))
+	assert.Equal(t, model.PlatformDesktop, Platform("Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"))
+	assert.Empty(t, Platform("curl/8.0"))
+	assert.Empty(t, Platform("Mozilla/5.0 (Mobile; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5"))
+}
+
+func TestService_Experiment(t *testing.T) {
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg}
+	ctx := context.Background()
+
+	invalid := [][]model.Variant{
+		{{URL: "https://example.com/a"}},
+		{{URL: "https://example.com/a"}, {URL: "example.com/b"}},
+		{{URL: "https://example.com/a", Weight: -1}, {URL: "https://example.com/b"}},
+		{{URL: "https://example.com/a", Weight: MaxVariantWeight + 1}, {URL: "https://example.com/b"}},
+		{{Name: "A", URL: "https://example.com/a"}, {Name: "A", URL: "https://example.com/b"}},
+		slices.Repeat([]model.Variant{{URL: "https://example.com/a"}}, MaxVariants+1),
+	}
+	for _, variants := range invalid {
+		_, err := s.CreateExperiment(ctx, model.ExperimentRequest{Variants: variants}, 1)
+		assert.ErrorIs(t, err, ErrInvalidExperiment)
+	}
+
+	e, err := s.CreateExperiment(ctx, model.ExperimentRequest{
+		Name: "landing",
+		Variants: []model.Variant{
+			{URL: "https://example.com/a", Weight: 70},
+			{URL: "https://example.com/b", Weight: 30},
+		},
+	}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "A", e.Variants[0].Name)
+	assert.Equal(t, "B", e.Variants[1].Name)
+	hash := e.ShortURL[strings.LastIndex(e.ShortURL, "/")+1:]
+
+	u, err := s.GetByHash(ctx, "", hash)
+	require.NoError(t, err)
+	assert.Equal(t, e.ID, u.Experiment)
+	assert.Equal(t, "landing", u.Title)
+
+	counts := map[string]int{}
+	for i := range 1000 {
+		visitor := fmt.Sprintf("visitor-%d", i)
+		link, err := s.Assign(ctx, e.ID, visitor)
+		require.NoError(t, err)
+		again, err := s.Assign(ctx, e.ID, visitor)
+		require.NoError(t, err)
+		assert.Equal(t, link, again, "a visitor always gets the same variant")
+		counts[link]++
+	}
+	assert.InDelta(t, 700, counts["https://example.com/a"], 50)
+	assert.InDelta(t, 300, counts["https://example.com/b"], 50)
+
+	got, err := s.GetExperiment(ctx, e.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), got.Redirects)
+	assert.Equal(t, int64(2*counts["https://example.com/a"]), got.Variants[0].Redirects)
+	assert.Equal(t, int64(2*counts["https://example.com/b"]), got.Variants[1].Redirects)
+
+	_, err = s.GetExperiment(ctx, e.ID, 2)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	list, err := s.ListExperiments(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, e.ID, list[0].ID)
+
+	link := "https://example.com/c"
+	_, err = s.Update(ctx, "", hash, model.LinkUpdate{OriginalURL: &link}, 1)
+	assert.ErrorIs(t, err, ErrInvalidExperiment)
+	title := "spring landing"
+	pair, err := s.Update(ctx, "", hash, model.LinkUpdate{Title: &title}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, e.ID, pair.ExperimentID)
+}
+
+func TestService_Webhooks(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var (
+		mu    sync.Mutex
+		got   []received
+		fails = 1
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, received{header: r.Header.Clone(), body: body})
+		if r.URL.Path == "/flaky" && fails > 0 {
+			fails--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{Service: serviceConf.Config{ServerURL: config.DefaultServerURL,
+		WebhookClickInterval: time.Hour, AllowPrivateTargets: true}}
+	store, err := repository.CreateStore(&config.Config{})
+	require.NoError(t, err)
+	s := &Service{store: store, config: cfg, hookWake: make(chan struct{}, 1)}
+	ctx := context.Background()
+
+	invalid := []model.WebhookRequest{
+		{URL: "ftp://example.com/hook"},
+		{URL: srv.URL, Events: []string{"visited"}},
+		{URL: srv.URL, Secret: strings.Repeat("s", MaxWebhookSecret+1)},
+	}
+	for _, req := range invalid {
+		_, err := s.CreateWebhook(ctx, req, 1)
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	}
+
+	flaky, err := s.CreateWebhook(ctx, model.WebhookRequest{
+		URL:    srv.URL + "/flaky",
+		Events: []string{model.WebhookExpired, model.WebhookCreated},
+	}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.WebhookCreated, model.WebhookExpired}, flaky.Events)
+	assert.NotEmpty(t, flaky.Secret, "generated secret is returned on create")
+	clicks, err := s.CreateWebhook(ctx, model.WebhookRequest{
+		URL:    srv.URL + "/clicks",
+		Events: []string{model.WebhookClicked},
+		Secret: "top-secret",
+	}, 1)
+	require.NoError(t, err)
+
+	got1, err := s.GetWebhook(ctx, flaky.ID, 1)
+	require.NoError(t, err)
+	assert.Empty(t, got1.Secret, "secret is not shown after create")
+	_, err = s.GetWebhook(ctx, flaky.ID, 2)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	require.NoError(t, s.notifyWebhooks(ctx, audit.Event{
+		Timestamp: time.Now(), Action: audit.Shorten, UserID: 1,
+		URL: "https://example.com", ShortURL: "http://localhost:8080/abc",
+	}))
+	for range 3 {
+		require.NoError(t, s.notifyWebhooks(ctx, audit.Event{
+			Timestamp: time.Now(), Action: audit.Follow, OwnerID: 1,
+			URL: "https://example.com", ShortURL: "http://localhost:8080/abc",
+		}))
+	}
+	require.NoError(t, s.notifyWebhooks(ctx, audit.Event{Timestamp: time.Now(), Action: audit.Shorten, UserID: 2}))
+	require.NoError(t, s.FlushWebhookClicks(ctx))
+
+	n, err := s.DeliverWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	deliveries, err := s.ListDeliveries(ctx, clicks.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, int64(3), deliveries[0].Payload.Clicks, "clicks are aggregated")
+	assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus)
+
+	deliveries, err = s.ListDeliveries(ctx, flaky.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.DeliveryPending, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseStatus)
+	require.NotNil(t, deliveries[0].NextAttemptAt)
+	assert.True(t, deliveries[0].NextAttemptAt.After(time.Now().Add(webhookRetryBase/2)))
+
+	mu.Lock()
+	for _, r := range got {
+		secret := flaky.Secret
+		if r.header.Get("X-Webhook-Event") == model.WebhookClicked {
+			secret = "top-secret"
+		}
+		sig := WebhookSignature(secret, r.header.Get("X-Webhook-Timestamp"), r.body)
+		assert.Equal(t, sig, r.header.Get("X-Webhook-Signature"))
+	}
+	mu.Unlock()
+
+	// Повторная попытка выполняется, когда наступает ее время.
+	pending, err := store.PendingDeliveries(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	pending[0].NextAttemptAt = time.Now()
+	require.NoError(t, store.UpdateDelivery(ctx, pending[0]))
+	n, err = s.DeliverWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	deliveries, err = s.ListDeliveries(ctx, flaky.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+
+	active := false
+	_, err = s.UpdateWebhook(ctx, flaky.ID, model.WebhookUpdate{Active: &active}, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.notifyWebhooks(ctx, audit.Event{Timestamp: time.Now(), Action: audit.Shorten, UserID: 1}))
+	deliveries, err = s.ListDeliveries(ctx, flaky.ID, 1)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1, "disabled webhooks get no deliveries")
+
+	require.NoError(t, s.DeleteWebhook(ctx, flaky.ID, 1))
+	_, err = s.ListDeliveries(ctx, flaky.ID, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, s.DeleteWebhook(ctx, clicks.ID, 2), repository.ErrNotFound)
+}
+
+func TestService_attemptDelivery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	s := &Service{config: config.Config{Service: serviceConf.Config{AllowPrivateTargets: true}}}
+	w := repository.Webhook{ID: 1, URL: srv.URL, Active: true}
+	d := repository.Delivery{ID: 1, WebhookID: 1, Status: model.DeliveryPending}
+	for i := 1; i < webhookMaxAttempts; i++ {
+		d = s.attemptDelivery(context.Background(), w, d)
+		require.Equal(t, model.DeliveryPending, d.Status)
+		assert.Equal(t, min(webhookRetryBase<<(i-1), webhookRetryMax), d.NextAttemptAt.Sub(d.LastAttemptAt))
+	}
+	d = s.attemptDelivery(context.Background(), w, d)
+	assert.Equal(t, model.DeliveryFailed, d.Status)
+	assert.Equal(t, webhookMaxAttempts, d.Attempts)
+
+	w.Active = false
+	d = s.attemptDelivery(context.Background(), w, repository.Delivery{ID: 2, WebhookID: 1})
+	assert.Equal(t, model.DeliveryFailed, d.Status)
+	assert.Zero(t, d.Attempts)
+}
+
+func TestService_attemptDeliveryPrivate(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+	s := &Service{}
+	targets := []string{srv.URL, "http://10.1.2.3:8080/hook", "http://169.254.169.254/latest/meta-data/"}
+	for i, target := range targets {
+		w := repository.Webhook{ID: 1, URL: target, Active: true}
+		d := s.attemptDelivery(context.Background(), w, repository.Delivery{ID: int64(i + 1), WebhookID: 1})
+		assert.Equal(t, 1, d.Attempts, target)
+		assert.False(t, d.NextAttemptAt.IsZero(), target)
+		assert.Zero(t, d.ResponseStatus, target)
+		assert.Equal(t, "destination address is not allowed", d.Error, target)
+	}
+	assert.Zero(t, hits.Load(), "private targets are not contacted")
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.215.14":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.0.0.1":         false,
+		"172.16.5.4":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"255.255.255.255":  false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"64:ff9b::a00:1":   false,
+		"ff02::1":          false,
+	}
+	for addr, want := range tests {
+		assert.Equal(t, want, publicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestService_Subscribe(t *testing.T) {
+	s := &Service{}
+	sub, err := s.Subscribe(1)
+	require.NoError(t, err)
+	other, err := s.Subscribe(2)
+	require.NoError(t, err)
+
+	s.publishStream(audit.Event{
+		Action: audit.Follow, UserID: 2, OwnerID: 1,
+		URL: "https://example.com", ShortURL: "http://localhost:8080/abc",
+	})
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, model.WebhookClicked, ev.Event)
+		assert.Equal(t, "http://localhost:8080/abc", ev.ShortURL)
+	default:
+		t.Fatal("the owner receives the event")
+	}
+	assert.Empty(t, other.Events(), "other users do not receive the event")
+	other.Close()
+	other.Close()
+	_, ok := <-other.Events()
+	assert.False(t, ok)
+	assert.NoError(t, other.Err())
+
+	for range MaxStreams - 1 {
+		_, err := s.Subscribe(1)
+		require.NoError(t, err)
+	}
+	_, err = s.Subscribe(1)
+	assert.ErrorIs(t, err, ErrTooManyStreams)
+
+	for range StreamBufferSize + 1 {
+		s.publishStream(audit.Event{Action: audit.Shorten, UserID: 1})
+	}
+	n := 0
+	for range sub.Events() {
+		n++
+	}
+	assert.Equal(t, StreamBufferSize, n)
+	assert.ErrorIs(t, sub.Err(), ErrStreamOverflow, "a slow subscriber is disconnected")
+
+	sub, err = s.Subscribe(1)
+	require.NoError(t, err, "the overflowed subscription is released")
+	s.streams.close()
+	_, ok = <-sub.Events()
+	assert.False(t, ok)
+	assert.ErrorIs(t, sub.Err(), ErrStreamClosed)
+	_, err = s.Subscribe(3)
+	assert.ErrorIs(t, err, ErrStreamClosed)
+}
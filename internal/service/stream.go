@@ -0,0 +1,155 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// Ограничения потоков событий.
+const (
+	MaxStreams       = 5  // Максимальное количество одновременных потоков пользователя
+	StreamBufferSize = 64 // Количество событий, ожидающих отправки подписчику
+)
+
+var (
+	// ErrTooManyStreams возвращается, если у пользователя открыто MaxStreams потоков.
+	ErrTooManyStreams = fault.New(fault.RateLimited, "too_many_streams", "too many streams")
+	// ErrStreamOverflow — причина закрытия подписки, не успевающей получать события.
+	ErrStreamOverflow = fault.New(fault.RateLimited, "stream_overflow", "stream overflow: client is too slow")
+	// ErrStreamClosed — причина закрытия подписки при остановке сервиса.
+	ErrStreamClosed = fault.New(fault.Unavailable, "stream_closed", "stream closed")
+)
+
+// Subscription — подписка на события ссылок пользователя.
+// Канал Events закрывается при отмене подписки, переполнении буфера
+// и остановке сервиса; причину возвращает Err.
+type Subscription struct {
+	hub    *streamHub
+	userID int
+	events chan model.StreamEvent
+	err    error // Защищено hub.mu
+}
+
+// Events возвращает канал событий подписки.
+func (sub *Subscription) Events() <-chan model.StreamEvent {
+	return sub.events
+}
+
+// Err возвращает причину закрытия канала Events или nil, если подписка отменена Close.
+func (sub *Subscription) Err() error {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	return sub.err
+}
+
+// Close отменяет подписку. Повторный вызов ничего не делает.
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.remove(sub, nil)
+}
+
+// streamHub рассылает события ссылок подписчикам их владельцев.
+// Нулевое значение готово к использованию.
+type streamHub struct {
+	mu     sync.Mutex
+	subs   map[int]map[*Subscription]struct{}
+	closed bool
+}
+
+// subscribe добавляет подписку на события ссылок пользователя.
+func (h *streamHub) subscribe(userID int) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrStreamClosed
+	}
+	if len(h.subs[userID]) >= MaxStreams {
+		return nil, ErrTooManyStreams
+	}
+	if h.subs == nil {
+		h.subs = make(map[int]map[*Subscription]struct{})
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	sub := &Subscription{hub: h, userID: userID, events: make(chan model.StreamEvent, StreamBufferSize)}
+	h.subs[userID][sub] = struct{}{}
+	return sub, nil
+}
+
+// publish отправляет событие подписчикам владельца, не блокируясь.
+// Подписка, буфер которой заполнен, закрывается с ошибкой ErrStreamOverflow:
+// клиент переподключается и запрашивает актуальное состояние, а медленный
+// клиент не задерживает обработку запросов.
+func (h *streamHub) publish(userID int, ev model.StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[userID] {
+		select {
+		case sub.events <- ev:
+		default:
+			h.remove(sub, ErrStreamOverflow)
+		}
+	}
+}
+
+// close закрывает все подписки с ошибкой ErrStreamClosed и запрещает новые.
+func (h *streamHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub, ErrStreamClosed)
+		}
+	}
+}
+
+// remove удаляет подписку и закрывает ее канал. Вызывается под h.mu.
+func (h *streamHub) remove(sub *Subscription, err error) {
+	subs := h.subs[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+	sub.err = err
+	close(sub.events)
+}
+
+// Subscribe открывает поток событий ссылок пользователя: создание, переходы,
+// удаление и исчерпание лимита переходов. Подписку нужно закрыть вызовом Close.
+// Пример:
+//
+//	sub, err := s.Subscribe(userID)
+//	if err != nil {
+//	    return err
+//	}
+//	defer sub.Close()
+//	for ev := range sub.Events() {
+//	    fmt.Println(ev.Event, ev.ShortURL)
+//	}
+func (s *Service) Subscribe(userID int) (*Subscription, error) {
+	return s.streams.subscribe(userID)
+}
+
+// publishStream отправляет событие аудита подписчикам потока владельца ссылки.
+func (s *Service) publishStream(e audit.Event) {
+	name, ok := webhookEvents[e.Action]
+	owner := e.Owner()
+	if !ok || owner == 0 {
+		return
+	}
+	s.streams.publish(owner, model.StreamEvent{
+		Event:       name,
+		Timestamp:   e.Timestamp,
+		ShortURL:    e.ShortURL,
+		OriginalURL: e.URL,
+	})
+}
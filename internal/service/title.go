@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Параметры загрузки заголовков страниц назначения.
+const (
+	// titleFetchTimeout ограничивает время загрузки страницы.
+	titleFetchTimeout = 10 * time.Second
+	// titleMaxBody — объем начала страницы, в котором ищется <title>.
+	titleMaxBody = 512 << 10
+	// titleConcurrency — количество одновременных загрузок заголовков.
+	titleConcurrency = 4
+	// titleQueueSize — количество ссылок, ожидающих загрузки заголовка; сверх него
+	// заголовки новых ссылок не загружаются.
+	titleQueueSize = 1024
+)
+
+// titleJob — ссылка, заголовок которой нужно загрузить.
+type titleJob struct {
+	domain, hash, link string
+}
+
+// titleQueue — очередь загрузки заголовков, которую разбирают не более
+// titleConcurrency горутин. Нулевое значение готово к использованию.
+type titleQueue struct {
+	mu      sync.Mutex
+	pending []titleJob
+	running int
+}
+
+// push ставит ссылку в очередь и сообщает, нужно ли запустить еще одну горутину
+// загрузки. Возвращает false в ok, если очередь заполнена.
+func (q *titleQueue) push(job titleJob) (start, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= titleQueueSize {
+		return false, false
+	}
+	q.pending = append(q.pending, job)
+	if q.running < titleConcurrency {
+		q.running++
+		return true, true
+	}
+	return false, true
+}
+
+// pop возвращает следующую ссылку из очереди. Для пустой очереди возвращает
+// false, и вызвавшая горутина должна завершиться.
+func (q *titleQueue) pop() (titleJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		q.running--
+		return titleJob{}, false
+	}
+	job := q.pending[0]
+	q.pending[0] = titleJob{}
+	q.pending = q.pending[1:]
+	return job, true
+}
+
+// titleClient возвращает клиент загрузки страниц назначения. Перенаправления
+// выполняются, но каждое соединение проверяется так же, как первое.
+func (s *Service) titleClient() *http.Client {
+	return &http.Client{Transport: s.outboundTransport(), Timeout: titleFetchTimeout}
+}
+
+// fetchTitleAsync ставит ссылку домена в очередь загрузки заголовка страницы link.
+// Заголовок сохраняется, если заголовок ссылки все еще не задан.
+// Остановка сервиса дожидается загрузки всех заголовков из очереди.
+func (s *Service) fetchTitleAsync(domain, hash, link string) {
+	start, ok := s.titles.push(titleJob{domain: domain, hash: hash, link: link})
+	if !ok {
+		log.Printf("fetch title %s: queue is full", link)
+		return
+	}
+	if start {
+		s.workers.Add(1)
+		go s.runTitleWorker()
+	}
+}
+
+// runTitleWorker загружает заголовки ссылок из очереди, пока она не опустеет.
+func (s *Service) runTitleWorker() {
+	defer s.workers.Done()
+	client := s.titleClient()
+	for {
+		job, ok := s.titles.pop()
+		if !ok {
+			return
+		}
+		s.saveTitle(client, job)
+	}
+}
+
+// saveTitle загружает заголовок страницы ссылки и сохраняет его.
+func (s *Service) saveTitle(client *http.Client, job titleJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), titleFetchTimeout)
+	defer cancel()
+	title, err := fetchTitle(ctx, client, job.link)
+	if err != nil {
+		log.Printf("fetch title %s: %v", job.link, err)
+		return
+	}
+	if title == "" {
+		return
+	}
+	if err := s.store.SetTitle(ctx, job.domain, job.hash, title); err != nil {
+		log.Printf("set title %s: %v", job.hash, err)
+	}
+}
+
+// fetchTitle загружает HTML-страницу и возвращает текст ее элемента <title>
+// с нормализованными пробелами, обрезанный до MaxTitleLen символов.
+// Для страниц без заголовка и не-HTML ответов возвращается пустая строка.
+func fetchTitle(ctx context.Context, client *http.Client, link string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return "", nil
+	}
+	return parseTitle(io.LimitReader(resp.Body, titleMaxBody)), nil
+}
+
+// parseTitle возвращает текст первого элемента <title> HTML-документа.
+func parseTitle(r io.Reader) string {
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if name, _ := z.TagName(); atom.Lookup(name) != atom.Title {
+				continue
+			}
+			var b strings.Builder
+			for z.Next() == html.TextToken {
+				b.Write(z.Text())
+			}
+			title := []rune(strings.Join(strings.Fields(b.String()), " "))
+			if len(title) > MaxTitleLen {
+				title = title[:MaxTitleLen]
+			}
+			return string(title)
+		}
+	}
+}
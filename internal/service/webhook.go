@@ -0,0 +1,558 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spitfy/urlshortener/internal/audit"
+	"github.com/spitfy/urlshortener/internal/model"
+	"github.com/spitfy/urlshortener/internal/repository"
+	"github.com/spitfy/urlshortener/internal/service/fault"
+)
+
+// Ограничения подписок на события ссылок.
+const (
+	MaxWebhooks      = 10  // Максимальное количество подписок пользователя
+	MaxWebhookSecret = 256 // Максимальная длина ключа подписи в символах
+	WebhookLogLimit  = 100 // Количество последних доставок в журнале подписки
+)
+
+// Параметры доставки событий подпискам.
+const (
+	// webhookTimeout ограничивает время одной попытки доставки.
+	webhookTimeout = 10 * time.Second
+	// webhookMaxAttempts — количество попыток, после которого доставка считается неудачной.
+	webhookMaxAttempts = 8
+	// webhookRetryBase и webhookRetryMax задают экспоненциальную задержку повторных попыток.
+	webhookRetryBase = time.Minute
+	webhookRetryMax  = time.Hour
+	// webhookBatchSize — количество доставок, выбираемых из хранилища за раз.
+	webhookBatchSize = 100
+	// webhookConcurrency — количество одновременных попыток доставки.
+	webhookConcurrency = 4
+	// webhookLogRetention — срок хранения завершенных доставок.
+	webhookLogRetention = 7 * 24 * time.Hour
+	// webhookSecretLen — длина генерируемого ключа подписи.
+	webhookSecretLen = 32
+)
+
+// ErrInvalidWebhook возвращается при недопустимых параметрах подписки.
+var ErrInvalidWebhook = fault.New(fault.Invalid, "invalid_webhook", "invalid webhook")
+
+// webhookEventNames перечисляет события подписок в порядке по умолчанию.
+var webhookEventNames = []string{model.WebhookCreated, model.WebhookClicked, model.WebhookDeleted, model.WebhookExpired}
+
+// webhookEvents сопоставляет действиям аудита события подписок и потоков.
+var webhookEvents = map[audit.Action]string{
+	audit.Shorten: model.WebhookCreated,
+	audit.Follow:  model.WebhookClicked,
+	audit.Delete:  model.WebhookDeleted,
+	audit.Expire:  model.WebhookExpired,
+}
+
+// webhookClient возвращает клиент доставки событий подпискам. Перенаправления
+// не выполняются: ответ 3xx считается неудачной попыткой.
+func (s *Service) webhookClient() *http.Client {
+	return &http.Client{
+		Transport: s.outboundTransport(),
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebhookSignature возвращает значение заголовка X-Webhook-Signature для тела запроса
+// body, отправленного в момент timestamp (секунды Unix в десятичной записи).
+// Получатель проверяет подпись, вычислив ее тем же ключом.
+// Пример:
+//
+//	ok := hmac.Equal([]byte(r.Header.Get("X-Webhook-Signature")),
+//	    []byte(service.WebhookSignature(secret, r.Header.Get("X-Webhook-Timestamp"), body)))
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook создает подписку пользователя на события его ссылок.
+// Ключ подписи генерируется, если не задан, и возвращается только в ответе на создание.
+// Для недопустимых параметров возвращается ErrInvalidWebhook.
+// Пример:
+//
+//	w, err := s.CreateWebhook(ctx, model.WebhookRequest{
+//	    URL:    "https://hooks.example.com/shortener",
+//	    Events: []string{model.WebhookCreated},
+//	}, userID)
+func (s *Service) CreateWebhook(ctx context.Context, req model.WebhookRequest, userID int) (model.Webhook, error) {
+	events, err := validateWebhook(req.URL, req.Events, req.Secret)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	list, err := s.store.ListWebhooks(ctx, userID)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	if len(list) >= MaxWebhooks {
+		return model.Webhook{}, fmt.Errorf("%w: at most %d webhooks per user", ErrInvalidWebhook, MaxWebhooks)
+	}
+	secret := req.Secret
+	if secret == "" {
+		secret = RandString(webhookSecretLen)
+	}
+	w, err := s.store.AddWebhook(ctx, repository.Webhook{
+		UserID: userID,
+		URL:    req.URL,
+		Secret: secret,
+		Events: events,
+		Active: true,
+	})
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	res := webhookModel(w)
+	res.Secret = w.Secret
+	return res, nil
+}
+
+// ListWebhooks возвращает подписки пользователя в порядке создания.
+func (s *Service) ListWebhooks(ctx context.Context, userID int) ([]model.Webhook, error) {
+	list, err := s.store.ListWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Webhook, 0, len(list))
+	for _, w := range list {
+		res = append(res, webhookModel(w))
+	}
+	return res, nil
+}
+
+// GetWebhook возвращает подписку пользователя.
+// Возвращает repository.ErrNotFound, если подписка принадлежит другому пользователю.
+func (s *Service) GetWebhook(ctx context.Context, id int64, userID int) (model.Webhook, error) {
+	w, err := s.userWebhook(ctx, id, userID)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	return webhookModel(w), nil
+}
+
+// UpdateWebhook изменяет заданные поля подписки пользователя.
+// Новый ключ подписи возвращается в ответе.
+// Пример:
+//
+//	active := false
+//	w, err := s.UpdateWebhook(ctx, 3, model.WebhookUpdate{Active: &active}, userID)
+func (s *Service) UpdateWebhook(ctx context.Context, id int64, upd model.WebhookUpdate, userID int) (model.Webhook, error) {
+	w, err := s.userWebhook(ctx, id, userID)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	if upd.URL != nil {
+		w.URL = *upd.URL
+	}
+	if upd.Secret != nil {
+		if *upd.Secret == "" {
+			return model.Webhook{}, fmt.Errorf("%w: secret must not be empty", ErrInvalidWebhook)
+		}
+		w.Secret = *upd.Secret
+	}
+	events := w.Events
+	if upd.Events != nil {
+		events = *upd.Events
+	}
+	if w.Events, err = validateWebhook(w.URL, events, w.Secret); err != nil {
+		return model.Webhook{}, err
+	}
+	if upd.Active != nil {
+		w.Active = *upd.Active
+	}
+	if err := s.store.UpdateWebhook(ctx, w); err != nil {
+		return model.Webhook{}, err
+	}
+	res := webhookModel(w)
+	if upd.Secret != nil {
+		res.Secret = w.Secret
+	}
+	return res, nil
+}
+
+// DeleteWebhook удаляет подписку пользователя вместе с журналом доставок.
+func (s *Service) DeleteWebhook(ctx context.Context, id int64, userID int) error {
+	if _, err := s.userWebhook(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+// ListDeliveries возвращает журнал доставок подписки пользователя:
+// последние WebhookLogLimit доставок, новые первыми.
+func (s *Service) ListDeliveries(ctx context.Context, id int64, userID int) ([]model.WebhookDelivery, error) {
+	if _, err := s.userWebhook(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	list, err := s.store.ListDeliveries(ctx, id, WebhookLogLimit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.WebhookDelivery, 0, len(list))
+	for _, d := range list {
+		res = append(res, deliveryModel(d))
+	}
+	return res, nil
+}
+
+// userWebhook возвращает подписку, если она принадлежит пользователю, иначе repository.ErrNotFound.
+func (s *Service) userWebhook(ctx context.Context, id int64, userID int) (repository.Webhook, error) {
+	w, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return repository.Webhook{}, err
+	}
+	if w.UserID != userID {
+		return repository.Webhook{}, repository.ErrNotFound
+	}
+	return w, nil
+}
+
+// validateWebhook проверяет адрес получателя, ключ подписи и события подписки
+// и возвращает события без повторов в порядке webhookEventNames.
+// Пустой список событий означает подписку на все события.
+func validateWebhook(link string, events []string, secret string) ([]string, error) {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: url: expected absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if utf8.RuneCountInString(secret) > MaxWebhookSecret {
+		return nil, fmt.Errorf("%w: secret is longer than %d characters", ErrInvalidWebhook, MaxWebhookSecret)
+	}
+	if len(events) == 0 {
+		return slices.Clone(webhookEventNames), nil
+	}
+	for _, e := range events {
+		if !slices.Contains(webhookEventNames, e) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, e)
+		}
+	}
+	res := make([]string, 0, len(events))
+	for _, e := range webhookEventNames {
+		if slices.Contains(events, e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// clickKey идентифицирует ссылку владельца в счетчике переходов.
+type clickKey struct {
+	owner    int
+	shortURL string
+}
+
+// clickCounter накапливает переходы по ссылкам между отправками событий clicked.
+// Нулевое значение готово к использованию.
+type clickCounter struct {
+	mu     sync.Mutex
+	clicks map[clickKey]model.WebhookEvent
+}
+
+// add учитывает переход по ссылке владельца owner.
+func (c *clickCounter) add(owner int, ev model.WebhookEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clicks == nil {
+		c.clicks = make(map[clickKey]model.WebhookEvent)
+	}
+	k := clickKey{owner, ev.ShortURL}
+	ev.Clicks = c.clicks[k].Clicks + 1
+	c.clicks[k] = ev
+}
+
+// take возвращает накопленные переходы и обнуляет счетчик.
+func (c *clickCounter) take() map[clickKey]model.WebhookEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := c.clicks
+	c.clicks = nil
+	return res
+}
+
+// notifyWebhooks — наблюдатель аудита, создающий доставки событий подпискам владельца ссылки.
+// Переходы накапливаются и отправляются одним событием clicked за период
+// WebhookClickInterval; при нулевом периоде событие создается на каждый переход.
+func (s *Service) notifyWebhooks(ctx context.Context, e audit.Event) error {
+	name, ok := webhookEvents[e.Action]
+	owner := e.Owner()
+	if !ok || owner == 0 {
+		return nil
+	}
+	ev := model.WebhookEvent{Event: name, Timestamp: e.Timestamp, ShortURL: e.ShortURL, OriginalURL: e.URL}
+	if name == model.WebhookClicked {
+		if s.config.Service.WebhookClickInterval > 0 {
+			s.clicks.add(owner, ev)
+			return nil
+		}
+		ev.Clicks = 1
+	}
+	// Событие уведомляет уже после ответа на запрос, контекст которого может быть отменен.
+	return s.enqueueWebhookEvent(context.WithoutCancel(ctx), owner, ev)
+}
+
+// FlushWebhookClicks создает события clicked по переходам, накопленным с предыдущего вызова.
+func (s *Service) FlushWebhookClicks(ctx context.Context) error {
+	var errs []error
+	now := time.Now()
+	for k, ev := range s.clicks.take() {
+		ev.Timestamp = now
+		if err := s.enqueueWebhookEvent(ctx, k.owner, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enqueueWebhookEvent создает доставки события активным подпискам владельца на это событие.
+func (s *Service) enqueueWebhookEvent(ctx context.Context, owner int, ev model.WebhookEvent) error {
+	hooks, err := s.store.ListWebhooks(ctx, owner)
+	if err != nil {
+		return err
+	}
+	added := false
+	for _, w := range hooks {
+		if !w.Active || !slices.Contains(w.Events, ev.Event) {
+			continue
+		}
+		_, err := s.store.AddDelivery(ctx, repository.Delivery{
+			WebhookID:     w.ID,
+			Payload:       ev,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		added = added || err == nil
+	}
+	if added {
+		select {
+		case s.hookWake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// runWebhookWorker доставляет события подпискам, периодически отправляет
+// накопленные переходы и удаляет устаревшие записи журнала доставок.
+// При остановке сервиса накопленные переходы сохраняются как доставки.
+func (s *Service) runWebhookWorker() {
+	defer s.workers.Done()
+	ctx, cancel := s.stopContext()
+	defer cancel()
+	poll := time.NewTicker(deletePollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+	var flush <-chan time.Time
+	if interval := s.config.Service.WebhookClickInterval; interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		flush = t.C
+	}
+	for {
+		select {
+		case <-s.stop:
+			if err := s.FlushWebhookClicks(context.Background()); err != nil {
+				log.Printf("webhook clicks error: %v", err)
+			}
+			return
+		case <-flush:
+			if err := s.FlushWebhookClicks(ctx); err != nil {
+				log.Printf("webhook clicks error: %v", err)
+			}
+		case <-purge.C:
+			if _, err := s.store.PurgeDeliveries(ctx, time.Now().Add(-webhookLogRetention)); err != nil {
+				log.Printf("purge webhook deliveries error: %v", err)
+			}
+		case <-s.hookWake:
+		case <-poll.C:
+		}
+		if _, err := s.DeliverWebhooks(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhook delivery error: %v", err)
+		}
+	}
+}
+
+// DeliverWebhooks выполняет попытки доставки, время которых наступило.
+// Неудачная попытка повторяется с экспоненциальной задержкой, после
+// webhookMaxAttempts попыток доставка отмечается неудачной.
+// Возвращает количество выполненных попыток.
+// Пример:
+//
+//	n, err := s.DeliverWebhooks(ctx)
+func (s *Service) DeliverWebhooks(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		list, err := s.store.PendingDeliveries(ctx, time.Now(), webhookBatchSize)
+		if err != nil {
+			return total, err
+		}
+		hooks := make(map[int64]repository.Webhook)
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+		sem := make(chan struct{}, webhookConcurrency)
+		for _, d := range list {
+			w, ok := hooks[d.WebhookID]
+			if !ok {
+				w, err = s.store.GetWebhook(ctx, d.WebhookID)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return total, err
+				}
+				hooks[d.WebhookID] = w
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				d = s.attemptDelivery(ctx, w, d)
+				if ctx.Err() != nil {
+					return
+				}
+				err := s.store.UpdateDelivery(ctx, d)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("update delivery %d: %w", d.ID, err))
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		total += len(list)
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		if len(errs) > 0 {
+			return total, errors.Join(errs...)
+		}
+		if len(list) < webhookBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// attemptDelivery отправляет событие подписке w и возвращает доставку
+// с результатом попытки. Доставки удаленных и отключенных подписок
+// отмечаются неудачными без отправки.
+func (s *Service) attemptDelivery(ctx context.Context, w repository.Webhook, d repository.Delivery) repository.Delivery {
+	now := time.Now()
+	if w.ID == 0 || !w.Active {
+		d.Status = model.DeliveryFailed
+		d.Error = "webhook is disabled"
+		return d
+	}
+	d.Attempts++
+	d.LastAttemptAt = now
+	d.ResponseStatus, d.Error = 0, ""
+	status, err := postWebhook(ctx, s.webhookClient(), w, d, now)
+	switch {
+	case err != nil:
+		// Ошибка видна владельцу подписки, поэтому текст сетевой ошибки не сохраняется.
+		log.Printf("deliver webhook %d: %v", w.ID, err)
+		d.Error = outboundError(err)
+	case status < 200 || status > 299:
+		d.ResponseStatus = status
+		d.Error = "unexpected status " + strconv.Itoa(status)
+	default:
+		d.ResponseStatus = status
+		d.Status = model.DeliveryDelivered
+		return d
+	}
+	if d.Attempts >= webhookMaxAttempts {
+		d.Status = model.DeliveryFailed
+		return d
+	}
+	d.NextAttemptAt = now.Add(min(webhookRetryBase<<(d.Attempts-1), webhookRetryMax))
+	return d
+}
+
+// postWebhook отправляет событие подписке с подписью HMAC-SHA256 и возвращает код ответа.
+func postWebhook(ctx context.Context, client *http.Client, w repository.Webhook, d repository.Delivery, now time.Time) (int, error) {
+	body, err := json.Marshal(d.Payload)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.Payload.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	req.Header.Set("X-Webhook-Signature", WebhookSignature(w.Secret, ts, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// webhookModel преобразует подписку хранилища в модель ответа без ключа подписи.
+func webhookModel(w repository.Webhook) model.Webhook {
+	return model.Webhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// deliveryModel преобразует доставку хранилища в модель ответа.
+func deliveryModel(d repository.Delivery) model.WebhookDelivery {
+	res := model.WebhookDelivery{
+		ID:             d.ID,
+		Event:          d.Payload.Event,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		Error:          d.Error,
+		CreatedAt:      d.CreatedAt,
+	}
+	if !d.LastAttemptAt.IsZero() {
+		last := d.LastAttemptAt
+		res.LastAttemptAt = &last
+	}
+	if d.Status == model.DeliveryPending {
+		next := d.NextAttemptAt
+		res.NextAttemptAt = &next
+	}
+	return res
+}
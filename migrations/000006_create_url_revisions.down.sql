@@ -0,0 +1,5 @@
+BEGIN;
+DROP TABLE IF EXISTS url_revisions;
+ALTER TABLE urls DROP COLUMN IF EXISTS tags;
+ALTER TABLE urls DROP COLUMN IF EXISTS title;
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE TABLE IF NOT EXISTS url_revisions (
+    id SERIAL PRIMARY KEY,
+    url_id INT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    original_url TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    redirect JSONB NOT NULL DEFAULT '{}'::jsonb,
+    edited_by INT,
+    edited_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (url_id, revision)
+);
+COMMIT;
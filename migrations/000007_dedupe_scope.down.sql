@@ -0,0 +1,6 @@
+BEGIN;
+DROP INDEX IF EXISTS idx_urls_user_id;
+DROP INDEX IF EXISTS idx_urls_unique_original_url_dedupe;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_unique_original_url ON urls(original_url);
+ALTER TABLE urls DROP COLUMN IF EXISTS dedupe_key;
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+-- dedupe_key задает область уникальности original_url:
+--   0       — глобальная дедупликация (прежнее поведение),
+--   user_id — дедупликация в пределах пользователя,
+--   NULL    — дедупликация отключена (NULL не участвует в уникальном индексе).
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS dedupe_key INT DEFAULT 0;
+DROP INDEX IF EXISTS idx_urls_unique_original_url;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_unique_original_url_dedupe ON urls(original_url, dedupe_key);
+CREATE INDEX IF NOT EXISTS idx_urls_user_id ON urls(user_id);
+COMMIT;
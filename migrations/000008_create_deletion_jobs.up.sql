@@ -0,0 +1,11 @@
+BEGIN;
+CREATE TABLE IF NOT EXISTS deletion_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    hashes TEXT[] NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_deletion_jobs_pending ON deletion_jobs(id) WHERE status = 'pending';
+COMMIT;
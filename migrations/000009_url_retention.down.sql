@@ -0,0 +1,6 @@
+BEGIN;
+DROP TABLE IF EXISTS url_purges;
+DROP INDEX IF EXISTS idx_urls_deleted_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS purged_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS deleted_at;
+COMMIT;
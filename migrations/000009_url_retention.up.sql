@@ -0,0 +1,14 @@
+BEGIN;
+-- deleted_at отсчитывает период, в течение которого владелец может восстановить ссылку.
+-- purged_at отмечает окончательно удаленные ссылки, хеш которых остается занятым.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS purged_at TIMESTAMP;
+UPDATE urls SET deleted_at = CURRENT_TIMESTAMP WHERE is_deleted AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_urls_deleted_at ON urls(deleted_at) WHERE is_deleted AND purged_at IS NULL;
+CREATE TABLE IF NOT EXISTS url_purges (
+    id BIGSERIAL PRIMARY KEY,
+    hash VARCHAR(255) NOT NULL,
+    user_id INT,
+    purged_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+ALTER TABLE url_purges DROP COLUMN IF EXISTS domain;
+ALTER TABLE deletion_jobs DROP COLUMN IF EXISTS domain;
+DROP INDEX IF EXISTS idx_urls_unique_domain_original_url_dedupe;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_unique_original_url_dedupe ON urls(original_url, dedupe_key);
+DROP INDEX IF EXISTS idx_urls_unique_domain_hash;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_unique_hash ON urls(hash);
+ALTER TABLE urls DROP COLUMN IF EXISTS domain;
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+-- domain задает домен короткой ссылки; пустая строка — домен сервиса по умолчанию.
+-- Хеш и оригинальный URL уникальны в пределах домена.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS domain VARCHAR(255) NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS idx_urls_unique_hash;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_unique_domain_hash ON urls(domain, hash);
+DROP INDEX IF EXISTS idx_urls_unique_original_url_dedupe;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_unique_domain_original_url_dedupe ON urls(domain, original_url, dedupe_key);
+ALTER TABLE deletion_jobs ADD COLUMN IF NOT EXISTS domain VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE url_purges ADD COLUMN IF NOT EXISTS domain VARCHAR(255) NOT NULL DEFAULT '';
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+DROP INDEX IF EXISTS idx_urls_user_clicks;
+DROP INDEX IF EXISTS idx_urls_user_created;
+DROP INDEX IF EXISTS idx_urls_tags;
+DROP INDEX IF EXISTS idx_urls_search;
+ALTER TABLE urls DROP COLUMN IF EXISTS search;
+ALTER TABLE url_revisions DROP COLUMN IF EXISTS note;
+ALTER TABLE urls DROP COLUMN IF EXISTS clicks;
+ALTER TABLE urls DROP COLUMN IF EXISTS note;
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+-- note — заметка владельца ссылки, clicks — счетчик переходов.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE url_revisions ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+-- search индексирует слова заголовка, заметки и оригинального URL: все символы,
+-- кроме букв и цифр, считаются разделителями (как при поиске в памяти).
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', regexp_replace(title || ' ' || note || ' ' || original_url, '[^[:alnum:]]+', ' ', 'g'))
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_urls_search ON urls USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_urls_tags ON urls USING GIN (tags);
+-- Индексы постраничной выдачи ссылок пользователя по курсору.
+CREATE INDEX IF NOT EXISTS idx_urls_user_created ON urls(user_id, created_at, domain, hash);
+CREATE INDEX IF NOT EXISTS idx_urls_user_clicks ON urls(user_id, clicks, domain, hash);
+COMMIT;
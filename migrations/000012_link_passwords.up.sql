@@ -0,0 +1,5 @@
+BEGIN;
+-- password_hash — bcrypt-хеш пароля ссылки; пустая строка — ссылка без пароля.
+-- Защищенные паролем ссылки не участвуют в дедупликации (dedupe_key = NULL).
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+-- max_clicks — допустимое число переходов по ссылке; 0 — без ограничения.
+-- Ограниченные ссылки не участвуют в дедупликации (dedupe_key = NULL).
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0;
+COMMIT;
@@ -0,0 +1,4 @@
+BEGIN;
+ALTER TABLE url_revisions DROP COLUMN IF EXISTS rules;
+ALTER TABLE urls DROP COLUMN IF EXISTS rules;
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+-- rules — упорядоченный список правил выбора адреса перенаправления (model.RouteRule).
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE url_revisions ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '[]'::jsonb;
+COMMIT;
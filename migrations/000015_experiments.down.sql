@@ -0,0 +1,5 @@
+BEGIN;
+ALTER TABLE urls DROP COLUMN IF EXISTS experiment_id;
+DROP TABLE IF EXISTS experiment_variants;
+DROP TABLE IF EXISTS experiments;
+COMMIT;
@@ -0,0 +1,24 @@
+BEGIN;
+-- experiments — A/B-эксперименты: короткая ссылка domain/hash распределяет
+-- посетителей между вариантами experiment_variants по весам.
+CREATE TABLE IF NOT EXISTS experiments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    domain VARCHAR(255) NOT NULL DEFAULT '',
+    hash VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_experiments_user ON experiments(user_id, id);
+CREATE TABLE IF NOT EXISTS experiment_variants (
+    experiment_id BIGINT NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    weight INT NOT NULL,
+    redirects BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (experiment_id, position)
+);
+-- Ссылки экспериментов не участвуют в дедупликации (dedupe_key = NULL).
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS experiment_id BIGINT REFERENCES experiments(id) ON DELETE SET NULL;
+COMMIT;
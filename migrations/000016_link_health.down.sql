@@ -0,0 +1,7 @@
+BEGIN;
+DROP INDEX IF EXISTS idx_urls_user_broken;
+DROP INDEX IF EXISTS idx_urls_checked_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS broken;
+ALTER TABLE urls DROP COLUMN IF EXISTS checked_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS health;
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+-- health — результат последней проверки адреса назначения (model.LinkHealth);
+-- checked_at и broken дублируют его поля для выбора ссылок на проверку и фильтра недоступных ссылок.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS health JSONB;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS broken BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_urls_checked_at ON urls(checked_at NULLS FIRST, id) WHERE NOT is_deleted;
+CREATE INDEX IF NOT EXISTS idx_urls_user_broken ON urls(user_id) WHERE broken;
+COMMIT;
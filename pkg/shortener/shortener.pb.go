@@ -10,6 +10,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -25,6 +26,14 @@ const (
 type URLShortenRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Redirect      *RedirectOptions       `protobuf:"bytes,2,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	Domain        string                 `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	Title         string                 `protobuf:"bytes,4,opt,name=title,proto3" json:"title,omitempty"`
+	Tags          []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	Note          string                 `protobuf:"bytes,6,opt,name=note,proto3" json:"note,omitempty"`
+	Password      string                 `protobuf:"bytes,7,opt,name=password,proto3" json:"password,omitempty"`
+	MaxClicks     int64                  `protobuf:"varint,8,opt,name=max_clicks,json=maxClicks,proto3" json:"max_clicks,omitempty"`
+	Rules         []*RouteRule           `protobuf:"bytes,9,rep,name=rules,proto3" json:"rules,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -66,28 +75,993 @@ func (x *URLShortenRequest) GetUrl() string {
 	return ""
 }
 
+func (x *URLShortenRequest) GetRedirect() *RedirectOptions {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *URLShortenRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *URLShortenRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *URLShortenRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *URLShortenRequest) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *URLShortenRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *URLShortenRequest) GetMaxClicks() int64 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *URLShortenRequest) GetRules() []*RouteRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type TimeWindow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	From          string                 `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	Location      string                 `protobuf:"bytes,5,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeWindow) Reset() {
+	*x = TimeWindow{}
+	mi := &file_pkg_shortener_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeWindow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeWindow) ProtoMessage() {}
+
+func (x *TimeWindow) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeWindow.ProtoReflect.Descriptor instead.
+func (*TimeWindow) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TimeWindow) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *TimeWindow) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *TimeWindow) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *TimeWindow) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *TimeWindow) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+type RouteRule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Platforms     []string               `protobuf:"bytes,2,rep,name=platforms,proto3" json:"platforms,omitempty"`
+	Languages     []string               `protobuf:"bytes,3,rep,name=languages,proto3" json:"languages,omitempty"`
+	Countries     []string               `protobuf:"bytes,4,rep,name=countries,proto3" json:"countries,omitempty"`
+	Time          *TimeWindow            `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	Percent       int32                  `protobuf:"varint,6,opt,name=percent,proto3" json:"percent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteRule) Reset() {
+	*x = RouteRule{}
+	mi := &file_pkg_shortener_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteRule) ProtoMessage() {}
+
+func (x *RouteRule) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteRule.ProtoReflect.Descriptor instead.
+func (*RouteRule) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RouteRule) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *RouteRule) GetPlatforms() []string {
+	if x != nil {
+		return x.Platforms
+	}
+	return nil
+}
+
+func (x *RouteRule) GetLanguages() []string {
+	if x != nil {
+		return x.Languages
+	}
+	return nil
+}
+
+func (x *RouteRule) GetCountries() []string {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+func (x *RouteRule) GetTime() *TimeWindow {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *RouteRule) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+type RuleList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*RouteRule           `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuleList) Reset() {
+	*x = RuleList{}
+	mi := &file_pkg_shortener_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuleList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleList) ProtoMessage() {}
+
+func (x *RuleList) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleList.ProtoReflect.Descriptor instead.
+func (*RuleList) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RuleList) GetRules() []*RouteRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type UTM struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Medium        string                 `protobuf:"bytes,2,opt,name=medium,proto3" json:"medium,omitempty"`
+	Campaign      string                 `protobuf:"bytes,3,opt,name=campaign,proto3" json:"campaign,omitempty"`
+	Term          string                 `protobuf:"bytes,4,opt,name=term,proto3" json:"term,omitempty"`
+	Content       string                 `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UTM) Reset() {
+	*x = UTM{}
+	mi := &file_pkg_shortener_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UTM) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UTM) ProtoMessage() {}
+
+func (x *UTM) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UTM.ProtoReflect.Descriptor instead.
+func (*UTM) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UTM) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *UTM) GetMedium() string {
+	if x != nil {
+		return x.Medium
+	}
+	return ""
+}
+
+func (x *UTM) GetCampaign() string {
+	if x != nil {
+		return x.Campaign
+	}
+	return ""
+}
+
+func (x *UTM) GetTerm() string {
+	if x != nil {
+		return x.Term
+	}
+	return ""
+}
+
+func (x *UTM) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type RedirectOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	PassQuery     bool                   `protobuf:"varint,2,opt,name=pass_query,json=passQuery,proto3" json:"pass_query,omitempty"`
+	Utm           *UTM                   `protobuf:"bytes,3,opt,name=utm,proto3" json:"utm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RedirectOptions) Reset() {
+	*x = RedirectOptions{}
+	mi := &file_pkg_shortener_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedirectOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedirectOptions) ProtoMessage() {}
+
+func (x *RedirectOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedirectOptions.ProtoReflect.Descriptor instead.
+func (*RedirectOptions) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RedirectOptions) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *RedirectOptions) GetPassQuery() bool {
+	if x != nil {
+		return x.PassQuery
+	}
+	return false
+}
+
+func (x *RedirectOptions) GetUtm() *UTM {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
 type URLShortenResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLShortenResponse) Reset() {
+	*x = URLShortenResponse{}
+	mi := &file_pkg_shortener_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLShortenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLShortenResponse) ProtoMessage() {}
+
+func (x *URLShortenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLShortenResponse.ProtoReflect.Descriptor instead.
+func (*URLShortenResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *URLShortenResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+type URLExpandRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLExpandRequest) Reset() {
+	*x = URLExpandRequest{}
+	mi := &file_pkg_shortener_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLExpandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLExpandRequest) ProtoMessage() {}
+
+func (x *URLExpandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLExpandRequest.ProtoReflect.Descriptor instead.
+func (*URLExpandRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *URLExpandRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *URLExpandRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type URLExpandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLExpandResponse) Reset() {
+	*x = URLExpandResponse{}
+	mi := &file_pkg_shortener_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLExpandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLExpandResponse) ProtoMessage() {}
+
+func (x *URLExpandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLExpandResponse.ProtoReflect.Descriptor instead.
+func (*URLExpandResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *URLExpandResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+type UserURLsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           []*URLData             `protobuf:"bytes,1,rep,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserURLsResponse) Reset() {
+	*x = UserURLsResponse{}
+	mi := &file_pkg_shortener_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserURLsResponse) ProtoMessage() {}
+
+func (x *UserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserURLsResponse.ProtoReflect.Descriptor instead.
+func (*UserURLsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UserURLsResponse) GetUrl() []*URLData {
+	if x != nil {
+		return x.Url
+	}
+	return nil
+}
+
+type URLData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Tags          []string               `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Redirect      *RedirectOptions       `protobuf:"bytes,5,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	Note          string                 `protobuf:"bytes,6,opt,name=note,proto3" json:"note,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Clicks        int64                  `protobuf:"varint,8,opt,name=clicks,proto3" json:"clicks,omitempty"`
+	Protected     bool                   `protobuf:"varint,9,opt,name=protected,proto3" json:"protected,omitempty"`
+	MaxClicks     int64                  `protobuf:"varint,10,opt,name=max_clicks,json=maxClicks,proto3" json:"max_clicks,omitempty"`
+	ClicksLeft    *int64                 `protobuf:"varint,11,opt,name=clicks_left,json=clicksLeft,proto3,oneof" json:"clicks_left,omitempty"`
+	Rules         []*RouteRule           `protobuf:"bytes,12,rep,name=rules,proto3" json:"rules,omitempty"`
+	ExperimentId  int64                  `protobuf:"varint,13,opt,name=experiment_id,json=experimentId,proto3" json:"experiment_id,omitempty"`
+	Broken        bool                   `protobuf:"varint,14,opt,name=broken,proto3" json:"broken,omitempty"`
+	Health        *LinkHealth            `protobuf:"bytes,15,opt,name=health,proto3" json:"health,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLData) Reset() {
+	*x = URLData{}
+	mi := &file_pkg_shortener_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLData) ProtoMessage() {}
+
+func (x *URLData) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLData.ProtoReflect.Descriptor instead.
+func (*URLData) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *URLData) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *URLData) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *URLData) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *URLData) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *URLData) GetRedirect() *RedirectOptions {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *URLData) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *URLData) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *URLData) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+func (x *URLData) GetProtected() bool {
+	if x != nil {
+		return x.Protected
+	}
+	return false
+}
+
+func (x *URLData) GetMaxClicks() int64 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *URLData) GetClicksLeft() int64 {
+	if x != nil && x.ClicksLeft != nil {
+		return *x.ClicksLeft
+	}
+	return 0
+}
+
+func (x *URLData) GetRules() []*RouteRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *URLData) GetExperimentId() int64 {
+	if x != nil {
+		return x.ExperimentId
+	}
+	return 0
+}
+
+func (x *URLData) GetBroken() bool {
+	if x != nil {
+		return x.Broken
+	}
+	return false
+}
+
+func (x *URLData) GetHealth() *LinkHealth {
+	if x != nil {
+		return x.Health
+	}
+	return nil
+}
+
+type LinkHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        int32                  `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Redirects     []string               `protobuf:"bytes,3,rep,name=redirects,proto3" json:"redirects,omitempty"`
+	Broken        bool                   `protobuf:"varint,4,opt,name=broken,proto3" json:"broken,omitempty"`
+	CheckedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=checked_at,json=checkedAt,proto3" json:"checked_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkHealth) Reset() {
+	*x = LinkHealth{}
+	mi := &file_pkg_shortener_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkHealth) ProtoMessage() {}
+
+func (x *LinkHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkHealth.ProtoReflect.Descriptor instead.
+func (*LinkHealth) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *LinkHealth) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *LinkHealth) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *LinkHealth) GetRedirects() []string {
+	if x != nil {
+		return x.Redirects
+	}
+	return nil
+}
+
+func (x *LinkHealth) GetBroken() bool {
+	if x != nil {
+		return x.Broken
+	}
+	return false
+}
+
+func (x *LinkHealth) GetCheckedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CheckedAt
+	}
+	return nil
+}
+
+type TagList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TagList) Reset() {
+	*x = TagList{}
+	mi := &file_pkg_shortener_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TagList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TagList) ProtoMessage() {}
+
+func (x *TagList) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TagList.ProtoReflect.Descriptor instead.
+func (*TagList) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TagList) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type URLUpdateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	OriginalUrl   *string                `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3,oneof" json:"original_url,omitempty"`
+	Title         *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Tags          *TagList               `protobuf:"bytes,4,opt,name=tags,proto3" json:"tags,omitempty"`
+	Redirect      *RedirectOptions       `protobuf:"bytes,5,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	Domain        string                 `protobuf:"bytes,6,opt,name=domain,proto3" json:"domain,omitempty"`
+	Note          *string                `protobuf:"bytes,7,opt,name=note,proto3,oneof" json:"note,omitempty"`
+	Password      *string                `protobuf:"bytes,8,opt,name=password,proto3,oneof" json:"password,omitempty"`
+	Rules         *RuleList              `protobuf:"bytes,9,opt,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLUpdateRequest) Reset() {
+	*x = URLUpdateRequest{}
+	mi := &file_pkg_shortener_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLUpdateRequest) ProtoMessage() {}
+
+func (x *URLUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLUpdateRequest.ProtoReflect.Descriptor instead.
+func (*URLUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *URLUpdateRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *URLUpdateRequest) GetOriginalUrl() string {
+	if x != nil && x.OriginalUrl != nil {
+		return *x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *URLUpdateRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *URLUpdateRequest) GetTags() *TagList {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *URLUpdateRequest) GetRedirect() *RedirectOptions {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *URLUpdateRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *URLUpdateRequest) GetNote() string {
+	if x != nil && x.Note != nil {
+		return *x.Note
+	}
+	return ""
+}
+
+func (x *URLUpdateRequest) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *URLUpdateRequest) GetRules() *RuleList {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type URLRevisionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *URLShortenResponse) Reset() {
-	*x = URLShortenResponse{}
-	mi := &file_pkg_shortener_proto_msgTypes[1]
+func (x *URLRevisionsRequest) Reset() {
+	*x = URLRevisionsRequest{}
+	mi := &file_pkg_shortener_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *URLShortenResponse) String() string {
+func (x *URLRevisionsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*URLShortenResponse) ProtoMessage() {}
+func (*URLRevisionsRequest) ProtoMessage() {}
 
-func (x *URLShortenResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_shortener_proto_msgTypes[1]
+func (x *URLRevisionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -98,40 +1072,54 @@ func (x *URLShortenResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use URLShortenResponse.ProtoReflect.Descriptor instead.
-func (*URLShortenResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_shortener_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use URLRevisionsRequest.ProtoReflect.Descriptor instead.
+func (*URLRevisionsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *URLShortenResponse) GetResult() string {
+func (x *URLRevisionsRequest) GetId() string {
 	if x != nil {
-		return x.Result
+		return x.Id
 	}
 	return ""
 }
 
-type URLExpandRequest struct {
+func (x *URLRevisionsRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type URLRevision struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Revision      int32                  `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Tags          []string               `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Redirect      *RedirectOptions       `protobuf:"bytes,5,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	EditedAt      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=edited_at,json=editedAt,proto3" json:"edited_at,omitempty"`
+	Note          string                 `protobuf:"bytes,7,opt,name=note,proto3" json:"note,omitempty"`
+	Rules         []*RouteRule           `protobuf:"bytes,8,rep,name=rules,proto3" json:"rules,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *URLExpandRequest) Reset() {
-	*x = URLExpandRequest{}
-	mi := &file_pkg_shortener_proto_msgTypes[2]
+func (x *URLRevision) Reset() {
+	*x = URLRevision{}
+	mi := &file_pkg_shortener_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *URLExpandRequest) String() string {
+func (x *URLRevision) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*URLExpandRequest) ProtoMessage() {}
+func (*URLRevision) ProtoMessage() {}
 
-func (x *URLExpandRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_shortener_proto_msgTypes[2]
+func (x *URLRevision) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -142,40 +1130,89 @@ func (x *URLExpandRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use URLExpandRequest.ProtoReflect.Descriptor instead.
-func (*URLExpandRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_shortener_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use URLRevision.ProtoReflect.Descriptor instead.
+func (*URLRevision) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *URLExpandRequest) GetId() string {
+func (x *URLRevision) GetRevision() int32 {
 	if x != nil {
-		return x.Id
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *URLRevision) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
 	}
 	return ""
 }
 
-type URLExpandResponse struct {
+func (x *URLRevision) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *URLRevision) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *URLRevision) GetRedirect() *RedirectOptions {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *URLRevision) GetEditedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EditedAt
+	}
+	return nil
+}
+
+func (x *URLRevision) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *URLRevision) GetRules() []*RouteRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type URLRevisionsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Revisions     []*URLRevision         `protobuf:"bytes,1,rep,name=revisions,proto3" json:"revisions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *URLExpandResponse) Reset() {
-	*x = URLExpandResponse{}
-	mi := &file_pkg_shortener_proto_msgTypes[3]
+func (x *URLRevisionsResponse) Reset() {
+	*x = URLRevisionsResponse{}
+	mi := &file_pkg_shortener_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *URLExpandResponse) String() string {
+func (x *URLRevisionsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*URLExpandResponse) ProtoMessage() {}
+func (*URLRevisionsResponse) ProtoMessage() {}
 
-func (x *URLExpandResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_shortener_proto_msgTypes[3]
+func (x *URLRevisionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -186,40 +1223,184 @@ func (x *URLExpandResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use URLExpandResponse.ProtoReflect.Descriptor instead.
-func (*URLExpandResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_shortener_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use URLRevisionsResponse.ProtoReflect.Descriptor instead.
+func (*URLRevisionsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *URLExpandResponse) GetResult() string {
+func (x *URLRevisionsResponse) GetRevisions() []*URLRevision {
+	if x != nil {
+		return x.Revisions
+	}
+	return nil
+}
+
+type StreamUserURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Tags          []string               `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	Sort          string                 `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	Order         string                 `protobuf:"bytes,4,opt,name=order,proto3" json:"order,omitempty"`
+	Broken        bool                   `protobuf:"varint,5,opt,name=broken,proto3" json:"broken,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamUserURLsRequest) Reset() {
+	*x = StreamUserURLsRequest{}
+	mi := &file_pkg_shortener_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUserURLsRequest) ProtoMessage() {}
+
+func (x *StreamUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUserURLsRequest.ProtoReflect.Descriptor instead.
+func (*StreamUserURLsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StreamUserURLsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *StreamUserURLsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *StreamUserURLsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *StreamUserURLsRequest) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+func (x *StreamUserURLsRequest) GetBroken() bool {
+	if x != nil {
+		return x.Broken
+	}
+	return false
+}
+
+type ShortenResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Result        string                 `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	Code          int32                  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShortenResult) Reset() {
+	*x = ShortenResult{}
+	mi := &file_pkg_shortener_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShortenResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShortenResult) ProtoMessage() {}
+
+func (x *ShortenResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShortenResult.ProtoReflect.Descriptor instead.
+func (*ShortenResult) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ShortenResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ShortenResult) GetResult() string {
 	if x != nil {
 		return x.Result
 	}
 	return ""
 }
 
-type UserURLsResponse struct {
+func (x *ShortenResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ShortenResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ShortenStreamResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Url           []*URLData             `protobuf:"bytes,1,rep,name=url,proto3" json:"url,omitempty"`
+	Results       []*ShortenResult       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UserURLsResponse) Reset() {
-	*x = UserURLsResponse{}
-	mi := &file_pkg_shortener_proto_msgTypes[4]
+func (x *ShortenStreamResponse) Reset() {
+	*x = ShortenStreamResponse{}
+	mi := &file_pkg_shortener_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UserURLsResponse) String() string {
+func (x *ShortenStreamResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UserURLsResponse) ProtoMessage() {}
+func (*ShortenStreamResponse) ProtoMessage() {}
 
-func (x *UserURLsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_shortener_proto_msgTypes[4]
+func (x *ShortenStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -230,41 +1411,40 @@ func (x *UserURLsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UserURLsResponse.ProtoReflect.Descriptor instead.
-func (*UserURLsResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_shortener_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use ShortenStreamResponse.ProtoReflect.Descriptor instead.
+func (*ShortenStreamResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{19}
 }
 
-func (x *UserURLsResponse) GetUrl() []*URLData {
+func (x *ShortenStreamResponse) GetResults() []*ShortenResult {
 	if x != nil {
-		return x.Url
+		return x.Results
 	}
 	return nil
 }
 
-type URLData struct {
+type WatchLinksRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
-	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Events        []string               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *URLData) Reset() {
-	*x = URLData{}
-	mi := &file_pkg_shortener_proto_msgTypes[5]
+func (x *WatchLinksRequest) Reset() {
+	*x = WatchLinksRequest{}
+	mi := &file_pkg_shortener_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *URLData) String() string {
+func (x *WatchLinksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*URLData) ProtoMessage() {}
+func (*WatchLinksRequest) ProtoMessage() {}
 
-func (x *URLData) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_shortener_proto_msgTypes[5]
+func (x *WatchLinksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -275,19 +1455,80 @@ func (x *URLData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use URLData.ProtoReflect.Descriptor instead.
-func (*URLData) Descriptor() ([]byte, []int) {
-	return file_pkg_shortener_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use WatchLinksRequest.ProtoReflect.Descriptor instead.
+func (*WatchLinksRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *URLData) GetShortUrl() string {
+func (x *WatchLinksRequest) GetEvents() []string {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type LinkEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         string                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	Ts            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts,proto3" json:"ts,omitempty"`
+	ShortUrl      string                 `protobuf:"bytes,3,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,4,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkEvent) Reset() {
+	*x = LinkEvent{}
+	mi := &file_pkg_shortener_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkEvent) ProtoMessage() {}
+
+func (x *LinkEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_shortener_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkEvent.ProtoReflect.Descriptor instead.
+func (*LinkEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LinkEvent) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *LinkEvent) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Ts
+	}
+	return nil
+}
+
+func (x *LinkEvent) GetShortUrl() string {
 	if x != nil {
 		return x.ShortUrl
 	}
 	return ""
 }
 
-func (x *URLData) GetOriginalUrl() string {
+func (x *LinkEvent) GetOriginalUrl() string {
 	if x != nil {
 		return x.OriginalUrl
 	}
@@ -298,25 +1539,144 @@ var File_pkg_shortener_proto protoreflect.FileDescriptor
 
 const file_pkg_shortener_proto_rawDesc = "" +
 	"\n" +
-	"\x13pkg/shortener.proto\x12\tshortener\x1a\x1bgoogle/protobuf/empty.proto\"%\n" +
+	"\x13pkg/shortener.proto\x12\tshortener\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9a\x02\n" +
 	"\x11URLShortenRequest\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url\",\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x126\n" +
+	"\bredirect\x18\x02 \x01(\v2\x1a.shortener.RedirectOptionsR\bredirect\x12\x16\n" +
+	"\x06domain\x18\x03 \x01(\tR\x06domain\x12\x14\n" +
+	"\x05title\x18\x04 \x01(\tR\x05title\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12\x12\n" +
+	"\x04note\x18\x06 \x01(\tR\x04note\x12\x1a\n" +
+	"\bpassword\x18\a \x01(\tR\bpassword\x12\x1d\n" +
+	"\n" +
+	"max_clicks\x18\b \x01(\x03R\tmaxClicks\x12*\n" +
+	"\x05rules\x18\t \x03(\v2\x14.shortener.RouteRuleR\x05rules\"\xac\x01\n" +
+	"\n" +
+	"TimeWindow\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x01(\tR\x02to\x12\x1a\n" +
+	"\blocation\x18\x05 \x01(\tR\blocation\"\xbc\x01\n" +
+	"\tRouteRule\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x1c\n" +
+	"\tplatforms\x18\x02 \x03(\tR\tplatforms\x12\x1c\n" +
+	"\tlanguages\x18\x03 \x03(\tR\tlanguages\x12\x1c\n" +
+	"\tcountries\x18\x04 \x03(\tR\tcountries\x12)\n" +
+	"\x04time\x18\x05 \x01(\v2\x15.shortener.TimeWindowR\x04time\x12\x18\n" +
+	"\apercent\x18\x06 \x01(\x05R\apercent\"6\n" +
+	"\bRuleList\x12*\n" +
+	"\x05rules\x18\x01 \x03(\v2\x14.shortener.RouteRuleR\x05rules\"\x7f\n" +
+	"\x03UTM\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x16\n" +
+	"\x06medium\x18\x02 \x01(\tR\x06medium\x12\x1a\n" +
+	"\bcampaign\x18\x03 \x01(\tR\bcampaign\x12\x12\n" +
+	"\x04term\x18\x04 \x01(\tR\x04term\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\tR\acontent\"f\n" +
+	"\x0fRedirectOptions\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x1d\n" +
+	"\n" +
+	"pass_query\x18\x02 \x01(\bR\tpassQuery\x12 \n" +
+	"\x03utm\x18\x03 \x01(\v2\x0e.shortener.UTMR\x03utm\",\n" +
 	"\x12URLShortenResponse\x12\x16\n" +
-	"\x06result\x18\x01 \x01(\tR\x06result\"\"\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\":\n" +
 	"\x10URLExpandRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"+\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\"+\n" +
 	"\x11URLExpandResponse\x12\x16\n" +
 	"\x06result\x18\x01 \x01(\tR\x06result\"8\n" +
 	"\x10UserURLsResponse\x12$\n" +
-	"\x03url\x18\x01 \x03(\v2\x12.shortener.URLDataR\x03url\"I\n" +
+	"\x03url\x18\x01 \x03(\v2\x12.shortener.URLDataR\x03url\"\x9d\x04\n" +
 	"\aURLData\x12\x1b\n" +
 	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl2\xea\x01\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x126\n" +
+	"\bredirect\x18\x05 \x01(\v2\x1a.shortener.RedirectOptionsR\bredirect\x12\x12\n" +
+	"\x04note\x18\x06 \x01(\tR\x04note\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x16\n" +
+	"\x06clicks\x18\b \x01(\x03R\x06clicks\x12\x1c\n" +
+	"\tprotected\x18\t \x01(\bR\tprotected\x12\x1d\n" +
+	"\n" +
+	"max_clicks\x18\n" +
+	" \x01(\x03R\tmaxClicks\x12$\n" +
+	"\vclicks_left\x18\v \x01(\x03H\x00R\n" +
+	"clicksLeft\x88\x01\x01\x12*\n" +
+	"\x05rules\x18\f \x03(\v2\x14.shortener.RouteRuleR\x05rules\x12#\n" +
+	"\rexperiment_id\x18\r \x01(\x03R\fexperimentId\x12\x16\n" +
+	"\x06broken\x18\x0e \x01(\bR\x06broken\x12-\n" +
+	"\x06health\x18\x0f \x01(\v2\x15.shortener.LinkHealthR\x06healthB\x0e\n" +
+	"\f_clicks_left\"\xab\x01\n" +
+	"\n" +
+	"LinkHealth\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\x05R\x06status\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1c\n" +
+	"\tredirects\x18\x03 \x03(\tR\tredirects\x12\x16\n" +
+	"\x06broken\x18\x04 \x01(\bR\x06broken\x129\n" +
+	"\n" +
+	"checked_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcheckedAt\"\x1d\n" +
+	"\aTagList\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\"\xf3\x02\n" +
+	"\x10URLUpdateRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12&\n" +
+	"\foriginal_url\x18\x02 \x01(\tH\x00R\voriginalUrl\x88\x01\x01\x12\x19\n" +
+	"\x05title\x18\x03 \x01(\tH\x01R\x05title\x88\x01\x01\x12&\n" +
+	"\x04tags\x18\x04 \x01(\v2\x12.shortener.TagListR\x04tags\x126\n" +
+	"\bredirect\x18\x05 \x01(\v2\x1a.shortener.RedirectOptionsR\bredirect\x12\x16\n" +
+	"\x06domain\x18\x06 \x01(\tR\x06domain\x12\x17\n" +
+	"\x04note\x18\a \x01(\tH\x02R\x04note\x88\x01\x01\x12\x1f\n" +
+	"\bpassword\x18\b \x01(\tH\x03R\bpassword\x88\x01\x01\x12)\n" +
+	"\x05rules\x18\t \x01(\v2\x13.shortener.RuleListR\x05rulesB\x0f\n" +
+	"\r_original_urlB\b\n" +
+	"\x06_titleB\a\n" +
+	"\x05_noteB\v\n" +
+	"\t_password\"=\n" +
+	"\x13URLRevisionsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\"\xa7\x02\n" +
+	"\vURLRevision\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x05R\brevision\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x126\n" +
+	"\bredirect\x18\x05 \x01(\v2\x1a.shortener.RedirectOptionsR\bredirect\x127\n" +
+	"\tedited_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\beditedAt\x12\x12\n" +
+	"\x04note\x18\a \x01(\tR\x04note\x12*\n" +
+	"\x05rules\x18\b \x03(\v2\x14.shortener.RouteRuleR\x05rules\"L\n" +
+	"\x14URLRevisionsResponse\x124\n" +
+	"\trevisions\x18\x01 \x03(\v2\x16.shortener.URLRevisionR\trevisions\"\x83\x01\n" +
+	"\x15StreamUserURLsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\x12\x12\n" +
+	"\x04sort\x18\x03 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05order\x18\x04 \x01(\tR\x05order\x12\x16\n" +
+	"\x06broken\x18\x05 \x01(\bR\x06broken\"g\n" +
+	"\rShortenResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x16\n" +
+	"\x06result\x18\x02 \x01(\tR\x06result\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"K\n" +
+	"\x15ShortenStreamResponse\x122\n" +
+	"\aresults\x18\x01 \x03(\v2\x18.shortener.ShortenResultR\aresults\"+\n" +
+	"\x11WatchLinksRequest\x12\x16\n" +
+	"\x06events\x18\x01 \x03(\tR\x06events\"\x8d\x01\n" +
+	"\tLinkEvent\x12\x14\n" +
+	"\x05event\x18\x01 \x01(\tR\x05event\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1b\n" +
+	"\tshort_url\x18\x03 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x04 \x01(\tR\voriginalUrl2\xe0\x04\n" +
 	"\x10ShortenerService\x12I\n" +
 	"\n" +
 	"ShortenURL\x12\x1c.shortener.URLShortenRequest\x1a\x1d.shortener.URLShortenResponse\x12F\n" +
 	"\tExpandURL\x12\x1b.shortener.URLExpandRequest\x1a\x1c.shortener.URLExpandResponse\x12C\n" +
-	"\fListUserURLs\x12\x16.google.protobuf.Empty\x1a\x1b.shortener.UserURLsResponseB\rZ\v.;shortenerb\x06proto3"
+	"\fListUserURLs\x12\x16.google.protobuf.Empty\x1a\x1b.shortener.UserURLsResponse\x12<\n" +
+	"\tUpdateURL\x12\x1b.shortener.URLUpdateRequest\x1a\x12.shortener.URLData\x12S\n" +
+	"\x10ListURLRevisions\x12\x1e.shortener.URLRevisionsRequest\x1a\x1f.shortener.URLRevisionsResponse\x12H\n" +
+	"\x0eStreamUserURLs\x12 .shortener.StreamUserURLsRequest\x1a\x12.shortener.URLData0\x01\x12Q\n" +
+	"\rShortenStream\x12\x1c.shortener.URLShortenRequest\x1a .shortener.ShortenStreamResponse(\x01\x12D\n" +
+	"\n" +
+	"WatchLinks\x12\x1c.shortener.WatchLinksRequest\x1a\x14.shortener.LinkEvent(\x010\x01B\rZ\v.;shortenerb\x06proto3"
 
 var (
 	file_pkg_shortener_proto_rawDescOnce sync.Once
@@ -330,29 +1690,77 @@ func file_pkg_shortener_proto_rawDescGZIP() []byte {
 	return file_pkg_shortener_proto_rawDescData
 }
 
-var file_pkg_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_pkg_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_pkg_shortener_proto_goTypes = []any{
-	(*URLShortenRequest)(nil),  // 0: shortener.URLShortenRequest
-	(*URLShortenResponse)(nil), // 1: shortener.URLShortenResponse
-	(*URLExpandRequest)(nil),   // 2: shortener.URLExpandRequest
-	(*URLExpandResponse)(nil),  // 3: shortener.URLExpandResponse
-	(*UserURLsResponse)(nil),   // 4: shortener.UserURLsResponse
-	(*URLData)(nil),            // 5: shortener.URLData
-	(*emptypb.Empty)(nil),      // 6: google.protobuf.Empty
+	(*URLShortenRequest)(nil),     // 0: shortener.URLShortenRequest
+	(*TimeWindow)(nil),            // 1: shortener.TimeWindow
+	(*RouteRule)(nil),             // 2: shortener.RouteRule
+	(*RuleList)(nil),              // 3: shortener.RuleList
+	(*UTM)(nil),                   // 4: shortener.UTM
+	(*RedirectOptions)(nil),       // 5: shortener.RedirectOptions
+	(*URLShortenResponse)(nil),    // 6: shortener.URLShortenResponse
+	(*URLExpandRequest)(nil),      // 7: shortener.URLExpandRequest
+	(*URLExpandResponse)(nil),     // 8: shortener.URLExpandResponse
+	(*UserURLsResponse)(nil),      // 9: shortener.UserURLsResponse
+	(*URLData)(nil),               // 10: shortener.URLData
+	(*LinkHealth)(nil),            // 11: shortener.LinkHealth
+	(*TagList)(nil),               // 12: shortener.TagList
+	(*URLUpdateRequest)(nil),      // 13: shortener.URLUpdateRequest
+	(*URLRevisionsRequest)(nil),   // 14: shortener.URLRevisionsRequest
+	(*URLRevision)(nil),           // 15: shortener.URLRevision
+	(*URLRevisionsResponse)(nil),  // 16: shortener.URLRevisionsResponse
+	(*StreamUserURLsRequest)(nil), // 17: shortener.StreamUserURLsRequest
+	(*ShortenResult)(nil),         // 18: shortener.ShortenResult
+	(*ShortenStreamResponse)(nil), // 19: shortener.ShortenStreamResponse
+	(*WatchLinksRequest)(nil),     // 20: shortener.WatchLinksRequest
+	(*LinkEvent)(nil),             // 21: shortener.LinkEvent
+	(*timestamppb.Timestamp)(nil), // 22: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 23: google.protobuf.Empty
 }
 var file_pkg_shortener_proto_depIdxs = []int32{
-	5, // 0: shortener.UserURLsResponse.url:type_name -> shortener.URLData
-	0, // 1: shortener.ShortenerService.ShortenURL:input_type -> shortener.URLShortenRequest
-	2, // 2: shortener.ShortenerService.ExpandURL:input_type -> shortener.URLExpandRequest
-	6, // 3: shortener.ShortenerService.ListUserURLs:input_type -> google.protobuf.Empty
-	1, // 4: shortener.ShortenerService.ShortenURL:output_type -> shortener.URLShortenResponse
-	3, // 5: shortener.ShortenerService.ExpandURL:output_type -> shortener.URLExpandResponse
-	4, // 6: shortener.ShortenerService.ListUserURLs:output_type -> shortener.UserURLsResponse
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	5,  // 0: shortener.URLShortenRequest.redirect:type_name -> shortener.RedirectOptions
+	2,  // 1: shortener.URLShortenRequest.rules:type_name -> shortener.RouteRule
+	22, // 2: shortener.TimeWindow.start:type_name -> google.protobuf.Timestamp
+	22, // 3: shortener.TimeWindow.end:type_name -> google.protobuf.Timestamp
+	1,  // 4: shortener.RouteRule.time:type_name -> shortener.TimeWindow
+	2,  // 5: shortener.RuleList.rules:type_name -> shortener.RouteRule
+	4,  // 6: shortener.RedirectOptions.utm:type_name -> shortener.UTM
+	10, // 7: shortener.UserURLsResponse.url:type_name -> shortener.URLData
+	5,  // 8: shortener.URLData.redirect:type_name -> shortener.RedirectOptions
+	22, // 9: shortener.URLData.created_at:type_name -> google.protobuf.Timestamp
+	2,  // 10: shortener.URLData.rules:type_name -> shortener.RouteRule
+	11, // 11: shortener.URLData.health:type_name -> shortener.LinkHealth
+	22, // 12: shortener.LinkHealth.checked_at:type_name -> google.protobuf.Timestamp
+	12, // 13: shortener.URLUpdateRequest.tags:type_name -> shortener.TagList
+	5,  // 14: shortener.URLUpdateRequest.redirect:type_name -> shortener.RedirectOptions
+	3,  // 15: shortener.URLUpdateRequest.rules:type_name -> shortener.RuleList
+	5,  // 16: shortener.URLRevision.redirect:type_name -> shortener.RedirectOptions
+	22, // 17: shortener.URLRevision.edited_at:type_name -> google.protobuf.Timestamp
+	2,  // 18: shortener.URLRevision.rules:type_name -> shortener.RouteRule
+	15, // 19: shortener.URLRevisionsResponse.revisions:type_name -> shortener.URLRevision
+	18, // 20: shortener.ShortenStreamResponse.results:type_name -> shortener.ShortenResult
+	22, // 21: shortener.LinkEvent.ts:type_name -> google.protobuf.Timestamp
+	0,  // 22: shortener.ShortenerService.ShortenURL:input_type -> shortener.URLShortenRequest
+	7,  // 23: shortener.ShortenerService.ExpandURL:input_type -> shortener.URLExpandRequest
+	23, // 24: shortener.ShortenerService.ListUserURLs:input_type -> google.protobuf.Empty
+	13, // 25: shortener.ShortenerService.UpdateURL:input_type -> shortener.URLUpdateRequest
+	14, // 26: shortener.ShortenerService.ListURLRevisions:input_type -> shortener.URLRevisionsRequest
+	17, // 27: shortener.ShortenerService.StreamUserURLs:input_type -> shortener.StreamUserURLsRequest
+	0,  // 28: shortener.ShortenerService.ShortenStream:input_type -> shortener.URLShortenRequest
+	20, // 29: shortener.ShortenerService.WatchLinks:input_type -> shortener.WatchLinksRequest
+	6,  // 30: shortener.ShortenerService.ShortenURL:output_type -> shortener.URLShortenResponse
+	8,  // 31: shortener.ShortenerService.ExpandURL:output_type -> shortener.URLExpandResponse
+	9,  // 32: shortener.ShortenerService.ListUserURLs:output_type -> shortener.UserURLsResponse
+	10, // 33: shortener.ShortenerService.UpdateURL:output_type -> shortener.URLData
+	16, // 34: shortener.ShortenerService.ListURLRevisions:output_type -> shortener.URLRevisionsResponse
+	10, // 35: shortener.ShortenerService.StreamUserURLs:output_type -> shortener.URLData
+	19, // 36: shortener.ShortenerService.ShortenStream:output_type -> shortener.ShortenStreamResponse
+	21, // 37: shortener.ShortenerService.WatchLinks:output_type -> shortener.LinkEvent
+	30, // [30:38] is the sub-list for method output_type
+	22, // [22:30] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
 }
 
 func init() { file_pkg_shortener_proto_init() }
@@ -360,13 +1768,15 @@ func file_pkg_shortener_proto_init() {
 	if File_pkg_shortener_proto != nil {
 		return
 	}
+	file_pkg_shortener_proto_msgTypes[10].OneofWrappers = []any{}
+	file_pkg_shortener_proto_msgTypes[13].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_shortener_proto_rawDesc), len(file_pkg_shortener_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,9 +20,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ShortenerService_ShortenURL_FullMethodName   = "/shortener.ShortenerService/ShortenURL"
-	ShortenerService_ExpandURL_FullMethodName    = "/shortener.ShortenerService/ExpandURL"
-	ShortenerService_ListUserURLs_FullMethodName = "/shortener.ShortenerService/ListUserURLs"
+	ShortenerService_ShortenURL_FullMethodName       = "/shortener.ShortenerService/ShortenURL"
+	ShortenerService_ExpandURL_FullMethodName        = "/shortener.ShortenerService/ExpandURL"
+	ShortenerService_ListUserURLs_FullMethodName     = "/shortener.ShortenerService/ListUserURLs"
+	ShortenerService_UpdateURL_FullMethodName        = "/shortener.ShortenerService/UpdateURL"
+	ShortenerService_ListURLRevisions_FullMethodName = "/shortener.ShortenerService/ListURLRevisions"
+	ShortenerService_StreamUserURLs_FullMethodName   = "/shortener.ShortenerService/StreamUserURLs"
+	ShortenerService_ShortenStream_FullMethodName    = "/shortener.ShortenerService/ShortenStream"
+	ShortenerService_WatchLinks_FullMethodName       = "/shortener.ShortenerService/WatchLinks"
 )
 
 // ShortenerServiceClient is the client API for ShortenerService service.
@@ -32,6 +37,11 @@ type ShortenerServiceClient interface {
 	ShortenURL(ctx context.Context, in *URLShortenRequest, opts ...grpc.CallOption) (*URLShortenResponse, error)
 	ExpandURL(ctx context.Context, in *URLExpandRequest, opts ...grpc.CallOption) (*URLExpandResponse, error)
 	ListUserURLs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*UserURLsResponse, error)
+	UpdateURL(ctx context.Context, in *URLUpdateRequest, opts ...grpc.CallOption) (*URLData, error)
+	ListURLRevisions(ctx context.Context, in *URLRevisionsRequest, opts ...grpc.CallOption) (*URLRevisionsResponse, error)
+	StreamUserURLs(ctx context.Context, in *StreamUserURLsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[URLData], error)
+	ShortenStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[URLShortenRequest, ShortenStreamResponse], error)
+	WatchLinks(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WatchLinksRequest, LinkEvent], error)
 }
 
 type shortenerServiceClient struct {
@@ -72,6 +82,71 @@ func (c *shortenerServiceClient) ListUserURLs(ctx context.Context, in *emptypb.E
 	return out, nil
 }
 
+func (c *shortenerServiceClient) UpdateURL(ctx context.Context, in *URLUpdateRequest, opts ...grpc.CallOption) (*URLData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLData)
+	err := c.cc.Invoke(ctx, ShortenerService_UpdateURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerServiceClient) ListURLRevisions(ctx context.Context, in *URLRevisionsRequest, opts ...grpc.CallOption) (*URLRevisionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLRevisionsResponse)
+	err := c.cc.Invoke(ctx, ShortenerService_ListURLRevisions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerServiceClient) StreamUserURLs(ctx context.Context, in *StreamUserURLsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[URLData], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShortenerService_ServiceDesc.Streams[0], ShortenerService_StreamUserURLs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamUserURLsRequest, URLData]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_StreamUserURLsClient = grpc.ServerStreamingClient[URLData]
+
+func (c *shortenerServiceClient) ShortenStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[URLShortenRequest, ShortenStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShortenerService_ServiceDesc.Streams[1], ShortenerService_ShortenStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[URLShortenRequest, ShortenStreamResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_ShortenStreamClient = grpc.ClientStreamingClient[URLShortenRequest, ShortenStreamResponse]
+
+func (c *shortenerServiceClient) WatchLinks(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WatchLinksRequest, LinkEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShortenerService_ServiceDesc.Streams[2], ShortenerService_WatchLinks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchLinksRequest, LinkEvent]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_WatchLinksClient = grpc.BidiStreamingClient[WatchLinksRequest, LinkEvent]
+
 // ShortenerServiceServer is the server API for ShortenerService service.
 // All implementations must embed UnimplementedShortenerServiceServer
 // for forward compatibility.
@@ -79,6 +154,11 @@ type ShortenerServiceServer interface {
 	ShortenURL(context.Context, *URLShortenRequest) (*URLShortenResponse, error)
 	ExpandURL(context.Context, *URLExpandRequest) (*URLExpandResponse, error)
 	ListUserURLs(context.Context, *emptypb.Empty) (*UserURLsResponse, error)
+	UpdateURL(context.Context, *URLUpdateRequest) (*URLData, error)
+	ListURLRevisions(context.Context, *URLRevisionsRequest) (*URLRevisionsResponse, error)
+	StreamUserURLs(*StreamUserURLsRequest, grpc.ServerStreamingServer[URLData]) error
+	ShortenStream(grpc.ClientStreamingServer[URLShortenRequest, ShortenStreamResponse]) error
+	WatchLinks(grpc.BidiStreamingServer[WatchLinksRequest, LinkEvent]) error
 	mustEmbedUnimplementedShortenerServiceServer()
 }
 
@@ -98,6 +178,21 @@ func (UnimplementedShortenerServiceServer) ExpandURL(context.Context, *URLExpand
 func (UnimplementedShortenerServiceServer) ListUserURLs(context.Context, *emptypb.Empty) (*UserURLsResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ListUserURLs not implemented")
 }
+func (UnimplementedShortenerServiceServer) UpdateURL(context.Context, *URLUpdateRequest) (*URLData, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateURL not implemented")
+}
+func (UnimplementedShortenerServiceServer) ListURLRevisions(context.Context, *URLRevisionsRequest) (*URLRevisionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListURLRevisions not implemented")
+}
+func (UnimplementedShortenerServiceServer) StreamUserURLs(*StreamUserURLsRequest, grpc.ServerStreamingServer[URLData]) error {
+	return status.Error(codes.Unimplemented, "method StreamUserURLs not implemented")
+}
+func (UnimplementedShortenerServiceServer) ShortenStream(grpc.ClientStreamingServer[URLShortenRequest, ShortenStreamResponse]) error {
+	return status.Error(codes.Unimplemented, "method ShortenStream not implemented")
+}
+func (UnimplementedShortenerServiceServer) WatchLinks(grpc.BidiStreamingServer[WatchLinksRequest, LinkEvent]) error {
+	return status.Error(codes.Unimplemented, "method WatchLinks not implemented")
+}
 func (UnimplementedShortenerServiceServer) mustEmbedUnimplementedShortenerServiceServer() {}
 func (UnimplementedShortenerServiceServer) testEmbeddedByValue()                          {}
 
@@ -173,6 +268,67 @@ func _ShortenerService_ListUserURLs_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortenerService_UpdateURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(URLUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).UpdateURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_UpdateURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).UpdateURL(ctx, req.(*URLUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerService_ListURLRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(URLRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).ListURLRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_ListURLRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).ListURLRevisions(ctx, req.(*URLRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerService_StreamUserURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamUserURLsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenerServiceServer).StreamUserURLs(m, &grpc.GenericServerStream[StreamUserURLsRequest, URLData]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_StreamUserURLsServer = grpc.ServerStreamingServer[URLData]
+
+func _ShortenerService_ShortenStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServiceServer).ShortenStream(&grpc.GenericServerStream[URLShortenRequest, ShortenStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_ShortenStreamServer = grpc.ClientStreamingServer[URLShortenRequest, ShortenStreamResponse]
+
+func _ShortenerService_WatchLinks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServiceServer).WatchLinks(&grpc.GenericServerStream[WatchLinksRequest, LinkEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_WatchLinksServer = grpc.BidiStreamingServer[WatchLinksRequest, LinkEvent]
+
 // ShortenerService_ServiceDesc is the grpc.ServiceDesc for ShortenerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -192,7 +348,32 @@ var ShortenerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListUserURLs",
 			Handler:    _ShortenerService_ListUserURLs_Handler,
 		},
+		{
+			MethodName: "UpdateURL",
+			Handler:    _ShortenerService_UpdateURL_Handler,
+		},
+		{
+			MethodName: "ListURLRevisions",
+			Handler:    _ShortenerService_ListURLRevisions_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUserURLs",
+			Handler:       _ShortenerService_StreamUserURLs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ShortenStream",
+			Handler:       _ShortenerService_ShortenStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchLinks",
+			Handler:       _ShortenerService_WatchLinks_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/shortener.proto",
 }
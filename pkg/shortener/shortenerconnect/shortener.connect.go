@@ -0,0 +1,313 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: pkg/shortener.proto
+
+package shortenerconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	shortener "github.com/spitfy/urlshortener/pkg/shortener"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// ShortenerServiceName is the fully-qualified name of the ShortenerService service.
+	ShortenerServiceName = "shortener.ShortenerService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// ShortenerServiceShortenURLProcedure is the fully-qualified name of the ShortenerService's
+	// ShortenURL RPC.
+	ShortenerServiceShortenURLProcedure = "/shortener.ShortenerService/ShortenURL"
+	// ShortenerServiceExpandURLProcedure is the fully-qualified name of the ShortenerService's
+	// ExpandURL RPC.
+	ShortenerServiceExpandURLProcedure = "/shortener.ShortenerService/ExpandURL"
+	// ShortenerServiceListUserURLsProcedure is the fully-qualified name of the ShortenerService's
+	// ListUserURLs RPC.
+	ShortenerServiceListUserURLsProcedure = "/shortener.ShortenerService/ListUserURLs"
+	// ShortenerServiceUpdateURLProcedure is the fully-qualified name of the ShortenerService's
+	// UpdateURL RPC.
+	ShortenerServiceUpdateURLProcedure = "/shortener.ShortenerService/UpdateURL"
+	// ShortenerServiceListURLRevisionsProcedure is the fully-qualified name of the ShortenerService's
+	// ListURLRevisions RPC.
+	ShortenerServiceListURLRevisionsProcedure = "/shortener.ShortenerService/ListURLRevisions"
+	// ShortenerServiceStreamUserURLsProcedure is the fully-qualified name of the ShortenerService's
+	// StreamUserURLs RPC.
+	ShortenerServiceStreamUserURLsProcedure = "/shortener.ShortenerService/StreamUserURLs"
+	// ShortenerServiceShortenStreamProcedure is the fully-qualified name of the ShortenerService's
+	// ShortenStream RPC.
+	ShortenerServiceShortenStreamProcedure = "/shortener.ShortenerService/ShortenStream"
+	// ShortenerServiceWatchLinksProcedure is the fully-qualified name of the ShortenerService's
+	// WatchLinks RPC.
+	ShortenerServiceWatchLinksProcedure = "/shortener.ShortenerService/WatchLinks"
+)
+
+// ShortenerServiceClient is a client for the shortener.ShortenerService service.
+type ShortenerServiceClient interface {
+	ShortenURL(context.Context, *connect.Request[shortener.URLShortenRequest]) (*connect.Response[shortener.URLShortenResponse], error)
+	ExpandURL(context.Context, *connect.Request[shortener.URLExpandRequest]) (*connect.Response[shortener.URLExpandResponse], error)
+	ListUserURLs(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[shortener.UserURLsResponse], error)
+	UpdateURL(context.Context, *connect.Request[shortener.URLUpdateRequest]) (*connect.Response[shortener.URLData], error)
+	ListURLRevisions(context.Context, *connect.Request[shortener.URLRevisionsRequest]) (*connect.Response[shortener.URLRevisionsResponse], error)
+	StreamUserURLs(context.Context, *connect.Request[shortener.StreamUserURLsRequest]) (*connect.ServerStreamForClient[shortener.URLData], error)
+	ShortenStream(context.Context) *connect.ClientStreamForClient[shortener.URLShortenRequest, shortener.ShortenStreamResponse]
+	WatchLinks(context.Context) *connect.BidiStreamForClient[shortener.WatchLinksRequest, shortener.LinkEvent]
+}
+
+// NewShortenerServiceClient constructs a client for the shortener.ShortenerService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewShortenerServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) ShortenerServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	shortenerServiceMethods := shortener.File_pkg_shortener_proto.Services().ByName("ShortenerService").Methods()
+	return &shortenerServiceClient{
+		shortenURL: connect.NewClient[shortener.URLShortenRequest, shortener.URLShortenResponse](
+			httpClient,
+			baseURL+ShortenerServiceShortenURLProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("ShortenURL")),
+			connect.WithClientOptions(opts...),
+		),
+		expandURL: connect.NewClient[shortener.URLExpandRequest, shortener.URLExpandResponse](
+			httpClient,
+			baseURL+ShortenerServiceExpandURLProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("ExpandURL")),
+			connect.WithClientOptions(opts...),
+		),
+		listUserURLs: connect.NewClient[emptypb.Empty, shortener.UserURLsResponse](
+			httpClient,
+			baseURL+ShortenerServiceListUserURLsProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("ListUserURLs")),
+			connect.WithClientOptions(opts...),
+		),
+		updateURL: connect.NewClient[shortener.URLUpdateRequest, shortener.URLData](
+			httpClient,
+			baseURL+ShortenerServiceUpdateURLProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("UpdateURL")),
+			connect.WithClientOptions(opts...),
+		),
+		listURLRevisions: connect.NewClient[shortener.URLRevisionsRequest, shortener.URLRevisionsResponse](
+			httpClient,
+			baseURL+ShortenerServiceListURLRevisionsProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("ListURLRevisions")),
+			connect.WithClientOptions(opts...),
+		),
+		streamUserURLs: connect.NewClient[shortener.StreamUserURLsRequest, shortener.URLData](
+			httpClient,
+			baseURL+ShortenerServiceStreamUserURLsProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("StreamUserURLs")),
+			connect.WithClientOptions(opts...),
+		),
+		shortenStream: connect.NewClient[shortener.URLShortenRequest, shortener.ShortenStreamResponse](
+			httpClient,
+			baseURL+ShortenerServiceShortenStreamProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("ShortenStream")),
+			connect.WithClientOptions(opts...),
+		),
+		watchLinks: connect.NewClient[shortener.WatchLinksRequest, shortener.LinkEvent](
+			httpClient,
+			baseURL+ShortenerServiceWatchLinksProcedure,
+			connect.WithSchema(shortenerServiceMethods.ByName("WatchLinks")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// shortenerServiceClient implements ShortenerServiceClient.
+type shortenerServiceClient struct {
+	shortenURL       *connect.Client[shortener.URLShortenRequest, shortener.URLShortenResponse]
+	expandURL        *connect.Client[shortener.URLExpandRequest, shortener.URLExpandResponse]
+	listUserURLs     *connect.Client[emptypb.Empty, shortener.UserURLsResponse]
+	updateURL        *connect.Client[shortener.URLUpdateRequest, shortener.URLData]
+	listURLRevisions *connect.Client[shortener.URLRevisionsRequest, shortener.URLRevisionsResponse]
+	streamUserURLs   *connect.Client[shortener.StreamUserURLsRequest, shortener.URLData]
+	shortenStream    *connect.Client[shortener.URLShortenRequest, shortener.ShortenStreamResponse]
+	watchLinks       *connect.Client[shortener.WatchLinksRequest, shortener.LinkEvent]
+}
+
+// ShortenURL calls shortener.ShortenerService.ShortenURL.
+func (c *shortenerServiceClient) ShortenURL(ctx context.Context, req *connect.Request[shortener.URLShortenRequest]) (*connect.Response[shortener.URLShortenResponse], error) {
+	return c.shortenURL.CallUnary(ctx, req)
+}
+
+// ExpandURL calls shortener.ShortenerService.ExpandURL.
+func (c *shortenerServiceClient) ExpandURL(ctx context.Context, req *connect.Request[shortener.URLExpandRequest]) (*connect.Response[shortener.URLExpandResponse], error) {
+	return c.expandURL.CallUnary(ctx, req)
+}
+
+// ListUserURLs calls shortener.ShortenerService.ListUserURLs.
+func (c *shortenerServiceClient) ListUserURLs(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[shortener.UserURLsResponse], error) {
+	return c.listUserURLs.CallUnary(ctx, req)
+}
+
+// UpdateURL calls shortener.ShortenerService.UpdateURL.
+func (c *shortenerServiceClient) UpdateURL(ctx context.Context, req *connect.Request[shortener.URLUpdateRequest]) (*connect.Response[shortener.URLData], error) {
+	return c.updateURL.CallUnary(ctx, req)
+}
+
+// ListURLRevisions calls shortener.ShortenerService.ListURLRevisions.
+func (c *shortenerServiceClient) ListURLRevisions(ctx context.Context, req *connect.Request[shortener.URLRevisionsRequest]) (*connect.Response[shortener.URLRevisionsResponse], error) {
+	return c.listURLRevisions.CallUnary(ctx, req)
+}
+
+// StreamUserURLs calls shortener.ShortenerService.StreamUserURLs.
+func (c *shortenerServiceClient) StreamUserURLs(ctx context.Context, req *connect.Request[shortener.StreamUserURLsRequest]) (*connect.ServerStreamForClient[shortener.URLData], error) {
+	return c.streamUserURLs.CallServerStream(ctx, req)
+}
+
+// ShortenStream calls shortener.ShortenerService.ShortenStream.
+func (c *shortenerServiceClient) ShortenStream(ctx context.Context) *connect.ClientStreamForClient[shortener.URLShortenRequest, shortener.ShortenStreamResponse] {
+	return c.shortenStream.CallClientStream(ctx)
+}
+
+// WatchLinks calls shortener.ShortenerService.WatchLinks.
+func (c *shortenerServiceClient) WatchLinks(ctx context.Context) *connect.BidiStreamForClient[shortener.WatchLinksRequest, shortener.LinkEvent] {
+	return c.watchLinks.CallBidiStream(ctx)
+}
+
+// ShortenerServiceHandler is an implementation of the shortener.ShortenerService service.
+type ShortenerServiceHandler interface {
+	ShortenURL(context.Context, *connect.Request[shortener.URLShortenRequest]) (*connect.Response[shortener.URLShortenResponse], error)
+	ExpandURL(context.Context, *connect.Request[shortener.URLExpandRequest]) (*connect.Response[shortener.URLExpandResponse], error)
+	ListUserURLs(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[shortener.UserURLsResponse], error)
+	UpdateURL(context.Context, *connect.Request[shortener.URLUpdateRequest]) (*connect.Response[shortener.URLData], error)
+	ListURLRevisions(context.Context, *connect.Request[shortener.URLRevisionsRequest]) (*connect.Response[shortener.URLRevisionsResponse], error)
+	StreamUserURLs(context.Context, *connect.Request[shortener.StreamUserURLsRequest], *connect.ServerStream[shortener.URLData]) error
+	ShortenStream(context.Context, *connect.ClientStream[shortener.URLShortenRequest]) (*connect.Response[shortener.ShortenStreamResponse], error)
+	WatchLinks(context.Context, *connect.BidiStream[shortener.WatchLinksRequest, shortener.LinkEvent]) error
+}
+
+// NewShortenerServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewShortenerServiceHandler(svc ShortenerServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	shortenerServiceMethods := shortener.File_pkg_shortener_proto.Services().ByName("ShortenerService").Methods()
+	shortenerServiceShortenURLHandler := connect.NewUnaryHandler(
+		ShortenerServiceShortenURLProcedure,
+		svc.ShortenURL,
+		connect.WithSchema(shortenerServiceMethods.ByName("ShortenURL")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceExpandURLHandler := connect.NewUnaryHandler(
+		ShortenerServiceExpandURLProcedure,
+		svc.ExpandURL,
+		connect.WithSchema(shortenerServiceMethods.ByName("ExpandURL")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceListUserURLsHandler := connect.NewUnaryHandler(
+		ShortenerServiceListUserURLsProcedure,
+		svc.ListUserURLs,
+		connect.WithSchema(shortenerServiceMethods.ByName("ListUserURLs")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceUpdateURLHandler := connect.NewUnaryHandler(
+		ShortenerServiceUpdateURLProcedure,
+		svc.UpdateURL,
+		connect.WithSchema(shortenerServiceMethods.ByName("UpdateURL")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceListURLRevisionsHandler := connect.NewUnaryHandler(
+		ShortenerServiceListURLRevisionsProcedure,
+		svc.ListURLRevisions,
+		connect.WithSchema(shortenerServiceMethods.ByName("ListURLRevisions")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceStreamUserURLsHandler := connect.NewServerStreamHandler(
+		ShortenerServiceStreamUserURLsProcedure,
+		svc.StreamUserURLs,
+		connect.WithSchema(shortenerServiceMethods.ByName("StreamUserURLs")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceShortenStreamHandler := connect.NewClientStreamHandler(
+		ShortenerServiceShortenStreamProcedure,
+		svc.ShortenStream,
+		connect.WithSchema(shortenerServiceMethods.ByName("ShortenStream")),
+		connect.WithHandlerOptions(opts...),
+	)
+	shortenerServiceWatchLinksHandler := connect.NewBidiStreamHandler(
+		ShortenerServiceWatchLinksProcedure,
+		svc.WatchLinks,
+		connect.WithSchema(shortenerServiceMethods.ByName("WatchLinks")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/shortener.ShortenerService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case ShortenerServiceShortenURLProcedure:
+			shortenerServiceShortenURLHandler.ServeHTTP(w, r)
+		case ShortenerServiceExpandURLProcedure:
+			shortenerServiceExpandURLHandler.ServeHTTP(w, r)
+		case ShortenerServiceListUserURLsProcedure:
+			shortenerServiceListUserURLsHandler.ServeHTTP(w, r)
+		case ShortenerServiceUpdateURLProcedure:
+			shortenerServiceUpdateURLHandler.ServeHTTP(w, r)
+		case ShortenerServiceListURLRevisionsProcedure:
+			shortenerServiceListURLRevisionsHandler.ServeHTTP(w, r)
+		case ShortenerServiceStreamUserURLsProcedure:
+			shortenerServiceStreamUserURLsHandler.ServeHTTP(w, r)
+		case ShortenerServiceShortenStreamProcedure:
+			shortenerServiceShortenStreamHandler.ServeHTTP(w, r)
+		case ShortenerServiceWatchLinksProcedure:
+			shortenerServiceWatchLinksHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedShortenerServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedShortenerServiceHandler struct{}
+
+func (UnimplementedShortenerServiceHandler) ShortenURL(context.Context, *connect.Request[shortener.URLShortenRequest]) (*connect.Response[shortener.URLShortenResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.ShortenURL is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) ExpandURL(context.Context, *connect.Request[shortener.URLExpandRequest]) (*connect.Response[shortener.URLExpandResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.ExpandURL is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) ListUserURLs(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[shortener.UserURLsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.ListUserURLs is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) UpdateURL(context.Context, *connect.Request[shortener.URLUpdateRequest]) (*connect.Response[shortener.URLData], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.UpdateURL is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) ListURLRevisions(context.Context, *connect.Request[shortener.URLRevisionsRequest]) (*connect.Response[shortener.URLRevisionsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.ListURLRevisions is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) StreamUserURLs(context.Context, *connect.Request[shortener.StreamUserURLsRequest], *connect.ServerStream[shortener.URLData]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.StreamUserURLs is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) ShortenStream(context.Context, *connect.ClientStream[shortener.URLShortenRequest]) (*connect.Response[shortener.ShortenStreamResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.ShortenStream is not implemented"))
+}
+
+func (UnimplementedShortenerServiceHandler) WatchLinks(context.Context, *connect.BidiStream[shortener.WatchLinksRequest, shortener.LinkEvent]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("shortener.ShortenerService.WatchLinks is not implemented"))
+}